@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/binname"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/configstate"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/pkgselect"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/nao1215/gup/internal/updateplan"
+	"github.com/spf13/cobra"
+)
+
+func newApplyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apply PLAN",
+		Short: "Install exactly the updates in a plan written by 'gup update --plan'",
+		Long: `Install exactly the updates in a plan written by 'gup update --plan'.
+
+apply installs every binary in PLAN at the exact version recorded there; no
+update channel is resolved again. Before installing anything, apply checks
+that each binary is still installed at the version the plan was made
+against. If any binary changed since planning, nothing is installed: make a
+new plan.
+
+When the plan was made with --main, --master or --latest, the channels are
+saved to gup.json as 'gup update' would save them. A binary with a Go
+toolchain saved in gup.json is built with that toolchain, like 'gup update'
+does.`,
+		Example: `  gup update --plan plan.json
+  gup apply plan.json`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return []string{"json"}, cobra.ShellCompDirectiveFilterFileExt
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(runApply(defaultDependencies(), printerFor(cmd), cmd, args))
+		},
+	}
+
+	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to save update channels to")
+	mustMarkFileFlagAsJSON(cmd)
+	cmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "specify the number of CPU cores to use")
+	mustRegisterFlagCompletion(cmd, "jobs", completeNCPUs)
+	addTimeoutFlag(cmd)
+
+	return cmd
+}
+
+func runApply(deps dependencies, p *print.Printer, cmd *cobra.Command, args []string) int {
+	if err := ensureGoCommandAvailable(); err != nil {
+		p.Err(err)
+		return 1
+	}
+	confFile, err := getFlagString(cmd, "file")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	cpus, err := getFlagInt(cmd, "jobs")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	cpus = clampJobs(cpus)
+	timeout, err := getTimeoutFlag(cmd)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	plan, err := updateplan.Read(args[0])
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if len(plan.Updates) == 0 {
+		p.Info("nothing to apply: " + args[0] + " plans no update")
+		return 0
+	}
+
+	names := make([]string, 0, len(plan.Updates))
+	for _, e := range plan.Updates {
+		names = append(names, e.Binary)
+	}
+	installed, _, _, err := pkgselect.PackageInfoByTargets(p, names)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	pkgs, moved, err := plannedPackages(plan, installed)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	confPkgs, err := readSavedConfig(confFile)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	pkgs = configstate.ApplyGoToolchains(pkgs, confPkgs, "")
+
+	p.Info(fmt.Sprintf("apply %s (planned %s)", args[0], plan.CreatedAt.Local().Format(time.DateTime)))
+	result, results := applyPlan(deps, p, pkgs, moved, cpus, timeout)
+
+	succeededPkgs, renamedPkgs := succeededAndRenamed(results)
+	if plan.Channels != nil || len(renamedPkgs) > 0 {
+		if err := saveAppliedChannels(confFile, plan, succeededPkgs, renamedPkgs); err != nil {
+			p.Warn(err)
+		}
+	}
+	return result
+}
+
+// plannedPackages pairs every planned update with the installed binary it was
+// planned against. It fails, listing every binary, when one is no longer
+// installed or is now at another version than the plan's "from": installing
+// the plan then would not be the update that was reviewed.
+//
+// moved maps the name of every binary whose module moved since it was
+// installed to its installed import path.
+func plannedPackages(plan updateplan.Plan, installed []goutil.Package) (pkgs []goutil.Package, moved map[string]string, err error) {
+	byName := make(map[string]goutil.Package, len(installed))
+	for _, pkg := range installed {
+		byName[binname.NormalizeForMatch(pkg.Name)] = pkg
+	}
+
+	pkgs = make([]goutil.Package, 0, len(plan.Updates))
+	moved = map[string]string{}
+	problems := []string{}
+	for _, e := range plan.Updates {
+		pkg, ok := byName[binname.NormalizeForMatch(e.Binary)]
+		if !ok {
+			problems = append(problems, e.Binary+" is no longer installed")
+			continue
+		}
+		current := ""
+		if pkg.Version != nil {
+			current = strings.TrimSpace(pkg.Version.Current)
+		}
+		if current != e.From {
+			problems = append(problems, fmt.Sprintf("%s is installed at %s, but the plan was made against %s", e.Binary, current, e.From))
+			continue
+		}
+
+		channel := goutil.NormalizeUpdateChannel(e.Channel)
+		planned := goutil.Package{
+			Name:          pkg.Name,
+			ImportPath:    e.ImportPath,
+			Version:       &goutil.Version{Current: current, Latest: e.To},
+			GoVersion:     pkg.GoVersion,
+			UpdateChannel: channel,
+		}
+		// The installed import path differs only when the module moved; the
+		// binary may then be renamed, and the old one is removed like update does.
+		// The module path of the new import path is not known until it installs.
+		if pkg.ImportPath != e.ImportPath {
+			moved[pkg.Name] = pkg.ImportPath
+		} else {
+			planned.ModulePath = pkg.ModulePath
+		}
+		if channel == goutil.UpdateChannelPinned {
+			planned.PinnedVersion = e.To
+		}
+		pkgs = append(pkgs, planned)
+	}
+	if len(problems) > 0 {
+		return nil, nil, errors.New("the installed binaries changed since the plan was made; nothing was installed:\n  " +
+			strings.Join(problems, "\n  ") + "\nrun 'gup update --plan' again")
+	}
+	return pkgs, moved, nil
+}
+
+// applyPlan installs every planned package at its Version.Latest. A package
+// named in moved was planned under a moved module path (see plannedPackages)
+// and may install under a new binary name.
+func applyPlan(deps dependencies, pr *print.Printer, pkgs []goutil.Package, moved map[string]string, cpus int, timeout time.Duration) (int, []updateResult) {
+	installer := func(ctx context.Context, deps dependencies, p goutil.Package) updateResult {
+		deps.toolchain = p.GoToolchain
+		originalName := p.Name
+		if err := deps.installVersion(ctx, p.ImportPath, p.Version.Latest); err != nil {
+			return updateResult{pkg: p, err: fmt.Errorf("%s: %w", p.Name, err), status: statusError}
+		}
+
+		var renamed string
+		if _, ok := moved[originalName]; ok {
+			newName := binaryNameFromImportPath(p.ImportPath)
+			if err := removeOldBinaryIfRenamed(originalName, newName); err != nil {
+				return updateResult{pkg: p, err: fmt.Errorf("%s: %w", originalName, err), status: statusError}
+			}
+			if newName != originalName {
+				p.Name = newName
+				renamed = originalName
+			}
+		}
+		if p.IsPinned() {
+			p.Version.Current = p.Version.Latest
+		}
+		if p.GoVersion != nil {
+			p.GoVersion.Current = p.GoVersion.Latest
+		}
+		return updateResult{updated: true, pkg: p, renamedFrom: renamed, status: statusUpdated}
+	}
+
+	return executePackages(pr, pkgs, cpus, timeout, deps, installer, resultLineRenderer(pr, false,
+		func(updateResult) bool { return true }, updateResultStr))
+}
+
+// readSavedConfig reads the gup.json apply saves channels to, or nothing when
+// there is none yet.
+func readSavedConfig(confFile string) ([]goutil.Package, error) {
+	confReadPath, err := config.ResolveImportFilePath(confFile)
+	if err != nil {
+		return nil, err
+	}
+	return configstate.ReadFileIfExists(confReadPath)
+}
+
+// saveAppliedChannels saves the channels recorded in plan, and any binary
+// renamed by a moved module, to gup.json, like 'gup update' does.
+func saveAppliedChannels(confFile string, plan updateplan.Plan, succeeded []goutil.Package, renamed map[string]string) error {
+	confReadPath, err := config.ResolveImportFilePath(confFile)
+	if err != nil {
+		return err
+	}
+	confPkgs, err := configstate.ReadFileIfExists(confReadPath)
+	if err != nil {
+		return err
+	}
+	channelMap := make(map[string]goutil.UpdateChannel, len(plan.Channels))
+	for name, channel := range plan.Channels {
+		channelMap[name] = goutil.NormalizeUpdateChannel(channel)
+	}
+	merged := configstate.MergePackages(confPkgs, succeeded, channelMap, renamed)
+	writePath := configstate.ResolveWritePath(confFile, confReadPath)
+	if err := writeConfigFile(writePath, merged); err != nil {
+		return fmt.Errorf("failed to write %s: %w", writePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/configstate"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/pkgselect"
+	"github.com/nao1215/gup/internal/print"
+)
+
+// autoExportEnabled reports whether the auto_export setting is on, from
+// settings.json or the GUP_AUTO_EXPORT environment variable.
+func autoExportEnabled() (bool, error) {
+	s, err := config.ReadSettings()
+	if err != nil {
+		return false, err
+	}
+	return s.AutoExport, nil
+}
+
+// autoExportInstalled merges installed into the gup.json resolved from
+// confFile, like 'gup update' persists its results. Each package keeps the
+// channel, pin and Go toolchain gup.json already records for it; a package new
+// to gup.json is recorded on @latest.
+func autoExportInstalled(confFile string, installed []goutil.Package) error {
+	confReadPath, err := config.ResolveImportFilePath(confFile)
+	if err != nil {
+		return err
+	}
+	confPkgs, err := configstate.ReadFileIfExists(confReadPath)
+	if err != nil {
+		return err
+	}
+	installed = configstate.ApplySavedChannels(installed, confPkgs)
+	merged := configstate.MergePackages(confPkgs, installed, nil, nil)
+	writePath := configstate.ResolveWritePath(confFile, confReadPath)
+	if err := writeConfigFile(writePath, merged); err != nil {
+		return fmt.Errorf("failed to write %s: %w", writePath, err)
+	}
+	return nil
+}
+
+// autoExportRemoved deletes the entries of the removed binaries from the
+// gup.json resolved from confFile. Without a gup.json there is nothing to do.
+func autoExportRemoved(confFile string, removed []string) error {
+	confReadPath, err := config.ResolveImportFilePath(confFile)
+	if err != nil {
+		return err
+	}
+	if !fileutil.IsFile(confReadPath) {
+		return nil
+	}
+	confPkgs, err := configstate.ReadFileIfExists(confReadPath)
+	if err != nil {
+		return err
+	}
+	kept := configstate.RemovePackages(confPkgs, removed)
+	if len(kept) == len(confPkgs) {
+		return nil
+	}
+	writePath := configstate.ResolveWritePath(confFile, confReadPath)
+	if err := writeConfigFile(writePath, kept); err != nil {
+		return fmt.Errorf("failed to write %s: %w", writePath, err)
+	}
+	return nil
+}
+
+// autoExportMarkRemoved marks the entries of the removed binaries as removed in
+// the gup.json resolved from confFile, and returns the names it marked.
+// Without a gup.json there is nothing to do.
+func autoExportMarkRemoved(confFile string, removed []string) ([]string, error) {
+	confReadPath, err := config.ResolveImportFilePath(confFile)
+	if err != nil {
+		return nil, err
+	}
+	if !fileutil.IsFile(confReadPath) {
+		return nil, nil
+	}
+	confPkgs, err := configstate.ReadFileIfExists(confReadPath)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, marked := configstate.MarkRemoved(confPkgs, removed)
+	if len(marked) == 0 {
+		return nil, nil
+	}
+	writePath := configstate.ResolveWritePath(confFile, confReadPath)
+	if err := writeConfigFile(writePath, pkgs); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", writePath, err)
+	}
+	return marked, nil
+}
+
+// autoExportDir records the go-install binaries under dir (narrowed to names,
+// when given) in gup.json. migrate uses it for AFTER_PATH, the new $GOBIN.
+func autoExportDir(p *print.Printer, dir string, names []string) error {
+	binList, err := goutil.BinaryPathList(dir)
+	if err != nil {
+		return fmt.Errorf("can't read binaries under %s: %w", dir, err)
+	}
+	pkgs := goutil.GetPackageInformationWithoutGoVersion(p, pkgselect.FilterBinaryPaths(binList, names))
+	if len(pkgs) == 0 {
+		return nil
+	}
+	return autoExportInstalled("", pkgs)
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// readConfSummary reads the gup.json at path as name -> "import@version channel".
+func readConfSummary(t *testing.T, path string) map[string]string {
+	t.Helper()
+	pkgs, err := config.ReadConfFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, pkg := range pkgs {
+		got[pkg.Name] = pkg.ImportPath + "@" + pkg.Version.Current + " " + string(pkg.UpdateChannel)
+	}
+	return got
+}
+
+//nolint:paralleltest // swaps XDG env and the working directory
+func Test_autoExportInstalled(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+
+	writePath, err := config.ResolveImportFilePath("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfigFile(writePath, []goutil.Package{
+		{Name: "kept", ImportPath: "example.com/kept", Version: &goutil.Version{Current: "v0.1.0"}, UpdateChannel: goutil.UpdateChannelPinned, PinnedVersion: "v0.1.0"},
+		{Name: "tool", ImportPath: "example.com/tool", Version: &goutil.Version{Current: "v1.0.0"}, UpdateChannel: goutil.UpdateChannelMain},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := autoExportInstalled("", []goutil.Package{
+		{Name: "tool", ImportPath: "example.com/tool", Version: &goutil.Version{Current: "v1.1.0"}},
+		{Name: "new", ImportPath: "example.com/new", Version: &goutil.Version{Current: "v2.0.0"}},
+	}); err != nil {
+		t.Fatalf("autoExportInstalled() error = %v", err)
+	}
+
+	want := map[string]string{
+		"kept": "example.com/kept@v0.1.0 pinned",
+		"tool": "example.com/tool@v1.1.0 main",
+		"new":  "example.com/new@v2.0.0 latest",
+	}
+	if diff := cmp.Diff(want, readConfSummary(t, writePath)); diff != "" {
+		t.Errorf("gup.json mismatch (-want +got):\n%s", diff)
+	}
+}
+
+//nolint:paralleltest // swaps XDG env and the working directory
+func Test_autoExportRemoved_noConfig(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+
+	if err := autoExportRemoved("", []string{"tool"}); err != nil {
+		t.Fatalf("autoExportRemoved() error = %v", err)
+	}
+	writePath, err := config.ResolveImportFilePath("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileutil.IsFile(writePath) {
+		t.Errorf("autoExportRemoved() created %s", writePath)
+	}
+}
+
+//nolint:paralleltest // swaps XDG env, GOBIN and GUP_AUTO_EXPORT
+func Test_remove_autoExport(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+	t.Setenv(config.AutoExportEnv, "1")
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+
+	name := "tool"
+	if GOOS == goosWindows {
+		name += dotExe
+	}
+	if err := os.WriteFile(filepath.Join(gobin, name), []byte("bin"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	writePath, err := config.ResolveImportFilePath("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfigFile(writePath, []goutil.Package{
+		{Name: "kept", ImportPath: "example.com/kept", Version: &goutil.Version{Current: "v0.1.0"}, UpdateChannel: goutil.UpdateChannelPinned, PinnedVersion: "v0.1.0"},
+		{Name: "tool", ImportPath: "example.com/tool", Version: &goutil.Version{Current: "v1.0.0"}, UpdateChannel: goutil.UpdateChannelLatest},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := newRemoveCmd()
+	if err := cmd.ParseFlags([]string{"--force"}); err != nil {
+		t.Fatal(err)
+	}
+	p, buf := newTestPrinter()
+	if got := remove(p, cmd, []string{"tool"}); got != 0 {
+		t.Fatalf("remove() = %d, want 0; output:\n%s", got, buf.String())
+	}
+
+	want := map[string]string{"kept": "example.com/kept@v0.1.0 pinned"}
+	if diff := cmp.Diff(want, readConfSummary(t, writePath)); diff != "" {
+		t.Errorf("gup.json mismatch (-want +got):\n%s", diff)
+	}
+}
+
+//nolint:paralleltest // swaps XDG env and GUP_AUTO_EXPORT
+func Test_remove_invalidAutoExport(t *testing.T) {
+	setupXDGBase(t)
+	t.Setenv(config.AutoExportEnv, "maybe")
+
+	cmd := newRemoveCmd()
+	if err := cmd.ParseFlags([]string{"--force"}); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := newTestPrinter()
+	if got := remove(p, cmd, []string{"tool"}); got != 1 {
+		t.Errorf("remove() = %d, want 1", got)
+	}
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nao1215/gup/internal/buildhist"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/parallel"
+	"github.com/nao1215/gup/internal/print"
+)
+
+// jobLimits splits the workers of an update into version lookups and builds:
+// lookupJobs lookups and buildJobs 'go install' runs at a time, where 0 means
+// jobs. pool is the number of workers, enough to keep both busy.
+//
+// With buildJobs given, each install also gets its share of the machine as
+// hints: numCPU/buildJobs for -p and GOMAXPROCS, and mem/buildJobs for
+// GOMEMLIMIT when mem (in bytes) is known. Without it, the go command sizes
+// itself as it always has.
+func jobLimits(jobs, lookupJobs, buildJobs, numCPU int, mem int64) (pool, lookup, build int, hints goutil.BuildLimits) {
+	lookup, build = jobs, jobs
+	if lookupJobs > 0 {
+		lookup = lookupJobs
+	}
+	if buildJobs > 0 {
+		build = buildJobs
+		hints.Procs = max(numCPU/buildJobs, 1)
+		if mem > 0 {
+			hints.MemLimit = mem / int64(buildJobs)
+		}
+	}
+	return max(lookup, build), lookup, build, hints
+}
+
+// limitJobs returns d with at most lookup version lookups and build installs
+// (see dependencies.install) running at once out of pool workers, each install
+// run with hints. The wait for a turn does not count toward the per-package
+// timeout, nor toward the time retries may take.
+func (d dependencies) limitJobs(pool, lookup, build int, hints goutil.BuildLimits) dependencies {
+	lookups := newJobSlots(lookup, pool)
+	d.buildSlots = newJobSlots(build, pool)
+	d.buildLimits = hints
+
+	getLatestVer, getVerByRef := d.getLatestVer, d.getVerByRef
+	d.getLatestVer = func(ctx context.Context, modulePath, toolchain string) (string, error) {
+		if err := lookups.acquire(ctx); err != nil {
+			return "", err
+		}
+		defer lookups.release()
+		return getLatestVer(ctx, modulePath, toolchain)
+	}
+	d.getVerByRef = func(ctx context.Context, modulePath, ref, toolchain string) (string, error) {
+		if err := lookups.acquire(ctx); err != nil {
+			return "", err
+		}
+		defer lookups.release()
+		return getVerByRef(ctx, modulePath, ref, toolchain)
+	}
+	return d
+}
+
+// jobSlots lets a limited number of operations run at once. A nil jobSlots
+// lets all of them run.
+type jobSlots chan struct{}
+
+// newJobSlots returns slots for limit operations, or nil when the pool of
+// workers can't run more than limit anyway.
+func newJobSlots(limit, pool int) jobSlots {
+	if limit >= pool {
+		return nil
+	}
+	return make(jobSlots, limit)
+}
+
+// acquire waits for a free slot, or for ctx to be done. The per-package
+// timeout of ctx is paused while it waits.
+func (s jobSlots) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	resume := parallel.Pause(ctx)
+	defer resume()
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees the slot taken by acquire.
+func (s jobSlots) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+// memoryBudget returns the memory the builds of an update may share, in bytes:
+// gup's own GOMEMLIMIT when it is set, else the physical memory. It returns 0
+// when neither is known.
+func memoryBudget() int64 {
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		return limit
+	}
+	return physicalMemory()
+}
+
+// physicalMemory returns MemTotal from /proc/meminfo, or 0 on systems without
+// it.
+func physicalMemory() int64 {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "MemTotal:" && fields[2] == "kB" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0
+			}
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+// buildHistoryPath returns the path of the build history in gup's state
+// directory.
+func buildHistoryPath() string {
+	return filepath.Join(config.StateDirPath(), buildhist.FileName)
+}
+
+// buildTimes keeps how long the install of each binary took, so an update
+// starts the longest builds first. A nil *buildTimes orders nothing and
+// records nothing.
+type buildTimes struct {
+	mu      sync.Mutex
+	path    string
+	hist    buildhist.History
+	changed bool
+}
+
+// loadBuildTimes reads the build history at path. A history gup can't read is
+// only a warning: the update runs in its own order and starts a new history.
+func loadBuildTimes(p *print.Printer, path string) *buildTimes {
+	hist, err := buildhist.Read(path)
+	if err != nil {
+		p.Warn(err)
+		hist = buildhist.New()
+	}
+	return &buildTimes{path: path, hist: hist}
+}
+
+// order returns the order to start pkgs in, longest known build first, or nil
+// to keep their order.
+func (b *buildTimes) order(pkgs []goutil.Package) []int {
+	if b == nil {
+		return nil
+	}
+	names := make([]string, len(pkgs))
+	for i, p := range pkgs {
+		names[i] = p.Name
+	}
+	return b.hist.Order(names)
+}
+
+// durations returns the last build time of each of names that has one, or
+// nil on a nil *buildTimes.
+func (b *buildTimes) durations(names []string) map[string]time.Duration {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	builds := map[string]time.Duration{}
+	for _, name := range names {
+		if d := b.hist.Duration(name); d > 0 {
+			builds[name] = d
+		}
+	}
+	return builds
+}
+
+// worker wraps worker so the build time of every package that installed
+// without an error is recorded. It returns worker as it is on a nil
+// *buildTimes.
+func (b *buildTimes) worker(worker pkgWorker) pkgWorker {
+	if b == nil {
+		return worker
+	}
+	return func(ctx context.Context, deps dependencies, pkg goutil.Package) updateResult {
+		var clock time.Duration
+		deps.buildClock = &clock
+		v := worker(ctx, deps, pkg)
+		if v.err == nil && clock > 0 {
+			b.mu.Lock()
+			b.hist.Record(v.pkg.Name, clock)
+			b.changed = true
+			b.mu.Unlock()
+		}
+		return v
+	}
+}
+
+// save writes the history when the update recorded a build. A failure is only
+// a warning.
+func (b *buildTimes) save(p *print.Printer) {
+	if b == nil || !b.changed {
+		return
+	}
+	if err := buildhist.Write(b.path, b.hist); err != nil {
+		p.Warn(err)
+	}
+}
+
+// inOrder returns items rearranged so that item order[i] comes i-th. A nil
+// order keeps items as they are.
+func inOrder[T any](items []T, order []int) []T {
+	if order == nil {
+		return items
+	}
+	out := make([]T, len(items))
+	for i, from := range order {
+		out[i] = items[from]
+	}
+	return out
+}
+
+// fromOrder undoes inOrder: it puts the i-th of items back at order[i].
+func fromOrder[T any](items []T, order []int) []T {
+	if order == nil {
+		return items
+	}
+	out := make([]T, len(items))
+	for i, to := range order {
+		out[to] = items[i]
+	}
+	return out
+}
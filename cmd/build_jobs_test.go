@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/buildhist"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+func Test_jobLimits(t *testing.T) {
+	t.Parallel()
+
+	const gib = 1 << 30
+	tests := []struct {
+		name                  string
+		jobs, lookup, build   int
+		mem                   int64
+		wantPool              int
+		wantLookup, wantBuild int
+		wantHints             goutil.BuildLimits
+	}{
+		{name: "jobs only", jobs: 8, wantPool: 8, wantLookup: 8, wantBuild: 8},
+		{name: "fewer builds", jobs: 16, build: 2, mem: 16 * gib, wantPool: 16, wantLookup: 16, wantBuild: 2, wantHints: goutil.BuildLimits{Procs: 4, MemLimit: 8 * gib}},
+		{name: "more lookups than jobs", jobs: 4, lookup: 32, build: 2, wantPool: 32, wantLookup: 32, wantBuild: 2, wantHints: goutil.BuildLimits{Procs: 4}},
+		{name: "more builds than CPUs", jobs: 4, build: 16, wantPool: 16, wantLookup: 4, wantBuild: 16, wantHints: goutil.BuildLimits{Procs: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pool, lookup, build, hints := jobLimits(tt.jobs, tt.lookup, tt.build, 8, tt.mem)
+			if pool != tt.wantPool || lookup != tt.wantLookup || build != tt.wantBuild || hints != tt.wantHints {
+				t.Errorf("jobLimits() = %d, %d, %d, %+v; want %d, %d, %d, %+v",
+					pool, lookup, build, hints, tt.wantPool, tt.wantLookup, tt.wantBuild, tt.wantHints)
+			}
+		})
+	}
+}
+
+func Test_limitJobs_boundsBuilds(t *testing.T) {
+	t.Parallel()
+
+	var running, most atomic.Int32
+	deps := testDeps()
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}
+	var lookups atomic.Int32
+	deps.getLatestVer = func(context.Context, string, string) (string, error) {
+		lookups.Add(1)
+		return testVersionOne, nil
+	}
+	deps = deps.limitJobs(4, 4, 2, goutil.BuildLimits{})
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = deps.getLatestVer(context.Background(), testImportExampleTool, "")
+			_ = deps.installVersion(context.Background(), testImportExampleTool, testVersionOne)
+		}()
+	}
+	wg.Wait()
+	if got := most.Load(); got > 2 {
+		t.Errorf("%d installs ran at once, want at most 2", got)
+	}
+	if got := lookups.Load(); got != 8 {
+		t.Errorf("%d lookups ran, want 8", got)
+	}
+}
+
+func Test_limitJobs_canceledWhileWaiting(t *testing.T) {
+	t.Parallel()
+
+	entered, release := make(chan struct{}), make(chan struct{})
+	deps := testDeps()
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error {
+		close(entered)
+		<-release
+		return nil
+	}
+	deps = deps.limitJobs(2, 2, 1, goutil.BuildLimits{})
+
+	installLatest := func(ctx context.Context) error {
+		return deps.install(ctx, func(ctx context.Context, opts goutil.InstallOptions) error {
+			return deps.installLatest(ctx, testImportExampleTool, opts)
+		})
+	}
+	done := make(chan error, 1)
+	go func() { done <- installLatest(context.Background()) }()
+	// The first install holds the only build slot.
+	<-entered
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := installLatest(ctx); err == nil {
+		t.Error("installLatest() waiting for a build slot error = nil, want the context error")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("first installLatest() error = %v", err)
+	}
+}
+
+func Test_limitJobs_waitIsNotTimed(t *testing.T) {
+	t.Parallel()
+
+	const build, timeout = 40 * time.Millisecond, 100 * time.Millisecond
+	deps := testDeps()
+	deps.installLatest = func(ctx context.Context, _ string, _ goutil.InstallOptions) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(build):
+			return nil
+		}
+	}
+	// One build slot for four workers: the last package waits three builds,
+	// longer than its timeout, before its own starts.
+	deps = deps.limitJobs(4, 4, 1, goutil.BuildLimits{})
+	worker := func(ctx context.Context, deps dependencies, pkg goutil.Package) updateResult {
+		err := deps.install(ctx, func(ctx context.Context, opts goutil.InstallOptions) error {
+			return deps.installLatest(ctx, pkg.ImportPath, opts)
+		})
+		if err != nil {
+			return updateResult{pkg: pkg, err: err}
+		}
+		return updateResult{pkg: pkg, updated: true, status: statusUpdated}
+	}
+	pkgs := []goutil.Package{
+		{Name: "a", ImportPath: "example.com/a"},
+		{Name: "b", ImportPath: "example.com/b"},
+		{Name: "c", ImportPath: "example.com/c"},
+		{Name: "d", ImportPath: "example.com/d"},
+	}
+	p, _ := newTestPrinter()
+	if result, results := executePackages(p, pkgs, 4, timeout, deps, worker, nil); result != 0 {
+		for _, v := range results {
+			if v.err != nil {
+				t.Errorf("%s: %v", v.pkg.Name, v.err)
+			}
+		}
+	}
+}
+
+func Test_buildTimes_ordersAndRecords(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), buildhist.FileName)
+	hist := buildhist.New()
+	hist.Record("slow", time.Minute)
+	if err := buildhist.Write(path, hist); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := newTestPrinter()
+	builds := loadBuildTimes(p, path)
+	if diff := cmp.Diff(map[string]time.Duration{"slow": time.Minute}, builds.durations([]string{"fast", "slow"})); diff != "" {
+		t.Errorf("durations() mismatch (-want +got):\n%s", diff)
+	}
+
+	deps := testDeps()
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}
+	deps = deps.limitJobs(1, 1, 1, goutil.BuildLimits{})
+
+	var started []string
+	worker := func(ctx context.Context, deps dependencies, pkg goutil.Package) updateResult {
+		started = append(started, pkg.Name)
+		err := deps.install(ctx, func(ctx context.Context, opts goutil.InstallOptions) error {
+			return deps.installLatest(ctx, pkg.ImportPath, opts)
+		})
+		if err != nil {
+			return updateResult{pkg: pkg, err: err}
+		}
+		return updateResult{pkg: pkg, updated: true, status: statusUpdated}
+	}
+	pkgs := []goutil.Package{
+		{Name: "fast", ImportPath: "example.com/fast"},
+		{Name: "slow", ImportPath: "example.com/slow"},
+	}
+	deps.builds = builds
+	_, succeeded, _ := runUpdates(context.Background(), p, deps, pkgs, 1, 0, worker, false, true, false, nil)
+
+	if diff := cmp.Diff([]string{"slow", "fast"}, started); diff != "" {
+		t.Errorf("start order mismatch (-want +got):\n%s", diff)
+	}
+	var names []string
+	for _, v := range succeeded {
+		names = append(names, v.Name)
+	}
+	if diff := cmp.Diff([]string{"fast", "slow"}, names); diff != "" {
+		t.Errorf("results are not in the order of pkgs (-want +got):\n%s", diff)
+	}
+
+	got, err := buildhist.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"fast", "slow"} {
+		if d := got.Duration(name); d < 5*time.Millisecond || d >= time.Minute {
+			t.Errorf("recorded build of %s = %v, want the time of this install", name, d)
+		}
+	}
+}
+
+func Test_inOrder_fromOrder(t *testing.T) {
+	t.Parallel()
+
+	items := []string{"a", "b", "c"}
+	order := []int{2, 0, 1}
+	in := inOrder(items, order)
+	if diff := cmp.Diff([]string{"c", "a", "b"}, in); diff != "" {
+		t.Errorf("inOrder() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(items, fromOrder(in, order)); diff != "" {
+		t.Errorf("fromOrder() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(items, inOrder(items, nil)); diff != "" {
+		t.Errorf("inOrder() with a nil order mismatch (-want +got):\n%s", diff)
+	}
+}
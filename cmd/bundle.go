@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/bundle"
+	"github.com/nao1215/gup/internal/cmdinfo"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+func newBundleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Pack the modules of every gup.json entry into one archive for offline installs",
+		Long: `Pack the modules of every gup.json entry into one archive for offline installs.
+
+bundle installs every tool listed in gup.json into a throwaway GOBIN and
+GOMODCACHE, then packs the module files 'go install' downloaded (.info, .mod,
+.zip and @v/list, in GOPROXY layout) and the checksum database records it
+looked up together with a gup.json into one tar.gz archive. Entries recorded as "latest" are written to the bundled
+gup.json with the version that was actually resolved, so the archive is
+self-contained.
+
+Copy the archive to a machine without network access and run
+'gup import --bundle FILE' there. The archive carries a manifest with the
+SHA-256 of every file, which import verifies before installing anything, and
+'go install' checks every module against the bundled checksum database
+records as it would online.
+
+bundle needs access to the module proxy configured by GOPROXY (or to the
+origin repositories with GOPROXY=direct) on the machine that builds it.`,
+		Example: `  gup bundle -o tools.tar.gz
+  gup bundle -o tools.tar.gz --file gup.json`,
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(runBundle(printerFor(cmd), cmd, args))
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "", "archive path to write (required)")
+	mustMarkFlagAsBundle(cmd, "output")
+	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to bundle")
+	mustMarkFileFlagAsJSON(cmd)
+	cmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "specify the number of CPU cores to use")
+	mustRegisterFlagCompletion(cmd, "jobs", completeNCPUs)
+	addTimeoutFlag(cmd)
+
+	return cmd
+}
+
+func runBundle(p *print.Printer, cmd *cobra.Command, _ []string) int {
+	if err := ensureGoCommandAvailable(); err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	output, err := getFlagString(cmd, "output")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if strings.TrimSpace(output) == "" {
+		p.Err("specify the archive to write with --output (e.g. 'gup bundle -o tools.tar.gz')")
+		return 1
+	}
+
+	confFile, err := getFlagString(cmd, "file")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	confFile, err = config.ResolveImportFilePath(confFile)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	cpus, err := getFlagInt(cmd, "jobs")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	cpus = clampJobs(cpus)
+
+	timeout, err := getTimeoutFlag(cmd)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	if !fileutil.IsFile(confFile) {
+		p.Err(fmt.Errorf("%s is not found", confFile))
+		return 1
+	}
+	pkgs, err := config.ReadConfFile(confFile)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	pkgs = withoutRemoved(p, pkgs)
+	if len(pkgs) == 0 {
+		p.Err("unable to bundle: no package information")
+		return 1
+	}
+
+	p.Info("start bundle based on " + confFile)
+	if err := buildBundle(p, pkgs, output, cpus, timeout); err != nil {
+		p.Err(err)
+		return 1
+	}
+	p.Info(fmt.Sprintf("Bundled %d package(s) into %s", len(pkgs), output))
+	return 0
+}
+
+// buildBundle installs pkgs into a throwaway GOBIN/GOMODCACHE and writes the
+// downloaded modules and the resolved gup.json to output. Nothing is written
+// when any install fails: an incomplete bundle would only fail later, on the
+// machine that cannot reach the network to recover.
+func buildBundle(pr *print.Printer, pkgs []goutil.Package, output string, cpus int, timeout time.Duration) (err error) {
+	work, err := os.MkdirTemp("", "gup-bundle-")
+	if err != nil {
+		return fmt.Errorf("can't create temp directory: %w", err)
+	}
+	defer func() {
+		if rmErr := os.RemoveAll(work); rmErr != nil && err == nil {
+			err = fmt.Errorf("can't remove temp directory %s: %w", work, rmErr)
+		}
+	}()
+
+	goflags, err := goutil.GoEnv(context.Background(), "GOFLAGS")
+	if err != nil {
+		return err
+	}
+	modCache := filepath.Join(work, "modcache")
+	binDir := filepath.Join(work, "bin")
+	restore, err := withEnv(map[string]string{
+		"GOMODCACHE": modCache,
+		"GOBIN":      binDir,
+		// The go command writes the module cache read-only; -modcacherw keeps
+		// the temp directory removable.
+		"GOFLAGS": strings.TrimSpace(goflags["GOFLAGS"] + " -modcacherw"),
+	})
+	if err != nil {
+		return fmt.Errorf("can't set up the bundle environment: %w", err)
+	}
+	defer restore()
+
+	exitCode, _ := executePackages(pr, pkgs, cpus, timeout, dependencies{}, installConfigured, func(prefix string, v updateResult) {
+		pr.Info(fmt.Sprintf("%s %s@%s", prefix, v.pkg.ImportPath, v.pkg.Version.Current))
+	})
+	if exitCode != 0 {
+		return errors.New("some packages could not be downloaded; no bundle was written")
+	}
+
+	var conf bytes.Buffer
+	if err := config.WriteConfFile(&conf, resolveBundleVersions(pr, pkgs, binDir)); err != nil {
+		return err
+	}
+	return writeBundleFile(output, filepath.Join(modCache, "cache", "download"), conf.Bytes())
+}
+
+// resolveBundleVersions replaces placeholder versions ("latest") with the
+// version of the binary that was just built into binDir, so importing the
+// bundle never has to resolve @latest against a proxy that only holds what
+// was bundled. Pinned entries keep their pin.
+func resolveBundleVersions(pr *print.Printer, pkgs []goutil.Package, binDir string) []goutil.Package {
+	built := map[string]string{}
+	if list, err := goutil.BinaryPathList(binDir); err == nil {
+		for _, b := range goutil.GetPackageInformationWithoutGoVersion(pr, list) {
+			if b.Version != nil {
+				built[b.ImportPath] = b.Version.Current
+			}
+		}
+	}
+
+	out := make([]goutil.Package, 0, len(pkgs))
+	for _, p := range pkgs {
+		v, ok := built[p.ImportPath]
+		if ok && !p.IsPinned() && v != "" && v != develVersionParen {
+			p.Version = &goutil.Version{Current: v}
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// writeBundleFile writes the bundle to path atomically: into a temp file in the
+// same directory first, renamed into place only once it is complete.
+func writeBundleFile(path, downloadDir string, conf []byte) (err error) {
+	path = filepath.Clean(path)
+	if fileutil.IsDir(path) {
+		return fmt.Errorf("%s is a directory, not a file", path)
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, fileutil.FileModeCreatingDir); err != nil {
+		return fmt.Errorf("can't create %s: %w", dir, err)
+	}
+	file, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("can't create temp file for %s: %w", path, err)
+	}
+	tmpPath := file.Name()
+	defer func() {
+		if file != nil {
+			_ = file.Close()
+		}
+		if err != nil {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if err = bundle.Write(file, downloadDir, conf, cmdinfo.Version); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return fmt.Errorf("can't sync %s: %w", tmpPath, err)
+	}
+	if err = file.Close(); err != nil {
+		file = nil
+		return fmt.Errorf("can't close %s: %w", tmpPath, err)
+	}
+	file = nil
+	if err = renameWithReplace(tmpPath, path); err != nil {
+		return fmt.Errorf("can't write %s: %w", path, err)
+	}
+	return nil
+}
+
+// openBundle extracts and verifies the bundle at path into a temp directory and
+// returns that directory and a function removing it.
+func openBundle(path string) (string, func(), error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", nil, fmt.Errorf("can't open bundle: %w", err)
+	}
+	defer f.Close()
+
+	dir, err := os.MkdirTemp("", "gup-bundle-")
+	if err != nil {
+		return "", nil, fmt.Errorf("can't create temp directory: %w", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+	if err := bundle.Extract(f, dir); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return dir, cleanup, nil
+}
+
+// bundleProxyEnv is the environment that makes 'go install' read modules only
+// from the bundle extracted into dir. GONOPROXY=none keeps GOPRIVATE modules
+// from bypassing the bundle. The checksum database stays on: the go command
+// fetches its records from the bundle's proxy/sumdb tree and checks them
+// against the database's public key, so the modules are verified offline.
+// skipSumDB turns GOSUMDB off instead (--insecure-skip-sumdb).
+func bundleProxyEnv(dir string, skipSumDB bool) map[string]string {
+	env := map[string]string{
+		"GOPROXY":   bundle.ProxyURL(dir),
+		"GONOPROXY": "none",
+	}
+	if skipSumDB {
+		env["GOSUMDB"] = "off"
+	}
+	return env
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// fakeModuleDownload stands in for 'go install': it writes the files the go
+// command would leave in $GOMODCACHE/cache/download for importPath@version.
+func fakeModuleDownload(_ context.Context, importPath, version string, _ goutil.InstallOptions) error {
+	dir := filepath.Join(os.Getenv("GOMODCACHE"), "cache", "download", importPath, "@v")
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	for ext, content := range map[string]string{
+		".info": `{"Version":"` + version + `"}`,
+		".mod":  "module " + importPath + "\n",
+		".zip":  "zip of " + importPath,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, version+ext), []byte(content), 0o600); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filepath.Join(dir, "list"), []byte(version+"\n"), 0o600)
+}
+
+//nolint:paralleltest // swaps installByVersionCtx and sets process environment
+func Test_bundle_importRoundTrip(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+
+	org := installByVersionCtx
+	t.Cleanup(func() { installByVersionCtx = org })
+	installByVersionCtx = fakeModuleDownload
+
+	if err := os.WriteFile(config.LocalFilePath(), []byte(validImportConf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "tools.tar.gz")
+
+	bundleCmd := newBundleCmd()
+	if err := bundleCmd.Flags().Set("output", archive); err != nil {
+		t.Fatal(err)
+	}
+	p, buf := newTestPrinter()
+	if got := runBundle(p, bundleCmd, nil); got != 0 {
+		t.Fatalf("runBundle() = %d, want 0; output: %s", got, buf.String())
+	}
+	if _, err := os.Stat(archive); err != nil {
+		t.Fatalf("bundle was not written: %v", err)
+	}
+
+	// Import on a "machine" without gup.json: the bundled one is used and the
+	// install sees only the bundle as its module proxy.
+	if err := os.Remove(config.LocalFilePath()); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOPROXY", "https://proxy.example.invalid")
+	t.Setenv("GOSUMDB", "sum.golang.org")
+	gobinDir := filepath.Join(t.TempDir(), "gobin")
+	t.Setenv("GOBIN", gobinDir)
+
+	var gotProxy, gotSumDB, gotVersion string
+	installByVersionCtx = func(_ context.Context, _, version string, _ goutil.InstallOptions) error {
+		gotProxy = os.Getenv("GOPROXY")
+		gotSumDB = os.Getenv("GOSUMDB")
+		gotVersion = version
+		return nil
+	}
+
+	importCmd := newImportCmd()
+	if err := importCmd.Flags().Set("bundle", archive); err != nil {
+		t.Fatal(err)
+	}
+	p, buf = newTestPrinter()
+	if got := runImport(p, importCmd, nil); got != 0 {
+		t.Fatalf("runImport() = %d, want 0; output: %s", got, buf.String())
+	}
+
+	if !strings.HasPrefix(gotProxy, "file://") || !strings.HasSuffix(gotProxy, "/proxy") {
+		t.Errorf("GOPROXY during install = %q, want the bundle's file:// proxy", gotProxy)
+	}
+	if gotVersion == "" {
+		t.Error("install did not receive a version from the bundled gup.json")
+	}
+	if os.Getenv("GOPROXY") != "https://proxy.example.invalid" {
+		t.Errorf("GOPROXY was not restored after import: %q", os.Getenv("GOPROXY"))
+	}
+	if gotSumDB != "sum.golang.org" {
+		t.Errorf("GOSUMDB during install = %q, want the checksum database kept on", gotSumDB)
+	}
+
+	importCmd = newImportCmd()
+	if err := importCmd.Flags().Set("bundle", archive); err != nil {
+		t.Fatal(err)
+	}
+	if err := importCmd.Flags().Set("insecure-skip-sumdb", "true"); err != nil {
+		t.Fatal(err)
+	}
+	p, buf = newTestPrinter()
+	if got := runImport(p, importCmd, nil); got != 0 {
+		t.Fatalf("runImport(--insecure-skip-sumdb) = %d, want 0; output: %s", got, buf.String())
+	}
+	if gotSumDB != "off" {
+		t.Errorf("GOSUMDB during install with --insecure-skip-sumdb = %q, want off", gotSumDB)
+	}
+	if got := os.Getenv("GOSUMDB"); got != "sum.golang.org" {
+		t.Errorf("GOSUMDB was not restored after import: %q", got)
+	}
+}
+
+func Test_runImport_skipSumDBNeedsBundle(t *testing.T) {
+	t.Parallel()
+
+	cmd := newImportCmd()
+	if err := cmd.Flags().Set("insecure-skip-sumdb", "true"); err != nil {
+		t.Fatal(err)
+	}
+	p, buf := newTestPrinter()
+	if got := runImport(p, cmd, nil); got != 1 {
+		t.Fatalf("runImport() = %d, want 1", got)
+	}
+	if !strings.Contains(buf.String(), "--bundle") {
+		t.Errorf("error should mention --bundle, got: %s", buf.String())
+	}
+}
+
+//nolint:paralleltest // swaps installByVersionCtx and sets process environment
+func Test_runBundle_skipsRemoved(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+
+	org := installByVersionCtx
+	t.Cleanup(func() { installByVersionCtx = org })
+	var installs []string
+	installByVersionCtx = func(ctx context.Context, importPath, version string, opts goutil.InstallOptions) error {
+		installs = append(installs, importPath+"@"+version)
+		return fakeModuleDownload(ctx, importPath, version, opts)
+	}
+
+	conf := `{"schema_version":2,"packages":[
+{"name":"kept","import_path":"example.com/kept","version":"v1.0.0","channel":"latest"},
+{"name":"gone","import_path":"example.com/gone","version":"v1.0.0","channel":"latest","removed":true}]}`
+	if err := os.WriteFile(config.LocalFilePath(), []byte(conf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := newBundleCmd()
+	if err := cmd.Flags().Set("output", filepath.Join(t.TempDir(), "tools.tar.gz")); err != nil {
+		t.Fatal(err)
+	}
+	p, buf := newTestPrinter()
+	if got := runBundle(p, cmd, nil); got != 0 {
+		t.Fatalf("runBundle() = %d, want 0; output: %s", got, buf.String())
+	}
+	if len(installs) != 1 || installs[0] != "example.com/kept@v1.0.0" {
+		t.Errorf("bundle downloaded %v, want only example.com/kept@v1.0.0", installs)
+	}
+}
+
+//nolint:paralleltest // swaps installByVersionCtx
+func Test_runBundle_installFailureWritesNothing(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+
+	org := installByVersionCtx
+	t.Cleanup(func() { installByVersionCtx = org })
+	installByVersionCtx = func(context.Context, string, string, goutil.InstallOptions) error {
+		return os.ErrNotExist
+	}
+
+	if err := os.WriteFile(config.LocalFilePath(), []byte(validImportConf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "tools.tar.gz")
+	cmd := newBundleCmd()
+	if err := cmd.Flags().Set("output", archive); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := newTestPrinter()
+	if got := runBundle(p, cmd, nil); got != 1 {
+		t.Fatalf("runBundle() = %d, want 1", got)
+	}
+	if _, err := os.Stat(archive); err == nil {
+		t.Fatal("a failed bundle must not leave an archive behind")
+	}
+}
+
+func Test_runBundle_requiresOutput(t *testing.T) {
+	t.Parallel()
+
+	p, buf := newTestPrinter()
+	if got := runBundle(p, newBundleCmd(), nil); got != 1 {
+		t.Fatalf("runBundle() = %d, want 1", got)
+	}
+	if !strings.Contains(buf.String(), "--output") {
+		t.Errorf("error should mention --output, got: %s", buf.String())
+	}
+}
+
+//nolint:paralleltest // sets process environment
+func Test_withEnv_restores(t *testing.T) {
+	t.Setenv("GUP_TEST_SET", "before")
+	if err := os.Unsetenv("GUP_TEST_SET"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GUP_TEST_KEEP", "before")
+
+	restore, err := withEnv(map[string]string{"GUP_TEST_SET": "x", "GUP_TEST_KEEP": "y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.Getenv("GUP_TEST_SET") != "x" || os.Getenv("GUP_TEST_KEEP") != "y" {
+		t.Fatal("withEnv did not set the variables")
+	}
+	restore()
+	if _, ok := os.LookupEnv("GUP_TEST_SET"); ok {
+		t.Error("a variable that was unset should be unset again")
+	}
+	if got := os.Getenv("GUP_TEST_KEEP"); got != "before" {
+		t.Errorf("GUP_TEST_KEEP = %q, want before", got)
+	}
+}
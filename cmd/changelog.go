@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/nao1215/gup/internal/changelog"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+// releaseDateLayout is how publish dates are shown in the human output.
+const releaseDateLayout = "2006-01-02"
+
+func newChangelogCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "changelog TOOL",
+		Short: "Show the release notes between the installed and the candidate version",
+		Long: `Show the release notes between the installed and the candidate version.
+
+changelog downloads the candidate version's module zip from the module proxy
+configured by GOPROXY, extracts its CHANGELOG.md, CHANGELOG, CHANGES.md or
+CHANGES file (or its release-notes/ directory), and prints the sections for the
+versions after the installed one, up to and including the candidate. It also
+lists the releases published in between, with their dates.
+
+The candidate version defaults to what 'gup update' would install: the pinned
+version for a pinned binary, otherwise the latest version on the binary's
+update channel. Use --to to read the notes up to another version.
+
+Nothing is installed. Private modules (GOPRIVATE/GONOPROXY) and GOPROXY=direct
+are not supported, because gup only reads from module proxies.`,
+		Example: `  gup changelog golangci-lint
+  gup changelog golangci-lint --to v1.62.0
+  gup changelog golangci-lint --json`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeFirstArgPathBinaries,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(runChangelog(defaultDependencies(), printerFor(cmd), cmd, args))
+		},
+	}
+	cmd.Flags().String("to", "", "show the notes up to this version instead of the update-channel target")
+	mustRegisterFlagCompletion(cmd, "to", cobra.NoFileCompletions)
+	cmd.Flags().Bool("json", false, "output result as machine-readable JSON")
+	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to read saved update channels from")
+	mustMarkFileFlagAsJSON(cmd)
+	addTimeoutFlag(cmd)
+	return cmd
+}
+
+// changelogReport is the changelog of one binary between two versions. It is
+// also the --json record (and the "changelog" field of 'check --changelog
+// --json'), so its field names are part of the public contract.
+type changelogReport struct {
+	Name           string             `json:"name"`
+	ModulePath     string             `json:"module_path"`
+	CurrentVersion string             `json:"current_version"`
+	TargetVersion  string             `json:"target_version"`
+	ReleaseCount   int                `json:"release_count"`
+	Releases       []changelogRelease `json:"releases"`
+	Source         string             `json:"source,omitempty"`
+	Sections       []changelogSection `json:"sections"`
+	Warnings       []string           `json:"warnings,omitempty"`
+}
+
+// changelogRelease is one release published after the installed version.
+type changelogRelease struct {
+	Version string `json:"version"`
+	// Time is the publish time reported by the proxy; omitted when unknown.
+	Time *time.Time `json:"time,omitempty"`
+}
+
+// changelogSection is the release notes of one version.
+type changelogSection struct {
+	Version string `json:"version"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+}
+
+func runChangelog(deps dependencies, p *print.Printer, cmd *cobra.Command, args []string) int {
+	if err := ensureGoCommandAvailable(); err != nil {
+		p.Err(err)
+		return 1
+	}
+	// changelog takes the same flags as diff-deps.
+	opts, err := parseDiffDepsFlags(cmd)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	pkg, err := resolveConfiguredTarget(p, args[0], opts.confFile)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	ctx, stop := newBoundedSignalContext(context.Background(), opts.timeout)
+	defer stop()
+
+	if strings.TrimSpace(pkg.ModulePath) == "" {
+		p.Err(fmt.Errorf("can't determine the module path of %s", pkg.Name))
+		return 1
+	}
+	target, err := candidateVersion(ctx, deps, pkg, opts.to)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	report, err := fetchChangelog(ctx, deps, pkg, target)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	if opts.jsonOut {
+		enc := json.NewEncoder(p.Out())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			p.Err(err)
+			return 1
+		}
+		return 0
+	}
+	printChangelog(p, report)
+	return 0
+}
+
+// fetchChangelog collects the releases after pkg's installed version up to
+// target and the matching release-notes sections. Only failing to reach the
+// proxy at all is an error; a missing version list, publish date or changelog
+// file is reported as a warning so the rest of the report is still useful.
+func fetchChangelog(ctx context.Context, deps dependencies, pkg goutil.Package, target string) (changelogReport, error) {
+	current := ""
+	if pkg.Version != nil {
+		current = pkg.Version.Current
+	}
+	report := changelogReport{
+		Name:           pkg.Name,
+		ModulePath:     pkg.ModulePath,
+		CurrentVersion: current,
+		TargetVersion:  target,
+		Releases:       make([]changelogRelease, 0),
+		Sections:       make([]changelogSection, 0),
+	}
+
+	proxy, err := deps.moduleProxy(ctx)
+	if err != nil {
+		return changelogReport{}, err
+	}
+
+	versions, err := proxy.List(ctx, pkg.ModulePath)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("can't list versions of %s: %v", pkg.ModulePath, err))
+	}
+	between := changelog.Between(versions, current, target)
+	if len(between) == 0 && target != current {
+		// A pseudo-version target (from @main/@master) is never in @v/list.
+		between = []string{target}
+	}
+	for _, v := range between {
+		rel := changelogRelease{Version: v}
+		if info, err := proxy.Info(ctx, pkg.ModulePath, v); err == nil && !info.Time.IsZero() {
+			t := info.Time.UTC()
+			rel.Time = &t
+		}
+		report.Releases = append(report.Releases, rel)
+	}
+	report.ReleaseCount = len(report.Releases)
+
+	zipData, err := proxy.Zip(ctx, pkg.ModulePath, target)
+	if err != nil {
+		if ctx.Err() != nil {
+			return changelogReport{}, err
+		}
+		report.Warnings = append(report.Warnings, fmt.Sprintf("can't download %s@%s: %v", pkg.ModulePath, target, err))
+		return report, nil
+	}
+	notes, err := changelog.Extract(zipData, pkg.ModulePath, current, target)
+	if err != nil {
+		if !errors.Is(err, changelog.ErrNoChangelog) {
+			return changelogReport{}, err
+		}
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s@%s: %v", pkg.ModulePath, target, err))
+		return report, nil
+	}
+	report.Source = notes.Source
+	for _, s := range notes.Sections {
+		report.Sections = append(report.Sections, changelogSection{Version: s.Version, Title: s.Title, Body: s.Body})
+	}
+	return report, nil
+}
+
+// printChangelog prints the human-readable changelog report.
+func printChangelog(p *print.Printer, r changelogReport) {
+	out := p.Out()
+	_, _ = fmt.Fprintf(out, "%s (%s) %s -> %s: %d release(s)\n",
+		r.Name, r.ModulePath, r.CurrentVersion, r.TargetVersion, r.ReleaseCount)
+	for _, rel := range r.Releases {
+		date := "unknown date"
+		if rel.Time != nil {
+			date = rel.Time.Format(releaseDateLayout)
+		}
+		_, _ = fmt.Fprintf(out, "  %s  %s\n", rel.Version, date)
+	}
+	for _, w := range r.Warnings {
+		p.Warn(w)
+	}
+	if r.Source == "" {
+		return
+	}
+	if len(r.Sections) == 0 {
+		_, _ = fmt.Fprintf(out, "\n%s has no entries for these versions\n", r.Source)
+		return
+	}
+	_, _ = fmt.Fprintln(out, "\n"+color.CyanString("--- "+r.Source+" ---"))
+	for _, s := range r.Sections {
+		_, _ = fmt.Fprintln(out, color.GreenString(s.Title))
+		if s.Body != "" {
+			_, _ = fmt.Fprintln(out, s.Body)
+		}
+		_, _ = fmt.Fprintln(out)
+	}
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nao1215/gup/internal/goproxy"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+const (
+	changelogTestModule = "example.com/tool"
+	changelogTestOld    = "v1.2.0"
+	changelogTestNew    = "v1.5.0"
+)
+
+func changelogTestZip(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(changelogTestModule + "@" + changelogTestNew + "/CHANGELOG.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte("# Changelog\n\n## v1.5.0\n- five\n\n## v1.4.0\n- four\n\n## v1.2.0\n- two\n"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func changelogTestDeps(t *testing.T) dependencies {
+	t.Helper()
+	d := testDeps()
+	d.getLatestVer = func(context.Context, string, string) (string, error) { return changelogTestNew, nil }
+	zipData := changelogTestZip(t)
+	d.moduleProxy = func(context.Context) (moduleProxy, error) {
+		return fakeProxy{
+			zips:  map[string][]byte{changelogTestModule + "@" + changelogTestNew: zipData},
+			lists: map[string][]string{changelogTestModule: {"v1.2.0", "v1.3.0", "v1.4.0", "v1.5.0"}},
+			infos: map[string]goproxy.Info{
+				changelogTestModule + "@v1.5.0": {Version: "v1.5.0", Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		}, nil
+	}
+	return d
+}
+
+func changelogTestPackage() goutil.Package {
+	return goutil.Package{
+		Name:       "tool",
+		ImportPath: changelogTestModule,
+		ModulePath: changelogTestModule,
+		Version:    &goutil.Version{Current: changelogTestOld},
+		GoVersion:  &goutil.Version{Current: "go1.22.0", Latest: "go1.22.0"},
+	}
+}
+
+func Test_fetchChangelog(t *testing.T) {
+	t.Parallel()
+
+	t.Run("collects releases and sections", func(t *testing.T) {
+		t.Parallel()
+		got, err := fetchChangelog(context.Background(), changelogTestDeps(t), changelogTestPackage(), changelogTestNew)
+		if err != nil {
+			t.Fatalf("fetchChangelog() error = %v", err)
+		}
+		if got.ReleaseCount != 3 || len(got.Releases) != 3 {
+			t.Errorf("ReleaseCount = %d, Releases = %+v, want 3", got.ReleaseCount, got.Releases)
+		}
+		if got.Releases[2].Time == nil || got.Releases[2].Time.Year() != 2024 {
+			t.Errorf("v1.5.0 publish time = %v", got.Releases[2].Time)
+		}
+		if got.Releases[0].Time != nil {
+			t.Errorf("v1.3.0 publish time = %v, want unknown", got.Releases[0].Time)
+		}
+		if got.Source != "CHANGELOG.md" || len(got.Sections) != 2 {
+			t.Errorf("Source = %q, Sections = %+v", got.Source, got.Sections)
+		}
+		if len(got.Warnings) != 0 {
+			t.Errorf("Warnings = %v", got.Warnings)
+		}
+	})
+
+	t.Run("missing zip is a warning", func(t *testing.T) {
+		t.Parallel()
+		got, err := fetchChangelog(context.Background(), changelogTestDeps(t), changelogTestPackage(), "v1.4.0")
+		if err != nil {
+			t.Fatalf("fetchChangelog() error = %v", err)
+		}
+		if got.ReleaseCount != 2 || len(got.Warnings) != 1 || len(got.Sections) != 0 {
+			t.Errorf("report = %+v", got)
+		}
+	})
+}
+
+func Test_printChangelog(t *testing.T) {
+	t.Parallel()
+
+	p, buf := newTestPrinter()
+	published := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	printChangelog(p, changelogReport{
+		Name:           "tool",
+		ModulePath:     changelogTestModule,
+		CurrentVersion: changelogTestOld,
+		TargetVersion:  changelogTestNew,
+		ReleaseCount:   2,
+		Releases:       []changelogRelease{{Version: "v1.4.0"}, {Version: "v1.5.0", Time: &published}},
+		Source:         "CHANGELOG.md",
+		Sections:       []changelogSection{{Version: "v1.5.0", Title: "## v1.5.0", Body: "- five"}},
+	})
+	out := buf.String()
+	for _, want := range []string{
+		"tool (example.com/tool) v1.2.0 -> v1.5.0: 2 release(s)",
+		"v1.4.0  unknown date",
+		"v1.5.0  2024-05-01",
+		"--- CHANGELOG.md ---",
+		"## v1.5.0\n- five",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func Test_runCheck_changelog(t *testing.T) {
+	t.Parallel()
+
+	t.Run("json records carry the changelog", func(t *testing.T) {
+		t.Parallel()
+		p, buf := newTestPrinter()
+		code := runCheck(context.Background(), changelogTestDeps(t), p, []goutil.Package{changelogTestPackage()},
+			checkOpts{cpus: 1, jsonOut: true, changelog: true})
+		if code != 0 {
+			t.Fatalf("runCheck() = %d, want 0", code)
+		}
+		var recs []jsonPackage
+		if err := json.Unmarshal(buf.Bytes(), &recs); err != nil {
+			t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+		}
+		if len(recs) != 1 || recs[0].Changelog == nil || recs[0].Changelog.ReleaseCount != 3 {
+			t.Fatalf("records = %+v, want one record with a changelog", recs)
+		}
+	})
+
+	t.Run("human output prints the notes", func(t *testing.T) {
+		t.Parallel()
+		p, buf := newTestPrinter()
+		runCheck(context.Background(), changelogTestDeps(t), p, []goutil.Package{changelogTestPackage()},
+			checkOpts{cpus: 1, changelog: true})
+		if !strings.Contains(buf.String(), "- five") {
+			t.Errorf("output missing changelog:\n%s", buf.String())
+		}
+	})
+
+	t.Run("up-to-date binaries have no changelog", func(t *testing.T) {
+		t.Parallel()
+		pkg := changelogTestPackage()
+		pkg.Version.Current = changelogTestNew
+		p, buf := newTestPrinter()
+		runCheck(context.Background(), changelogTestDeps(t), p, []goutil.Package{pkg}, checkOpts{cpus: 1, jsonOut: true, changelog: true})
+		if strings.Contains(buf.String(), `"changelog"`) {
+			t.Errorf("unexpected changelog:\n%s", buf.String())
+		}
+	})
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"runtime"
 	"strings"
@@ -14,18 +15,28 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// exitCodeUpdatesAvailable is the exit code of 'gup check --exit-code' when a
+// binary has an update or differs from its pin. Errors keep exit code 1, so CI
+// can tell an outdated tool set from a failed check.
+const exitCodeUpdatesAvailable = 2
+
 func newCheckCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "check",
 		Short: "Check the latest version of the binary installed by 'go install'",
 		Example: `  gup check
-  gup check --quiet`,
+  gup check --quiet
+  gup check --format markdown
+  gup check --exit-code`,
 		Long: `Check the latest version and build toolchain of the binary installed by 'go install'
 
 check subcommand checks if the binary is the latest version
 and if it has been built with the current version of go installed,
 and displays the name of the binary that needs to be updated.
-It does not update them.`,
+It does not update them.
+
+With --exit-code, check exits 2 when a binary has an update or differs from
+its pin, so CI can fail on an outdated tool set. A failed check still exits 1.`,
 		ValidArgsFunction: completePathBinaries,
 		Run: func(cmd *cobra.Command, args []string) {
 			OsExit(check(defaultDependencies(), printerFor(cmd), cmd, args))
@@ -36,10 +47,17 @@ It does not update them.`,
 	mustRegisterFlagCompletion(cmd, "jobs", completeNCPUs)
 	cmd.Flags().Bool("ignore-go-update", false, "ignore updates to the Go toolchain")
 	cmd.Flags().Bool("json", false, "output result as machine-readable JSON")
+	addJSONFormatFlag(cmd, "JSON layout: v1 is the bare --json array, v2 wraps it with a summary, timings and the environment (implies --json)")
+	addJSONStreamFlag(cmd)
+	addFormatFlag(cmd)
 	cmd.Flags().BoolP("quiet", "q", false, "suppress up-to-date lines; show only update-available/failed binaries plus a summary")
 	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to read saved update channels from")
 	mustMarkFileFlagAsJSON(cmd)
+	cmd.Flags().Bool("changelog", false, "show the release notes of every binary with an available update")
+	cmd.Flags().String("metrics-textfile", "", "also write the result as Prometheus metrics to this node_exporter textfile")
+	cmd.Flags().Bool("exit-code", false, "exit 2 when a binary has an update or differs from its pin (errors still exit 1)")
 	addTimeoutFlag(cmd)
+	addRetryFlags(cmd)
 
 	return cmd
 }
@@ -48,10 +66,17 @@ It does not update them.`,
 type checkOpts struct {
 	cpus           int // already clamped to >= 1
 	ignoreGoUpdate bool
-	jsonOut        bool
+	jsonOut        bool // also set by --json-format v2 and --format
+	jsonV2         bool
+	jsonStream     bool
+	format         *outputFormat
+	report         *jsonReport // where --json-stream, v2 or --format output goes; set by check()
 	quiet          bool
 	timeout        time.Duration
 	confFile       string
+	changelog      bool
+	metricsFile    string
+	exitCode       bool
 }
 
 // parseCheckFlags reads every flag of the check command in one place so check()
@@ -70,6 +95,23 @@ func parseCheckFlags(cmd *cobra.Command) (checkOpts, error) {
 	if opts.jsonOut, err = getFlagBool(cmd, "json"); err != nil {
 		return checkOpts{}, err
 	}
+	if opts.jsonV2, err = getJSONFormatV2(cmd); err != nil {
+		return checkOpts{}, err
+	}
+	opts.jsonOut = opts.jsonOut || opts.jsonV2
+	if opts.jsonStream, err = getFlagBool(cmd, jsonStreamFlagName); err != nil {
+		return checkOpts{}, err
+	}
+	if opts.jsonOut && opts.jsonStream {
+		return checkOpts{}, errors.New("--json and --json-format v2 can't be used with --json-stream")
+	}
+	if opts.format, err = getFormatFlag(cmd, checkFormatFields); err != nil {
+		return checkOpts{}, err
+	}
+	if opts.format != nil && (opts.jsonOut || opts.jsonStream) {
+		return checkOpts{}, errors.New("--format can't be used with --json, --json-format v2 or --json-stream")
+	}
+	opts.jsonOut = opts.jsonOut || opts.format != nil
 	if opts.quiet, err = getFlagBool(cmd, "quiet"); err != nil {
 		return checkOpts{}, err
 	}
@@ -79,6 +121,15 @@ func parseCheckFlags(cmd *cobra.Command) (checkOpts, error) {
 	if opts.confFile, err = getFlagString(cmd, "file"); err != nil {
 		return checkOpts{}, err
 	}
+	if opts.changelog, err = getFlagBool(cmd, "changelog"); err != nil {
+		return checkOpts{}, err
+	}
+	if opts.metricsFile, err = getFlagString(cmd, "metrics-textfile"); err != nil {
+		return checkOpts{}, err
+	}
+	if opts.exitCode, err = getFlagBool(cmd, "exit-code"); err != nil {
+		return checkOpts{}, err
+	}
 	return opts, nil
 }
 
@@ -96,6 +147,14 @@ func check(deps dependencies, p *print.Printer, cmd *cobra.Command, args []strin
 		p.Err(err)
 		return 1
 	}
+	if deps.retry, err = getRetryPolicy(cmd); err != nil {
+		p.Err(err)
+		return 1
+	}
+	opts.report, p = newJSONReport(p, "check", opts.jsonStream, opts.jsonV2, opts.confFile)
+	if opts.format != nil {
+		opts.report = &jsonReport{format: opts.format}
+	}
 
 	pkgs, missingTargets, goVersionAvailable, err := pkgselect.PackageInfoByTargets(p, args)
 	if err != nil {
@@ -109,8 +168,8 @@ func check(deps dependencies, p *print.Printer, cmd *cobra.Command, args []strin
 	pkgselect.WarnMissing(missingTargets, func(msg string) { p.Warn(msg) })
 
 	if len(pkgs) == 0 {
-		return handleEmptyEnvironment(p, opts.confFile, opts.jsonOut, len(args) != 0,
-			"unable to check package: no package information")
+		return handleEmptyEnvironmentReport(p, opts.confFile, opts.jsonOut, len(args) != 0,
+			"unable to check package: no package information", opts.report)
 	}
 
 	pkgs, err = configstate.ResolveAndApplyChannels(pkgs, opts.confFile)
@@ -118,10 +177,8 @@ func check(deps dependencies, p *print.Printer, cmd *cobra.Command, args []strin
 		p.Err(err)
 		return 1
 	}
-	if opts.jsonOut {
-		return doCheckJSON(deps, p, pkgs, opts.cpus, opts.timeout, ignoreGoUpdate)
-	}
-	return doCheck(deps, p, pkgs, opts.cpus, opts.timeout, ignoreGoUpdate, opts.quiet)
+	opts.ignoreGoUpdate = ignoreGoUpdate
+	return runCheck(commandContext(cmd), deps, p, pkgs, opts)
 }
 
 func doCheck(deps dependencies, p *print.Printer, pkgs []goutil.Package, cpus int, timeout time.Duration, ignoreGoUpdate, quiet bool) int {
@@ -135,13 +192,107 @@ func doCheckJSON(deps dependencies, p *print.Printer, pkgs []goutil.Package, cpu
 }
 
 func doCheckWith(deps dependencies, p *print.Printer, pkgs []goutil.Package, cpus int, timeout time.Duration, ignoreGoUpdate, quiet, jsonOut bool) int {
-	verCache := deps.newVerCache()
+	return runCheck(context.Background(), deps, p, pkgs, checkOpts{
+		cpus:           cpus,
+		timeout:        timeout,
+		ignoreGoUpdate: ignoreGoUpdate,
+		quiet:          quiet,
+		jsonOut:        jsonOut,
+	})
+}
+
+// runCheck checks pkgs under ctx and reports the result. opts.ignoreGoUpdate
+// must already account for an undetectable Go version; opts.confFile is not
+// read here.
+func runCheck(ctx context.Context, deps dependencies, p *print.Printer, pkgs []goutil.Package, opts checkOpts) int {
+	cpus, timeout := opts.cpus, opts.timeout
+	quiet, jsonOut := opts.quiet, opts.jsonOut
 
 	if !jsonOut && !quiet {
 		p.Info("check binary under $GOPATH/bin or $GOBIN")
 	}
+	checker := newCheckWorker(deps, p, opts)
+
+	var onResult func(prefix string, v updateResult)
+	if !jsonOut {
+		// In quiet mode show only binaries with an available update.
+		showInQuiet := func(v updateResult) bool {
+			return v.status == statusUpdateAvailable || v.status == statusPinMismatch || v.status == statusNeedsNewerGo
+		}
+		renderLine := resultLineRenderer(p, quiet, showInQuiet, checkResultStr)
+		onResult = func(prefix string, v updateResult) {
+			renderLine(prefix, v)
+			if note := v.toolchain.note(); note != "" && (!quiet || showInQuiet(v)) {
+				if v.status == statusNeedsNewerGo {
+					p.Warn(v.pkg.Name + " " + note)
+				} else {
+					p.Info("    " + note)
+				}
+			}
+		}
+	}
+
+	result, results := executePackagesStream(ctx, p, opts.report.events(), !jsonOut && !quiet, nil, pkgs, cpus, timeout, deps, checker, onResult)
+
+	if opts.metricsFile != "" {
+		if err := writeCheckMetrics(opts.metricsFile, results); err != nil {
+			p.Err(err)
+			result = 1
+		}
+	}
 
-	checker := func(ctx context.Context, p goutil.Package) updateResult {
+	var changelogs map[int]changelogReport
+	if opts.changelog {
+		changelogs = collectChangelogs(ctx, deps, p, results, timeout, jsonOut)
+	}
+
+	if jsonOut {
+		recs := resultsToJSONPackages(results)
+		for i, r := range changelogs {
+			recs[i].Changelog = &r
+		}
+		if err := encodeJSONResults(p, opts.report, recs, results); err != nil {
+			p.Err(err)
+			return 1
+		}
+		return checkExitCode(result, results, opts.exitCode)
+	}
+
+	printUpdatablePkgInfo(p, collectNeedUpdatePkgs(results))
+	if quiet {
+		p.Info(summarizeResults(results, true))
+	}
+	for i := range results {
+		if r, ok := changelogs[i]; ok {
+			_, _ = fmt.Fprintln(p.Out())
+			printChangelog(p, r)
+		}
+	}
+	return checkExitCode(result, results, opts.exitCode)
+}
+
+// checkExitCode returns the exit code of a check that ended with result. With
+// --exit-code (exitCode), a check without errors whose results include an
+// update that 'gup update' would install exits exitCodeUpdatesAvailable.
+func checkExitCode(result int, results []updateResult, exitCode bool) int {
+	if !exitCode || result != 0 {
+		return result
+	}
+	if len(collectNeedUpdatePkgs(results)) != 0 {
+		return exitCodeUpdatesAvailable
+	}
+	return 0
+}
+
+// newCheckWorker returns the worker that checks one package for an update.
+// opts.ignoreGoUpdate must already account for an undetectable Go version.
+func newCheckWorker(deps dependencies, p *print.Printer, opts checkOpts) pkgWorker {
+	ignoreGoUpdate, jsonOut := opts.ignoreGoUpdate, opts.jsonOut
+	verCache := deps.newVerCache()
+	preflight := newToolchainPreflight(deps, opts.timeout)
+	warn := func(msg string) { p.Warn(msg) }
+
+	return deps.retryWorker(func(ctx context.Context, deps dependencies, p goutil.Package) updateResult {
 		// A pinned package is compared against its recorded version, never against
 		// @latest: reporting "update available" for a pin would be wrong.
 		if p.IsPinned() {
@@ -155,7 +306,7 @@ func doCheckWith(deps dependencies, p *print.Printer, pkgs []goutil.Package, cpu
 		} else {
 			var latestVer string
 			modulePathChanged := false
-			latestVer, err = verCache.Get(ctx, p.ModulePath, p.UpdateChannel)
+			latestVer, err = deps.lookup(ctx, verCache, p.ModulePath, p.UpdateChannel)
 			if err != nil {
 				newPkg, changed := resolveModulePathChange(p, err)
 				if !changed {
@@ -163,7 +314,7 @@ func doCheckWith(deps dependencies, p *print.Printer, pkgs []goutil.Package, cpu
 				} else {
 					modulePathChanged = true
 					p = newPkg
-					latestVer, err = verCache.Get(ctx, p.ModulePath, p.UpdateChannel)
+					latestVer, err = deps.lookup(ctx, verCache, p.ModulePath, p.UpdateChannel)
 					if err != nil {
 						err = fmt.Errorf("%s %w", p.Name, err)
 					}
@@ -171,6 +322,7 @@ func doCheckWith(deps dependencies, p *print.Printer, pkgs []goutil.Package, cpu
 			}
 			if err == nil {
 				p.Version.Latest = latestVer
+				deps.event(eventResolved, p)
 
 				shouldUpdate := modulePathChanged || !p.IsPackageUpToDate() || (!ignoreGoUpdate && !p.IsGoUpToDate())
 				if shouldUpdate {
@@ -184,38 +336,53 @@ func doCheckWith(deps dependencies, p *print.Printer, pkgs []goutil.Package, cpu
 			}
 		}
 
-		return updateResult{
-			pkg:    p,
-			err:    err,
-			status: status,
+		// Read the candidate's go.mod before anything is built, so an update the
+		// installed Go can't build is reported now instead of failing later.
+		var toolchain *toolchainCheck
+		if err == nil && status == statusUpdateAvailable {
+			var tcErr error
+			if toolchain, tcErr = preflight.check(ctx, p); tcErr != nil && !jsonOut {
+				warn(fmt.Sprintf("can't check the Go toolchain %s needs: %v", p.Name, tcErr))
+			}
+			if toolchain.blocksUpdate() {
+				status = statusNeedsNewerGo
+			}
 		}
-	}
-
-	var onResult func(prefix string, v updateResult)
-	if !jsonOut {
-		// In quiet mode show only binaries with an available update.
-		onResult = resultLineRenderer(p, quiet,
-			func(v updateResult) bool {
-				return v.status == statusUpdateAvailable || v.status == statusPinMismatch
-			},
-			checkResultStr)
-	}
 
-	result, results := executePackages(p, pkgs, cpus, timeout, checker, onResult)
-
-	if jsonOut {
-		if err := encodeJSONPackages(p, resultsToJSONPackages(results)); err != nil {
-			p.Err(err)
-			return 1
+		return updateResult{
+			pkg:       p,
+			err:       err,
+			status:    status,
+			toolchain: toolchain,
 		}
-		return result
-	}
+	})
+}
 
-	printUpdatablePkgInfo(p, collectNeedUpdatePkgs(results))
-	if quiet {
-		p.Info(summarizeResults(results, true))
+// collectChangelogs fetches the changelog of every result with an available
+// update, keyed by result index. A failed fetch is reported as a warning and
+// never changes the check's exit code: the version check itself succeeded.
+func collectChangelogs(ctx context.Context, deps dependencies, p *print.Printer, results []updateResult, timeout time.Duration, jsonOut bool) map[int]changelogReport {
+	out := map[int]changelogReport{}
+	for i, v := range results {
+		if v.err != nil || (v.status != statusUpdateAvailable && v.status != statusNeedsNewerGo) || v.pkg.Version == nil {
+			continue
+		}
+		// A Go-toolchain-only rebuild has no new release notes to show.
+		if v.pkg.Version.Current == v.pkg.Version.Latest {
+			continue
+		}
+		fetchCtx, stop := newBoundedSignalContext(ctx, timeout)
+		r, err := fetchChangelog(fetchCtx, deps, v.pkg, v.pkg.Version.Latest)
+		stop()
+		if err != nil {
+			if !jsonOut {
+				p.Warn(fmt.Sprintf("can't fetch the changelog of %s: %v", v.pkg.Name, err))
+			}
+			continue
+		}
+		out[i] = r
 	}
-	return result
+	return out
 }
 
 // checkResultStr renders the per-binary check line, using the pinned-specific
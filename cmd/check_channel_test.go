@@ -53,10 +53,10 @@ func Test_doCheck_respectsSavedChannels(t *testing.T) {
 	deps := testDeps()
 	// @latest always reports v1.0.0 so a main/master binary would look
 	// up-to-date if check wrongly ignored the saved channel.
-	deps.getLatestVer = func(_ context.Context, _ string) (string, error) {
+	deps.getLatestVer = func(_ context.Context, _, _ string) (string, error) {
 		return testVersionOne, nil
 	}
-	deps.getVerByRef = func(_ context.Context, _ string, ref string) (string, error) {
+	deps.getVerByRef = func(_ context.Context, _, ref, _ string) (string, error) {
 		switch ref {
 		case refMain:
 			return "v1.5.0", nil
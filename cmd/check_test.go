@@ -289,7 +289,7 @@ func Test_doCheck_modulePathChanged(t *testing.T) {
 	)
 
 	deps := testDeps()
-	deps.getLatestVer = func(_ context.Context, modulePath string) (string, error) {
+	deps.getLatestVer = func(_ context.Context, modulePath, _ string) (string, error) {
 		if modulePath == oldModule {
 			return "", errors.New("version constraints conflict:\n" +
 				"module declares its path as: " + newModule + "\n" +
@@ -334,7 +334,7 @@ func Test_doCheck_modulePathChanged(t *testing.T) {
 // line still printed "current: goX, installed: goY", contradicting the decision.
 func Test_doCheck_ignoreGoUpdate_hidesGoOnlyDelta(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionOne, nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionOne, nil }
 
 	p, buf := newTestPrinter()
 
@@ -368,7 +368,7 @@ func Test_doCheck_ignoreGoUpdate_hidesGoOnlyDelta(t *testing.T) {
 
 func Test_doCheck_customGoBuildTag_noFalsePositiveUpdate(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionOne, nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionOne, nil }
 
 	p, buf := newTestPrinter()
 
@@ -405,7 +405,7 @@ func Test_doCheck_customGoBuildTag_goVersionDiffColor(t *testing.T) {
 	t.Cleanup(func() { color.NoColor = oldNoColor })
 
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionOne, nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionOne, nil }
 
 	p, buf := newTestPrinter()
 
@@ -452,3 +452,77 @@ func TestDoCheckWith_notInstalledByGoInstall(t *testing.T) {
 		t.Fatal("doCheckWith() exit = 0, want non-zero for an uncheckable binary")
 	}
 }
+
+func Test_checkExitCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		result   int
+		results  []updateResult
+		exitCode bool
+		want     int
+	}{
+		{name: "up to date", results: []updateResult{{status: statusUpToDate}, {status: statusPinned}}, exitCode: true, want: 0},
+		{name: "update available", results: []updateResult{{status: statusUpToDate}, {status: statusUpdateAvailable}}, exitCode: true, want: exitCodeUpdatesAvailable},
+		{name: "pin mismatch", results: []updateResult{{status: statusPinMismatch}}, exitCode: true, want: exitCodeUpdatesAvailable},
+		{name: "needs newer go", results: []updateResult{{status: statusNeedsNewerGo}}, exitCode: true, want: 0},
+		{name: "error wins", result: 1, results: []updateResult{{status: statusUpdateAvailable}, {err: errors.New("proxy down")}}, exitCode: true, want: 1},
+		{name: "without the flag", results: []updateResult{{status: statusUpdateAvailable}}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := checkExitCode(tt.result, tt.results, tt.exitCode); got != tt.want {
+				t.Errorf("checkExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_runCheck_canceledContext(t *testing.T) {
+	t.Parallel()
+
+	deps := testDeps()
+	deps.getLatestVer = func(ctx context.Context, _, _ string) (string, error) {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		return testVersionTwo, nil
+	}
+	pkgs := []goutil.Package{{
+		Name:       testBinTool,
+		ImportPath: testImportPathTool,
+		ModulePath: testImportPathTool,
+		Version:    &goutil.Version{Current: testVersionOne},
+		GoVersion:  &goutil.Version{Current: testGoVersion1224, Latest: testGoVersion1224},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts := checkOpts{cpus: 1, ignoreGoUpdate: true, jsonOut: true, exitCode: true}
+	if got := runCheck(ctx, deps, discardPrinter(), pkgs, opts); got != 1 {
+		t.Errorf("runCheck() under a canceled context = %d, want 1", got)
+	}
+}
+
+func Test_runCheck_exitCode(t *testing.T) {
+	t.Parallel()
+
+	deps := testDeps()
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionTwo, nil }
+	pkgs := []goutil.Package{{
+		Name:       testBinTool,
+		ImportPath: testImportPathTool,
+		ModulePath: testImportPathTool,
+		Version:    &goutil.Version{Current: testVersionOne},
+		GoVersion:  &goutil.Version{Current: testGoVersion1224, Latest: testGoVersion1224},
+	}}
+	opts := checkOpts{cpus: 1, ignoreGoUpdate: true, jsonOut: true, exitCode: true}
+	if got := runCheck(context.Background(), deps, discardPrinter(), pkgs, opts); got != exitCodeUpdatesAvailable {
+		t.Errorf("runCheck() = %d, want %d", got, exitCodeUpdatesAvailable)
+	}
+	opts.exitCode = false
+	if got := runCheck(context.Background(), deps, discardPrinter(), pkgs, opts); got != 0 {
+		t.Errorf("runCheck() without --exit-code = %d, want 0", got)
+	}
+}
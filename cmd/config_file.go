@@ -75,6 +75,42 @@ func writeConfigFile(path string, pkgs []goutil.Package) (err error) {
 	return nil
 }
 
+// writeFileAtomically writes data to path through a temp file in the same
+// directory, renamed into place once it is complete, so a crash or a
+// concurrent reader never sees a partial file. The file gets mode 0600.
+func writeFileAtomically(path string, data []byte) (err error) {
+	dir := filepath.Dir(path)
+	file, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("can't create temp file for %s: %w", path, err)
+	}
+	tmpPath := file.Name()
+	defer func() {
+		if file != nil {
+			_ = file.Close()
+		}
+		if err != nil {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err = file.Write(data); err != nil {
+		return fmt.Errorf("can't write %s: %w", tmpPath, err)
+	}
+	if err = file.Sync(); err != nil {
+		return fmt.Errorf("can't sync %s: %w", tmpPath, err)
+	}
+	if err = file.Close(); err != nil {
+		file = nil
+		return fmt.Errorf("can't close %s: %w", tmpPath, err)
+	}
+	file = nil
+	if err = renameWithReplace(tmpPath, path); err != nil {
+		return fmt.Errorf("can't write %s: %w", path, err)
+	}
+	return nil
+}
+
 func renameWithReplace(src, dst string) error {
 	if err := renameFunc(src, dst); err != nil {
 		// Windows cannot overwrite an existing file with os.Rename.
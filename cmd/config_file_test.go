@@ -490,3 +490,50 @@ func TestWriteConfigFile_backupSwapPath(t *testing.T) {
 		t.Fatalf("config not written through backup swap: %s", data)
 	}
 }
+
+func Test_writeFileAtomically(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schedule-last.json")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomically(path, []byte("new")); err != nil {
+		t.Fatalf("writeFileAtomically() error = %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "new" {
+		t.Errorf("content = %q, want new", got)
+	}
+	assertNoTempFiles(t, dir, filepath.Base(path))
+}
+
+//nolint:paralleltest // swaps renameFunc
+func Test_writeFileAtomically_keepsOldFileOnRenameFailure(t *testing.T) {
+	origRename := renameFunc
+	t.Cleanup(func() { renameFunc = origRename })
+	renameFunc = func(_, _ string) error {
+		return errors.New("forced rename failure")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schedule-last.json")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomically(path, []byte("new")); err == nil {
+		t.Fatal("writeFileAtomically() should return error when rename fails")
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "old" {
+		t.Errorf("content = %q, want the old file kept", got)
+	}
+	assertNoTempFiles(t, dir, filepath.Base(path))
+}
@@ -2,13 +2,18 @@ package cmd
 
 import (
 	"context"
+	"time"
 
+	"github.com/nao1215/gup/internal/goproxy"
 	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/retry"
 	"github.com/nao1215/gup/internal/vercache"
 )
 
 // dependencies bundles the go-toolchain operations the update and check flows
-// rely on: the online version lookups and the per-channel install variants.
+// rely on: the online version lookups, the per-channel install variants, the
+// module-proxy and build-info readers used to inspect a candidate version, and
+// the go env reader.
 //
 // Threading these through the operation flow as a value (rather than reading
 // package-level globals deep inside the business logic) lets the runner take its
@@ -17,12 +22,58 @@ import (
 // the single place that names the real goutil operations, so the rest of the
 // package depends only on the injected value and tests inject directly and run
 // in parallel.
+//
+// A worker is called with its own copy (see pkgWorker), on which the wrappers
+// around it set the fields that belong to the package it runs: the toolchain,
+// the retry counter, the live display and the observers of its steps.
 type dependencies struct {
-	getLatestVer        func(ctx context.Context, modulePath string) (string, error)
-	getVerByRef         func(ctx context.Context, modulePath, ref string) (string, error)
-	installLatest       func(ctx context.Context, importPath string) error
-	installMainOrMaster func(ctx context.Context, importPath string) error
-	installByVersion    func(ctx context.Context, importPath, version string) error
+	getLatestVer        func(ctx context.Context, modulePath, toolchain string) (string, error)
+	getVerByRef         func(ctx context.Context, modulePath, ref, toolchain string) (string, error)
+	installLatest       func(ctx context.Context, importPath string, opts goutil.InstallOptions) error
+	installMainOrMaster func(ctx context.Context, importPath string, opts goutil.InstallOptions) error
+	installByVersion    func(ctx context.Context, importPath, version string, opts goutil.InstallOptions) error
+	moduleProxy         func(ctx context.Context) (moduleProxy, error)
+	readBuildDeps       func(path string) ([]goutil.Module, error)
+	goEnv               func(ctx context.Context, keys ...string) (map[string]string, error)
+	// retry is how the version lookups and installs retry a network error
+	// (--retries, --retry-backoff). The zero value never retries.
+	retry retry.Policy
+	// builds orders an update longest build first and records its build
+	// times. It is nil, doing neither, unless 'gup update' sets it.
+	builds *buildTimes
+	// buildSlots and buildLimits bound the installs running at once and the
+	// share of the machine each may use (see limitJobs). The zero values
+	// bound neither.
+	buildSlots  jobSlots
+	buildLimits goutil.BuildLimits
+
+	// toolchain is the Go release the package is built with (go_toolchain),
+	// or "" for the go command's own.
+	toolchain string
+	// retries counts the retries of the package, when retrying is on.
+	retries *retry.Counter
+	// installPhase reports the phases of 'go install' to the live display.
+	installPhase func(phase string)
+	// buildClock adds up the time the installs of the package took, when the
+	// build times are recorded.
+	buildClock *time.Duration
+	// onEvent reports the steps of the package (see event).
+	onEvent func(event string, pkg goutil.Package)
+}
+
+// moduleProxy is the read-only view of the GOPROXY protocol that gup needs. It is
+// satisfied by *goproxy.Client; tests substitute an in-memory fake.
+type moduleProxy interface {
+	GoMod(ctx context.Context, modulePath, version string) ([]byte, error)
+	Zip(ctx context.Context, modulePath, version string) ([]byte, error)
+	Info(ctx context.Context, modulePath, version string) (goproxy.Info, error)
+	List(ctx context.Context, modulePath string) ([]string, error)
+}
+
+// newModuleProxy builds a proxy client from the go command's effective GOPROXY,
+// GONOPROXY and GOPRIVATE settings.
+func newModuleProxy(ctx context.Context) (moduleProxy, error) {
+	return goproxy.FromEnv(ctx)
 }
 
 // defaultDependencies wires the real goutil operations used in production. It is
@@ -36,20 +87,101 @@ func defaultDependencies() dependencies {
 		installLatest:       goutil.InstallLatestWithContext,
 		installMainOrMaster: goutil.InstallMainOrMasterWithContext,
 		installByVersion:    goutil.InstallWithContext,
+		moduleProxy:         newModuleProxy,
+		readBuildDeps:       goutil.ReadBuildDeps,
+		goEnv:               goutil.GoEnv,
 	}
 }
 
 // newVerCache builds the per-(module,channel) version cache used by update and
 // check, wiring the injected lookup operations into vercache's channel policy.
 // The seams are read through the injected funcs so a test that supplies its own
-// dependencies controls the resolved versions without touching globals.
+// dependencies controls the resolved versions without touching globals. The
+// lookups retry by d.retry.
 func (d dependencies) newVerCache() *vercache.Cache {
 	return vercache.New(vercache.ChannelResolver(
-		func(ctx context.Context, modulePath string) (string, error) {
-			return d.getLatestVer(ctx, modulePath)
+		func(ctx context.Context, modulePath, toolchain string) (string, error) {
+			return d.getLatestVer(ctx, modulePath, toolchain)
 		},
-		func(ctx context.Context, modulePath, ref string) (string, error) {
-			return d.getVerByRef(ctx, modulePath, ref)
+		func(ctx context.Context, modulePath, ref, toolchain string) (string, error) {
+			return d.getVerByRef(ctx, modulePath, ref, toolchain)
 		},
-	))
+	), d.retry)
+}
+
+// lookup returns the version channel resolves to for modulePath from cache,
+// looked up with the toolchain of the package d runs and counting its retries.
+func (d dependencies) lookup(ctx context.Context, cache *vercache.Cache, modulePath string, channel goutil.UpdateChannel) (string, error) {
+	return cache.Get(ctx, modulePath, channel, d.toolchain, d.retries)
+}
+
+// installOptions returns the options of an install for the package d runs.
+func (d dependencies) installOptions() goutil.InstallOptions {
+	return goutil.InstallOptions{
+		Toolchain: d.toolchain,
+		Limits:    d.buildLimits,
+		Phase:     d.installPhase,
+		Retry:     d.retry,
+		Retries:   d.retries,
+	}
+}
+
+// install runs op, one of the install operations, with the options of the
+// package d runs. It waits for a build slot first, and adds the time op took,
+// not counting that wait, to the build clock of the package. The wait does not
+// count toward the per-package timeout either.
+func (d dependencies) install(ctx context.Context, op func(ctx context.Context, opts goutil.InstallOptions) error) error {
+	if err := d.buildSlots.acquire(ctx); err != nil {
+		return err
+	}
+	defer d.buildSlots.release()
+	if d.buildClock != nil {
+		begin := time.Now()
+		defer func() { *d.buildClock += time.Since(begin) }()
+	}
+	return op(ctx, d.installOptions())
+}
+
+// installVersion installs importPath at version by d.install.
+func (d dependencies) installVersion(ctx context.Context, importPath, version string) error {
+	return d.install(ctx, func(ctx context.Context, opts goutil.InstallOptions) error {
+		return d.installByVersion(ctx, importPath, version, opts)
+	})
+}
+
+// event reports event for pkg, the package d runs, to the observers the
+// wrappers of its worker added: the --json-stream output, the live progress
+// display and the record of the update run. Workers call it for the steps
+// executePackagesStream can't see: resolved and installing.
+func (d dependencies) event(event string, pkg goutil.Package) {
+	if d.onEvent != nil {
+		d.onEvent(event, pkg)
+	}
+}
+
+// observe returns d with observer added to the observers of event.
+func (d dependencies) observe(observer func(event string, pkg goutil.Package)) dependencies {
+	prev := d.onEvent
+	d.onEvent = func(event string, pkg goutil.Package) {
+		if prev != nil {
+			prev(event, pkg)
+		}
+		observer(event, pkg)
+	}
+	return d
+}
+
+// retryWorker wraps worker so the result records the attempts the version
+// lookups and installs of each package took. It returns worker as it is when
+// d.retry is off.
+func (d dependencies) retryWorker(worker pkgWorker) pkgWorker {
+	if !d.retry.Enabled() {
+		return worker
+	}
+	return func(ctx context.Context, deps dependencies, pkg goutil.Package) updateResult {
+		deps.retries = &retry.Counter{}
+		v := worker(ctx, deps, pkg)
+		v.attempts = 1 + deps.retries.Retries()
+		return v
+	}
 }
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"runtime"
 	"testing"
 
+	"github.com/nao1215/gup/internal/goproxy"
+	"github.com/nao1215/gup/internal/goutil"
 	"github.com/nao1215/gup/internal/print"
 )
 
@@ -57,11 +60,14 @@ func newTestPrinter() (*print.Printer, *bytes.Buffer) {
 // owns its dependencies instead of mutating package globals.
 func testDeps() dependencies {
 	return dependencies{
-		getLatestVer:        func(context.Context, string) (string, error) { return "", nil },
-		getVerByRef:         func(context.Context, string, string) (string, error) { return "", nil },
-		installLatest:       func(context.Context, string) error { return nil },
-		installMainOrMaster: func(context.Context, string) error { return nil },
-		installByVersion:    func(context.Context, string, string) error { return nil },
+		getLatestVer:        func(context.Context, string, string) (string, error) { return "", nil },
+		getVerByRef:         func(context.Context, string, string, string) (string, error) { return "", nil },
+		installLatest:       func(context.Context, string, goutil.InstallOptions) error { return nil },
+		installMainOrMaster: func(context.Context, string, goutil.InstallOptions) error { return nil },
+		installByVersion:    func(context.Context, string, string, goutil.InstallOptions) error { return nil },
+		moduleProxy:         func(context.Context) (moduleProxy, error) { return fakeProxy{}, nil },
+		readBuildDeps:       func(string) ([]goutil.Module, error) { return nil, nil },
+		goEnv:               func(context.Context, ...string) (map[string]string, error) { return map[string]string{}, nil },
 	}
 }
 
@@ -71,9 +77,47 @@ func testDeps() dependencies {
 // the old helper_stubUpdateOps global-swap helper.
 func stubUpdateDeps() dependencies {
 	d := testDeps()
-	d.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
+	d.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
 	// The channel-aware skip/update decision resolves @main/@master versions
 	// through this ref lookup, so stub it alongside the @latest lookup.
-	d.getVerByRef = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
+	d.getVerByRef = func(context.Context, string, string, string) (string, error) { return testVersionNine, nil }
 	return d
 }
+
+// fakeProxy is an in-memory moduleProxy. go.mod files, zips and info are keyed
+// by "module@version" and version lists by module path. A missing entry is
+// reported as goproxy.ErrNotFound, like a real proxy's 404.
+type fakeProxy struct {
+	mods  map[string]string
+	zips  map[string][]byte
+	infos map[string]goproxy.Info
+	lists map[string][]string
+}
+
+func (f fakeProxy) GoMod(_ context.Context, modulePath, version string) ([]byte, error) {
+	if v, ok := f.mods[modulePath+"@"+version]; ok {
+		return []byte(v), nil
+	}
+	return nil, fmt.Errorf("%s@%s: %w", modulePath, version, goproxy.ErrNotFound)
+}
+
+func (f fakeProxy) Zip(_ context.Context, modulePath, version string) ([]byte, error) {
+	if v, ok := f.zips[modulePath+"@"+version]; ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s@%s: %w", modulePath, version, goproxy.ErrNotFound)
+}
+
+func (f fakeProxy) Info(_ context.Context, modulePath, version string) (goproxy.Info, error) {
+	if v, ok := f.infos[modulePath+"@"+version]; ok {
+		return v, nil
+	}
+	return goproxy.Info{}, fmt.Errorf("%s@%s: %w", modulePath, version, goproxy.ErrNotFound)
+}
+
+func (f fakeProxy) List(_ context.Context, modulePath string) ([]string, error) {
+	if v, ok := f.lists[modulePath]; ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s: %w", modulePath, goproxy.ErrNotFound)
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fatih/color"
+	"github.com/nao1215/gup/internal/configstate"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+// exitCodeDrift is the exit code of 'gup diff' when the installed binaries
+// differ from gup.json. Errors keep exit code 1, so scripts can tell drift
+// from failure.
+const exitCodeDrift = 2
+
+func newDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show how the installed binaries differ from gup.json",
+		Long: `Show how the installed binaries differ from gup.json.
+
+diff compares the gup.json entries with the binaries under $GOPATH/bin or
+$GOBIN and reports every difference:
+  missing  a gup.json entry that is not installed
+  removed  a gup.json entry 'gup watch' marked as removed, not installed
+  extra    an installed binary that gup.json does not list
+  version  the installed version differs from the one in gup.json
+  channel  the binary is tracked on another update channel
+  pin      a pinned binary is not installed at its pinned version
+
+Binaries are matched to gup.json entries by import path first, then by name.
+An entry recorded as "latest" is not compared by version. With --file, the
+installed binaries are compared with the channels gup keeps for them in its
+own gup.json.
+
+Nothing is installed or removed; 'gup sync' applies the differences. diff
+exits with 0 when there is no difference, 2 when there is, and 1 on error.`,
+		Example: `  gup diff
+  gup diff --file gup.json --unified
+  gup diff --json`,
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(runDiff(printerFor(cmd), cmd, args))
+		},
+	}
+
+	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to compare with")
+	mustMarkFileFlagAsJSON(cmd)
+	cmd.Flags().Bool("json", false, "output result as machine-readable JSON")
+	cmd.Flags().BoolP("unified", "u", false, "output result in a unified-diff-like form")
+
+	return cmd
+}
+
+func runDiff(p *print.Printer, cmd *cobra.Command, _ []string) int {
+	confFile, err := getFlagString(cmd, "file")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	jsonOut, err := getFlagBool(cmd, "json")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	unified, err := getFlagBool(cmd, "unified")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if jsonOut && unified {
+		p.Err(errors.New("--json and --unified can't be used together"))
+		return 1
+	}
+
+	confReadPath, confPkgs, err := readDesiredConfig(confFile)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	installed, err := installedState(p, confFile, confPkgs)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	drifts := configstate.CompareInstalled(confPkgs, installed)
+
+	switch {
+	case jsonOut:
+		enc := json.NewEncoder(p.Out())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(newDiffReport(confReadPath, drifts)); err != nil {
+			p.Err(err)
+			return 1
+		}
+	case unified:
+		printUnifiedDiff(p.Out(), confReadPath, drifts)
+	default:
+		printDiff(p.Out(), confReadPath, drifts)
+	}
+
+	if len(drifts) > 0 {
+		return exitCodeDrift
+	}
+	return 0
+}
+
+// diffReport is the 'gup diff --json' output. Its field names are part of the
+// public contract.
+type diffReport struct {
+	Config string      `json:"config"`
+	InSync bool        `json:"in_sync"`
+	Drift  []driftJSON `json:"drift"`
+}
+
+// driftJSON is one package that differs. Config is its gup.json entry and
+// Installed the installed binary; each is omitted when absent.
+type driftJSON struct {
+	Name       string     `json:"name"`
+	ImportPath string     `json:"import_path"`
+	Kinds      []string   `json:"kinds"`
+	Config     *driftSide `json:"config,omitempty"`
+	Installed  *driftSide `json:"installed,omitempty"`
+}
+
+// driftSide is one side of a driftJSON.
+type driftSide struct {
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	Channel       string `json:"channel"`
+	PinnedVersion string `json:"pinned_version,omitempty"`
+}
+
+func newDiffReport(confPath string, drifts []configstate.Drift) diffReport {
+	report := diffReport{Config: confPath, InSync: len(drifts) == 0, Drift: make([]driftJSON, 0, len(drifts))}
+	for _, d := range drifts {
+		rec := driftJSON{Name: d.Name, ImportPath: driftImportPath(d), Kinds: make([]string, 0, len(d.Kinds))}
+		for _, k := range d.Kinds {
+			rec.Kinds = append(rec.Kinds, string(k))
+		}
+		rec.Config = newDriftSide(d.Want)
+		rec.Installed = newDriftSide(d.Have)
+		report.Drift = append(report.Drift, rec)
+	}
+	return report
+}
+
+func newDriftSide(p *goutil.Package) *driftSide {
+	if p == nil {
+		return nil
+	}
+	side := &driftSide{Name: p.Name, Version: driftVersion(p), Channel: string(driftChannel(p))}
+	if p.IsPinned() {
+		side.PinnedVersion = p.PinnedVersion
+	}
+	return side
+}
+
+// driftImportPath is the import path of d, preferring the gup.json entry.
+func driftImportPath(d configstate.Drift) string {
+	if d.Want != nil && d.Want.ImportPath != "" {
+		return d.Want.ImportPath
+	}
+	if d.Have != nil {
+		return d.Have.ImportPath
+	}
+	return ""
+}
+
+// printDiff prints one line per difference.
+func printDiff(w io.Writer, confPath string, drifts []configstate.Drift) {
+	if len(drifts) == 0 {
+		_, _ = fmt.Fprintf(w, "no difference: the installed binaries match %s\n", confPath)
+		return
+	}
+	_, _ = fmt.Fprintf(w, "differences from %s:\n", confPath)
+	for _, d := range drifts {
+		for _, k := range d.Kinds {
+			_, _ = fmt.Fprintf(w, "  %-8s %s (%s)\n", k, d.Name, describeDrift(d, k))
+		}
+	}
+	_, _ = fmt.Fprintf(w, "%d package(s) differ\n", len(drifts))
+}
+
+// describeDrift explains one kind of difference of d.
+func describeDrift(d configstate.Drift, kind configstate.DriftKind) string {
+	switch kind {
+	case configstate.DriftMissing:
+		return fmt.Sprintf("%s@%s is not installed", d.Want.ImportPath, driftVersion(d.Want))
+	case configstate.DriftRemoved:
+		return fmt.Sprintf("%s@%s was deleted and is marked as removed in gup.json", d.Want.ImportPath, driftVersion(d.Want))
+	case configstate.DriftExtra:
+		return fmt.Sprintf("%s@%s is not in gup.json", d.Have.ImportPath, driftVersion(d.Have))
+	case configstate.DriftVersion:
+		return fmt.Sprintf("installed %s, gup.json %s", driftVersion(d.Have), driftVersion(d.Want))
+	case configstate.DriftChannel:
+		return fmt.Sprintf("installed on %s, gup.json %s", driftChannel(d.Have), driftChannel(d.Want))
+	case configstate.DriftPin:
+		return fmt.Sprintf("pinned to %s, installed %s", d.Want.PinnedVersion, driftVersion(d.Have))
+	}
+	return ""
+}
+
+// printUnifiedDiff prints the differences like a unified diff of gup.json
+// against the installed binaries: "-" lines are gup.json entries and "+" lines
+// the installed binaries. Packages that match are not printed.
+func printUnifiedDiff(w io.Writer, confPath string, drifts []configstate.Drift) {
+	if len(drifts) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "--- %s\n+++ installed\n", confPath)
+	for _, d := range drifts {
+		if d.Want != nil {
+			_, _ = fmt.Fprintln(w, color.RedString("-%s", unifiedDiffLine(d.Want)))
+		}
+		if d.Have != nil {
+			_, _ = fmt.Fprintln(w, color.GreenString("+%s", unifiedDiffLine(d.Have)))
+		}
+	}
+}
+
+func unifiedDiffLine(p *goutil.Package) string {
+	version := driftVersion(p)
+	if p.IsPinned() {
+		version = p.PinnedVersion
+	}
+	return fmt.Sprintf("%s %s@%s (%s)", p.Name, p.ImportPath, version, driftChannel(p))
+}
@@ -0,0 +1,320 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/nao1215/gup/internal/configstate"
+	"github.com/nao1215/gup/internal/depdiff"
+	"github.com/nao1215/gup/internal/goproxy"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/pkgselect"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+// prunedModuleGraphGoVersion is the first go directive whose go.mod lists every
+// module that provides a package to the main module (module graph pruning).
+// Before it, go.mod only lists direct requirements, so "removed" and "added"
+// entries are incomplete.
+const prunedModuleGraphGoVersion = "1.17"
+
+func newDiffDepsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff-deps TOOL",
+		Short: "Show how a binary's dependencies change between the installed and the candidate version",
+		Long: `Show how a binary's dependencies change between the installed and the candidate version.
+
+diff-deps compares the modules linked into the installed binary (its build
+info) with the requirements in the candidate version's go.mod, fetched from the
+module proxy configured by GOPROXY, and lists the modules that would be added,
+removed, upgraded or downgraded by an update. Modules that commonly carry
+security fixes (x/crypto, x/net, grpc, JWT/JOSE libraries, ...) are marked
+[security].
+
+The candidate version defaults to what 'gup update' would install: the pinned
+version for a pinned binary, otherwise the latest version on the binary's
+update channel. Use --to to compare against any other version or branch.
+
+Nothing is installed. Private modules (GOPRIVATE/GONOPROXY) and GOPROXY=direct
+are not supported, because gup only reads from module proxies.`,
+		Example: `  gup diff-deps gopls
+  gup diff-deps gopls --to v0.16.0
+  gup diff-deps gopls --json`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeFirstArgPathBinaries,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(runDiffDeps(defaultDependencies(), printerFor(cmd), cmd, args))
+		},
+	}
+	cmd.Flags().String("to", "", "compare against this version, branch or commit instead of the update-channel target")
+	mustRegisterFlagCompletion(cmd, "to", cobra.NoFileCompletions)
+	cmd.Flags().Bool("json", false, "output result as machine-readable JSON")
+	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to read saved update channels from")
+	mustMarkFileFlagAsJSON(cmd)
+	addTimeoutFlag(cmd)
+	return cmd
+}
+
+// diffDepsOpts holds the parsed command-line flags for the diff-deps command.
+type diffDepsOpts struct {
+	to       string
+	jsonOut  bool
+	confFile string
+	timeout  time.Duration
+}
+
+func parseDiffDepsFlags(cmd *cobra.Command) (diffDepsOpts, error) {
+	var opts diffDepsOpts
+	var err error
+
+	if opts.to, err = getFlagString(cmd, "to"); err != nil {
+		return diffDepsOpts{}, err
+	}
+	opts.to = strings.TrimSpace(opts.to)
+	if opts.jsonOut, err = getFlagBool(cmd, "json"); err != nil {
+		return diffDepsOpts{}, err
+	}
+	if opts.confFile, err = getFlagString(cmd, "file"); err != nil {
+		return diffDepsOpts{}, err
+	}
+	if opts.timeout, err = getTimeoutFlag(cmd); err != nil {
+		return diffDepsOpts{}, err
+	}
+	return opts, nil
+}
+
+// depDiffReport is the result of comparing one binary's dependencies. It is also
+// the --json record, so its field names are part of the public contract.
+type depDiffReport struct {
+	Name             string          `json:"name"`
+	ImportPath       string          `json:"import_path"`
+	ModulePath       string          `json:"module_path"`
+	CurrentVersion   string          `json:"current_version"`
+	CandidateVersion string          `json:"candidate_version"`
+	CandidateGo      string          `json:"candidate_go_version,omitempty"`
+	Changes          []depChangeJSON `json:"changes"`
+	// Warnings explains why the comparison may be incomplete.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// depChangeJSON is one dependency change in the --json output.
+type depChangeJSON struct {
+	Module   string `json:"module"`
+	Kind     string `json:"kind"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	Security bool   `json:"security"`
+}
+
+func runDiffDeps(deps dependencies, p *print.Printer, cmd *cobra.Command, args []string) int {
+	if err := ensureGoCommandAvailable(); err != nil {
+		p.Err(err)
+		return 1
+	}
+	opts, err := parseDiffDepsFlags(cmd)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	pkg, err := resolveConfiguredTarget(p, args[0], opts.confFile)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	gobin, err := goutil.GoBin()
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	ctx, stop := newBoundedSignalContext(context.Background(), opts.timeout)
+	defer stop()
+
+	report, err := diffDeps(ctx, deps, pkg, filepath.Join(gobin, pkg.Name), opts.to)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	if opts.jsonOut {
+		enc := json.NewEncoder(p.Out())
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			p.Err(err)
+			return 1
+		}
+		return 0
+	}
+	for _, w := range report.Warnings {
+		p.Warn(w)
+	}
+	printDepDiff(p, report)
+	return 0
+}
+
+// resolveConfiguredTarget resolves target to one installed package and applies
+// its saved update channel (or pin) from gup.json, so the commands that inspect
+// a single binary compare against the same version 'gup update' would install.
+func resolveConfiguredTarget(p *print.Printer, target, confFile string) (goutil.Package, error) {
+	installed, err := pkgselect.PackageInfo(p)
+	if err != nil {
+		return goutil.Package{}, err
+	}
+	pkg, err := resolveInstalledTarget(installed, strings.TrimSpace(target), "inspect")
+	if err != nil {
+		return goutil.Package{}, err
+	}
+	annotated, err := configstate.ResolveAndApplyChannels([]goutil.Package{pkg}, confFile)
+	if err != nil {
+		return goutil.Package{}, err
+	}
+	return annotated[0], nil
+}
+
+// diffDeps builds the dependency comparison for pkg, whose binary is at
+// binPath. to overrides the candidate version; when empty the version 'gup
+// update' would install is used.
+func diffDeps(ctx context.Context, deps dependencies, pkg goutil.Package, binPath, to string) (depDiffReport, error) {
+	if strings.TrimSpace(pkg.ModulePath) == "" {
+		return depDiffReport{}, fmt.Errorf("can't determine the module path of %s", pkg.Name)
+	}
+
+	candidate, err := candidateVersion(ctx, deps, pkg, to)
+	if err != nil {
+		return depDiffReport{}, err
+	}
+
+	binDeps, err := deps.readBuildDeps(binPath)
+	if err != nil {
+		return depDiffReport{}, err
+	}
+	proxy, err := deps.moduleProxy(ctx)
+	if err != nil {
+		return depDiffReport{}, err
+	}
+	raw, err := proxy.GoMod(ctx, pkg.ModulePath, candidate)
+	if err != nil {
+		return depDiffReport{}, fmt.Errorf("can't fetch go.mod of %s@%s: %w", pkg.ModulePath, candidate, err)
+	}
+	candidateMod, err := goproxy.ParseGoMod(raw)
+	if err != nil {
+		return depDiffReport{}, fmt.Errorf("can't parse go.mod of %s@%s: %w", pkg.ModulePath, candidate, err)
+	}
+
+	current := ""
+	if pkg.Version != nil {
+		current = pkg.Version.Current
+	}
+	report := depDiffReport{
+		Name:             pkg.Name,
+		ImportPath:       pkg.ImportPath,
+		ModulePath:       pkg.ModulePath,
+		CurrentVersion:   current,
+		CandidateVersion: candidate,
+		CandidateGo:      candidateMod.Go,
+	}
+
+	// The binary only records the modules it links, while go.mod also requires
+	// modules used by other packages and tests. Overlaying the binary on the
+	// installed version's own go.mod keeps those from showing up as "added".
+	// A binary without a release version (unknown, or built from a local
+	// checkout) has no go.mod on the proxy, so it is not asked for one.
+	installedMods := binDeps
+	switch current {
+	case "", develVersion, develVersionParen:
+		report.Warnings = append(report.Warnings,
+			"the installed version is unknown or a devel build; modules required but not linked may be listed as added")
+	default:
+		if raw, err := proxy.GoMod(ctx, pkg.ModulePath, current); err == nil {
+			if mf, err := goproxy.ParseGoMod(raw); err == nil {
+				installedMods = depdiff.Overlay(mf.Require, binDeps)
+			}
+		} else {
+			report.Warnings = append(report.Warnings, fmt.Sprintf(
+				"can't fetch go.mod of the installed version %s; modules required but not linked may be listed as added", current))
+		}
+	}
+	if candidateMod.Go != "" && !goutil.GoVersionUpToDate(candidateMod.Go, prunedModuleGraphGoVersion) {
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"%s@%s declares go %s, so its go.mod lists only direct requirements; the diff is incomplete",
+			pkg.ModulePath, candidate, candidateMod.Go))
+	}
+
+	report.Changes = make([]depChangeJSON, 0)
+	for _, c := range depdiff.Compare(installedMods, candidateMod.Require) {
+		report.Changes = append(report.Changes, depChangeJSON{
+			Module: c.Path, Kind: string(c.Kind), From: c.From, To: c.To, Security: c.Security,
+		})
+	}
+	return report, nil
+}
+
+// candidateVersion resolves the version to compare against. An explicit --to is
+// resolved through 'go list -m' so branch names and short commits become
+// canonical versions. Otherwise a pin wins, and the package's update channel
+// selects the version exactly as 'gup update' would.
+func candidateVersion(ctx context.Context, deps dependencies, pkg goutil.Package, to string) (string, error) {
+	if to != "" {
+		v, err := deps.getVerByRef(ctx, pkg.ModulePath, to, "")
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(v) == "" {
+			return "", fmt.Errorf("can't resolve %s@%s", pkg.ModulePath, to)
+		}
+		return v, nil
+	}
+	if pkg.IsPinned() {
+		return pkg.PinnedVersion, nil
+	}
+	v, err := deps.lookup(ctx, deps.newVerCache(), pkg.ModulePath, pkg.UpdateChannel)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(v) == "" {
+		return "", errors.New("can't resolve the candidate version of " + pkg.ModulePath)
+	}
+	return v, nil
+}
+
+// printDepDiff prints the human-readable dependency diff.
+func printDepDiff(p *print.Printer, r depDiffReport) {
+	out := p.Out()
+	_, _ = fmt.Fprintf(out, "%s (%s) %s -> %s\n", r.Name, r.ModulePath, r.CurrentVersion, r.CandidateVersion)
+	if len(r.Changes) == 0 {
+		_, _ = fmt.Fprintln(out, "  no dependency changes")
+		return
+	}
+
+	counts := map[string]int{}
+	security := 0
+	for _, c := range r.Changes {
+		counts[c.Kind]++
+		line := fmt.Sprintf("  %-10s %s", c.Kind, c.Module)
+		switch depdiff.Kind(c.Kind) {
+		case depdiff.Added:
+			line += " " + c.To
+		case depdiff.Removed:
+			line += " " + c.From
+		default:
+			line += fmt.Sprintf(" %s -> %s", c.From, c.To)
+		}
+		if c.Security {
+			security++
+			line += " " + color.RedString("[security]")
+		}
+		_, _ = fmt.Fprintln(out, line)
+	}
+	_, _ = fmt.Fprintf(out, "%d upgraded, %d downgraded, %d added, %d removed (%d security-relevant)\n",
+		counts[string(depdiff.Upgraded)], counts[string(depdiff.Downgraded)],
+		counts[string(depdiff.Added)], counts[string(depdiff.Removed)], security)
+}
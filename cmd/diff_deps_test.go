@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goproxy"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+const (
+	diffDepsTestModule = "golang.org/x/tools/gopls"
+	diffDepsTestOld    = "v0.15.0"
+	diffDepsTestNew    = "v0.16.0"
+)
+
+// diffDepsTestDeps wires a fake proxy serving the installed (v0.15.0) and
+// candidate (v0.16.0) go.mod files, and a binary linking x/crypto and cobra.
+func diffDepsTestDeps() dependencies {
+	d := testDeps()
+	d.getLatestVer = func(context.Context, string, string) (string, error) { return diffDepsTestNew, nil }
+	d.getVerByRef = func(_ context.Context, _, ref, _ string) (string, error) { return ref, nil }
+	d.moduleProxy = func(context.Context) (moduleProxy, error) {
+		return fakeProxy{mods: map[string]string{
+			diffDepsTestModule + "@" + diffDepsTestOld: "module " + diffDepsTestModule + "\n\ngo 1.21\n\nrequire (\n" +
+				"\tgolang.org/x/crypto v0.20.0\n\tgithub.com/spf13/cobra v1.8.0\n\tgithub.com/test/only v1.0.0 // indirect\n)\n",
+			diffDepsTestModule + "@" + diffDepsTestNew: "module " + diffDepsTestModule + "\n\ngo 1.22\n\nrequire (\n" +
+				"\tgolang.org/x/crypto v0.31.0\n\tgithub.com/spf13/cobra v1.8.1\n\tgithub.com/test/only v1.0.0\n\tgithub.com/new/dep v0.1.0\n)\n",
+		}}, nil
+	}
+	d.readBuildDeps = func(string) ([]goutil.Module, error) {
+		return []goutil.Module{
+			{Path: "golang.org/x/crypto", Version: "v0.20.0"},
+			{Path: "github.com/spf13/cobra", Version: "v1.8.1"},
+			{Path: "github.com/gone/dep", Version: "v1.0.0"},
+		}, nil
+	}
+	return d
+}
+
+// recordingProxy passes go.mod requests through, recording their versions.
+type recordingProxy struct {
+	moduleProxy
+	record func(version string)
+}
+
+func (r recordingProxy) GoMod(ctx context.Context, modulePath, version string) ([]byte, error) {
+	r.record(version)
+	return r.moduleProxy.GoMod(ctx, modulePath, version)
+}
+
+func diffDepsTestPackage() goutil.Package {
+	return goutil.Package{
+		Name:       "gopls",
+		ImportPath: diffDepsTestModule,
+		ModulePath: diffDepsTestModule,
+		Version:    &goutil.Version{Current: diffDepsTestOld},
+	}
+}
+
+func Test_diffDeps(t *testing.T) {
+	t.Parallel()
+
+	t.Run("compares the binary with the candidate go.mod", func(t *testing.T) {
+		t.Parallel()
+		got, err := diffDeps(context.Background(), diffDepsTestDeps(), diffDepsTestPackage(), "gopls", "")
+		if err != nil {
+			t.Fatalf("diffDeps() error = %v", err)
+		}
+		want := depDiffReport{
+			Name:             "gopls",
+			ImportPath:       diffDepsTestModule,
+			ModulePath:       diffDepsTestModule,
+			CurrentVersion:   diffDepsTestOld,
+			CandidateVersion: diffDepsTestNew,
+			CandidateGo:      "1.22",
+			Changes: []depChangeJSON{
+				// cobra is already v1.8.1 in the binary (MVS picked it), so it is
+				// not reported; the test-only module is known from the old go.mod.
+				{Module: "github.com/gone/dep", Kind: "removed", From: "v1.0.0"},
+				{Module: "github.com/new/dep", Kind: "added", To: "v0.1.0"},
+				{Module: "golang.org/x/crypto", Kind: "upgraded", From: "v0.20.0", To: "v0.31.0", Security: true},
+			},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("diffDeps() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("--to overrides the channel target", func(t *testing.T) {
+		t.Parallel()
+		got, err := diffDeps(context.Background(), diffDepsTestDeps(), diffDepsTestPackage(), "gopls", diffDepsTestOld)
+		if err != nil {
+			t.Fatalf("diffDeps() error = %v", err)
+		}
+		if got.CandidateVersion != diffDepsTestOld {
+			t.Errorf("CandidateVersion = %q, want %q", got.CandidateVersion, diffDepsTestOld)
+		}
+	})
+
+	t.Run("pinned package compares against the pin", func(t *testing.T) {
+		t.Parallel()
+		pkg := diffDepsTestPackage()
+		pkg.UpdateChannel = goutil.UpdateChannelPinned
+		pkg.PinnedVersion = diffDepsTestOld
+		got, err := diffDeps(context.Background(), diffDepsTestDeps(), pkg, "gopls", "")
+		if err != nil {
+			t.Fatalf("diffDeps() error = %v", err)
+		}
+		if got.CandidateVersion != diffDepsTestOld {
+			t.Errorf("CandidateVersion = %q, want the pinned %q", got.CandidateVersion, diffDepsTestOld)
+		}
+	})
+
+	t.Run("warns when the installed go.mod is unavailable", func(t *testing.T) {
+		t.Parallel()
+		pkg := diffDepsTestPackage()
+		pkg.Version.Current = "v0.14.0"
+		got, err := diffDeps(context.Background(), diffDepsTestDeps(), pkg, "gopls", "")
+		if err != nil {
+			t.Fatalf("diffDeps() error = %v", err)
+		}
+		if len(got.Warnings) != 1 || !strings.Contains(got.Warnings[0], "can't fetch go.mod") {
+			t.Errorf("Warnings = %v, want one fetch warning", got.Warnings)
+		}
+	})
+
+	for _, current := range []string{"", "(devel)"} {
+		t.Run("skips the proxy for installed version "+strconv.Quote(current), func(t *testing.T) {
+			t.Parallel()
+			deps := diffDepsTestDeps()
+			proxyFor := deps.moduleProxy
+			var asked []string
+			var mu sync.Mutex
+			deps.moduleProxy = func(ctx context.Context) (moduleProxy, error) {
+				proxy, err := proxyFor(ctx)
+				return recordingProxy{moduleProxy: proxy, record: func(v string) {
+					mu.Lock()
+					defer mu.Unlock()
+					asked = append(asked, v)
+				}}, err
+			}
+			pkg := diffDepsTestPackage()
+			pkg.Version.Current = current
+			got, err := diffDeps(context.Background(), deps, pkg, "gopls", "")
+			if err != nil {
+				t.Fatalf("diffDeps() error = %v", err)
+			}
+			if diff := cmp.Diff([]string{diffDepsTestNew}, asked); diff != "" {
+				t.Errorf("go.mod versions fetched mismatch (-want +got):\n%s", diff)
+			}
+			if len(got.Warnings) != 1 || !strings.Contains(got.Warnings[0], "unknown or a devel build") {
+				t.Errorf("Warnings = %v, want one devel build warning", got.Warnings)
+			}
+		})
+	}
+
+	t.Run("candidate go.mod not found", func(t *testing.T) {
+		t.Parallel()
+		_, err := diffDeps(context.Background(), diffDepsTestDeps(), diffDepsTestPackage(), "gopls", "v9.9.9")
+		if !errors.Is(err, goproxy.ErrNotFound) {
+			t.Fatalf("diffDeps() error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("missing module path", func(t *testing.T) {
+		t.Parallel()
+		pkg := diffDepsTestPackage()
+		pkg.ModulePath = ""
+		if _, err := diffDeps(context.Background(), diffDepsTestDeps(), pkg, "gopls", ""); err == nil {
+			t.Fatal("diffDeps() error = nil, want error")
+		}
+	})
+}
+
+func Test_printDepDiff(t *testing.T) {
+	t.Parallel()
+
+	p, buf := newTestPrinter()
+	printDepDiff(p, depDiffReport{
+		Name:             "gopls",
+		ModulePath:       diffDepsTestModule,
+		CurrentVersion:   diffDepsTestOld,
+		CandidateVersion: diffDepsTestNew,
+		Changes: []depChangeJSON{
+			{Module: "github.com/new/dep", Kind: "added", To: "v0.1.0"},
+			{Module: "golang.org/x/crypto", Kind: "upgraded", From: "v0.20.0", To: "v0.31.0", Security: true},
+		},
+	})
+	out := buf.String()
+	for _, want := range []string{
+		"gopls (golang.org/x/tools/gopls) v0.15.0 -> v0.16.0",
+		"added      github.com/new/dep v0.1.0",
+		"upgraded   golang.org/x/crypto v0.20.0 -> v0.31.0",
+		"[security]",
+		"1 upgraded, 0 downgraded, 1 added, 0 removed (1 security-relevant)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	p, buf = newTestPrinter()
+	printDepDiff(p, depDiffReport{Name: "gopls"})
+	if !strings.Contains(buf.String(), "no dependency changes") {
+		t.Errorf("output = %q, want no-changes message", buf.String())
+	}
+}
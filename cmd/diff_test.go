@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// runDiffWith runs 'gup diff' against syncTestConf and syncTestInstalled with
+// the given flags and returns the exit code and output.
+func runDiffWith(t *testing.T, flags map[string]string) (int, string) {
+	t.Helper()
+	setupXDGBase(t)
+	chdirToTemp(t)
+	stubInstalledPackageInfo(t, syncTestInstalled())
+	confPath := filepath.Join(t.TempDir(), "gup.json")
+	if err := os.WriteFile(confPath, []byte(syncTestConf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := newDiffCmd()
+	if err := cmd.Flags().Set("file", confPath); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range flags {
+		if err := cmd.Flags().Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, buf := newTestPrinter()
+	return runDiff(p, cmd, nil), buf.String()
+}
+
+//nolint:paralleltest // swaps package globals and XDG env
+func Test_runDiff_human(t *testing.T) {
+	code, out := runDiffWith(t, nil)
+	if code != exitCodeDrift {
+		t.Fatalf("runDiff() = %d, want %d; output: %s", code, exitCodeDrift, out)
+	}
+	for _, want := range []string{
+		"missing  missing (example.com/missing@v1.0.0 is not installed)",
+		"extra    extra (example.com/extra@v1.0.0 is not in gup.json)",
+		"version  old (installed v1.3.0, gup.json v1.2.0)",
+		"channel  tracked (installed on latest, gup.json main)",
+		"channel  held (installed on latest, gup.json pinned)",
+		"pin      held (pinned to v0.9.0, installed v1.0.0)",
+		"removed  gone (example.com/gone@v1.0.0 was deleted and is marked as removed in gup.json)",
+		"6 package(s) differ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+//nolint:paralleltest // swaps package globals and XDG env
+func Test_runDiff_json(t *testing.T) {
+	code, out := runDiffWith(t, map[string]string{"json": "true"})
+	if code != exitCodeDrift {
+		t.Fatalf("runDiff() = %d, want %d; output: %s", code, exitCodeDrift, out)
+	}
+	var report diffReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out)
+	}
+	if report.InSync || len(report.Drift) != 6 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if gone := report.Drift[1]; gone.Name != "gone" || !slices.Equal(gone.Kinds, []string{"removed"}) || gone.Installed != nil {
+		t.Errorf("an entry marked as removed is reported as removed: %+v", gone)
+	}
+	held := report.Drift[2]
+	want := driftJSON{
+		Name:       "held",
+		ImportPath: "example.com/held",
+		Kinds:      []string{"channel", "pin"},
+		Config:     &driftSide{Name: "held", Version: "v0.9.0", Channel: "pinned", PinnedVersion: "v0.9.0"},
+		Installed:  &driftSide{Name: "held", Version: "v1.0.0", Channel: "latest"},
+	}
+	if diff := cmp.Diff(want, held); diff != "" {
+		t.Errorf("held record mismatch (-want +got):\n%s", diff)
+	}
+	if report.Drift[0].Name != "extra" || report.Drift[0].Config != nil {
+		t.Errorf("an extra binary has no config side: %+v", report.Drift[0])
+	}
+}
+
+//nolint:paralleltest // swaps package globals and XDG env
+func Test_runDiff_unified(t *testing.T) {
+	code, out := runDiffWith(t, map[string]string{"unified": "true"})
+	if code != exitCodeDrift {
+		t.Fatalf("runDiff() = %d, want %d; output: %s", code, exitCodeDrift, out)
+	}
+	for _, want := range []string{
+		"+++ installed",
+		"-old example.com/old@v1.2.0 (latest)",
+		"+old example.com/old@v1.3.0 (latest)",
+		"-missing example.com/missing@v1.0.0 (latest)",
+		"+extra example.com/extra@v1.0.0 (latest)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "same") {
+		t.Errorf("a matching package must not be printed:\n%s", out)
+	}
+}
+
+func Test_runDiff_jsonAndUnified(t *testing.T) {
+	t.Parallel()
+
+	cmd := newDiffCmd()
+	for _, name := range []string{"json", "unified"} {
+		if err := cmd.Flags().Set(name, "true"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, buf := newTestPrinter()
+	if got := runDiff(p, cmd, nil); got != 1 {
+		t.Fatalf("runDiff() = %d, want 1", got)
+	}
+	if !strings.Contains(buf.String(), "can't be used together") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
+
+//nolint:paralleltest // swaps package globals and XDG env
+func Test_runDiff_inSync(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+	stubInstalledPackageInfo(t, []goutil.Package{
+		{Name: "same", ImportPath: "example.com/same", Version: &goutil.Version{Current: "v3.0.0"}},
+	})
+	if err := os.MkdirAll(filepath.Dir(config.FilePath()), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	conf := `{"schema_version":1,"packages":[{"name":"same","import_path":"example.com/same","version":"v3.0.0","channel":"latest"}]}`
+	if err := os.WriteFile(config.FilePath(), []byte(conf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, buf := newTestPrinter()
+	if got := runDiff(p, newDiffCmd(), nil); got != 0 {
+		t.Fatalf("runDiff() = %d, want 0; output: %s", got, buf.String())
+	}
+	if !strings.Contains(buf.String(), "no difference") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
+
+//nolint:paralleltest // swaps XDG env
+func Test_runDiff_missingConfig(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+
+	p, buf := newTestPrinter()
+	if got := runDiff(p, newDiffCmd(), nil); got != 1 {
+		t.Fatalf("runDiff() without gup.json = %d, want 1", got)
+	}
+	if !strings.Contains(buf.String(), "is not found") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
@@ -20,6 +20,12 @@ import (
 // config problem, the command emits an empty JSON array (--json) or an
 // informational note before exiting 0.
 func handleEmptyEnvironment(p *print.Printer, confFile string, jsonOut, explicitSelection bool, usageErr string) int {
+	return handleEmptyEnvironmentReport(p, confFile, jsonOut, explicitSelection, usageErr, nil)
+}
+
+// handleEmptyEnvironmentReport is handleEmptyEnvironment that writes the empty
+// result as report asks: an empty v2 envelope, or a lone summary event.
+func handleEmptyEnvironmentReport(p *print.Printer, confFile string, jsonOut, explicitSelection bool, usageErr string, report *jsonReport) int {
 	if explicitSelection {
 		p.Err(usageErr)
 		return 1
@@ -29,12 +35,15 @@ func handleEmptyEnvironment(p *print.Printer, confFile string, jsonOut, explicit
 		return 1
 	}
 	if jsonOut {
-		if err := encodeJSONPackages(p, nil); err != nil {
+		if err := encodeJSONResults(p, report, nil, nil); err != nil {
 			p.Err(err)
 			return 1
 		}
 		return 0
 	}
 	p.Info(emptyEnvMessage)
+	if s := report.events(); s != nil {
+		s.summary(nil, 0)
+	}
 	return 0
 }
@@ -18,9 +18,19 @@ func newExportCmd() *cobra.Command {
 Use export/import if you want to install the same Go binaries
 across multiple systems. This sub-command writes gup.json
 (default: $XDG_CONFIG_HOME/gup/gup.json), and the target system can
-apply it with 'gup import'.`,
+apply it with 'gup import'.
+
+With --build, export cross-compiles the tool set instead: every package is
+built at its recorded version (the pinned version for a pinned package) for
+--goos/--goarch into the --out directory, together with a gup-manifest.json
+that records each binary's package, version and SHA-256. The packages come
+from the installed binaries, with channels and pins from gup.json, or from
+the gup.json given with --file. Copy the directory to the target machine and
+run 'gup migrate DIR "$(go env GOPATH)/bin"' there: migrate verifies the
+hashes and installs the prebuilt binaries without rebuilding them.`,
 		Example: `  gup export
-  gup export --output > gup.json`,
+  gup export --output > gup.json
+  gup export --build --goos linux --goarch arm64 --out ./dist`,
 		Args:              cobra.NoArgs,
 		ValidArgsFunction: cobra.NoFileCompletions,
 		Run: func(cmd *cobra.Command, args []string) {
@@ -30,11 +40,25 @@ apply it with 'gup import'.`,
 	cmd.Flags().BoolP("output", "o", false, "print command path information at STDOUT")
 	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to export")
 	mustMarkFileFlagAsJSON(cmd)
+	addExportBuildFlags(cmd)
 
 	return cmd
 }
 
 func export(p *print.Printer, cmd *cobra.Command, _ []string) int {
+	build, err := getFlagBool(cmd, "build")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if build {
+		return runExportBuild(p, cmd)
+	}
+	if err := rejectBuildOnlyFlags(cmd); err != nil {
+		p.Err(err)
+		return 1
+	}
+
 	// export only reads local build info from $GOBIN and writes gup.json; it never
 	// invokes the Go toolchain, so it must not fail when 'go' is absent (mirrors
 	// 'gup unpin').
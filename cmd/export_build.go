@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"context"
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nao1215/gup/internal/buildmanifest"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/configstate"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/pkgselect"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+// buildForPlatformCtx cross-compiles one package. It is a package-level
+// variable so tests can swap the implementation.
+var buildForPlatformCtx = goutil.BuildForPlatformWithContext //nolint:gochecknoglobals // swapped in tests
+
+// defaultBuildOutDir is where 'export --build' writes when --out is not given.
+const defaultBuildOutDir = "dist"
+
+// exportBuildOnlyFlags are the export flags that only make sense with --build.
+var exportBuildOnlyFlags = []string{"goos", "goarch", "out", "jobs", timeoutFlagName} //nolint:gochecknoglobals // read-only table
+
+func addExportBuildFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("build", false, "cross-compile the tool set into --out instead of writing gup.json")
+	cmd.Flags().String("goos", runtime.GOOS, "target operating system for --build")
+	mustRegisterFlagCompletion(cmd, "goos", cobra.NoFileCompletions)
+	cmd.Flags().String("goarch", runtime.GOARCH, "target architecture for --build")
+	mustRegisterFlagCompletion(cmd, "goarch", cobra.NoFileCompletions)
+	cmd.Flags().String("out", defaultBuildOutDir, "directory to write the --build binaries and gup-manifest.json to")
+	if err := cmd.MarkFlagDirname("out"); err != nil {
+		panic(err)
+	}
+	cmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "specify the number of CPU cores to use for --build")
+	mustRegisterFlagCompletion(cmd, "jobs", completeNCPUs)
+	addTimeoutFlag(cmd)
+}
+
+// rejectBuildOnlyFlags fails when a --build flag is given without --build, so a
+// forgotten --build never silently exports gup.json instead of building.
+func rejectBuildOnlyFlags(cmd *cobra.Command) error {
+	for _, name := range exportBuildOnlyFlags {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s requires --build", name)
+		}
+	}
+	return nil
+}
+
+// exportBuildOpts holds the parsed command-line flags for 'export --build'.
+type exportBuildOpts struct {
+	platform goutil.Platform
+	outDir   string
+	confFile string
+	cpus     int
+	timeout  time.Duration
+}
+
+func parseExportBuildFlags(cmd *cobra.Command) (exportBuildOpts, error) {
+	var opts exportBuildOpts
+	var err error
+
+	if output, err := getFlagBool(cmd, "output"); err != nil {
+		return exportBuildOpts{}, err
+	} else if output {
+		return exportBuildOpts{}, errors.New("--output can't be used with --build")
+	}
+	if opts.platform.OS, err = getFlagString(cmd, "goos"); err != nil {
+		return exportBuildOpts{}, err
+	}
+	if opts.platform.Arch, err = getFlagString(cmd, "goarch"); err != nil {
+		return exportBuildOpts{}, err
+	}
+	opts.platform.OS = strings.TrimSpace(opts.platform.OS)
+	opts.platform.Arch = strings.TrimSpace(opts.platform.Arch)
+	if opts.platform.OS == "" || opts.platform.Arch == "" {
+		return exportBuildOpts{}, errors.New("--goos and --goarch must not be empty")
+	}
+	if opts.outDir, err = getFlagString(cmd, "out"); err != nil {
+		return exportBuildOpts{}, err
+	}
+	if strings.TrimSpace(opts.outDir) == "" {
+		return exportBuildOpts{}, errors.New("--out must not be empty")
+	}
+	if opts.confFile, err = getFlagString(cmd, "file"); err != nil {
+		return exportBuildOpts{}, err
+	}
+	if opts.cpus, err = getFlagInt(cmd, "jobs"); err != nil {
+		return exportBuildOpts{}, err
+	}
+	opts.cpus = clampJobs(opts.cpus)
+	if opts.timeout, err = getTimeoutFlag(cmd); err != nil {
+		return exportBuildOpts{}, err
+	}
+	return opts, nil
+}
+
+func runExportBuild(p *print.Printer, cmd *cobra.Command) int {
+	if err := ensureGoCommandAvailable(); err != nil {
+		p.Err(err)
+		return 1
+	}
+	opts, err := parseExportBuildFlags(cmd)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	pkgs, err := exportBuildPackages(p, opts.confFile)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if len(pkgs) == 0 {
+		p.Err("unable to build: no package information")
+		return 1
+	}
+
+	p.Info(fmt.Sprintf("start building %d package(s) for %s into %s", len(pkgs), opts.platform, opts.outDir))
+	m, exitCode, err := buildPackagesForPlatform(p, pkgs, opts)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if exitCode == 0 {
+		p.Info(fmt.Sprintf("Built %d binaries for %s; manifest: %s", len(m.Binaries), opts.platform,
+			filepath.Join(opts.outDir, buildmanifest.FileName)))
+	}
+	return exitCode
+}
+
+// exportBuildPackages returns the packages to build: the entries of the
+// gup.json given with --file, or else the installed binaries with their saved
+// channels and pins applied, so each one is built at the version 'gup update'
+// keeps it on.
+func exportBuildPackages(p *print.Printer, confFile string) ([]goutil.Package, error) {
+	if confFile != "" {
+		if !fileutil.IsFile(confFile) {
+			return nil, fmt.Errorf("%s is not found", confFile)
+		}
+		return config.ReadConfFile(confFile)
+	}
+	installed, err := pkgselect.PackageInfo(p)
+	if err != nil {
+		return nil, err
+	}
+	return configstate.ResolveAndApplyChannels(validPkgInfo(p, installed), "")
+}
+
+// buildVersion is the version 'export --build' builds pkg at: the pin, else the
+// recorded version, else the head of its update channel when no concrete
+// version was recorded.
+func buildVersion(pkg goutil.Package) string {
+	if pkg.IsPinned() {
+		return pkg.PinnedVersion
+	}
+	v := ""
+	if pkg.Version != nil {
+		v = strings.TrimSpace(pkg.Version.Current)
+	}
+	switch v {
+	case "", develVersion, develVersionParen, latestKeyword, "unknown":
+		switch goutil.NormalizeUpdateChannel(string(pkg.UpdateChannel)) {
+		case goutil.UpdateChannelMain:
+			return string(goutil.UpdateChannelMain)
+		case goutil.UpdateChannelMaster:
+			return string(goutil.UpdateChannelMaster)
+		default:
+			return latestKeyword
+		}
+	}
+	return v
+}
+
+// buildPackagesForPlatform builds pkgs for opts.platform into opts.outDir and
+// writes the manifest of the binaries that built. A package that fails to build
+// makes the exit code 1 but does not discard the others.
+func buildPackagesForPlatform(pr *print.Printer, pkgs []goutil.Package, opts exportBuildOpts) (buildmanifest.Manifest, int, error) {
+	// The go command requires an absolute GOPATH, and the build GOPATHs live
+	// inside outDir.
+	outDir, err := filepath.Abs(opts.outDir)
+	if err != nil {
+		return buildmanifest.Manifest{}, 1, fmt.Errorf("can't resolve %s: %w", opts.outDir, err)
+	}
+	if err := os.MkdirAll(outDir, fileutil.FileModeCreatingDir); err != nil {
+		return buildmanifest.Manifest{}, 1, fmt.Errorf("can't create %s: %w", outDir, err)
+	}
+	env, err := goutil.GoEnv(context.Background(), "GOMODCACHE")
+	if err != nil {
+		return buildmanifest.Manifest{}, 1, err
+	}
+	// Build inside outDir so moving a finished binary into place is a rename on
+	// the same filesystem.
+	work, err := os.MkdirTemp(outDir, ".gup-build-")
+	if err != nil {
+		return buildmanifest.Manifest{}, 1, fmt.Errorf("can't create temp directory: %w", err)
+	}
+	defer os.RemoveAll(work)
+
+	m := buildmanifest.New(opts.platform)
+	var mu sync.Mutex
+	claimed := map[string]string{}
+
+	builder := func(ctx context.Context, _ dependencies, p goutil.Package) updateResult {
+		if p.ImportPath == "" {
+			return updateResult{pkg: p, err: fmt.Errorf("%s: import path is empty", p.Name)}
+		}
+		version := buildVersion(p)
+		dir, err := os.MkdirTemp(work, "pkg-")
+		if err != nil {
+			return updateResult{pkg: p, err: fmt.Errorf("%s: %w", p.Name, err)}
+		}
+		built, err := buildForPlatformCtx(ctx, p.ImportPath, version, opts.platform, dir, env["GOMODCACHE"])
+		if err != nil {
+			return updateResult{pkg: p, err: fmt.Errorf("%s: %w", p.Name, err)}
+		}
+
+		name := filepath.Base(built)
+		mu.Lock()
+		other, dup := claimed[name]
+		if !dup {
+			claimed[name] = p.ImportPath
+		}
+		mu.Unlock()
+		if dup {
+			return updateResult{pkg: p, err: fmt.Errorf("%s: %s and %s both build a binary named %s", p.Name, other, p.ImportPath, name)}
+		}
+
+		dst := filepath.Join(outDir, name)
+		if err := renameWithReplace(built, dst); err != nil {
+			return updateResult{pkg: p, err: fmt.Errorf("%s: can't move the binary into %s: %w", p.Name, outDir, err)}
+		}
+		sum, err := buildmanifest.HashFile(dst)
+		if err != nil {
+			return updateResult{pkg: p, err: fmt.Errorf("%s: %w", p.Name, err)}
+		}
+		// Record what was actually built: "latest" and branch names resolve to
+		// a concrete version only at build time.
+		if info, err := buildinfo.ReadFile(dst); err == nil && info.Main.Version != "" {
+			version = info.Main.Version
+		}
+
+		mu.Lock()
+		m.Binaries = append(m.Binaries, buildmanifest.Entry{
+			File:       name,
+			ImportPath: p.ImportPath,
+			Version:    version,
+			Channel:    string(goutil.NormalizeUpdateChannel(string(p.UpdateChannel))),
+			SHA256:     sum,
+		})
+		mu.Unlock()
+
+		p.Version = &goutil.Version{Current: version}
+		return updateResult{updated: true, pkg: p}
+	}
+
+	exitCode, _ := executePackages(pr, pkgs, opts.cpus, opts.timeout, dependencies{}, builder, func(prefix string, v updateResult) {
+		pr.Info(fmt.Sprintf("%s %s@%s", prefix, v.pkg.ImportPath, v.pkg.Version.Current))
+	})
+
+	if len(m.Binaries) == 0 {
+		// Keep a previous manifest rather than replacing it with an empty one.
+		return m, exitCode, nil
+	}
+	sort.Slice(m.Binaries, func(i, j int) bool { return m.Binaries[i].File < m.Binaries[j].File })
+	if err := buildmanifest.Write(outDir, m); err != nil {
+		return buildmanifest.Manifest{}, 1, err
+	}
+	return m, exitCode, nil
+}
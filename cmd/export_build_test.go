@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nao1215/gup/internal/buildmanifest"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// fakeCrossBuild stands in for a cross-compiling 'go install': it writes a
+// "binary" named after the last import path element into workDir/bin.
+func fakeCrossBuild(_ context.Context, importPath, version string, _ goutil.Platform, workDir, _ string) (string, error) {
+	bin := filepath.Join(workDir, "bin", filepath.Base(importPath))
+	if err := os.MkdirAll(filepath.Dir(bin), 0o750); err != nil {
+		return "", err
+	}
+	return bin, os.WriteFile(bin, []byte(importPath+"@"+version), 0o600)
+}
+
+func Test_buildVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		pkg  goutil.Package
+		want string
+	}{
+		{
+			name: "recorded version",
+			pkg:  goutil.Package{Version: &goutil.Version{Current: "v1.2.3"}},
+			want: "v1.2.3",
+		},
+		{
+			name: "pin wins over the installed version",
+			pkg: goutil.Package{
+				Version:       &goutil.Version{Current: "v1.2.3"},
+				UpdateChannel: goutil.UpdateChannelPinned,
+				PinnedVersion: "v1.0.0",
+			},
+			want: "v1.0.0",
+		},
+		{
+			name: "devel build on the main channel",
+			pkg:  goutil.Package{Version: &goutil.Version{Current: "(devel)"}, UpdateChannel: goutil.UpdateChannelMain},
+			want: "main",
+		},
+		{
+			name: "latest placeholder",
+			pkg:  goutil.Package{Version: &goutil.Version{Current: "latest"}},
+			want: latestKeyword,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := buildVersion(tt.pkg); got != tt.want {
+				t.Errorf("buildVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_export_buildFlagsRequireBuild(t *testing.T) {
+	t.Parallel()
+
+	cmd := newExportCmd()
+	if err := cmd.Flags().Set("goarch", "arm64"); err != nil {
+		t.Fatal(err)
+	}
+	p, buf := newTestPrinter()
+	if got := export(p, cmd, nil); got != 1 {
+		t.Fatalf("export() = %d, want 1", got)
+	}
+	if !strings.Contains(buf.String(), "--goarch requires --build") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
+
+//nolint:paralleltest // swaps buildForPlatformCtx
+func Test_exportBuild_thenMigratePrebuilt(t *testing.T) {
+	org := buildForPlatformCtx
+	t.Cleanup(func() { buildForPlatformCtx = org })
+	buildForPlatformCtx = fakeCrossBuild
+
+	outDir := filepath.Join(t.TempDir(), "dist")
+	pkgs := []goutil.Package{
+		{Name: "tool", ImportPath: "example.com/tool", Version: &goutil.Version{Current: "v1.2.0"}},
+		{
+			Name: "held", ImportPath: "example.com/cmd/held", Version: &goutil.Version{Current: "v2.0.0"},
+			UpdateChannel: goutil.UpdateChannelPinned, PinnedVersion: "v1.9.0",
+		},
+	}
+	opts := exportBuildOpts{platform: goutil.HostPlatform(), outDir: outDir, cpus: 2}
+
+	p, buf := newTestPrinter()
+	m, exitCode, err := buildPackagesForPlatform(p, pkgs, opts)
+	if err != nil || exitCode != 0 {
+		t.Fatalf("buildPackagesForPlatform() = %d, %v; output: %s", exitCode, err, buf.String())
+	}
+	if len(m.Binaries) != 2 || m.Binaries[0].File != "held" || m.Binaries[0].Version != "v1.9.0" ||
+		m.Binaries[0].Channel != string(goutil.UpdateChannelPinned) {
+		t.Fatalf("unexpected manifest binaries: %+v", m.Binaries)
+	}
+	written, ok, err := buildmanifest.Read(outDir)
+	if err != nil || !ok || len(written.Binaries) != 2 {
+		t.Fatalf("manifest on disk = %+v, %v, %v", written, ok, err)
+	}
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("out dir should hold 2 binaries and the manifest (no temp dirs), got %d entries", len(entries))
+	}
+
+	// migrate consumes the directory: the prebuilt binaries are copied as-is.
+	afterDir := filepath.Join(t.TempDir(), "gobin")
+	migrate := newMigrateCmd()
+	p, buf = newTestPrinter()
+	if got := runMigrate(p, migrate, []string{outDir, afterDir}); got != 0 {
+		t.Fatalf("runMigrate() = %d, want 0; output: %s", got, buf.String())
+	}
+	got, err := os.ReadFile(filepath.Join(afterDir, "held"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "example.com/cmd/held@v1.9.0" {
+		t.Errorf("migrated binary content = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(afterDir, buildmanifest.FileName)); err == nil {
+		t.Error("the manifest must not be copied into AFTER_PATH")
+	}
+
+	// A modified binary is refused.
+	if err := os.WriteFile(filepath.Join(outDir, "tool"), []byte("tampered"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	migrate = newMigrateCmd()
+	if err := migrate.Flags().Set("force", "true"); err != nil {
+		t.Fatal(err)
+	}
+	p, buf = newTestPrinter()
+	if got := runMigrate(p, migrate, []string{outDir, afterDir, "tool"}); got != 1 {
+		t.Fatalf("runMigrate() of a tampered binary = %d, want 1", got)
+	}
+	if !strings.Contains(buf.String(), "does not match") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
+
+func Test_copyPrebuiltBinary_checksTheCopy(t *testing.T) {
+	t.Parallel()
+
+	src := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(src, []byte("binary"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := buildmanifest.HashFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The recorded hash is of other content, as when the file changes after
+	// it was verified: nothing is installed and no temp file is left.
+	after := t.TempDir()
+	dst := filepath.Join(after, "tool")
+	err = copyPrebuiltBinary(src, dst, buildmanifest.Entry{File: "tool", SHA256: strings.Repeat("0", len(sum))})
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("copyPrebuiltBinary() error = %v, want a mismatch", err)
+	}
+	if entries, err := os.ReadDir(after); err != nil || len(entries) != 0 {
+		t.Fatalf("AFTER_PATH after a mismatch = %v, %v; want it empty", entries, err)
+	}
+
+	if err := copyPrebuiltBinary(src, dst, buildmanifest.Entry{File: "tool", SHA256: sum}); err != nil {
+		t.Fatalf("copyPrebuiltBinary() error = %v", err)
+	}
+	if got, err := os.ReadFile(dst); err != nil || string(got) != "binary" {
+		t.Errorf("installed binary = %q, %v", got, err)
+	}
+}
+
+//nolint:paralleltest // swaps buildForPlatformCtx
+func Test_buildPackagesForPlatform_nameConflict(t *testing.T) {
+	org := buildForPlatformCtx
+	t.Cleanup(func() { buildForPlatformCtx = org })
+	buildForPlatformCtx = fakeCrossBuild
+
+	pkgs := []goutil.Package{
+		{Name: "tool", ImportPath: "example.com/a/tool", Version: &goutil.Version{Current: "v1.0.0"}},
+		{Name: "tool", ImportPath: "example.com/b/tool", Version: &goutil.Version{Current: "v1.0.0"}},
+	}
+	opts := exportBuildOpts{platform: goutil.HostPlatform(), outDir: t.TempDir(), cpus: 1}
+
+	p, buf := newTestPrinter()
+	m, exitCode, err := buildPackagesForPlatform(p, pkgs, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exitCode != 1 || len(m.Binaries) != 1 {
+		t.Fatalf("exit = %d, binaries = %d; want 1 and 1", exitCode, len(m.Binaries))
+	}
+	if !strings.Contains(buf.String(), "both build a binary named tool") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
@@ -3,9 +3,14 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/diagnose"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/retry"
 	"github.com/spf13/cobra"
 )
 
@@ -61,7 +66,21 @@ const fileFlagName = "file"
 // build-time programmer error, so it panics. Centralizing the call keeps the
 // "file"/"json" pair from being repeated in every command constructor.
 func mustMarkFileFlagAsJSON(cmd *cobra.Command) {
-	if err := cmd.MarkFlagFilename(fileFlagName, "json"); err != nil {
+	mustMarkFlagAsJSON(cmd, fileFlagName)
+}
+
+// mustMarkFlagAsJSON marks flag name as completing to .json files, with the
+// same panic-on-unknown-flag contract.
+func mustMarkFlagAsJSON(cmd *cobra.Command, name string) {
+	if err := cmd.MarkFlagFilename(name, "json"); err != nil {
+		panic(err)
+	}
+}
+
+// mustMarkFlagAsBundle marks flag name as completing to bundle archives
+// (.tar.gz/.tgz), with the same panic-on-unknown-flag contract.
+func mustMarkFlagAsBundle(cmd *cobra.Command, name string) {
+	if err := cmd.MarkFlagFilename(name, "gz", "tgz"); err != nil {
 		panic(err)
 	}
 }
@@ -91,6 +110,164 @@ func getTimeoutFlag(cmd *cobra.Command) (time.Duration, error) {
 	return v, nil
 }
 
+// Names of the shared --retries and --retry-backoff flags.
+const (
+	retriesFlagName      = "retries"
+	retryBackoffFlagName = "retry-backoff"
+)
+
+// addRetryFlags registers the shared --retries and --retry-backoff flags used
+// by commands that look up versions and run 'go install' (update, check).
+// Their defaults come from settings.json, so the flag defaults only stand in
+// for a missing setting.
+func addRetryFlags(cmd *cobra.Command) {
+	cmd.Flags().Int(retriesFlagName, 0,
+		"retry a version lookup or go install that failed with a network error up to N more times (default from settings.json \"retries\", else 0)")
+	cmd.Flags().Duration(retryBackoffFlagName, retry.DefaultBackoff,
+		"wait before the first retry, doubled before each next one (default from settings.json \"retry_backoff\")")
+	mustRegisterFlagCompletion(cmd, retriesFlagName, cobra.NoFileCompletions)
+	mustRegisterFlagCompletion(cmd, retryBackoffFlagName, cobra.NoFileCompletions)
+}
+
+// getRetryPolicy reads the shared --retries and --retry-backoff flags into the
+// policy the version lookups and installs retry by. A flag that is not given
+// takes its value from settings.json.
+func getRetryPolicy(cmd *cobra.Command) (retry.Policy, error) {
+	retries, err := getFlagInt(cmd, retriesFlagName)
+	if err != nil {
+		return retry.Policy{}, err
+	}
+	backoff, err := cmd.Flags().GetDuration(retryBackoffFlagName)
+	if err != nil {
+		return retry.Policy{}, fmt.Errorf("can not parse command line argument (--%s): %w", retryBackoffFlagName, err)
+	}
+	if !cmd.Flags().Changed(retriesFlagName) || !cmd.Flags().Changed(retryBackoffFlagName) {
+		s, err := config.ReadSettings()
+		if err != nil {
+			return retry.Policy{}, err
+		}
+		if !cmd.Flags().Changed(retriesFlagName) {
+			retries = s.Retries
+		}
+		if !cmd.Flags().Changed(retryBackoffFlagName) && s.Backoff() > 0 {
+			backoff = s.Backoff()
+		}
+	}
+	if retries < 0 {
+		return retry.Policy{}, fmt.Errorf("can not parse command line argument (--%s): must be >= 0", retriesFlagName)
+	}
+	if backoff <= 0 {
+		return retry.Policy{}, fmt.Errorf("can not parse command line argument (--%s): must be > 0", retryBackoffFlagName)
+	}
+	return retry.Policy{Retries: retries, Backoff: backoff, Transient: diagnose.Transient}, nil
+}
+
+// jsonStreamFlagName is the shared flag that streams progress events as NDJSON.
+const jsonStreamFlagName = "json-stream"
+
+// addJSONStreamFlag registers the shared --json-stream flag used by commands
+// that work through packages in parallel (update, check, import, migrate).
+func addJSONStreamFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool(jsonStreamFlagName, false, "print one JSON event per line as each binary progresses, then a summary; other output goes to STDERR")
+}
+
+// jsonFormatFlagName is the shared flag that selects the JSON layout.
+const jsonFormatFlagName = "json-format"
+
+// The --json-format values.
+const (
+	jsonFormatV1 = "v1"
+	jsonFormatV2 = "v2"
+)
+
+// addJSONFormatFlag registers the shared --json-format flag used by the
+// commands that print JSON (list, check, update, import, migrate).
+func addJSONFormatFlag(cmd *cobra.Command, usage string) {
+	cmd.Flags().String(jsonFormatFlagName, jsonFormatV1, usage)
+	mustRegisterFlagCompletion(cmd, jsonFormatFlagName, func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return []string{jsonFormatV1, jsonFormatV2}, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// getJSONFormatV2 reads the shared --json-format flag and reports whether it
+// selects the v2 envelope.
+func getJSONFormatV2(cmd *cobra.Command) (bool, error) {
+	v, err := getFlagString(cmd, jsonFormatFlagName)
+	if err != nil {
+		return false, err
+	}
+	switch v {
+	case jsonFormatV1:
+		return false, nil
+	case jsonFormatV2:
+		return true, nil
+	}
+	return false, fmt.Errorf("can not parse command line argument (--%s): %q is neither %s nor %s", jsonFormatFlagName, v, jsonFormatV1, jsonFormatV2)
+}
+
+// formatFlagName is the shared flag that prints records in a layout other than
+// the human-readable one.
+const formatFlagName = "format"
+
+// The columns of --format table, csv and markdown when no fields are given.
+var (
+	listFormatFields  = []string{"name", "import_path", "current_version", "channel"}
+	checkFormatFields = []string{"name", "current_version", "latest_version", "status"}
+)
+
+// addFormatFlag registers the shared --format flag used by list, check and
+// update.
+func addFormatFlag(cmd *cobra.Command) {
+	cmd.Flags().String(formatFlagName, "",
+		"print the result as table, csv or markdown (optionally =FIELD,... to pick the columns), or template=GO_TEMPLATE over the --json fields")
+	mustRegisterFlagCompletion(cmd, formatFlagName, func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return []string{formatTable, formatCSV, formatMarkdown, formatTemplate + "="}, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	})
+}
+
+// getFormatFlag reads the shared --format flag. It returns nil when the flag is
+// not given; defaultFields are the columns of a layout given without fields.
+func getFormatFlag(cmd *cobra.Command, defaultFields []string) (*outputFormat, error) {
+	v, err := getFlagString(cmd, formatFlagName)
+	if err != nil || v == "" {
+		return nil, err
+	}
+	f, err := parseOutputFormat(v, defaultFields)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse command line argument (--%s): %w", formatFlagName, err)
+	}
+	return f, nil
+}
+
+// goFlagName is the name of the shared --go flag.
+const goFlagName = "go"
+
+// addGoToolchainFlag registers the shared --go flag used by commands that build
+// binaries from gup.json entries (update, import).
+func addGoToolchainFlag(cmd *cobra.Command, usage string) {
+	cmd.Flags().String(goFlagName, "", usage)
+	mustRegisterFlagCompletion(cmd, goFlagName, cobra.NoFileCompletions)
+}
+
+// getGoToolchainFlag reads the shared --go flag as a GOTOOLCHAIN release name
+// ("go1.22.5"). It returns goutil.LocalToolchain for "local" and "" when the
+// flag is not given.
+func getGoToolchainFlag(cmd *cobra.Command) (string, error) {
+	v, err := getFlagString(cmd, goFlagName)
+	if err != nil {
+		return "", err
+	}
+	switch v = strings.TrimSpace(v); v {
+	case "", goutil.LocalToolchain:
+		return v, nil
+	}
+	toolchain, err := goutil.ParseGoToolchain(v)
+	if err != nil {
+		return "", fmt.Errorf("can not parse command line argument (--%s): %w", goFlagName, err)
+	}
+	return toolchain, nil
+}
+
 // noColorFlagName is the name of the persistent --no-color flag.
 const noColorFlagName = "no-color"
 
@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"os"
 	"testing"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/nao1215/gup/internal/config"
 	"github.com/spf13/cobra"
 )
 
@@ -296,3 +298,53 @@ func TestAddTimeoutFlag_completion(t *testing.T) {
 		})
 	}
 }
+
+func TestGetRetryPolicy(t *testing.T) { //nolint:paralleltest // setupXDGBase mutates xdg globals
+	tests := []struct {
+		name        string
+		settings    string   // settings.json content; "" writes no file
+		args        []string // flags given on the command line
+		wantRetries int
+		wantBackoff time.Duration
+		wantErr     bool
+	}{
+		{name: "defaults", wantRetries: 0, wantBackoff: time.Second},
+		{name: "settings.json", settings: `{"retries": 3, "retry_backoff": "2s"}`, wantRetries: 3, wantBackoff: 2 * time.Second},
+		{name: "flags override settings.json", settings: `{"retries": 3, "retry_backoff": "2s"}`, args: []string{"--retries=1", "--retry-backoff=500ms"}, wantRetries: 1, wantBackoff: 500 * time.Millisecond},
+		{name: "negative retries", args: []string{"--retries=-1"}, wantErr: true},
+		{name: "zero backoff", args: []string{"--retries=2", "--retry-backoff=0s"}, wantErr: true},
+		{name: "malformed settings.json", settings: `{"retries": "many"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupXDGBase(t)
+			if tt.settings != "" {
+				if err := os.MkdirAll(config.DirPath(), 0o750); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(config.SettingsFilePath(), []byte(tt.settings), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cmd := &cobra.Command{}
+			addRetryFlags(cmd)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := getRetryPolicy(cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getRetryPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Retries != tt.wantRetries || got.Backoff != tt.wantBackoff {
+				t.Errorf("getRetryPolicy() = %d retries, %v backoff; want %d, %v", got.Retries, got.Backoff, tt.wantRetries, tt.wantBackoff)
+			}
+			if got.Transient == nil {
+				t.Error("getRetryPolicy() has no transient error classifier")
+			}
+		})
+	}
+}
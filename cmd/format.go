@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+// The --format layouts. Each takes an optional "=..." argument: the fields to
+// show for table, csv and markdown, and the template itself for template.
+const (
+	formatTable    = "table"
+	formatCSV      = "csv"
+	formatMarkdown = "markdown"
+	formatTemplate = "template"
+)
+
+// outputFormat renders jsonPackage records in a --format layout.
+type outputFormat struct {
+	kind   string
+	fields []string           // JSON names of the columns; unused by template
+	tmpl   *template.Template // only for template
+}
+
+// parseOutputFormat parses a --format value such as "table",
+// "csv=name,status" or "template={{.Name}}". defaultFields are the columns of a
+// layout given without fields.
+func parseOutputFormat(value string, defaultFields []string) (*outputFormat, error) {
+	kind, arg, hasArg := strings.Cut(value, "=")
+	f := &outputFormat{kind: kind}
+	switch kind {
+	case formatTable, formatCSV, formatMarkdown:
+		if !hasArg {
+			f.fields = defaultFields
+			return f, nil
+		}
+		for _, name := range strings.Split(arg, ",") {
+			name = strings.TrimSpace(name)
+			if _, ok := jsonPackageField(name); !ok {
+				return nil, fmt.Errorf("unknown field %q; choose from %s", name, strings.Join(jsonPackageFieldNames(), ", "))
+			}
+			f.fields = append(f.fields, name)
+		}
+		return f, nil
+	case formatTemplate:
+		if arg == "" {
+			return nil, fmt.Errorf("template needs a template, e.g. %s='{{.Name}} {{.CurrentVersion}}'", formatTemplate)
+		}
+		tmpl, err := template.New("format").Parse(arg)
+		if err != nil {
+			return nil, err
+		}
+		f.tmpl = tmpl
+		return f, nil
+	}
+	return nil, fmt.Errorf("%q is not one of %s, %s, %s or %s=TEMPLATE", value, formatTable, formatCSV, formatMarkdown, formatTemplate)
+}
+
+// render writes recs to w. A template is run once per record and ends the
+// record with a newline unless the template already does.
+func (f *outputFormat) render(w io.Writer, recs []jsonPackage) error {
+	switch f.kind {
+	case formatTemplate:
+		for _, rec := range recs {
+			var b strings.Builder
+			if err := f.tmpl.Execute(&b, rec); err != nil {
+				return err
+			}
+			if !strings.HasSuffix(b.String(), "\n") {
+				b.WriteString("\n")
+			}
+			if _, err := io.WriteString(w, b.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		_ = cw.Write(f.fields)
+		for _, rec := range recs {
+			_ = cw.Write(f.row(rec))
+		}
+		cw.Flush()
+		return cw.Error()
+	case formatMarkdown:
+		var b strings.Builder
+		b.WriteString("| " + strings.Join(f.fields, " | ") + " |\n")
+		b.WriteString("|" + strings.Repeat(" --- |", len(f.fields)) + "\n")
+		for _, rec := range recs {
+			row := f.row(rec)
+			for i, v := range row {
+				row[i] = strings.ReplaceAll(v, "|", `\|`)
+			}
+			b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := make([]string, 0, len(f.fields))
+	for _, name := range f.fields {
+		header = append(header, strings.ToUpper(strings.ReplaceAll(name, "_", " ")))
+	}
+	_, _ = fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, rec := range recs {
+		_, _ = fmt.Fprintln(tw, strings.Join(f.row(rec), "\t"))
+	}
+	return tw.Flush()
+}
+
+// row returns the values of rec's columns.
+func (f *outputFormat) row(rec jsonPackage) []string {
+	v := reflect.ValueOf(rec)
+	row := make([]string, 0, len(f.fields))
+	for _, name := range f.fields {
+		i, _ := jsonPackageField(name)
+		row = append(row, v.Field(i).String())
+	}
+	return row
+}
+
+// jsonPackageField returns the index of the string field of jsonPackage whose
+// JSON name is name. Only string fields can be columns.
+func jsonPackageField(name string) (int, bool) {
+	t := reflect.TypeFor[jsonPackage]()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if tag == name && field.Type.Kind() == reflect.String {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// jsonPackageFieldNames returns the JSON names of the fields that can be
+// columns, in declaration order.
+func jsonPackageFieldNames() []string {
+	t := reflect.TypeFor[jsonPackage]()
+	names := make([]string, 0, t.NumField())
+	for i := range t.NumField() {
+		if field := t.Field(i); field.Type.Kind() == reflect.String {
+			tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			names = append(names, tag)
+		}
+	}
+	return names
+}
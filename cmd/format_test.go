@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_parseOutputFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value      string
+		wantKind   string
+		wantFields []string
+		wantErr    bool
+	}{
+		{value: "table", wantKind: formatTable, wantFields: checkFormatFields},
+		{value: "csv=name, status", wantKind: formatCSV, wantFields: []string{"name", "status"}},
+		{value: "markdown=name", wantKind: formatMarkdown, wantFields: []string{"name"}},
+		{value: "template={{.Name}}", wantKind: formatTemplate},
+		{value: "table=name,changelog", wantErr: true},
+		{value: "csv=nmae", wantErr: true},
+		{value: "template=", wantErr: true},
+		{value: "template={{.Name", wantErr: true},
+		{value: "yaml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseOutputFormat(tt.value, checkFormatFields)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOutputFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.kind != tt.wantKind {
+				t.Errorf("kind = %q, want %q", got.kind, tt.wantKind)
+			}
+			if diff := cmp.Diff(tt.wantFields, got.fields); diff != "" {
+				t.Errorf("fields mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_outputFormat_render(t *testing.T) {
+	t.Parallel()
+
+	recs := []jsonPackage{
+		{Name: "gup", CurrentVersion: "v1.0.0", LatestVersion: "v1.1.0", Status: statusUpdateAvailable},
+		{Name: "a|b", CurrentVersion: "v0.1.0", LatestVersion: "v0.1.0", Status: statusUpToDate},
+	}
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{
+			value: "table",
+			want: "NAME  CURRENT VERSION  LATEST VERSION  STATUS\n" +
+				"gup   v1.0.0           v1.1.0          update-available\n" +
+				"a|b   v0.1.0           v0.1.0          up-to-date\n",
+		},
+		{
+			value: "csv=name,status",
+			want:  "name,status\ngup,update-available\na|b,up-to-date\n",
+		},
+		{
+			value: "markdown=name,current_version",
+			want:  "| name | current_version |\n| --- | --- |\n| gup | v1.0.0 |\n| a\\|b | v0.1.0 |\n",
+		},
+		{
+			value: "template={{.Name}} {{.CurrentVersion}}",
+			want:  "gup v1.0.0\na|b v0.1.0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+			f, err := parseOutputFormat(tt.value, checkFormatFields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := f.render(&out, recs); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, out.String()); diff != "" {
+				t.Errorf("render() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_parseCheckFlags_formatConflicts(t *testing.T) {
+	t.Parallel()
+
+	for _, args := range [][]string{
+		{"--format", "csv", "--json"},
+		{"--format", "csv", "--json-stream"},
+		{"--format", "xml"},
+	} {
+		cmd := newCheckCmd()
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseCheckFlags(cmd); err == nil {
+			t.Errorf("parseCheckFlags(%v) succeeded", args)
+		}
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
 
+	"github.com/nao1215/gup/internal/bundle"
 	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/configstate"
 	"github.com/nao1215/gup/internal/fileutil"
 	"github.com/nao1215/gup/internal/goutil"
 	"github.com/nao1215/gup/internal/print"
@@ -27,9 +30,26 @@ Use export/import if you want to install the same Go binaries
 across multiple systems.
 First, run 'gup export' on the source environment and copy gup.json.
 Then run 'gup import' on the target environment to install the
-versions recorded in that gup.json.`,
+versions recorded in that gup.json.
+
+With --bundle, the tools are installed from an archive written by
+'gup bundle' instead of the network: the archive is verified against its
+manifest, extracted to a temporary directory, and served to 'go install' as
+a file:// GOPROXY. The bundle's own gup.json is imported unless --file is
+given. The bundle also carries the checksum database records 'gup bundle'
+looked up, and 'go install' reads them through the same proxy, so every
+module is still checked against the checksum database's signed tree without
+network access. --insecure-skip-sumdb turns GOSUMDB off for the install
+instead; use it only when the bundle comes from a trusted source and the
+checksum check can't succeed (for example a bundle built with GOSUMDB=off).
+
+An entry with a "go_toolchain" is built with that Go release through
+GOTOOLCHAIN. --go builds every entry with the given release instead, for
+this import only; '--go local' builds every entry with the installed Go.`,
 		Example: `  gup import
-  gup import --file gup.json`,
+  gup import --file gup.json
+  gup import --bundle tools.tar.gz
+  gup import --go 1.22.5`,
 		Args:              cobra.NoArgs,
 		ValidArgsFunction: cobra.NoFileCompletions,
 		Run: func(cmd *cobra.Command, args []string) {
@@ -41,8 +61,14 @@ versions recorded in that gup.json.`,
 	cmd.Flags().BoolP("notify", "N", false, "enable desktop notifications")
 	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to import")
 	mustMarkFileFlagAsJSON(cmd)
+	cmd.Flags().String("bundle", "", "install offline from an archive written by 'gup bundle'")
+	mustMarkFlagAsBundle(cmd, "bundle")
+	cmd.Flags().Bool("insecure-skip-sumdb", false, "with --bundle, install without checking modules against the checksum database")
 	cmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "specify the number of CPU cores to use")
 	mustRegisterFlagCompletion(cmd, "jobs", completeNCPUs)
+	addGoToolchainFlag(cmd, "build every binary with this Go release (e.g. 1.22.5) instead of the go_toolchain in gup.json")
+	addJSONFormatFlag(cmd, "v2 prints the result as a JSON envelope with a summary, timings and the environment; other output goes to STDERR")
+	addJSONStreamFlag(cmd)
 	addTimeoutFlag(cmd)
 
 	return cmd
@@ -59,17 +85,71 @@ func runImport(p *print.Printer, cmd *cobra.Command, _ []string) int {
 		p.Err(err)
 		return 1
 	}
+	jsonStreamOut, err := getFlagBool(cmd, jsonStreamFlagName)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	jsonV2, err := getJSONFormatV2(cmd)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if jsonV2 && jsonStreamOut {
+		p.Err("--json-format v2 can't be used with --json-stream")
+		return 1
+	}
 
 	confFile, err := getFlagString(cmd, "file")
 	if err != nil {
 		p.Err(err)
 		return 1
 	}
+	report, p := newJSONReport(p, "import", jsonStreamOut, jsonV2, confFile)
+	bundlePath, err := getFlagString(cmd, "bundle")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	skipSumDB, err := getFlagBool(cmd, "insecure-skip-sumdb")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if skipSumDB && bundlePath == "" {
+		p.Err("--insecure-skip-sumdb can only be used with --bundle")
+		return 1
+	}
+	if bundlePath != "" {
+		dir, cleanup, err := openBundle(bundlePath)
+		if err != nil {
+			p.Err(err)
+			return 1
+		}
+		defer cleanup()
+		if confFile == "" {
+			confFile = filepath.Join(dir, bundle.ConfigName)
+		}
+		restore, err := withEnv(bundleProxyEnv(dir, skipSumDB))
+		if err != nil {
+			p.Err(fmt.Errorf("can't point GOPROXY at the bundle: %w", err))
+			return 1
+		}
+		defer restore()
+		p.Info("using " + bundlePath + " as the module proxy")
+		if skipSumDB {
+			p.Warn("--insecure-skip-sumdb: modules are not checked against the checksum database")
+		}
+	}
+
 	confFile, err = config.ResolveImportFilePath(confFile)
 	if err != nil {
 		p.Err(err)
 		return 1
 	}
+	if env := report.environment(); env != nil {
+		env.ConfigFile = confFile // --bundle may have picked its own gup.json
+	}
 
 	notify, err := getFlagBool(cmd, "notify")
 	if err != nil {
@@ -90,6 +170,12 @@ func runImport(p *print.Printer, cmd *cobra.Command, _ []string) int {
 		return 1
 	}
 
+	goToolchain, err := getGoToolchainFlag(cmd)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
 	if !fileutil.IsFile(confFile) {
 		p.Err(fmt.Errorf("%s is not found", confFile))
 		return 1
@@ -101,16 +187,21 @@ func runImport(p *print.Printer, cmd *cobra.Command, _ []string) int {
 		return 1
 	}
 
+	pkgs = withoutRemoved(p, pkgs)
 	if len(pkgs) == 0 {
 		p.Err("unable to import package: no package information")
 		return 1
 	}
 
+	if goToolchain != "" {
+		pkgs = configstate.ApplyGoToolchains(pkgs, nil, goToolchain)
+	}
+
 	p.Info("start import based on " + confFile)
-	return installFromConfig(p, pkgs, dryRun, notify, cpus, timeout)
+	return installFromConfig(p, pkgs, dryRun, notify, cpus, timeout, report)
 }
 
-func installFromConfig(pr *print.Printer, pkgs []goutil.Package, dryRun, notification bool, cpus int, timeout time.Duration) (exitCode int) {
+func installFromConfig(pr *print.Printer, pkgs []goutil.Package, dryRun, notification bool, cpus int, timeout time.Duration, report *jsonReport) (exitCode int) {
 	dryRunManager := goutil.NewGoPaths()
 
 	if dryRun {
@@ -128,50 +219,80 @@ func installFromConfig(pr *print.Printer, pkgs []goutil.Package, dryRun, notific
 		}()
 	}
 
-	installer := func(ctx context.Context, p goutil.Package) updateResult {
-		ver, err := versionFromConfig(p)
-		if err != nil {
-			return updateResult{
-				updated: false,
-				pkg:     p,
-				err:     fmt.Errorf("%s: %w", p.Name, err),
-			}
-		}
-		if p.ImportPath == "" {
-			return updateResult{
-				updated: false,
-				pkg:     p,
-				err:     fmt.Errorf("%s: import path is empty", p.Name),
-			}
-		}
+	result, results := executePackagesStream(context.Background(), pr, report.events(), report.v2() == nil, nil, pkgs, cpus, timeout, dependencies{}, installConfigured, func(prefix string, v updateResult) {
+		pr.Info(fmt.Sprintf("%s %s@%s%s", prefix, v.pkg.ImportPath, v.pkg.Version.Current, builtWithStr(v.pkg)))
+	})
 
-		// Store resolved version for display in the result loop
-		if p.Version == nil {
-			p.Version = &goutil.Version{}
-		}
-		p.Version.Current = ver
+	desktopNotifyIfNeeded(pr, result, notification)
+	return report.writeResults(pr, results, result)
+}
 
-		if err := installByVersionCtx(ctx, p.ImportPath, ver); err != nil {
-			return updateResult{
-				updated: false,
-				pkg:     p,
-				err:     fmt.Errorf("%s: %w", p.Name, err),
-			}
+// withoutRemoved drops the gup.json entries 'gup watch' marked as removed:
+// their binaries were deleted on purpose, so nothing installs them again.
+func withoutRemoved(p *print.Printer, pkgs []goutil.Package) []goutil.Package {
+	kept := make([]goutil.Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		if pkg.Removed {
+			p.Info("skip " + pkg.Name + ": marked as removed in gup.json")
+			continue
 		}
+		kept = append(kept, pkg)
+	}
+	return kept
+}
 
+// builtWithStr names the Go toolchain selected for p, or returns "" when p is
+// built with the installed Go.
+func builtWithStr(p goutil.Package) string {
+	if p.GoToolchain == "" {
+		return ""
+	}
+	return " (with " + p.GoToolchain + ")"
+}
+
+// installConfigured installs one gup.json entry at its recorded version, with
+// the entry's Go toolchain when it has one.
+func installConfigured(ctx context.Context, deps dependencies, p goutil.Package) updateResult {
+	deps.toolchain = p.GoToolchain
+	ver, err := versionFromConfig(p)
+	if err != nil {
 		return updateResult{
-			updated: true,
+			updated: false,
 			pkg:     p,
-			err:     nil,
+			err:     fmt.Errorf("%s: %w", p.Name, err),
+		}
+	}
+	if p.ImportPath == "" {
+		return updateResult{
+			updated: false,
+			pkg:     p,
+			err:     fmt.Errorf("%s: import path is empty", p.Name),
 		}
 	}
 
-	result, _ := executePackages(pr, pkgs, cpus, timeout, installer, func(prefix string, v updateResult) {
-		pr.Info(fmt.Sprintf("%s %s@%s", prefix, v.pkg.ImportPath, v.pkg.Version.Current))
+	// Store resolved version for display in the result loop
+	if p.Version == nil {
+		p.Version = &goutil.Version{}
+	}
+	p.Version.Current = ver
+
+	deps.event(eventInstalling, p)
+	err = deps.install(ctx, func(ctx context.Context, opts goutil.InstallOptions) error {
+		return installByVersionCtx(ctx, p.ImportPath, ver, opts)
 	})
+	if err != nil {
+		return updateResult{
+			updated: false,
+			pkg:     p,
+			err:     fmt.Errorf("%s: %w", p.Name, err),
+		}
+	}
 
-	desktopNotifyIfNeeded(pr, result, notification)
-	return result
+	return updateResult{
+		updated: true,
+		pkg:     p,
+		err:     nil,
+	}
 }
 
 func versionFromConfig(pkg goutil.Package) (string, error) {
@@ -7,9 +7,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/nao1215/gup/internal/config"
 	"github.com/nao1215/gup/internal/goutil"
 	"github.com/spf13/cobra"
@@ -202,6 +204,31 @@ func Test_runImport_jobsClamp(t *testing.T) {
 	}
 }
 
+//nolint:paralleltest // swaps installByVersionCtx and sets GOBIN
+func Test_runImport_skipsRemoved(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+	installs := recordInstalls(t)
+	t.Setenv("GOBIN", t.TempDir())
+	conf := `{"schema_version":2,"packages":[
+{"name":"kept","import_path":"example.com/kept","version":"v1.0.0","channel":"latest"},
+{"name":"gone","import_path":"example.com/gone","version":"v1.0.0","channel":"latest","removed":true}]}`
+	if err := os.WriteFile(config.LocalFilePath(), []byte(conf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, buf := newTestPrinter()
+	if got := runImport(p, newImportCmd(), nil); got != 0 {
+		t.Fatalf("runImport() = %d, want 0; output: %s", got, buf.String())
+	}
+	if diff := cmp.Diff([]string{"example.com/kept@v1.0.0"}, installs()); diff != "" {
+		t.Errorf("installs mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(buf.String(), "skip gone: marked as removed") {
+		t.Errorf("the skipped entry should be reported, got: %s", buf.String())
+	}
+}
+
 func Test_installFromConfig_UseVersion(t *testing.T) {
 	originalInstaller := installByVersionCtx
 	t.Cleanup(func() {
@@ -210,7 +237,7 @@ func Test_installFromConfig_UseVersion(t *testing.T) {
 
 	var gotImportPath string
 	var gotVersion string
-	installByVersionCtx = func(_ context.Context, importPath, version string) error {
+	installByVersionCtx = func(_ context.Context, importPath, version string, _ goutil.InstallOptions) error {
 		gotImportPath = importPath
 		gotVersion = version
 		return nil
@@ -225,7 +252,7 @@ func Test_installFromConfig_UseVersion(t *testing.T) {
 	}
 
 	p, _ := newTestPrinter()
-	if got := installFromConfig(p, pkgs, false, false, 1, 0); got != 0 {
+	if got := installFromConfig(p, pkgs, false, false, 1, 0, nil); got != 0 {
 		t.Fatalf("installFromConfig() = %d, want 0", got)
 	}
 
@@ -237,6 +264,38 @@ func Test_installFromConfig_UseVersion(t *testing.T) {
 	}
 }
 
+func Test_installFromConfig_goToolchain(t *testing.T) {
+	originalInstaller := installByVersionCtx
+	t.Cleanup(func() {
+		installByVersionCtx = originalInstaller
+	})
+
+	var mu sync.Mutex
+	got := map[string]string{}
+	installByVersionCtx = func(_ context.Context, importPath, _ string, opts goutil.InstallOptions) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got[importPath] = opts.Toolchain
+		return nil
+	}
+
+	pkgs := []goutil.Package{
+		{Name: "old", ImportPath: "example.com/old", Version: &goutil.Version{Current: testVersionOne}, GoToolchain: "go1.22.5"},
+		{Name: "new", ImportPath: "example.com/new", Version: &goutil.Version{Current: testVersionOne}},
+	}
+	p, buf := newTestPrinter()
+	if code := installFromConfig(p, pkgs, false, false, 2, 0, nil); code != 0 {
+		t.Fatalf("installFromConfig() = %d, want 0", code)
+	}
+	want := map[string]string{"example.com/old": "go1.22.5", "example.com/new": ""}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("install toolchains mismatch (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(buf.String(), "example.com/old@"+testVersionOne+" (with go1.22.5)") {
+		t.Errorf("output does not name the selected toolchain:\n%s", buf.String())
+	}
+}
+
 func Test_versionFromConfig_NormalizeDevel(t *testing.T) {
 	t.Parallel()
 
@@ -326,7 +385,7 @@ func Test_installFromConfig_installError(t *testing.T) {
 		installByVersionCtx = originalInstaller
 	})
 
-	installByVersionCtx = func(context.Context, string, string) error {
+	installByVersionCtx = func(context.Context, string, string, goutil.InstallOptions) error {
 		return errors.New("install failed")
 	}
 
@@ -339,7 +398,7 @@ func Test_installFromConfig_installError(t *testing.T) {
 	}
 
 	p, _ := newTestPrinter()
-	if got := installFromConfig(p, pkgs, false, false, 1, 0); got != 1 {
+	if got := installFromConfig(p, pkgs, false, false, 1, 0, nil); got != 1 {
 		t.Fatalf("installFromConfig() = %d, want 1", got)
 	}
 }
@@ -354,7 +413,7 @@ func Test_installFromConfig_emptyImportPath(t *testing.T) {
 	}
 
 	p, _ := newTestPrinter()
-	if got := installFromConfig(p, pkgs, false, false, 1, 0); got != 1 {
+	if got := installFromConfig(p, pkgs, false, false, 1, 0, nil); got != 1 {
 		t.Fatalf("installFromConfig() = %d, want 1", got)
 	}
 }
@@ -367,7 +426,7 @@ func Test_installFromConfig_dryRun(t *testing.T) {
 		installByVersionCtx = originalInstaller
 	})
 
-	installByVersionCtx = func(context.Context, string, string) error { return nil }
+	installByVersionCtx = func(context.Context, string, string, goutil.InstallOptions) error { return nil }
 
 	pkgs := []goutil.Package{
 		{
@@ -378,7 +437,7 @@ func Test_installFromConfig_dryRun(t *testing.T) {
 	}
 
 	p, _ := newTestPrinter()
-	if got := installFromConfig(p, pkgs, true, false, 1, 0); got != 0 {
+	if got := installFromConfig(p, pkgs, true, false, 1, 0, nil); got != 0 {
 		t.Fatalf("installFromConfig() dry-run = %d, want 0", got)
 	}
 }
@@ -457,7 +516,7 @@ func Test_runImport_timeoutPropagation(t *testing.T) {
 	org := installByVersionCtx
 	var sawDeadline bool
 	var remaining time.Duration
-	installByVersionCtx = func(ctx context.Context, _, _ string) error {
+	installByVersionCtx = func(ctx context.Context, _, _ string, _ goutil.InstallOptions) error {
 		if dl, ok := ctx.Deadline(); ok {
 			sawDeadline = true
 			remaining = time.Until(dl)
@@ -498,7 +557,7 @@ func Test_runImport_timeoutZeroDisablesDeadline(t *testing.T) {
 
 	org := installByVersionCtx
 	var sawDeadline bool
-	installByVersionCtx = func(ctx context.Context, _, _ string) error {
+	installByVersionCtx = func(ctx context.Context, _, _ string, _ goutil.InstallOptions) error {
 		if _, ok := ctx.Deadline(); ok {
 			sawDeadline = true
 		}
@@ -534,7 +593,7 @@ func Test_runImport_timeoutZeroDisablesDeadline(t *testing.T) {
 func TestInstallFromConfig_missingVersion(t *testing.T) {
 	t.Parallel()
 	pkgs := []goutil.Package{{Name: "tool", ImportPath: testImportExampleTool, Version: nil}}
-	if code := installFromConfig(discardPrinter(), pkgs, false, false, 1, 0); code == 0 {
+	if code := installFromConfig(discardPrinter(), pkgs, false, false, 1, 0, nil); code == 0 {
 		t.Fatal("installFromConfig() exit = 0, want non-zero for a version-less package")
 	}
 }
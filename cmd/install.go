@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/configstate"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+func newInstallCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install IMPORT_PATH[@VERSION]...",
+		Short: "Install binaries with 'go install' and record them in gup.json",
+		Long: `Install binaries with 'go install' and record them in gup.json.
+
+Each IMPORT_PATH is installed at VERSION, which is @latest when omitted.
+@main and @master install the branch head and record the main or master
+update channel, so 'gup update' keeps following the branch. Any other
+VERSION installs that exact version and records the @latest channel, unless
+--pin is given: then the tool is pinned at the version installed (also with
+@latest, which pins the version @latest resolved to).
+
+Only the installed tools' entries are added to or replaced in gup.json; the
+rest of the file is left as it is. Several import paths are installed in
+parallel.`,
+		Example: `  gup install golang.org/x/tools/gopls
+  gup install github.com/golangci/golangci-lint/cmd/golangci-lint@v1.62.0 --pin
+  gup install github.com/nao1215/posixer@main`,
+		Args: requireMinArgs(1, "install needs the import path of at least one binary",
+			"gup install golang.org/x/tools/gopls", "gup install golang.org/x/tools/gopls@v0.16.2 --pin"),
+		ValidArgsFunction: cobra.NoFileCompletions,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(runInstall(defaultDependencies(), printerFor(cmd), cmd, args))
+		},
+	}
+
+	cmd.Flags().Bool("pin", false, "pin the binaries at the installed version")
+	cmd.Flags().BoolP("dry-run", "n", false, "perform the trial install with no changes")
+	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to record the binaries in")
+	mustMarkFileFlagAsJSON(cmd)
+	cmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "specify the number of CPU cores to use")
+	mustRegisterFlagCompletion(cmd, "jobs", completeNCPUs)
+	addTimeoutFlag(cmd)
+
+	return cmd
+}
+
+// installTarget is one IMPORT_PATH[@VERSION] argument of 'gup install'.
+type installTarget struct {
+	importPath string
+	// version is the version given to 'go install': "latest", "main", "master"
+	// or a concrete version.
+	version string
+	channel goutil.UpdateChannel
+}
+
+// parseInstallTarget splits arg into an import path and the version to install,
+// and picks the update channel recorded for it.
+func parseInstallTarget(arg string, pin bool) (installTarget, error) {
+	importPath, version, found := strings.Cut(strings.TrimSpace(arg), "@")
+	importPath = strings.TrimSpace(importPath)
+	version = strings.TrimSpace(version)
+	if importPath == "" {
+		return installTarget{}, fmt.Errorf("%q has no import path", arg)
+	}
+	if found && version == "" {
+		return installTarget{}, fmt.Errorf("%q has an empty version", arg)
+	}
+	if version == "" {
+		version = latestKeyword
+	}
+
+	target := installTarget{importPath: importPath, version: version, channel: goutil.UpdateChannelLatest}
+	switch version {
+	case string(goutil.UpdateChannelMain), string(goutil.UpdateChannelMaster):
+		if pin {
+			return installTarget{}, fmt.Errorf("can't pin %s to the %s branch: give a version to pin", importPath, version)
+		}
+		target.channel = goutil.UpdateChannel(version)
+	case latestKeyword:
+	default:
+		if pin {
+			if err := goutil.ValidatePinnedVersion(version); err != nil {
+				return installTarget{}, fmt.Errorf("%s: %w", importPath, err)
+			}
+		}
+	}
+	if pin {
+		target.channel = goutil.UpdateChannelPinned
+	}
+	return target, nil
+}
+
+func runInstall(deps dependencies, p *print.Printer, cmd *cobra.Command, args []string) int {
+	if err := ensureGoCommandAvailable(); err != nil {
+		p.Err(err)
+		return 1
+	}
+	pin, err := getFlagBool(cmd, "pin")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	dryRun, err := getFlagBool(cmd, "dry-run")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	confFile, err := getFlagString(cmd, "file")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	cpus, err := getFlagInt(cmd, "jobs")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	cpus = clampJobs(cpus)
+	timeout, err := getTimeoutFlag(cmd)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	targets := make([]installTarget, 0, len(args))
+	for _, arg := range args {
+		target, err := parseInstallTarget(arg, pin)
+		if err != nil {
+			p.Err(err)
+			return 1
+		}
+		targets = append(targets, target)
+	}
+
+	// Read gup.json before installing anything, so a config that can't be
+	// updated fails the command while nothing has changed yet.
+	confReadPath, err := config.ResolveImportFilePath(confFile)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	confPkgs, err := configstate.ReadFileIfExists(confReadPath)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	result, installed := installTargets(deps, p, targets, dryRun, cpus, timeout)
+	if dryRun || len(installed) == 0 {
+		return result
+	}
+
+	entries, err := installedEntries(p, installed)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if len(entries) == 0 {
+		return 1
+	}
+	channelMap := make(map[string]goutil.UpdateChannel, len(entries))
+	for _, e := range entries {
+		channelMap[e.Name] = e.UpdateChannel
+	}
+	merged := configstate.MergePackages(confPkgs, entries, channelMap, nil)
+	writePath := configstate.ResolveWritePath(confFile, confReadPath)
+	if err := writeConfigFile(writePath, merged); err != nil {
+		p.Err(fmt.Errorf("failed to write %s: %w", writePath, err))
+		return 1
+	}
+	for _, e := range entries {
+		p.Info(fmt.Sprintf("recorded %s@%s (%s) in %s", e.Name, e.Version.Current, e.UpdateChannel, writePath))
+	}
+	if len(entries) < len(installed) {
+		return 1
+	}
+	return result
+}
+
+// installTargets runs 'go install' for every target in parallel and returns
+// the targets that were installed. With dryRun, the binaries are installed into
+// a temporary directory that is removed afterwards.
+func installTargets(deps dependencies, pr *print.Printer, targets []installTarget, dryRun bool, cpus int, timeout time.Duration) (exitCode int, installed []installTarget) {
+	if dryRun {
+		dryRunManager := goutil.NewGoPaths()
+		if err := dryRunManager.StartDryRunMode(); err != nil {
+			pr.Err(fmt.Errorf("can not change to dry run mode: %w", err))
+			return 1, nil
+		}
+		defer func() {
+			if err := dryRunManager.EndDryRunMode(); err != nil {
+				pr.Err(fmt.Errorf("can not change dry run mode to normal mode: %w", err))
+				exitCode = 1
+			}
+		}()
+	}
+
+	pkgs := make([]goutil.Package, 0, len(targets))
+	byImportPath := make(map[string]installTarget, len(targets))
+	for _, t := range targets {
+		pkgs = append(pkgs, goutil.Package{
+			Name:          binaryNameFromImportPath(t.importPath),
+			ImportPath:    t.importPath,
+			Version:       &goutil.Version{Current: t.version},
+			UpdateChannel: t.channel,
+		})
+		byImportPath[t.importPath] = t
+	}
+
+	installer := func(ctx context.Context, deps dependencies, p goutil.Package) updateResult {
+		var err error
+		switch version := p.Version.Current; version {
+		case latestKeyword, string(goutil.UpdateChannelMain), string(goutil.UpdateChannelMaster):
+			channel := goutil.UpdateChannel(version)
+			err = installWithSelectedVersion(deps, ctx, p.ImportPath, channel)
+		default:
+			err = deps.installVersion(ctx, p.ImportPath, version)
+		}
+		if err != nil {
+			return updateResult{pkg: p, err: fmt.Errorf("%s: %w", p.ImportPath, err)}
+		}
+		return updateResult{updated: true, pkg: p}
+	}
+
+	exitCode, results := executePackages(pr, pkgs, cpus, timeout, deps, installer, func(prefix string, v updateResult) {
+		pr.Info(fmt.Sprintf("%s %s@%s", prefix, v.pkg.ImportPath, v.pkg.Version.Current))
+	})
+	for _, r := range results {
+		if r.err == nil {
+			installed = append(installed, byImportPath[r.pkg.ImportPath])
+		}
+	}
+	return exitCode, installed
+}
+
+// installedEntries builds the gup.json entry of every installed target from
+// the build info of the binary 'go install' wrote, so the entry records the
+// binary's real name and the exact version @latest or a branch resolved to. A
+// target whose binary can't be found, or that can't be pinned at its version,
+// is reported and left out.
+func installedEntries(p *print.Printer, targets []installTarget) ([]goutil.Package, error) {
+	pkgs, err := installedPackageInfo(p)
+	if err != nil {
+		return nil, err
+	}
+	byImportPath := make(map[string]goutil.Package, len(pkgs))
+	for _, pkg := range pkgs {
+		byImportPath[pkg.ImportPath] = pkg
+	}
+
+	entries := make([]goutil.Package, 0, len(targets))
+	for _, t := range targets {
+		pkg, ok := byImportPath[t.importPath]
+		if !ok || pkg.Version == nil {
+			p.Err(fmt.Sprintf("installed %s, but can't find its binary under $GOBIN: not recorded in gup.json", t.importPath))
+			continue
+		}
+		version := strings.TrimSpace(pkg.Version.Current)
+		entry := goutil.Package{
+			Name:          pkg.Name,
+			ImportPath:    pkg.ImportPath,
+			Version:       &goutil.Version{Current: version},
+			UpdateChannel: t.channel,
+		}
+		if t.channel == goutil.UpdateChannelPinned {
+			if err := goutil.ValidatePinnedVersion(version); err != nil {
+				p.Err(fmt.Sprintf("installed %s, but can't pin it: %v", t.importPath, err))
+				continue
+			}
+			entry.PinnedVersion = version
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+func Test_parseInstallTarget(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		arg     string
+		pin     bool
+		want    installTarget
+		wantErr bool
+	}{
+		{
+			name: "no version is latest",
+			arg:  "example.com/tool",
+			want: installTarget{importPath: "example.com/tool", version: latestKeyword, channel: goutil.UpdateChannelLatest},
+		},
+		{
+			name: "branch records its channel",
+			arg:  "example.com/tool@main",
+			want: installTarget{importPath: "example.com/tool", version: "main", channel: goutil.UpdateChannelMain},
+		},
+		{
+			name: "concrete version stays on latest",
+			arg:  "example.com/tool@v1.2.0",
+			want: installTarget{importPath: "example.com/tool", version: "v1.2.0", channel: goutil.UpdateChannelLatest},
+		},
+		{
+			name: "pinned version",
+			arg:  "example.com/tool@v1.2.0",
+			pin:  true,
+			want: installTarget{importPath: "example.com/tool", version: "v1.2.0", channel: goutil.UpdateChannelPinned},
+		},
+		{
+			name: "pinned latest",
+			arg:  "example.com/tool",
+			pin:  true,
+			want: installTarget{importPath: "example.com/tool", version: latestKeyword, channel: goutil.UpdateChannelPinned},
+		},
+		{name: "pinned branch", arg: "example.com/tool@master", pin: true, wantErr: true},
+		{name: "empty version", arg: "example.com/tool@", wantErr: true},
+		{name: "no import path", arg: "@v1.0.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseInstallTarget(tt.arg, tt.pin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseInstallTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(installTarget{})); diff != "" {
+				t.Errorf("parseInstallTarget() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // swaps XDG env and the installed-package seam
+func Test_runInstall(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+
+	// gup.json already holds another tool, which must be kept as it is.
+	writePath, err := config.ResolveImportFilePath("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfigFile(writePath, []goutil.Package{
+		{Name: "kept", ImportPath: "example.com/kept", Version: &goutil.Version{Current: "v0.1.0"}, UpdateChannel: goutil.UpdateChannelMaster},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	stubInstalledPackageInfo(t, []goutil.Package{
+		{Name: "kept", ImportPath: "example.com/kept", Version: &goutil.Version{Current: "v0.1.0"}},
+		{Name: "tool", ImportPath: "example.com/tool/cmd/tool", Version: &goutil.Version{Current: "v1.3.0"}},
+		{Name: "held", ImportPath: "example.com/held", Version: &goutil.Version{Current: "v1.1.0"}},
+	})
+
+	var mu sync.Mutex
+	calls := []string{}
+	record := func(importPath, version string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, importPath+"@"+version)
+	}
+	deps := testDeps()
+	deps.installLatest = func(_ context.Context, importPath string, _ goutil.InstallOptions) error {
+		record(importPath, "latest")
+		return nil
+	}
+	deps.installByVersion = func(_ context.Context, importPath, version string, _ goutil.InstallOptions) error {
+		record(importPath, version)
+		return nil
+	}
+
+	cmd := newInstallCmd()
+	if err := cmd.ParseFlags([]string{"--pin", "-j", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	p, buf := newTestPrinter()
+	if got := runInstall(deps, p, cmd, []string{"example.com/tool/cmd/tool", "example.com/held@v1.1.0"}); got != 0 {
+		t.Fatalf("runInstall() = %d, want 0; output:\n%s", got, buf.String())
+	}
+
+	sort.Strings(calls)
+	if diff := cmp.Diff([]string{"example.com/held@v1.1.0", "example.com/tool/cmd/tool@latest"}, calls); diff != "" {
+		t.Errorf("installs mismatch (-want +got):\n%s", diff)
+	}
+	pkgs, err := config.ReadConfFile(writePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, pkg := range pkgs {
+		got[pkg.Name] = pkg.ImportPath + "@" + pkg.Version.Current + " " + string(pkg.UpdateChannel)
+	}
+	want := map[string]string{
+		"kept": "example.com/kept@v0.1.0 master",
+		"tool": "example.com/tool/cmd/tool@v1.3.0 pinned",
+		"held": "example.com/held@v1.1.0 pinned",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("gup.json mismatch (-want +got):\n%s", diff)
+	}
+}
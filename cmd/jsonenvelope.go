@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+)
+
+// jsonSchemaVersion is the schema_version of the --json-format v2 envelope.
+const jsonSchemaVersion = 2
+
+// jsonEnvelope is the --json-format v2 output: the same records as --json,
+// wrapped with what a consumer would otherwise have to work out itself.
+type jsonEnvelope struct {
+	SchemaVersion int               `json:"schema_version"`
+	Command       string            `json:"command"`
+	Packages      []jsonTimedRecord `json:"packages"`
+	Summary       resultCounts      `json:"summary"`
+	// DurationMS is the wall time of the whole command.
+	DurationMS  int64           `json:"duration_ms"`
+	Environment jsonEnvironment `json:"environment"`
+}
+
+// jsonTimedRecord is a jsonPackage with the time its package took. list does
+// no work per package, so its records have no duration.
+type jsonTimedRecord struct {
+	jsonPackage
+	DurationMS *int64 `json:"duration_ms,omitempty"`
+}
+
+// jsonEnvironment is what the command ran against.
+type jsonEnvironment struct {
+	GOBIN string `json:"gobin"`
+	// ConfigFile is the gup.json the command read, or "" when it read none.
+	ConfigFile string `json:"config_file"`
+	// GoVersion is the installed Go, or "" when 'go version' failed.
+	GoVersion string `json:"go_version"`
+}
+
+// newJSONEnvironment describes the environment of a command that reads the
+// gup.json confFile resolves to. Call it before a dry run swaps $GOBIN.
+func newJSONEnvironment(confFile string) jsonEnvironment {
+	var env jsonEnvironment
+	env.GOBIN, _ = goutil.GoBin()
+	if path, err := config.ResolveImportFilePath(confFile); err == nil && fileutil.IsFile(path) {
+		env.ConfigFile = path
+	}
+	env.GoVersion, _ = goutil.GetInstalledGoVersion()
+	return env
+}
+
+// jsonEnvelopeWriter writes a command's --json-format v2 envelope.
+type jsonEnvelopeWriter struct {
+	w       io.Writer
+	command string
+	begin   time.Time
+	env     jsonEnvironment
+}
+
+func newJSONEnvelopeWriter(w io.Writer, command string, env jsonEnvironment) *jsonEnvelopeWriter {
+	return &jsonEnvelopeWriter{w: w, command: command, begin: time.Now(), env: env}
+}
+
+// write writes recs, the records of results in the same order, as the
+// envelope. Like --json, it is indented and never has a null array.
+func (e *jsonEnvelopeWriter) write(recs []jsonPackage, results []updateResult) error {
+	env := jsonEnvelope{
+		SchemaVersion: jsonSchemaVersion,
+		Command:       e.command,
+		Packages:      make([]jsonTimedRecord, 0, len(recs)),
+		Summary:       countResults(results),
+		DurationMS:    time.Since(e.begin).Milliseconds(),
+		Environment:   e.env,
+	}
+	for i, rec := range recs {
+		r := jsonTimedRecord{jsonPackage: rec}
+		if i < len(results) && results[i].elapsed > 0 {
+			ms := results[i].elapsed.Milliseconds()
+			r.DurationMS = &ms
+		}
+		env.Packages = append(env.Packages, r)
+	}
+	enc := json.NewEncoder(e.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(env)
+}
+
+// writeResults writes results as the envelope, with the records --json-stream
+// would report. It is for import and migrate, which have no --json array.
+func (e *jsonEnvelopeWriter) writeResults(results []updateResult) error {
+	recs := make([]jsonPackage, 0, len(results))
+	for _, v := range results {
+		recs = append(recs, reportRecord(v))
+	}
+	return e.write(recs, results)
+}
+
+// jsonReport is the machine-readable output a command writes instead of, or
+// besides, its --json array: --json-stream events, the --json-format v2
+// envelope, or the records in a --format layout. A nil *jsonReport writes none
+// of them.
+type jsonReport struct {
+	stream   *jsonStream
+	envelope *jsonEnvelopeWriter
+	format   *outputFormat
+}
+
+// newJSONReport returns the report for --json-stream or --json-format v2 and
+// the printer the command should use, which keeps the normal output for the
+// JSON. Without either flag it returns nil and p. confFile is the --file of a
+// command that reads gup.json.
+func newJSONReport(p *print.Printer, command string, stream, v2 bool, confFile string) (*jsonReport, *print.Printer) {
+	switch {
+	case stream:
+		return &jsonReport{stream: newJSONStream(p.Out(), command)}, jsonPrinter(p)
+	case v2:
+		return &jsonReport{envelope: newJSONEnvelopeWriter(p.Out(), command, newJSONEnvironment(confFile))}, jsonPrinter(p)
+	}
+	return nil, p
+}
+
+// events returns where --json-stream events go, or nil.
+func (r *jsonReport) events() *jsonStream {
+	if r == nil {
+		return nil
+	}
+	return r.stream
+}
+
+// v2 returns the --json-format v2 writer, or nil.
+func (r *jsonReport) v2() *jsonEnvelopeWriter {
+	if r == nil {
+		return nil
+	}
+	return r.envelope
+}
+
+// layout returns the --format layout, or nil.
+func (r *jsonReport) layout() *outputFormat {
+	if r == nil {
+		return nil
+	}
+	return r.format
+}
+
+// environment returns the v2 envelope's environment for a command to correct,
+// or nil.
+func (r *jsonReport) environment() *jsonEnvironment {
+	if e := r.v2(); e != nil {
+		return &e.env
+	}
+	return nil
+}
+
+// writeResults writes the v2 envelope of results, if there is one, and
+// returns exitCode, or 1 when the envelope can't be written.
+func (r *jsonReport) writeResults(p *print.Printer, results []updateResult, exitCode int) int {
+	e := r.v2()
+	if e == nil {
+		return exitCode
+	}
+	if err := e.writeResults(results); err != nil {
+		p.Err(err)
+		return 1
+	}
+	return exitCode
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+func Test_updateWithChannels_jsonFormatV2(t *testing.T) {
+	t.Parallel()
+
+	deps := testDeps()
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionTwo, nil }
+	pkgs := []goutil.Package{
+		{
+			Name:       testBinTool,
+			ImportPath: testImportExampleTool,
+			ModulePath: testImportExampleTool,
+			Version:    &goutil.Version{Current: testVersionOne},
+			GoVersion:  &goutil.Version{Current: testGoVersion1224, Latest: testGoVersion1224},
+		},
+		{
+			Name:       "uptodate",
+			ImportPath: testImportExampleUpToDate,
+			ModulePath: testImportExampleUpToDate,
+			Version:    &goutil.Version{Current: testVersionTwo},
+			GoVersion:  &goutil.Version{Current: testGoVersion1224, Latest: testGoVersion1224},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	env := jsonEnvironment{GOBIN: "/home/me/go/bin", ConfigFile: "/home/me/.config/gup/gup.json", GoVersion: testGoVersion1224}
+	report := &jsonReport{envelope: newJSONEnvelopeWriter(out, "update", env)}
+	p, human := newTestPrinter()
+	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, nil, nil, 0, true, false, report)
+	if result != 0 {
+		t.Fatalf("updateWithChannels() = %d, want 0; output:\n%s", result, human.String())
+	}
+
+	var got jsonEnvelope
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("output is not an envelope: %v\n%s", err, out.String())
+	}
+	if got.SchemaVersion != jsonSchemaVersion || got.Command != "update" {
+		t.Errorf("schema_version, command = %d, %q", got.SchemaVersion, got.Command)
+	}
+	if diff := cmp.Diff(env, got.Environment); diff != "" {
+		t.Errorf("environment mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(resultCounts{Total: 2, Updated: 1, UpToDate: 1}, got.Summary); diff != "" {
+		t.Errorf("summary mismatch (-want +got):\n%s", diff)
+	}
+	if len(got.Packages) != 2 {
+		t.Fatalf("got %d packages, want 2", len(got.Packages))
+	}
+	for _, rec := range got.Packages {
+		if rec.DurationMS == nil {
+			t.Errorf("%s has no duration_ms", rec.Name)
+		}
+	}
+}
+
+func Test_encodeListJSON_v2(t *testing.T) {
+	t.Parallel()
+
+	out := &bytes.Buffer{}
+	report := &jsonReport{envelope: newJSONEnvelopeWriter(out, "list", jsonEnvironment{})}
+	p, _ := newTestPrinter()
+	pkgs := []goutil.Package{{Name: testBinTool, ImportPath: testImportExampleTool, Version: &goutil.Version{Current: testVersionOne}}}
+	if err := encodeListJSON(p, report, pkgs); err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	recs, _ := got["packages"].([]any)
+	if len(recs) != 1 {
+		t.Fatalf("packages = %v, want one record", got["packages"])
+	}
+	if _, ok := recs[0].(map[string]any)["duration_ms"]; ok {
+		t.Errorf("list record has a duration_ms: %v", recs[0])
+	}
+	if summary := got["summary"].(map[string]any); summary["installed"] != 1.0 {
+		t.Errorf("summary = %v, want one installed", summary)
+	}
+}
+
+func Test_countResults(t *testing.T) {
+	t.Parallel()
+
+	results := []updateResult{
+		{status: statusUpdated},
+		{status: statusUpToDate},
+		{status: statusPinned},
+		{status: statusUpdateAvailable},
+		{status: statusNeedsNewerGo},
+		{status: statusInstalled},
+		{updated: true},
+		{skipped: true},
+		{err: errors.New("boom"), status: statusError},
+	}
+	want := resultCounts{Total: 9, Updated: 1, UpToDate: 2, UpdateAvailable: 1, NeedsNewerGo: 1, Installed: 2, Skipped: 1, Failed: 1}
+	if diff := cmp.Diff(want, countResults(results)); diff != "" {
+		t.Errorf("countResults() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_getJSONFormatV2(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		args    []string
+		want    bool
+		wantErr bool
+	}{
+		{args: nil},
+		{args: []string{"--json-format", "v1"}},
+		{args: []string{"--json-format", "v2"}, want: true},
+		{args: []string{"--json-format", "2"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		cmd := newCheckCmd()
+		if err := cmd.ParseFlags(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		got, err := getJSONFormatV2(cmd)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("getJSONFormatV2(%v) = %v, %v; want %v, error %v", tt.args, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
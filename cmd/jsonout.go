@@ -11,7 +11,8 @@ import (
 // Status values reported in the machine-readable (--json) output. They form a
 // stable contract for scripting and CI use, so existing values must not change.
 const (
-	// statusInstalled is reported by 'list' for every installed binary.
+	// statusInstalled is reported by 'list' for every installed binary, and by
+	// --json-stream for a package import or migrate installed.
 	statusInstalled = "installed"
 	// statusUpToDate means the binary already matches its update channel.
 	statusUpToDate = "up-to-date"
@@ -25,8 +26,15 @@ const (
 	// statusPinMismatch means a pinned binary's installed version differs from its
 	// pinned version, so 'gup update' would reinstall it at the pinned version.
 	statusPinMismatch = "pin-mismatch"
+	// statusNeedsNewerGo means 'check' found a newer version whose go.mod needs
+	// a newer Go than the installed one, and GOTOOLCHAIN will not download it:
+	// 'gup update' would fail to build it.
+	statusNeedsNewerGo = "needs-newer-go"
 	// statusError means the package could not be processed; see the error field.
 	statusError = "error"
+	// statusSkipped is reported only by --json-stream, for a package import or
+	// migrate left alone on purpose.
+	statusSkipped = "skipped"
 )
 
 // jsonPackage is the stable, machine-readable record emitted by --json. The
@@ -47,6 +55,15 @@ type jsonPackage struct {
 	Status             string `json:"status"`
 	Error              string `json:"error,omitempty"`
 	Hint               string `json:"hint,omitempty"`
+	// Changelog is emitted only by 'check --changelog' for a binary with an
+	// available update.
+	Changelog *changelogReport `json:"changelog,omitempty"`
+	// Toolchain is emitted only by 'check' for a binary with an available
+	// update, when the candidate version's go.mod could be read.
+	Toolchain *toolchainCheck `json:"toolchain,omitempty"`
+	// Attempts is emitted only with --retries: 1 plus the retries the
+	// package's version lookups and installs took after network errors.
+	Attempts int `json:"attempts,omitempty"`
 }
 
 // newJSONPackage builds a jsonPackage from package information, the resolved
@@ -86,7 +103,10 @@ func newJSONPackage(p goutil.Package, status string, err error) jsonPackage {
 // resultToJSONPackage converts an execution result into a JSON record. Error
 // results are always reported with statusError regardless of the worker status.
 func resultToJSONPackage(v updateResult) jsonPackage {
-	return newJSONPackage(v.pkg, v.status, v.err)
+	rec := newJSONPackage(v.pkg, v.status, v.err)
+	rec.Toolchain = v.toolchain
+	rec.Attempts = v.attempts
+	return rec
 }
 
 // resultsToJSONPackages converts execution results into JSON records, preserving
@@ -101,6 +121,19 @@ func resultsToJSONPackages(results []updateResult) []jsonPackage {
 	return recs
 }
 
+// encodeJSONResults writes recs, the records of results, as the --json array,
+// as the envelope when report asks for --json-format v2, or in report's
+// --format layout.
+func encodeJSONResults(p *print.Printer, report *jsonReport, recs []jsonPackage, results []updateResult) error {
+	if env := report.v2(); env != nil {
+		return env.write(recs, results)
+	}
+	if f := report.layout(); f != nil {
+		return f.render(p.Out(), recs)
+	}
+	return encodeJSONPackages(p, recs)
+}
+
 // encodeJSONPackages writes records to stdout as an indented JSON array. A nil
 // or empty slice is emitted as "[]" (never "null") so consumers always receive
 // a valid JSON array.
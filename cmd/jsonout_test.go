@@ -10,12 +10,15 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/diagnose"
 	"github.com/nao1215/gup/internal/goutil"
 	"github.com/nao1215/gup/internal/print"
+	"github.com/nao1215/gup/internal/retry"
 	"github.com/spf13/cobra"
 )
 
@@ -144,10 +147,10 @@ func readJSON(t *testing.T, fn func(p *print.Printer) int) []jsonPackage {
 
 func Test_doCheck_jsonOutput(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(_ context.Context, _ string) (string, error) {
+	deps.getLatestVer = func(_ context.Context, _, _ string) (string, error) {
 		return testVersionTwo, nil
 	}
-	deps.getVerByRef = func(_ context.Context, _ string, _ string) (string, error) {
+	deps.getVerByRef = func(_ context.Context, _, _, _ string) (string, error) {
 		return testVersionNine, nil
 	}
 
@@ -188,7 +191,7 @@ func Test_doCheckJSON_stableInputOrder(t *testing.T) {
 	}
 
 	deps := testDeps()
-	deps.getLatestVer = func(_ context.Context, modulePath string) (string, error) {
+	deps.getLatestVer = func(_ context.Context, modulePath, _ string) (string, error) {
 		time.Sleep(sleepByModule[modulePath])
 		return testVersionTwo, nil
 	}
@@ -241,8 +244,8 @@ func Test_listJSONRecords(t *testing.T) {
 func Test_updateWithChannels_jsonOutput(t *testing.T) {
 	deps := testDeps()
 	// tool: outdated -> will be updated; uptodate: already current -> up-to-date
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionTwo, nil }
-	deps.installLatest = func(context.Context, string) error { return nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionTwo, nil }
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error { return nil }
 
 	pkgs := []goutil.Package{
 		{
@@ -268,7 +271,7 @@ func Test_updateWithChannels_jsonOutput(t *testing.T) {
 	var recs []jsonPackage
 	var result int
 	out := captureCheckOutput(t, func(p *print.Printer) int {
-		result, _, _ = updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, true, false)
+		result, _, _ = updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, true, false, nil)
 		return result
 	})
 
@@ -292,7 +295,7 @@ func Test_updateWithChannels_jsonOutput(t *testing.T) {
 
 func Test_doCheck_jsonOutput_errorRecord(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(_ context.Context, _ string) (string, error) {
+	deps.getLatestVer = func(_ context.Context, _, _ string) (string, error) {
 		return "", errors.New("module not found")
 	}
 
@@ -320,7 +323,7 @@ func Test_check_jsonFlag(t *testing.T) {
 	t.Setenv("GOBIN", filepath.Join("testdata", "check_success"))
 
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
 
 	cmd := newCheckCmd()
 	if err := cmd.Flags().Set("json", "true"); err != nil {
@@ -526,7 +529,7 @@ func Test_emptyEnv_listJSON_validatesAutoDetectedMalformedConfig(t *testing.T) {
 // flag-parsing and JSON-dispatch branches in gup()/updateWithChannels are
 // covered without performing real installs.
 func Test_gup_jsonFlag(t *testing.T) {
-	deps := helper_stubUpdateForJSON(t, func(context.Context, string) error { return nil })
+	deps := helper_stubUpdateForJSON(t, func(context.Context, string, goutil.InstallOptions) error { return nil })
 
 	cmd := helper_newJSONDryRunUpdateCmd(t)
 
@@ -549,12 +552,12 @@ func Test_gup_jsonFlag(t *testing.T) {
 // installs. The caller passes the returned deps to gup(), and readJSON fails the
 // test if STDOUT is not valid JSON, which is exactly the contamination this
 // issue (#291) guards against.
-func helper_stubUpdateForJSON(t *testing.T, install func(context.Context, string) error) dependencies {
+func helper_stubUpdateForJSON(t *testing.T, install func(context.Context, string, goutil.InstallOptions) error) dependencies {
 	t.Helper()
 	t.Setenv("GOBIN", filepath.Join("testdata", "check_success"))
 
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
 	deps.installLatest = install
 	return deps
 }
@@ -579,7 +582,7 @@ func helper_newJSONDryRunUpdateCmd(t *testing.T) *cobra.Command {
 // in normal mode. This is the core regression for issue #291: before the fix,
 // excludePkgs wrote that line to STDOUT (print.Info) and broke JSON parsing.
 func Test_gup_jsonFlag_excludeKeepsStdoutPure(t *testing.T) {
-	deps := helper_stubUpdateForJSON(t, func(context.Context, string) error { return nil })
+	deps := helper_stubUpdateForJSON(t, func(context.Context, string, goutil.InstallOptions) error { return nil })
 
 	cmd := helper_newJSONDryRunUpdateCmd(t)
 	if err := cmd.Flags().Set("exclude", testBinPosixer); err != nil {
@@ -609,7 +612,7 @@ func Test_gup_jsonFlag_excludeKeepsStdoutPure(t *testing.T) {
 // not contaminate the JSON written to STDOUT, while the valid packages are still
 // reported. This pins STDOUT purity for the missing-targets edge case.
 func Test_gup_jsonFlag_missingFlagTargetKeepsStdoutPure(t *testing.T) {
-	deps := helper_stubUpdateForJSON(t, func(context.Context, string) error { return nil })
+	deps := helper_stubUpdateForJSON(t, func(context.Context, string, goutil.InstallOptions) error { return nil })
 
 	cmd := helper_newJSONDryRunUpdateCmd(t)
 	if err := cmd.Flags().Set("main", "doesnotexist"); err != nil {
@@ -633,7 +636,7 @@ func Test_gup_jsonFlag_missingFlagTargetKeepsStdoutPure(t *testing.T) {
 // fails to install, its error (emitted via print.Err to STDERR) does not
 // contaminate STDOUT and the JSON still reports a per-package error status.
 func Test_gup_jsonFlag_partialFailureKeepsStdoutPure(t *testing.T) {
-	deps := helper_stubUpdateForJSON(t, func(_ context.Context, importPath string) error {
+	deps := helper_stubUpdateForJSON(t, func(_ context.Context, importPath string, _ goutil.InstallOptions) error {
 		if strings.Contains(importPath, testBinPosixer) {
 			return errors.New("install failed")
 		}
@@ -664,3 +667,29 @@ func Test_gup_jsonFlag_partialFailureKeepsStdoutPure(t *testing.T) {
 		}
 	}
 }
+
+// Test_doCheckJSON_retryAttempts verifies that with --retries a version lookup
+// that hits a network error is tried again, and the record reports the
+// attempts it took.
+func Test_doCheckJSON_retryAttempts(t *testing.T) {
+	var calls atomic.Int32
+	deps := testDeps()
+	deps.getLatestVer = func(context.Context, string, string) (string, error) {
+		if calls.Add(1) == 1 {
+			return "", errors.New("dial tcp 127.0.0.1:443: connect: connection refused")
+		}
+		return testVersionTwo, nil
+	}
+	deps.retry = retry.Policy{Retries: 2, Backoff: time.Millisecond, Transient: diagnose.Transient}
+
+	recs := readJSON(t, func(p *print.Printer) int {
+		return doCheckJSON(deps, p, []goutil.Package{newCheckPkg("flaky", testVersionOne, goutil.UpdateChannelLatest)}, 1, 0, true)
+	})
+
+	if len(recs) != 1 {
+		t.Fatalf("got %d records, want 1", len(recs))
+	}
+	if recs[0].Status != statusUpdateAvailable || recs[0].Attempts != 2 {
+		t.Errorf("record = status %q, attempts %d; want %q after 2 attempts", recs[0].Status, recs[0].Attempts, statusUpdateAvailable)
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+)
+
+// Events in the --json-stream output, one JSON object per line. A package goes
+// through started, then resolved and/or installing when the command gets that
+// far, then done or error. The last line is always the summary.
+const (
+	// eventStarted means a worker picked the package up.
+	eventStarted = "started"
+	// eventResolved means the version the command compares with or installs is
+	// known; it is in latest_version.
+	eventResolved = "resolved"
+	// eventInstalling means 'go install' started for the package.
+	eventInstalling = "installing"
+	// eventDone means the package finished without an error.
+	eventDone = "done"
+	// eventError means the package failed; see the error field.
+	eventError = "error"
+	// eventSummary ends the stream with the counts and the exit code.
+	eventSummary = "summary"
+)
+
+// jsonEvent is one line of --json-stream output. Package events carry the
+// fields of the package's jsonPackage record; the summary event carries the
+// counts instead.
+type jsonEvent struct {
+	Event   string `json:"event"`
+	Command string `json:"command"`
+	// Time is when the event happened, in RFC 3339 with nanoseconds, in UTC.
+	Time string `json:"time"`
+	*jsonPackage
+	// DurationMS is how long the package took, for done and error, or the
+	// whole command took, for the summary.
+	DurationMS *int64 `json:"duration_ms,omitempty"`
+	// Total, Counts and ExitCode are set only on the summary. Counts is keyed
+	// by status.
+	Total    *int           `json:"total,omitempty"`
+	Counts   map[string]int `json:"counts,omitempty"`
+	ExitCode *int           `json:"exit_code,omitempty"`
+}
+
+// jsonStream writes --json-stream events. Workers emit concurrently, so every
+// line is written under mu.
+type jsonStream struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	command string
+	now     func() time.Time
+	begin   time.Time
+}
+
+// newJSONStream returns a stream of command's events written to w.
+func newJSONStream(w io.Writer, command string) *jsonStream {
+	return &jsonStream{enc: json.NewEncoder(w), command: command, now: time.Now, begin: time.Now()}
+}
+
+// jsonPrinter returns the printer a command uses while its JSON goes to the
+// normal output of p: the human-readable lines move to p's error output, so
+// the normal output stays JSON only.
+func jsonPrinter(p *print.Printer) *print.Printer {
+	return print.New(p.ErrOut(), p.ErrOut())
+}
+
+func (s *jsonStream) write(ev jsonEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ev.Command = s.command
+	ev.Time = s.now().UTC().Format(time.RFC3339Nano)
+	_ = s.enc.Encode(ev)
+}
+
+// pkgEvent reports event for pkg.
+func (s *jsonStream) pkgEvent(event string, pkg goutil.Package) {
+	rec := newJSONPackage(pkg, "", nil)
+	s.write(jsonEvent{Event: event, jsonPackage: &rec})
+}
+
+// result reports a finished package as done or error.
+func (s *jsonStream) result(v updateResult) {
+	rec := reportRecord(v)
+	event := eventDone
+	if v.err != nil {
+		event = eventError
+	}
+	ms := v.elapsed.Milliseconds()
+	s.write(jsonEvent{Event: event, jsonPackage: &rec, DurationMS: &ms})
+}
+
+// summary ends the stream with the count of every status in results.
+func (s *jsonStream) summary(results []updateResult, exitCode int) {
+	counts := map[string]int{}
+	for _, v := range results {
+		counts[reportRecord(v).Status]++
+	}
+	total := len(results)
+	ms := s.now().Sub(s.begin).Milliseconds()
+	s.write(jsonEvent{Event: eventSummary, DurationMS: &ms, Total: &total, Counts: counts, ExitCode: &exitCode})
+}
+
+// reportRecord converts a result into its record in --json-stream and
+// --json-format v2 output. import and migrate do not set a status, so theirs
+// is derived here.
+func reportRecord(v updateResult) jsonPackage {
+	rec := resultToJSONPackage(v)
+	if rec.Status == "" {
+		rec.Status = statusInstalled
+		if v.skipped {
+			rec.Status = statusSkipped
+		}
+	}
+	return rec
+}
+
+// streamWorker wraps worker so it reports the started event, and the steps
+// the worker reports by dependencies.event, to s.
+func streamWorker(s *jsonStream, worker pkgWorker) pkgWorker {
+	return func(ctx context.Context, deps dependencies, pkg goutil.Package) updateResult {
+		s.pkgEvent(eventStarted, pkg)
+		return worker(ctx, deps.observe(s.pkgEvent), pkg)
+	}
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// readJSONEvents decodes --json-stream output, one event per line.
+func readJSONEvents(t *testing.T, out []byte) []map[string]any {
+	t.Helper()
+	var events []map[string]any
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		var ev map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			t.Fatalf("line %q is not a JSON object: %v", sc.Text(), err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+func Test_updateWithChannels_jsonStream(t *testing.T) {
+	t.Parallel()
+
+	deps := testDeps()
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionTwo, nil }
+	pkgs := []goutil.Package{
+		{
+			Name:       testBinTool,
+			ImportPath: testImportExampleTool,
+			ModulePath: testImportExampleTool,
+			Version:    &goutil.Version{Current: testVersionOne},
+			GoVersion:  &goutil.Version{Current: testGoVersion1224, Latest: testGoVersion1224},
+		},
+		{
+			Name:       "uptodate",
+			ImportPath: testImportExampleUpToDate,
+			ModulePath: testImportExampleUpToDate,
+			Version:    &goutil.Version{Current: testVersionTwo},
+			GoVersion:  &goutil.Version{Current: testGoVersion1224, Latest: testGoVersion1224},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	stream := newJSONStream(out, "update")
+	p, human := newTestPrinter()
+	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, nil, nil, 0, false, false, &jsonReport{stream: stream})
+	if result != 0 {
+		t.Fatalf("updateWithChannels() = %d, want 0; output:\n%s", result, human.String())
+	}
+
+	events := readJSONEvents(t, out.Bytes())
+	got := map[string][]string{}
+	for _, ev := range events[:len(events)-1] {
+		name, _ := ev["name"].(string)
+		got[name] = append(got[name], ev["event"].(string))
+		if ev["command"] != "update" || ev["time"] == "" {
+			t.Errorf("event lacks command or time: %v", ev)
+		}
+		if ev["event"] == eventDone {
+			if _, ok := ev["duration_ms"]; !ok {
+				t.Errorf("done event lacks duration_ms: %v", ev)
+			}
+		}
+	}
+	want := map[string][]string{
+		testBinTool: {eventStarted, eventResolved, eventInstalling, eventDone},
+		"uptodate":  {eventStarted, eventResolved, eventDone},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("package events mismatch (-want +got):\n%s", diff)
+	}
+
+	summary := events[len(events)-1]
+	if summary["event"] != eventSummary || summary["total"] != float64(2) || summary["exit_code"] != float64(0) {
+		t.Errorf("last event = %v, want the summary", summary)
+	}
+	wantCounts := map[string]any{statusUpdated: float64(1), statusUpToDate: float64(1)}
+	if diff := cmp.Diff(wantCounts, summary["counts"]); diff != "" {
+		t.Errorf("summary counts mismatch (-want +got):\n%s", diff)
+	}
+	if _, ok := summary["name"]; ok {
+		t.Errorf("summary carries package fields: %v", summary)
+	}
+}
+
+func Test_reportRecord(t *testing.T) {
+	t.Parallel()
+
+	pkg := goutil.Package{Name: testBinTool, Version: &goutil.Version{Current: testVersionOne}}
+	tests := []struct {
+		name string
+		v    updateResult
+		want string
+	}{
+		{name: "installed", v: updateResult{updated: true, pkg: pkg}, want: statusInstalled},
+		{name: "skipped", v: updateResult{skipped: true, pkg: pkg}, want: statusSkipped},
+		{name: "failed", v: updateResult{pkg: pkg, err: errors.New("boom")}, want: statusError},
+		{name: "status kept", v: updateResult{pkg: pkg, status: statusPinned, skipped: true}, want: statusPinned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := reportRecord(tt.v).Status; got != tt.want {
+				t.Errorf("reportRecord().Status = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseUpdateFlags_jsonStreamConflicts(t *testing.T) {
+	t.Parallel()
+
+	for _, args := range [][]string{
+		{"--json", "--json-stream"},
+		{"--plan", "plan.json", "--json-stream"},
+		{"--json-format", "v2", "--json-stream"},
+		{"--json-format", "v3"},
+	} {
+		cmd := newUpdateCmd()
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseUpdateFlags(cmd); err == nil {
+			t.Errorf("parseUpdateFlags(%v) succeeded", args)
+		}
+	}
+}
@@ -18,7 +18,9 @@ func newListCmd() *cobra.Command {
 		Short: "List command names with package path and version under $GOPATH/bin or $GOBIN",
 		Long:  `List command names with package path and version under $GOPATH/bin or $GOBIN`,
 		Example: `  gup list
-  gup list --json`,
+  gup list --json
+  gup list --json-format v2
+  gup list --format csv=name,current_version`,
 		Args:              cobra.NoArgs,
 		ValidArgsFunction: cobra.NoFileCompletions,
 		Run: func(cmd *cobra.Command, args []string) {
@@ -26,6 +28,8 @@ func newListCmd() *cobra.Command {
 		},
 	}
 	cmd.Flags().Bool("json", false, "output result as machine-readable JSON")
+	addJSONFormatFlag(cmd, "JSON layout: v1 is the bare --json array, v2 wraps it with a summary and the environment (implies --json)")
+	addFormatFlag(cmd)
 	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to read saved update channels from (with --json)")
 	mustMarkFileFlagAsJSON(cmd)
 	return cmd
@@ -46,11 +50,31 @@ func list(p *print.Printer, cmd *cobra.Command, _ []string) int {
 		return 1
 	}
 
+	jsonV2, err := getJSONFormatV2(cmd)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	format, err := getFormatFlag(cmd, listFormatFields)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if format != nil && jsonOut {
+		p.Err("--format can't be used with --json or --json-format v2")
+		return 1
+	}
+	jsonOut = jsonOut || format != nil
+
 	confFile, err := getFlagString(cmd, "file")
 	if err != nil {
 		p.Err(err)
 		return 1
 	}
+	report, p := newJSONReport(p, "list", false, jsonV2, confFile)
+	if format != nil {
+		report = &jsonReport{format: format}
+	}
 
 	if jsonOut {
 		// An empty environment has no packages to annotate, so emit a valid
@@ -63,7 +87,7 @@ func list(p *print.Printer, cmd *cobra.Command, _ []string) int {
 				p.Err(err)
 				return 1
 			}
-			if err := encodeJSONPackages(p, listJSONRecords(nil)); err != nil {
+			if err := encodeListJSON(p, report, nil); err != nil {
 				p.Err(err)
 				return 1
 			}
@@ -78,7 +102,7 @@ func list(p *print.Printer, cmd *cobra.Command, _ []string) int {
 			p.Err(cerr)
 			return 1
 		}
-		if err := encodeJSONPackages(p, listJSONRecords(annotated)); err != nil {
+		if err := encodeListJSON(p, report, annotated); err != nil {
 			p.Err(err)
 			return 1
 		}
@@ -107,6 +131,16 @@ func listJSONRecords(pkgs []goutil.Package) []jsonPackage {
 	return recs
 }
 
+// encodeListJSON writes the list JSON for pkgs, as the --json array or the
+// --json-format v2 envelope that report asks for.
+func encodeListJSON(p *print.Printer, report *jsonReport, pkgs []goutil.Package) error {
+	results := make([]updateResult, 0, len(pkgs))
+	for _, v := range pkgs {
+		results = append(results, updateResult{pkg: v, status: statusInstalled})
+	}
+	return encodeJSONResults(p, report, listJSONRecords(pkgs), results)
+}
+
 // PackageList list up command package in $GOPATH/bin or $GOBIN.
 func printPackageList(p *print.Printer, pkgs []goutil.Package) {
 	max := 0
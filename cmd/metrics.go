@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/fileutil"
+)
+
+// lastUpdateFileName is the file in the state directory that holds the time
+// 'gup update' last finished without errors.
+const lastUpdateFileName = "last-update"
+
+// recordLastUpdate saves t as the time 'gup update' last finished without
+// errors, for the gup_last_update_timestamp metric. The file is replaced
+// atomically, so a metrics scrape never reads a half-written time.
+func recordLastUpdate(t time.Time) error {
+	dir := config.StateDirPath()
+	if err := os.MkdirAll(dir, fileutil.FileModeCreatingDir); err != nil {
+		return fmt.Errorf("can't create %s: %w", dir, err)
+	}
+	return writeFileAtomically(filepath.Join(dir, lastUpdateFileName), []byte(t.UTC().Format(time.RFC3339)+"\n"))
+}
+
+// readLastUpdate returns the time saved by recordLastUpdate, or the zero time
+// when 'gup update' has not finished without errors yet.
+func readLastUpdate() (time.Time, error) {
+	raw, err := os.ReadFile(filepath.Join(config.StateDirPath(), lastUpdateFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(raw)))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %w", lastUpdateFileName, err)
+	}
+	return t, nil
+}
+
+// outdatedValue maps a --json status to the gup_tool_outdated value. ok is
+// false for statuses that say nothing about the version, such as an error.
+func outdatedValue(status string) (value int, ok bool) {
+	switch status {
+	case statusUpdateAvailable, statusPinMismatch, statusNeedsNewerGo:
+		return 1, true
+	case statusUpToDate, statusPinned, statusUpdated:
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// writeMetrics writes recs in the Prometheus text format. lastUpdate is left
+// out when it is the zero time.
+func writeMetrics(w io.Writer, recs []jsonPackage, lastUpdate time.Time) error {
+	var b strings.Builder
+	b.WriteString("# HELP gup_tool_outdated Whether a newer version of the tool is available on its update channel.\n")
+	b.WriteString("# TYPE gup_tool_outdated gauge\n")
+	for _, r := range recs {
+		if v, ok := outdatedValue(r.Status); ok {
+			_, _ = fmt.Fprintf(&b, "gup_tool_outdated{name=%s,channel=%s} %d\n", metricLabel(r.Name), metricLabel(r.Channel), v)
+		}
+	}
+	b.WriteString("# HELP gup_tool_info Installed version of the tool and the Go it was built with.\n")
+	b.WriteString("# TYPE gup_tool_info gauge\n")
+	for _, r := range recs {
+		_, _ = fmt.Fprintf(&b, "gup_tool_info{name=%s,version=%s,go_version=%s} 1\n",
+			metricLabel(r.Name), metricLabel(r.CurrentVersion), metricLabel(r.CurrentGoVersion))
+	}
+	if !lastUpdate.IsZero() {
+		b.WriteString("# HELP gup_last_update_timestamp Unix time 'gup update' last finished without errors.\n")
+		b.WriteString("# TYPE gup_last_update_timestamp gauge\n")
+		_, _ = fmt.Fprintf(&b, "gup_last_update_timestamp %d\n", lastUpdate.Unix())
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// metricLabel quotes a label value as the Prometheus text format expects.
+func metricLabel(v string) string {
+	v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+	return `"` + v + `"`
+}
+
+// writeMetricsTextfile writes the metrics to path atomically, so the
+// node_exporter textfile collector never reads half a file.
+func writeMetricsTextfile(path string, recs []jsonPackage, lastUpdate time.Time) (err error) {
+	path = filepath.Clean(path)
+	if fileutil.IsDir(path) {
+		return fmt.Errorf("%s is a directory, not a file", path)
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, fileutil.FileModeCreatingDir); err != nil {
+		return fmt.Errorf("can't create %s: %w", dir, err)
+	}
+	// The collector ignores files without the .prom extension, so it skips the
+	// temp file.
+	file, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("can't create temp file for %s: %w", path, err)
+	}
+	tmpPath := file.Name()
+	defer func() {
+		if file != nil {
+			_ = file.Close()
+		}
+		if err != nil {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if err = writeMetrics(file, recs, lastUpdate); err != nil {
+		return err
+	}
+	// node_exporter usually runs as another user, so the file must be readable
+	// by everyone, not only by the 0600 os.CreateTemp applies.
+	if err = file.Chmod(0o644); err != nil { //nolint:gosec // metrics are meant to be read by node_exporter
+		return fmt.Errorf("can't chmod %s: %w", tmpPath, err)
+	}
+	if err = file.Sync(); err != nil {
+		return fmt.Errorf("can't sync %s: %w", tmpPath, err)
+	}
+	if err = file.Close(); err != nil {
+		file = nil
+		return fmt.Errorf("can't close %s: %w", tmpPath, err)
+	}
+	file = nil
+	if err = renameWithReplace(tmpPath, path); err != nil {
+		return fmt.Errorf("can't write %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeCheckMetrics writes the metrics of a finished check to path.
+func writeCheckMetrics(path string, results []updateResult) error {
+	lastUpdate, err := readLastUpdate()
+	if err != nil {
+		return err
+	}
+	return writeMetricsTextfile(path, resultsToJSONPackages(results), lastUpdate)
+}
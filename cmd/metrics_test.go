@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func Test_writeMetrics(t *testing.T) {
+	t.Parallel()
+
+	recs := []jsonPackage{
+		{Name: "gopls", Channel: "latest", CurrentVersion: "v0.16.1", CurrentGoVersion: "go1.22.5", Status: statusUpdateAvailable},
+		{Name: "tool", Channel: "pinned", CurrentVersion: "v1.0.0", CurrentGoVersion: "go1.22.5", Status: statusPinned},
+		{Name: `odd"name`, Channel: "main", Status: statusError},
+	}
+	buf := &bytes.Buffer{}
+	if err := writeMetrics(buf, recs, time.Unix(1760000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP gup_tool_outdated Whether a newer version of the tool is available on its update channel.
+# TYPE gup_tool_outdated gauge
+gup_tool_outdated{name="gopls",channel="latest"} 1
+gup_tool_outdated{name="tool",channel="pinned"} 0
+# HELP gup_tool_info Installed version of the tool and the Go it was built with.
+# TYPE gup_tool_info gauge
+gup_tool_info{name="gopls",version="v0.16.1",go_version="go1.22.5"} 1
+gup_tool_info{name="tool",version="v1.0.0",go_version="go1.22.5"} 1
+gup_tool_info{name="odd\"name",version="",go_version=""} 1
+# HELP gup_last_update_timestamp Unix time 'gup update' last finished without errors.
+# TYPE gup_last_update_timestamp gauge
+gup_last_update_timestamp 1760000000
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("writeMetrics() mismatch (-want +got):\n%s", diff)
+	}
+
+	buf.Reset()
+	if err := writeMetrics(buf, nil, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("gup_last_update_timestamp")) {
+		t.Errorf("writeMetrics() without a last update:\n%s", buf.String())
+	}
+}
+
+func Test_writeMetricsTextfile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "textfile", "gup.prom")
+	recs := []jsonPackage{{Name: "gopls", Channel: "latest", Status: statusUpToDate}}
+	if err := writeMetricsTextfile(path, recs, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(got, []byte(`gup_tool_outdated{name="gopls",channel="latest"} 0`+"\n")) {
+		t.Errorf("textfile:\n%s", got)
+	}
+	if runtime.GOOS != goosWindows {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o644 {
+			t.Errorf("textfile mode = %o, want 644", perm)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("textfile directory holds %d files, want only gup.prom", len(entries))
+	}
+
+	if err := writeMetricsTextfile(dir, recs, time.Time{}); err == nil {
+		t.Error("writeMetricsTextfile() to a directory succeeded")
+	}
+}
+
+//nolint:paralleltest // swaps XDG state paths
+func Test_recordLastUpdate(t *testing.T) {
+	setupXDGBase(t)
+
+	got, err := readLastUpdate()
+	if err != nil || !got.IsZero() {
+		t.Fatalf("readLastUpdate() before any update = %v, %v", got, err)
+	}
+	want := time.Date(2026, 10, 19, 3, 0, 4, 0, time.UTC)
+	if err := recordLastUpdate(want); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = readLastUpdate(); err != nil || !got.Equal(want) {
+		t.Errorf("readLastUpdate() = %v, %v; want %v", got, err, want)
+	}
+
+	// A later update replaces the time in place, leaving no temp file.
+	want = want.Add(24 * time.Hour)
+	if err := recordLastUpdate(want); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = readLastUpdate(); err != nil || !got.Equal(want) {
+		t.Errorf("readLastUpdate() after a second update = %v, %v; want %v", got, err, want)
+	}
+	assertNoTempFiles(t, config.StateDirPath(), lastUpdateFileName)
+}
+
+//nolint:paralleltest // swaps XDG env, GOBIN and the working directory
+func Test_gup_recordsLastUpdateOfFullRunsOnly(t *testing.T) {
+	gobin, err := filepath.Abs(filepath.Join("testdata", "check_success"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOBIN", gobin)
+	setupXDGBase(t)
+	chdirToTemp(t)
+
+	for name, run := range map[string]func(cmd *cobra.Command) []string{
+		"a binary by name": func(*cobra.Command) []string { return []string{"gal"} },
+		"--exclude": func(cmd *cobra.Command) []string {
+			if err := cmd.Flags().Set("exclude", "gal"); err != nil {
+				t.Fatal(err)
+			}
+			return nil
+		},
+	} {
+		cmd := newUpdateCmd()
+		args := run(cmd)
+		p, buf := newTestPrinter()
+		if got := gup(stubUpdateDeps(), p, cmd, args); got != 0 {
+			t.Fatalf("gup() of %s = %d, want 0; output:\n%s", name, got, buf.String())
+		}
+		if last, err := readLastUpdate(); err != nil || !last.IsZero() {
+			t.Errorf("last update after updating %s = %v, %v; want none", name, last, err)
+		}
+	}
+
+	p, buf := newTestPrinter()
+	if got := gup(stubUpdateDeps(), p, newUpdateCmd(), nil); got != 0 {
+		t.Fatalf("gup() = %d, want 0; output:\n%s", got, buf.String())
+	}
+	if last, err := readLastUpdate(); err != nil || last.IsZero() {
+		t.Errorf("last update after updating every binary = %v, %v; want it recorded", last, err)
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/nao1215/gup/internal/buildmanifest"
 	"github.com/nao1215/gup/internal/goutil"
 	"github.com/nao1215/gup/internal/pkgselect"
 	"github.com/nao1215/gup/internal/print"
@@ -54,9 +56,16 @@ $GOBIN.
 migrate is add-only: it never deletes files in AFTER_PATH, and by default it
 skips binaries that already exist there. Use --force to reinstall over them.
 
-If BINARY arguments are given, only those binaries are migrated.`,
+If BINARY arguments are given, only those binaries are migrated.
+
+BEFORE_PATH may also be a directory written by 'gup export --build'. When its
+gup-manifest.json names this machine's GOOS/GOARCH, the prebuilt binaries are
+checked against their SHA-256 in the manifest and copied into AFTER_PATH
+without rebuilding (no go toolchain needed). Binaries built for another
+platform are reinstalled from source at the versions they record.`,
 		Example: `  gup migrate /old/gobin /new/gobin
-  gup migrate /old/gobin /new/gobin gopls`,
+  gup migrate /old/gobin /new/gobin gopls
+  gup migrate ./dist "$(go env GOPATH)/bin"`,
 		Args: requireMinArgs(migrateMinArgs,
 			"requires BEFORE_PATH and AFTER_PATH",
 			"gup migrate /old/gobin /new/gobin"),
@@ -71,6 +80,8 @@ If BINARY arguments are given, only those binaries are migrated.`,
 	cmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "specify the number of CPU cores to use")
 	mustRegisterFlagCompletion(cmd, "jobs", completeNCPUs)
 	cmd.Flags().Bool("force", false, "reinstall even if the binary already exists in AFTER_PATH")
+	addJSONFormatFlag(cmd, "v2 prints the result as a JSON envelope with a summary, timings and the environment; other output goes to STDERR")
+	addJSONStreamFlag(cmd)
 	addTimeoutFlag(cmd)
 
 	return cmd
@@ -89,11 +100,6 @@ func completeMigrateArgs(_ *cobra.Command, args []string, toComplete string) ([]
 }
 
 func runMigrate(p *print.Printer, cmd *cobra.Command, args []string) int {
-	if err := ensureGoCommandAvailable(); err != nil {
-		p.Err(err)
-		return 1
-	}
-
 	dryRun, err := getFlagBool(cmd, "dry-run")
 	if err != nil {
 		p.Err(err)
@@ -120,21 +126,70 @@ func runMigrate(p *print.Printer, cmd *cobra.Command, args []string) int {
 		p.Err(err)
 		return 1
 	}
+	jsonStreamOut, err := getFlagBool(cmd, jsonStreamFlagName)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	jsonV2, err := getJSONFormatV2(cmd)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if jsonV2 && jsonStreamOut {
+		p.Err("--json-format v2 can't be used with --json-stream")
+		return 1
+	}
+	report, p := newJSONReport(p, "migrate", jsonStreamOut, jsonV2, "")
 
 	beforePath := args[0]
 	afterPath := args[1]
 	binaries := args[2:]
+	if env := report.environment(); env != nil {
+		// migrate installs into AFTER_PATH and reads no gup.json.
+		env.GOBIN, env.ConfigFile = afterPath, ""
+	}
 
 	if err := validateMigratePaths(beforePath, afterPath, dryRun); err != nil {
 		p.Err(err)
 		return 1
 	}
 
+	// A directory written by 'export --build' holds prebuilt binaries; when they
+	// were built for this machine, they are copied instead of rebuilt, which
+	// needs no go toolchain.
+	manifest, hasManifest, err := buildmanifest.Read(beforePath)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	autoExport, err := autoExportEnabled()
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if hasManifest {
+		if manifest.Platform() == goutil.HostPlatform() {
+			result := migratePrebuilt(p, manifest, beforePath, afterPath, binaries, dryRun, notify, force, report)
+			return autoExportMigrated(p, result, afterPath, binaries, autoExport && !dryRun)
+		}
+		p.Warn(fmt.Sprintf("%s holds binaries built for %s; reinstalling them from source for %s",
+			beforePath, manifest.Platform(), goutil.HostPlatform()))
+	}
+
+	if err := ensureGoCommandAvailable(); err != nil {
+		p.Err(err)
+		return 1
+	}
+
 	binList, err := goutil.BinaryPathList(beforePath)
 	if err != nil {
 		p.Err(fmt.Errorf("can't read binaries under %s: %w", beforePath, err))
 		return 1
 	}
+	binList = slices.DeleteFunc(binList, func(path string) bool {
+		return filepath.Base(path) == buildmanifest.FileName
+	})
 	// Derive "missing" from the binary paths before filtering, so a requested
 	// name that exists on disk but can't be read (no build info) is not
 	// mislabeled as "not found". This matches update/check via MissingTargets.
@@ -150,7 +205,19 @@ func runMigrate(p *print.Printer, cmd *cobra.Command, args []string) int {
 	}
 
 	p.Info(fmt.Sprintf("start migration from %s to %s", beforePath, afterPath))
-	return migratePackages(p, pkgs, afterPath, dryRun, notify, cpus, force, timeout)
+	result := migratePackages(p, pkgs, afterPath, dryRun, notify, cpus, force, timeout, report)
+	return autoExportMigrated(p, result, afterPath, binaries, autoExport && !dryRun)
+}
+
+// autoExportMigrated records the binaries now in AFTER_PATH, the new $GOBIN,
+// in gup.json when export is true, and passes the migration's exit code on.
+func autoExportMigrated(p *print.Printer, result int, afterPath string, binaries []string, export bool) int {
+	if export {
+		if err := autoExportDir(p, afterPath, binaries); err != nil {
+			p.Warn(err)
+		}
+	}
+	return result
 }
 
 // validateMigratePaths validates BEFORE_PATH and AFTER_PATH.
@@ -263,7 +330,7 @@ func sameDirPath(a, b string) (bool, error) {
 	return false, nil
 }
 
-func migratePackages(pr *print.Printer, pkgs []goutil.Package, afterPath string, dryRun, notification bool, cpus int, force bool, timeout time.Duration) int {
+func migratePackages(pr *print.Printer, pkgs []goutil.Package, afterPath string, dryRun, notification bool, cpus int, force bool, timeout time.Duration, report *jsonReport) int {
 	// Point GOBIN at AFTER_PATH so the existing 'go install' path reinstalls
 	// into the target directory. Restore the environment afterward. Dry-run
 	// never installs, so the environment is left untouched.
@@ -276,7 +343,7 @@ func migratePackages(pr *print.Printer, pkgs []goutil.Package, afterPath string,
 		defer restore()
 	}
 
-	migrator := func(ctx context.Context, p goutil.Package) updateResult {
+	migrator := func(ctx context.Context, deps dependencies, p goutil.Package) updateResult {
 		version, skip, reason := resolveMigrateVersion(p)
 		if skip {
 			return updateResult{pkg: p, skipped: true, skipReason: reason}
@@ -297,7 +364,13 @@ func migratePackages(pr *print.Printer, pkgs []goutil.Package, afterPath string,
 			return updateResult{updated: true, pkg: p}
 		}
 
-		if err := installByVersionMigrateCtx(ctx, p.ImportPath, version); err != nil {
+		install := func(importPath string) error {
+			return deps.install(ctx, func(ctx context.Context, opts goutil.InstallOptions) error {
+				return installByVersionMigrateCtx(ctx, importPath, version, opts)
+			})
+		}
+		deps.event(eventInstalling, p)
+		if err := install(p.ImportPath); err != nil {
 			newPkg, changed := resolveModulePathChange(p, err)
 			if !changed {
 				return updateResult{pkg: p, err: fmt.Errorf("%s: %w", p.Name, err)}
@@ -305,7 +378,7 @@ func migratePackages(pr *print.Printer, pkgs []goutil.Package, afterPath string,
 			// The module was renamed: retry with the new import path, keeping
 			// the same exact version. No old-binary removal is needed here.
 			newPkg.Version = p.Version
-			if retryErr := installByVersionMigrateCtx(ctx, newPkg.ImportPath, version); retryErr != nil {
+			if retryErr := install(newPkg.ImportPath); retryErr != nil {
 				return updateResult{pkg: newPkg, err: fmt.Errorf("%s: %w", p.Name, retryErr)}
 			}
 			return updateResult{updated: true, pkg: newPkg}
@@ -313,7 +386,7 @@ func migratePackages(pr *print.Printer, pkgs []goutil.Package, afterPath string,
 		return updateResult{updated: true, pkg: p}
 	}
 
-	result, _ := executePackages(pr, pkgs, cpus, timeout, migrator, func(prefix string, v updateResult) {
+	result, results := executePackagesStream(context.Background(), pr, report.events(), report.v2() == nil, nil, pkgs, cpus, timeout, dependencies{}, migrator, func(prefix string, v updateResult) {
 		if v.skipped {
 			pr.Info(fmt.Sprintf("%s skip %s: %s", prefix, v.pkg.Name, v.skipReason))
 			return
@@ -322,7 +395,7 @@ func migratePackages(pr *print.Printer, pkgs []goutil.Package, afterPath string,
 	})
 
 	desktopNotifyIfNeeded(pr, result, notification)
-	return result
+	return report.writeResults(pr, results, result)
 }
 
 // warnMissingMigrateTargets warns about each requested binary name that has no
@@ -375,15 +448,34 @@ func binaryExistsInDir(dir, name string) bool {
 // withGoBin sets the GOBIN environment variable to path and returns a function
 // that restores the previous value (or unsets it when it was not present).
 func withGoBin(path string) (func(), error) {
-	orig, had := os.LookupEnv("GOBIN")
-	if err := os.Setenv("GOBIN", path); err != nil {
-		return nil, err
-	}
-	return func() {
-		if had {
-			_ = os.Setenv("GOBIN", orig)
-		} else {
-			_ = os.Unsetenv("GOBIN")
+	return withEnv(map[string]string{"GOBIN": path})
+}
+
+// withEnv sets every variable in env and returns a function that restores the
+// previous values, unsetting the ones that were not present. When setting a
+// variable fails, the ones already set are restored before returning.
+func withEnv(env map[string]string) (func(), error) {
+	type saved struct {
+		value string
+		had   bool
+	}
+	orig := make(map[string]saved, len(env))
+	restore := func() {
+		for k, v := range orig {
+			if v.had {
+				_ = os.Setenv(k, v.value)
+			} else {
+				_ = os.Unsetenv(k)
+			}
 		}
-	}, nil
+	}
+	for k, v := range env {
+		value, had := os.LookupEnv(k)
+		orig[k] = saved{value: value, had: had}
+		if err := os.Setenv(k, v); err != nil {
+			restore()
+			return nil, err
+		}
+	}
+	return restore, nil
 }
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nao1215/gup/internal/buildmanifest"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/pkgselect"
+	"github.com/nao1215/gup/internal/print"
+)
+
+// prebuiltBinaryPerm is the mode of a binary copied by migrate, matching what
+// 'go install' gives the binaries it writes.
+const prebuiltBinaryPerm = 0o755
+
+// migratePrebuilt installs the binaries listed in an 'export --build' manifest
+// into afterPath by copying them. Each binary is checked against its recorded
+// SHA-256 (also in dry-run), so a truncated or modified file is reported
+// instead of installed. It follows migratePackages' rules: add-only, existing
+// binaries are skipped unless force, and BINARY arguments narrow the set.
+func migratePrebuilt(pr *print.Printer, m buildmanifest.Manifest, beforePath, afterPath string, binaries []string, dryRun, notification, force bool, report *jsonReport) int {
+	entries := make(map[string]buildmanifest.Entry, len(m.Binaries))
+	paths := make([]string, 0, len(m.Binaries))
+	for _, e := range m.Binaries {
+		path := filepath.Join(beforePath, e.File)
+		entries[path] = e
+		paths = append(paths, path)
+	}
+	warnMissingMigrateTargets(pr, pkgselect.MissingTargets(paths, binaries), beforePath)
+	selected := pkgselect.FilterBinaryPaths(paths, binaries)
+	if len(selected) == 0 {
+		pr.Err(fmt.Errorf("no go-install binary to migrate under %s", beforePath))
+		return 1
+	}
+
+	pr.Info(fmt.Sprintf("start migration from %s to %s (prebuilt for %s)", beforePath, afterPath, m.Platform()))
+	exitCode := 0
+	countFmt := countFormat(len(selected))
+	results := make([]updateResult, 0, len(selected))
+	for i, path := range selected {
+		e := entries[path]
+		prefix := fmt.Sprintf(countFmt, i+1, len(selected))
+		v := migratePrebuiltOne(pr, report.events(), e, path, beforePath, afterPath, prefix, dryRun, force)
+		if v.err != nil {
+			exitCode = 1
+		}
+		results = append(results, v)
+	}
+	if stream := report.events(); stream != nil {
+		stream.summary(results, exitCode)
+	}
+
+	desktopNotifyIfNeeded(pr, exitCode, notification)
+	return report.writeResults(pr, results, exitCode)
+}
+
+// migratePrebuiltOne copies one prebuilt binary into afterPath and reports it,
+// on the printer and to stream when it is not nil.
+func migratePrebuiltOne(pr *print.Printer, stream *jsonStream, e buildmanifest.Entry, path, beforePath, afterPath, prefix string, dryRun, force bool) (v updateResult) {
+	v = updateResult{pkg: goutil.Package{Name: e.File, ImportPath: e.ImportPath, Version: &goutil.Version{Current: e.Version}}}
+	now := time.Now
+	if stream != nil {
+		now = stream.now
+		stream.pkgEvent(eventStarted, v.pkg)
+	}
+	begin := now()
+	defer func() {
+		v.elapsed = now().Sub(begin)
+		if stream != nil {
+			stream.result(v)
+		}
+	}()
+	if !force && binaryExistsInDir(afterPath, e.File) {
+		v.skipped, v.skipReason = true, "already exists in AFTER_PATH (use --force to overwrite)"
+		pr.Info(fmt.Sprintf("%s skip %s: %s", prefix, e.File, v.skipReason))
+		return v
+	}
+	var err error
+	if dryRun {
+		err = buildmanifest.Verify(beforePath, e)
+	} else {
+		err = copyPrebuiltBinary(path, filepath.Join(afterPath, e.File), e)
+	}
+	if err != nil {
+		v.err = fmt.Errorf("%s: %w", e.File, err)
+		pr.Err(fmt.Errorf("%s %w", prefix, v.err))
+		return v
+	}
+	v.updated = true
+	pr.Info(fmt.Sprintf("%s %s@%s", prefix, e.ImportPath, e.Version))
+	return v
+}
+
+// copyPrebuiltBinary copies src to dst through a temp file in dst's directory,
+// so dst is never left half-written. The copy is hashed as it is written and
+// only renamed into place when it matches e's recorded SHA-256, so a file
+// changed after a separate check can't slip through.
+func copyPrebuiltBinary(src, dst string, e buildmanifest.Entry) (err error) {
+	in, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := out.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+	h := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(out, h), in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	if err = e.CheckSum(hex.EncodeToString(h.Sum(nil))); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpPath, prebuiltBinaryPerm); err != nil { //nolint:gosec // an installed binary must be executable
+		return err
+	}
+	if err = renameWithReplace(tmpPath, dst); err != nil {
+		return fmt.Errorf("can't install %s: %w", dst, err)
+	}
+	return nil
+}
@@ -302,7 +302,7 @@ func Test_migratePackages_install(t *testing.T) {
 		version    string
 	}
 	var calls []call
-	installByVersionMigrateCtx = func(_ context.Context, importPath, version string) error {
+	installByVersionMigrateCtx = func(_ context.Context, importPath, version string, _ goutil.InstallOptions) error {
 		calls = append(calls, call{importPath, version})
 		return nil
 	}
@@ -312,7 +312,7 @@ func Test_migratePackages_install(t *testing.T) {
 	}
 
 	out := captureMigrateOutput(t, func(p *print.Printer) {
-		if got := migratePackages(p, pkgs, after, false, false, 1, false, 0); got != 0 {
+		if got := migratePackages(p, pkgs, after, false, false, 1, false, 0, nil); got != 0 {
 			t.Fatalf("migratePackages() = %d, want 0", got)
 		}
 	})
@@ -339,7 +339,7 @@ func Test_migratePackages_addOnlySkip(t *testing.T) {
 	t.Cleanup(func() { installByVersionMigrateCtx = original })
 
 	called := false
-	installByVersionMigrateCtx = func(context.Context, string, string) error {
+	installByVersionMigrateCtx = func(context.Context, string, string, goutil.InstallOptions) error {
 		called = true
 		return nil
 	}
@@ -349,7 +349,7 @@ func Test_migratePackages_addOnlySkip(t *testing.T) {
 	}
 
 	out := captureMigrateOutput(t, func(p *print.Printer) {
-		if got := migratePackages(p, pkgs, after, false, false, 1, false, 0); got != 0 {
+		if got := migratePackages(p, pkgs, after, false, false, 1, false, 0, nil); got != 0 {
 			t.Fatalf("migratePackages() = %d, want 0", got)
 		}
 	})
@@ -374,7 +374,7 @@ func Test_migratePackages_force(t *testing.T) {
 	t.Cleanup(func() { installByVersionMigrateCtx = original })
 
 	called := false
-	installByVersionMigrateCtx = func(context.Context, string, string) error {
+	installByVersionMigrateCtx = func(context.Context, string, string, goutil.InstallOptions) error {
 		called = true
 		return nil
 	}
@@ -384,7 +384,7 @@ func Test_migratePackages_force(t *testing.T) {
 	}
 
 	captureMigrateOutput(t, func(p *print.Printer) {
-		if got := migratePackages(p, pkgs, after, false, false, 1, true, 0); got != 0 {
+		if got := migratePackages(p, pkgs, after, false, false, 1, true, 0, nil); got != 0 {
 			t.Fatalf("migratePackages() = %d, want 0", got)
 		}
 	})
@@ -401,7 +401,7 @@ func Test_migratePackages_dryRun(t *testing.T) {
 	t.Cleanup(func() { installByVersionMigrateCtx = original })
 
 	called := false
-	installByVersionMigrateCtx = func(context.Context, string, string) error {
+	installByVersionMigrateCtx = func(context.Context, string, string, goutil.InstallOptions) error {
 		called = true
 		return nil
 	}
@@ -411,7 +411,7 @@ func Test_migratePackages_dryRun(t *testing.T) {
 	}
 
 	captureMigrateOutput(t, func(p *print.Printer) {
-		if got := migratePackages(p, pkgs, after, true, false, 1, false, 0); got != 0 {
+		if got := migratePackages(p, pkgs, after, true, false, 1, false, 0, nil); got != 0 {
 			t.Fatalf("migratePackages() dry-run = %d, want 0", got)
 		}
 	})
@@ -429,7 +429,7 @@ func Test_migratePackages_skipDevelAndUnknown(t *testing.T) {
 	t.Cleanup(func() { installByVersionMigrateCtx = original })
 
 	called := 0
-	installByVersionMigrateCtx = func(context.Context, string, string) error {
+	installByVersionMigrateCtx = func(context.Context, string, string, goutil.InstallOptions) error {
 		called++
 		return nil
 	}
@@ -441,7 +441,7 @@ func Test_migratePackages_skipDevelAndUnknown(t *testing.T) {
 	}
 
 	captureMigrateOutput(t, func(p *print.Printer) {
-		if got := migratePackages(p, pkgs, after, false, false, 2, false, 0); got != 0 {
+		if got := migratePackages(p, pkgs, after, false, false, 2, false, 0, nil); got != 0 {
 			t.Fatalf("migratePackages() = %d, want 0", got)
 		}
 	})
@@ -459,7 +459,7 @@ func Test_migratePackages_modulePathMismatchRetry(t *testing.T) {
 	t.Cleanup(func() { installByVersionMigrateCtx = original })
 
 	var installedPaths []string
-	installByVersionMigrateCtx = func(_ context.Context, importPath, _ string) error {
+	installByVersionMigrateCtx = func(_ context.Context, importPath, _ string, _ goutil.InstallOptions) error {
 		installedPaths = append(installedPaths, importPath)
 		if importPath == "github.com/old/mod/cmd/tool" {
 			return errors.New("go install: module declares its path as: github.com/new/mod\n\tbut was required as: github.com/old/mod")
@@ -477,7 +477,7 @@ func Test_migratePackages_modulePathMismatchRetry(t *testing.T) {
 	}
 
 	captureMigrateOutput(t, func(p *print.Printer) {
-		if got := migratePackages(p, pkgs, after, false, false, 1, false, 0); got != 0 {
+		if got := migratePackages(p, pkgs, after, false, false, 1, false, 0, nil); got != 0 {
 			t.Fatalf("migratePackages() = %d, want 0", got)
 		}
 	})
@@ -497,7 +497,7 @@ func Test_migratePackages_installError(t *testing.T) {
 	original := installByVersionMigrateCtx
 	t.Cleanup(func() { installByVersionMigrateCtx = original })
 
-	installByVersionMigrateCtx = func(context.Context, string, string) error {
+	installByVersionMigrateCtx = func(context.Context, string, string, goutil.InstallOptions) error {
 		return errors.New("install failed")
 	}
 
@@ -506,7 +506,7 @@ func Test_migratePackages_installError(t *testing.T) {
 	}
 
 	captureMigrateOutput(t, func(p *print.Printer) {
-		if got := migratePackages(p, pkgs, after, false, false, 1, false, 0); got != 1 {
+		if got := migratePackages(p, pkgs, after, false, false, 1, false, 0, nil); got != 1 {
 			t.Fatalf("migratePackages() = %d, want 1 on install error", got)
 		}
 	})
@@ -519,7 +519,7 @@ func Test_migratePackages_jobsBoundary(t *testing.T) {
 	original := installByVersionMigrateCtx
 	t.Cleanup(func() { installByVersionMigrateCtx = original })
 
-	installByVersionMigrateCtx = func(context.Context, string, string) error { return nil }
+	installByVersionMigrateCtx = func(context.Context, string, string, goutil.InstallOptions) error { return nil }
 
 	pkgs := []goutil.Package{
 		{Name: "a", ImportPath: "github.com/example/a", Version: &goutil.Version{Current: testVersionOne}},
@@ -528,8 +528,8 @@ func Test_migratePackages_jobsBoundary(t *testing.T) {
 
 	for _, jobs := range []int{-1, 0, 1, 100} {
 		captureMigrateOutput(t, func(p *print.Printer) {
-			if got := migratePackages(p, pkgs, after, false, false, jobs, false, 0); got != 0 {
-				t.Fatalf("migratePackages(jobs=%d) = %d, want 0", jobs, got)
+			if got := migratePackages(p, pkgs, after, false, false, jobs, false, 0, nil); got != 0 {
+				t.Fatalf("migratePackages(jobs=%d, nil) = %d, want 0", jobs, got)
 			}
 		})
 	}
@@ -544,7 +544,7 @@ func Test_runMigrate_filterByBinary(t *testing.T) {
 	t.Cleanup(func() { installByVersionMigrateCtx = original })
 
 	var installed []string
-	installByVersionMigrateCtx = func(_ context.Context, importPath, _ string) error {
+	installByVersionMigrateCtx = func(_ context.Context, importPath, _ string, _ goutil.InstallOptions) error {
 		installed = append(installed, importPath)
 		return nil
 	}
@@ -573,7 +573,7 @@ func Test_runMigrate_missingTargetWarning(t *testing.T) {
 	t.Cleanup(func() { installByVersionMigrateCtx = original })
 
 	var installed []string
-	installByVersionMigrateCtx = func(_ context.Context, importPath, _ string) error {
+	installByVersionMigrateCtx = func(_ context.Context, importPath, _ string, _ goutil.InstallOptions) error {
 		installed = append(installed, importPath)
 		return nil
 	}
@@ -647,7 +647,7 @@ func Test_runMigrate_missingTargetTrimAndDedup(t *testing.T) {
 
 	original := installByVersionMigrateCtx
 	t.Cleanup(func() { installByVersionMigrateCtx = original })
-	installByVersionMigrateCtx = func(context.Context, string, string) error { return nil }
+	installByVersionMigrateCtx = func(context.Context, string, string, goutil.InstallOptions) error { return nil }
 
 	cmd := newMigrateCmd()
 	out := captureMigrateOutput(t, func(p *print.Printer) {
@@ -734,7 +734,7 @@ func TestMigratePackages_modulePathRetryFailure(t *testing.T) {
 	t.Cleanup(func() { installByVersionMigrateCtx = orig })
 
 	calls := 0
-	installByVersionMigrateCtx = func(context.Context, string, string) error {
+	installByVersionMigrateCtx = func(context.Context, string, string, goutil.InstallOptions) error {
 		calls++
 		if calls == 1 {
 			// A module-path-mismatch error triggers the rename+retry path.
@@ -744,7 +744,7 @@ func TestMigratePackages_modulePathRetryFailure(t *testing.T) {
 	}
 
 	pkgs := []goutil.Package{{Name: testNameOld, ImportPath: testImportExampleOld, Version: &goutil.Version{Current: testVersionOne}}}
-	code := migratePackages(discardPrinter(), pkgs, t.TempDir(), false, false, 1, true, 0)
+	code := migratePackages(discardPrinter(), pkgs, t.TempDir(), false, false, 1, true, 0, nil)
 	if code == 0 {
 		t.Fatal("migratePackages() exit = 0, want non-zero after retry failure")
 	}
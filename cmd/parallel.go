@@ -24,33 +24,64 @@ func countFormat(total int) string {
 // over the update wording (updated). Failures are counted first because a
 // failed result may still carry a non-error status.
 //
-// The status cases below cover every non-failed result that check and update
-// produce: check sets statusUpToDate/statusUpdateAvailable (and, for pinned
-// packages, statusPinned/statusPinMismatch), and update sets
+// The status cases in countResults cover every non-failed result that check
+// and update produce: check sets statusUpToDate/statusUpdateAvailable (and, for
+// pinned packages, statusPinned/statusPinMismatch), and update sets
 // statusUpToDate/statusUpdated (and statusPinned when a pin is already
 // satisfied). A satisfied pin counts as up-to-date and an out-of-sync pin counts
 // as an available update, so the summary totals stay consistent with the
 // per-package lines. statusError is reached only with v.err set (counted as
-// failed above), and statusInstalled is list-only, so neither needs a status
-// case here. summarizeResults is not used by other commands.
+// failed above), so it needs no status case. summarizeResults is not used by
+// other commands.
 func summarizeResults(results []updateResult, isCheck bool) string {
-	var updated, upToDate, available, failed int
+	c := countResults(results)
+	if isCheck {
+		summary := fmt.Sprintf("gup: %d update available, %d up-to-date, %d failed", c.UpdateAvailable, c.UpToDate, c.Failed)
+		if c.NeedsNewerGo > 0 {
+			summary += fmt.Sprintf(", %d need a newer Go", c.NeedsNewerGo)
+		}
+		return summary
+	}
+	return fmt.Sprintf("gup: %d updated, %d up-to-date, %d failed", c.Updated, c.UpToDate, c.Failed)
+}
+
+// resultCounts is the tally behind summarizeResults. It is also the summary
+// of the --json-format v2 envelope, hence the JSON tags.
+type resultCounts struct {
+	Total           int `json:"total"`
+	Updated         int `json:"updated"`
+	UpToDate        int `json:"up_to_date"`
+	UpdateAvailable int `json:"update_available"`
+	NeedsNewerGo    int `json:"needs_newer_go"`
+	Installed       int `json:"installed"`
+	Skipped         int `json:"skipped"`
+	Failed          int `json:"failed"`
+}
+
+// countResults tallies results as summarizeResults describes. list, import and
+// migrate results, which check and update never produce, count as installed
+// or skipped.
+func countResults(results []updateResult) resultCounts {
+	c := resultCounts{Total: len(results)}
 	for _, v := range results {
 		switch {
 		case v.err != nil:
-			failed++
+			c.Failed++
 		case v.status == statusUpdateAvailable, v.status == statusPinMismatch:
-			available++
+			c.UpdateAvailable++
+		case v.status == statusNeedsNewerGo:
+			c.NeedsNewerGo++
 		case v.status == statusUpdated:
-			updated++
+			c.Updated++
 		case v.status == statusUpToDate, v.status == statusPinned:
-			upToDate++
+			c.UpToDate++
+		case v.status == statusInstalled, v.status == "" && v.updated:
+			c.Installed++
+		case v.status == "" && v.skipped:
+			c.Skipped++
 		}
 	}
-	if isCheck {
-		return fmt.Sprintf("gup: %d update available, %d up-to-date, %d failed", available, upToDate, failed)
-	}
-	return fmt.Sprintf("gup: %d updated, %d up-to-date, %d failed", updated, upToDate, failed)
+	return c
 }
 
 // resultLineRenderer builds the per-package progress callback shared by update
@@ -84,20 +115,66 @@ func resultLineRenderer(p *print.Printer, quiet bool, showInQuiet func(updateRes
 // slice is in input order, so machine-readable (--json) output is deterministic
 // across runs regardless of worker scheduling (#365). onResult may be nil
 // (used by --json callers that render from the returned results instead).
+// Every finished package, failed or not, is also reported to p.Progress.
+// worker is called with a copy of deps for each package.
 func executePackages(p *print.Printer, pkgs []goutil.Package, cpus int, timeout time.Duration,
-	worker func(context.Context, goutil.Package) updateResult,
+	deps dependencies, worker pkgWorker,
+	onResult func(prefix string, v updateResult)) (int, []updateResult) {
+	return executePackagesStream(context.Background(), p, nil, false, nil, pkgs, cpus, timeout, deps, worker, onResult)
+}
+
+// pkgWorker runs a command on one package. deps is the copy of the
+// dependencies of the command for that package, on which the wrappers around
+// the worker (timedWorker, retryWorker, streamWorker, ...) set what belongs to
+// it.
+type pkgWorker func(ctx context.Context, deps dependencies, pkg goutil.Package) updateResult
+
+// timedWorker wraps worker so every result records how long it took.
+func timedWorker(worker pkgWorker) pkgWorker {
+	return func(ctx context.Context, deps dependencies, pkg goutil.Package) updateResult {
+		begin := time.Now()
+		v := worker(ctx, deps, pkg)
+		v.elapsed = time.Since(begin)
+		return v
+	}
+}
+
+// executePackagesStream is executePackages that runs under ctx and also
+// reports every package to stream, when it is not nil, and ends the stream
+// with the summary. With live,
+// and stdout on a terminal, a live display of the running packages and an
+// overall bar with an ETA, seeded from builds, stays below the per-package
+// lines while they run.
+func executePackagesStream(ctx context.Context, p *print.Printer, stream *jsonStream, live bool, builds *buildTimes, pkgs []goutil.Package, cpus int, timeout time.Duration,
+	deps dependencies, worker pkgWorker,
 	onResult func(prefix string, v updateResult)) (int, []updateResult) {
-	ctx, cancel, signals := newSignalCancelContext()
+	ctx, cancel, signals := newSignalCancelContext(ctx)
 	defer stopSignalCancelContext(cancel, signals)
 
+	display := startLiveProgress(p, live && stream == nil, pkgs, cpus, builds)
+	defer display.stop(p)
+
+	worker = timedWorker(display.worker(worker))
+	if stream != nil {
+		worker = streamWorker(stream, worker)
+	}
+
 	countFmt := countFormat(len(pkgs))
 	exitCode := 0
-	results := parallel.Run(ctx, pkgs, cpus, timeout, worker,
+	run := func(ctx context.Context, pkg goutil.Package) updateResult {
+		return worker(ctx, deps, pkg)
+	}
+	results := parallel.Run(ctx, pkgs, cpus, timeout, run,
 		func(p goutil.Package, err error) updateResult {
 			return updateResult{pkg: p, err: err}
 		},
 		func(done, total int, v updateResult) {
 			prefix := fmt.Sprintf(countFmt, done, total)
+			display.finished(v.elapsed)
+			p.Progress(fmt.Sprintf("%s %s %s", prefix, v.pkg.Name, resultToJSONPackage(v).Status))
+			if stream != nil {
+				stream.result(v)
+			}
 			if v.err != nil {
 				exitCode = 1
 				p.Err(fmt.Errorf("%s %s", prefix, v.err.Error()))
@@ -108,5 +185,8 @@ func executePackages(p *print.Printer, pkgs []goutil.Package, cpus int, timeout
 				onResult(prefix, v)
 			}
 		})
+	if stream != nil {
+		stream.summary(results, exitCode)
+	}
 	return exitCode, results
 }
@@ -65,8 +65,8 @@ func TestExecutePackages_TimeoutIsolation(t *testing.T) {
 
 	p, _ := newTestPrinter()
 	var succeeded int64
-	code, results := executePackages(p, pkgs, 2, 20*time.Millisecond,
-		func(ctx context.Context, p goutil.Package) updateResult {
+	code, results := executePackages(p, pkgs, 2, 20*time.Millisecond, dependencies{},
+		func(ctx context.Context, _ dependencies, p goutil.Package) updateResult {
 			if p.Name == pkgSlow {
 				<-ctx.Done()
 				return updateResult{pkg: p, err: ctx.Err()}
@@ -116,8 +116,8 @@ func TestExecutePackages_StableInputOrder(t *testing.T) {
 	}
 
 	p, _ := newTestPrinter()
-	code, results := executePackages(p, pkgs, total, 0,
-		func(_ context.Context, p goutil.Package) updateResult {
+	code, results := executePackages(p, pkgs, total, 0, dependencies{},
+		func(_ context.Context, _ dependencies, p goutil.Package) updateResult {
 			time.Sleep(sleepByName[p.Name])
 			return updateResult{pkg: p, status: statusUpdated}
 		},
@@ -166,8 +166,8 @@ func TestExecutePackages_StableInputOrderWithFailures(t *testing.T) {
 	}
 
 	p, _ := newTestPrinter()
-	code, results := executePackages(p, pkgs, total, 0,
-		func(_ context.Context, p goutil.Package) updateResult {
+	code, results := executePackages(p, pkgs, total, 0, dependencies{},
+		func(_ context.Context, _ dependencies, p goutil.Package) updateResult {
 			time.Sleep(sleepByName[p.Name])
 			if failByName[p.Name] {
 				return updateResult{pkg: p, err: errors.New("boom"), status: statusError}
@@ -206,8 +206,8 @@ func TestExecutePackages_streamsPrefixesAndReportsErrors(t *testing.T) {
 	pkgs := []goutil.Package{{Name: "a"}, {Name: pkgFail}, {Name: "c"}}
 
 	var prefixes []string
-	code, results := executePackages(p, pkgs, 1, 0,
-		func(_ context.Context, p goutil.Package) updateResult {
+	code, results := executePackages(p, pkgs, 1, 0, dependencies{},
+		func(_ context.Context, _ dependencies, p goutil.Package) updateResult {
 			if p.Name == pkgFail {
 				return updateResult{pkg: p, err: errors.New("boom")}
 			}
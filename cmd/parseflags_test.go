@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
 	"github.com/spf13/cobra"
 )
 
@@ -74,6 +75,7 @@ func TestParseUpdateFlags_values(t *testing.T) {
 		"--master", "m2",
 		"--latest", "l1",
 		testFlagFile, "/tmp/gup.json",
+		"--go", "1.22.5",
 	}
 	if err := cmd.ParseFlags(args); err != nil {
 		t.Fatalf("ParseFlags() error = %v", err)
@@ -97,6 +99,7 @@ func TestParseUpdateFlags_values(t *testing.T) {
 		masterPkgNames: []string{"m2"},
 		latestPkgNames: []string{"l1"},
 		confFile:       "/tmp/gup.json",
+		goToolchain:    "go1.22.5",
 	}
 	if diff := cmp.Diff(want, opts, cmp.AllowUnexported(updateOpts{})); diff != "" {
 		t.Errorf("parseUpdateFlags() mismatch (-want +got):\n%s", diff)
@@ -121,6 +124,36 @@ func TestParseUpdateFlags_clampsJobs(t *testing.T) {
 	}
 }
 
+func TestParseUpdateFlags_goToolchain(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "local", args: []string{"--go", "local"}, want: goutil.LocalToolchain},
+		{name: "language version", args: []string{"--go", "1.22"}, wantErr: true},
+		{name: "with --plan", args: []string{"--go", "1.22.5", "--plan", "plan.json"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cmd := newUpdateCmd()
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags() error = %v", err)
+			}
+			opts, err := parseUpdateFlags(cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseUpdateFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if opts.goToolchain != tt.want {
+				t.Errorf("goToolchain = %q, want %q", opts.goToolchain, tt.want)
+			}
+		})
+	}
+}
+
 // TestParseUpdateFlags_error verifies that a missing/unregistered flag surfaces
 // as an error instead of panicking, so gup() can handle it once.
 func TestParseUpdateFlags_error(t *testing.T) {
@@ -326,8 +359,8 @@ func registerUpToMigrate(stopAt string) *cobra.Command {
 }
 
 // TestRunMigrate_perFlagError drives each getFlag error branch in runMigrate.
-// ensureGoCommandAvailable runs first and succeeds because the go toolchain is
-// on PATH under `go test`; the flag reads then fail one at a time.
+// The flag reads run before anything touches the filesystem or the go
+// toolchain, so they fail one at a time.
 func TestRunMigrate_perFlagError(t *testing.T) {
 	t.Parallel()
 	for _, name := range []string{fnDryRun, fnNotify, fnJobs, fnForce, timeoutFlagName} {
@@ -224,6 +224,13 @@ func runUnpin(p *print.Printer, cmd *cobra.Command, args []string) int {
 // would break every later check/update/export (export already drops such
 // packages; pin must not be the one command that lets them through).
 func resolvePinTarget(installed []goutil.Package, target string) (goutil.Package, error) {
+	return resolveInstalledTarget(installed, target, "pin")
+}
+
+// resolveInstalledTarget implements resolvePinTarget's matching rules for any
+// command that acts on exactly one installed binary; action names the command's
+// verb in the error messages.
+func resolveInstalledTarget(installed []goutil.Package, target, action string) (goutil.Package, error) {
 	for _, p := range installed {
 		if p.ImportPath != "" && p.ImportPath == target {
 			return p, nil
@@ -237,7 +244,7 @@ func resolvePinTarget(installed []goutil.Package, target string) (goutil.Package
 			continue
 		}
 		if match != nil {
-			return goutil.Package{}, fmt.Errorf("'%s' matches multiple installed binaries; pin by full import path", target)
+			return goutil.Package{}, fmt.Errorf("'%s' matches multiple installed binaries; %s by full import path", target, action)
 		}
 		match = &installed[i]
 	}
@@ -245,7 +252,7 @@ func resolvePinTarget(installed []goutil.Package, target string) (goutil.Package
 		return goutil.Package{}, fmt.Errorf("'%s' is not managed by gup: install it with 'go install' first", target)
 	}
 	if strings.TrimSpace(match.ImportPath) == "" {
-		return goutil.Package{}, fmt.Errorf("can't pin '%s': gup can't determine its import path (it was built by an old Go version); reinstall it with 'go install' first", match.Name)
+		return goutil.Package{}, fmt.Errorf("can't %s '%s': gup can't determine its import path (it was built by an old Go version); reinstall it with 'go install' first", action, match.Name)
 	}
 	return *match, nil
 }
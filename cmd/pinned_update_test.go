@@ -16,7 +16,7 @@ func TestUpdatePinned_missingVersionField(t *testing.T) {
 	t.Parallel()
 	installed := ""
 	deps := testDeps()
-	deps.installByVersion = func(_ context.Context, importPath, version string) error {
+	deps.installByVersion = func(_ context.Context, importPath, version string, _ goutil.InstallOptions) error {
 		installed = importPath + "@" + version
 		return nil
 	}
@@ -74,7 +74,7 @@ func TestUpdatePinned_noImportPath(t *testing.T) {
 func TestUpdatePinned_installFailure(t *testing.T) {
 	t.Parallel()
 	deps := testDeps()
-	deps.installByVersion = func(context.Context, string, string) error {
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error {
 		return errors.New("boom")
 	}
 	p := goutil.Package{
@@ -106,8 +106,11 @@ func TestInstallWithSelectedVersion_masterAndMain(t *testing.T) {
 	t.Parallel()
 	var called string
 	deps := testDeps()
-	deps.installByVersion = func(_ context.Context, _, version string) error { called = "byVersion:" + version; return nil }
-	deps.installMainOrMaster = func(context.Context, string) error { called = "mainOrMaster"; return nil }
+	deps.installByVersion = func(_ context.Context, _, version string, _ goutil.InstallOptions) error {
+		called = "byVersion:" + version
+		return nil
+	}
+	deps.installMainOrMaster = func(context.Context, string, goutil.InstallOptions) error { called = "mainOrMaster"; return nil }
 
 	if err := installWithSelectedVersion(deps, context.Background(), testImportExampleTool, goutil.UpdateChannelMaster); err != nil {
 		t.Fatalf("master: err = %v", err)
@@ -165,16 +168,16 @@ func TestUpdatePinned_reinstallsAtPinWhenDifferent(t *testing.T) {
 	var gotImport, gotVersion string
 	called := false
 	deps := testDeps()
-	deps.installByVersion = func(_ context.Context, importPath, version string) error {
+	deps.installByVersion = func(_ context.Context, importPath, version string, _ goutil.InstallOptions) error {
 		called = true
 		gotImport, gotVersion = importPath, version
 		return nil
 	}
-	deps.getLatestVer = func(context.Context, string) (string, error) {
+	deps.getLatestVer = func(context.Context, string, string) (string, error) {
 		t.Fatal("pinned update must not resolve @latest")
 		return "", nil
 	}
-	deps.installLatest = func(context.Context, string) error {
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error {
 		t.Fatal("pinned update must not install @latest")
 		return nil
 	}
@@ -195,7 +198,7 @@ func TestUpdatePinned_reinstallsAtPinWhenDifferent(t *testing.T) {
 func TestUpdatePinned_skipsWhenAlreadyAtPin(t *testing.T) {
 	t.Parallel()
 	deps := testDeps()
-	deps.installByVersion = func(context.Context, string, string) error {
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error {
 		t.Fatal("must not reinstall when already at the pinned version")
 		return nil
 	}
@@ -216,7 +219,7 @@ func TestUpdatePinned_skipsWhenAlreadyAtPin(t *testing.T) {
 func TestUpdatePinned_emptyPinIsError(t *testing.T) {
 	t.Parallel()
 	deps := testDeps()
-	deps.installByVersion = func(context.Context, string, string) error {
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error {
 		t.Fatal("must not install when the pin target is missing")
 		return nil
 	}
@@ -269,7 +272,7 @@ func TestUpdatePinned_reinstallsWhenGoOutdated(t *testing.T) {
 	var gotVersion string
 	called := false
 	deps := testDeps()
-	deps.installByVersion = func(_ context.Context, _ string, version string) error {
+	deps.installByVersion = func(_ context.Context, _ string, version string, _ goutil.InstallOptions) error {
 		called = true
 		gotVersion = version
 		return nil
@@ -291,7 +294,7 @@ func TestUpdatePinned_reinstallsWhenGoOutdated(t *testing.T) {
 func TestUpdatePinned_ignoreGoUpdateKeepsPin(t *testing.T) {
 	t.Parallel()
 	deps := testDeps()
-	deps.installByVersion = func(context.Context, string, string) error {
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error {
 		t.Fatal("must not reinstall a satisfied pin when Go updates are ignored")
 		return nil
 	}
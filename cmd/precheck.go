@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/nao1215/gup/internal/goutil"
+	"github.com/spf13/cobra"
 )
 
 // defaultGoOpTimeout bounds a single package's go operations (version lookup
@@ -33,8 +34,19 @@ func clampJobs(cpus int) int {
 	return cpus
 }
 
-func newSignalCancelContext() (context.Context, context.CancelFunc, chan os.Signal) {
-	ctx, cancel := context.WithCancel(context.Background())
+// commandContext is the context cmd runs in: the request context when 'gup
+// serve' runs the command, context.Background() otherwise.
+func commandContext(cmd *cobra.Command) context.Context {
+	if ctx := cmd.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// newSignalCancelContext returns a context derived from parent that is also
+// canceled by a signal.
+func newSignalCancelContext(parent context.Context) (context.Context, context.CancelFunc, chan os.Signal) {
+	ctx, cancel := context.WithCancel(parent)
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP,
 		syscall.SIGQUIT, syscall.SIGABRT)
@@ -42,6 +54,22 @@ func newSignalCancelContext() (context.Context, context.CancelFunc, chan os.Sign
 	return ctx, cancel, signals
 }
 
+// newBoundedSignalContext returns a signal-canceled context derived from
+// parent that is also bounded by timeout (0 disables the bound), for the
+// commands that run a single sequence of go/proxy operations rather than a
+// per-package worker pool. The returned func releases both.
+func newBoundedSignalContext(parent context.Context, timeout time.Duration) (context.Context, func()) {
+	ctx, cancel, signals := newSignalCancelContext(parent)
+	if timeout <= 0 {
+		return ctx, func() { stopSignalCancelContext(cancel, signals) }
+	}
+	boundedCtx, cancelTimeout := context.WithTimeout(ctx, timeout)
+	return boundedCtx, func() {
+		cancelTimeout()
+		stopSignalCancelContext(cancel, signals)
+	}
+}
+
 func stopSignalCancelContext(cancel context.CancelFunc, signals chan os.Signal) {
 	signal.Stop(signals)
 	close(signals)
@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+)
+
+// phaseResolving is the phase the live progress display shows for a package
+// until 'go install' starts. The downloading and building phases come from
+// 'go install' itself (goutil.InstallOptions.Phase).
+const phaseResolving = "resolving"
+
+const (
+	// liveRedrawInterval is how often the live display updates elapsed times.
+	liveRedrawInterval = 200 * time.Millisecond
+	// liveBarWidth is the width of the overall progress bar.
+	liveBarWidth = 30
+	// liveNameWidth is where a long package name is cut in the worker lines.
+	liveNameWidth = 28
+)
+
+// stdoutIsTerminal reports whether os.Stdout is connected to a terminal (TTY)
+// that can move the cursor. It is a package-level variable so that it can be
+// overridden in unit tests.
+var stdoutIsTerminal = func() bool { //nolint:gochecknoglobals
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return (info.Mode() & os.ModeCharDevice) != 0
+}
+
+// liveTask is one package a worker is processing.
+type liveTask struct {
+	name  string
+	phase string
+	begin time.Time
+}
+
+// liveProgress is the terminal display of executePackagesStream: a line per
+// active worker with its package, phase and elapsed time, and an overall bar
+// with an ETA from the build history and the durations of the packages done
+// so far. It is drawn below the normal per-package lines through
+// print.Printer.SetLive.
+type liveProgress struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	total   int
+	cpus    int
+	done    int
+	spent   time.Duration            // sum of the durations of the done packages
+	left    map[string]int           // packages not ended yet, by name
+	builds  map[string]time.Duration // last build time of the packages that have one
+	active  []*liveTask              // in start order
+	drawn   int                      // lines the last Draw wrote
+	width   func() int               // terminal columns, 0 or nil when unknown
+	stopped chan struct{}
+}
+
+// newLiveProgress returns the display for the packages named names run by
+// cpus workers. builds holds the last build time of the packages that have
+// one.
+func newLiveProgress(names []string, cpus int, now func() time.Time, builds map[string]time.Duration) *liveProgress {
+	left := make(map[string]int, len(names))
+	for _, name := range names {
+		left[name]++
+	}
+	return &liveProgress{now: now, total: len(names), cpus: max(cpus, 1), left: left, builds: builds, stopped: make(chan struct{})}
+}
+
+// startLiveProgress shows the live display of pkgs on p when live is set and
+// stdout is a terminal, with an ETA seeded from builds. Otherwise it returns
+// nil, and the output stays one line per package.
+func startLiveProgress(p *print.Printer, live bool, pkgs []goutil.Package, cpus int, builds *buildTimes) *liveProgress {
+	if !live || len(pkgs) == 0 || !stdoutIsTerminal() {
+		return nil
+	}
+	names := make([]string, len(pkgs))
+	for i, pkg := range pkgs {
+		names[i] = pkg.Name
+	}
+	lp := newLiveProgress(names, cpus, time.Now, builds.durations(names))
+	lp.width = stdoutWidth
+	p.SetLive(lp)
+	go func() {
+		ticker := time.NewTicker(liveRedrawInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.RedrawLive()
+			case <-lp.stopped:
+				return
+			}
+		}
+	}()
+	return lp
+}
+
+// stop removes the display from p. It does nothing on a nil display.
+func (lp *liveProgress) stop(p *print.Printer) {
+	if lp == nil {
+		return
+	}
+	close(lp.stopped)
+	p.SetLive(nil)
+}
+
+// worker wraps worker so the display shows each package while it runs, in the
+// phase its steps and 'go install' report. It returns worker as it is on a
+// nil display.
+func (lp *liveProgress) worker(worker pkgWorker) pkgWorker {
+	if lp == nil {
+		return worker
+	}
+	return func(ctx context.Context, deps dependencies, pkg goutil.Package) updateResult {
+		task := lp.begin(pkg.Name)
+		defer lp.end(task)
+		deps.installPhase = func(phase string) { lp.setPhase(task, phase) }
+		deps = deps.observe(func(event string, _ goutil.Package) {
+			// 'go install' starting means building until it reports
+			// downloads.
+			if event == eventInstalling {
+				lp.setPhase(task, goutil.PhaseBuilding)
+			}
+		})
+		return worker(ctx, deps, pkg)
+	}
+}
+
+// begin adds a running package.
+func (lp *liveProgress) begin(name string) *liveTask {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	task := &liveTask{name: name, phase: phaseResolving, begin: lp.now()}
+	lp.active = append(lp.active, task)
+	return task
+}
+
+// setPhase moves task to phase.
+func (lp *liveProgress) setPhase(task *liveTask, phase string) {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	task.phase = phase
+}
+
+// end removes a package that finished.
+func (lp *liveProgress) end(task *liveTask) {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	if lp.left[task.name]--; lp.left[task.name] <= 0 {
+		delete(lp.left, task.name)
+	}
+	for i, v := range lp.active {
+		if v == task {
+			lp.active = append(lp.active[:i], lp.active[i+1:]...)
+			return
+		}
+	}
+}
+
+// finished counts a package done after elapsed, for the bar and the ETA.
+func (lp *liveProgress) finished(elapsed time.Duration) {
+	if lp == nil {
+		return
+	}
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	lp.done++
+	lp.spent += elapsed
+}
+
+// eta estimates the time left: every package left takes its last build time,
+// or else the mean duration of the packages done in this run, less the time
+// it has been running, and the workers share the sum. Until a package is done,
+// a package without a build time takes the mean build time of the others. It
+// reports false while nothing is known to estimate from.
+func (lp *liveProgress) eta() (time.Duration, bool) {
+	if len(lp.left) == 0 {
+		return 0, lp.done > 0
+	}
+	var mean time.Duration
+	switch {
+	case lp.done > 0:
+		mean = lp.spent / time.Duration(lp.done)
+	case len(lp.builds) > 0:
+		for _, d := range lp.builds {
+			mean += d
+		}
+		mean /= time.Duration(len(lp.builds))
+	default:
+		return 0, false
+	}
+
+	running := make(map[string]time.Duration, len(lp.active))
+	now := lp.now()
+	for _, v := range lp.active {
+		running[v.name] = now.Sub(v.begin)
+	}
+	var sum, longest time.Duration
+	for name, n := range lp.left {
+		d, ok := lp.builds[name]
+		if !ok {
+			d = mean
+		}
+		d = max(d-running[name], 0)
+		sum += d * time.Duration(n)
+		longest = max(longest, d)
+	}
+	// One package can't be shared: the run takes at least its longest one.
+	return max(sum/time.Duration(lp.cpus), longest), true
+}
+
+// Draw writes a line per active worker and the overall bar. Lines are cut
+// short of the terminal width: a wrapped line would take two rows and Erase
+// would leave part of the display behind.
+func (lp *liveProgress) Draw(w io.Writer) {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	// Writing the last column wraps on some terminals, so it is left empty.
+	cols := 0
+	if lp.width != nil {
+		cols = lp.width() - 1
+	}
+	var b strings.Builder
+	now := lp.now()
+	for _, v := range lp.active {
+		name := truncateWidth(v.name, liveNameWidth)
+		name += strings.Repeat(" ", liveNameWidth-displayWidth(name))
+		line := fmt.Sprintf("  %s  %-11s  %s", name, v.phase, formatElapsed(now.Sub(v.begin)))
+		b.WriteString(fitLine(line, cols))
+	}
+	filled := liveBarWidth * lp.done / max(lp.total, 1)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", liveBarWidth-filled)
+	eta := "ETA --"
+	if d, ok := lp.eta(); ok {
+		eta = "ETA " + formatElapsed(d)
+	}
+	b.WriteString(fitLine(fmt.Sprintf("[%s] "+countFormat(lp.total)+" %s", bar, lp.done, lp.total, eta), cols))
+	_, _ = io.WriteString(w, b.String())
+	lp.drawn = len(lp.active) + 1
+}
+
+// Erase moves the cursor back over the lines of the last Draw and clears them.
+func (lp *liveProgress) Erase(w io.Writer) {
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	if lp.drawn == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "\x1b[%dA\r\x1b[J", lp.drawn)
+	lp.drawn = 0
+}
+
+// fitLine cuts line to cols columns, when cols is positive, and ends it with
+// a newline.
+func fitLine(line string, cols int) string {
+	if cols > 0 {
+		line = truncateWidth(line, cols)
+	}
+	return line + "\n"
+}
+
+// truncateWidth cuts s to at most n terminal columns, marking the cut with
+// "…". It cuts between runes, so a multi-byte character is never split.
+func truncateWidth(s string, n int) string {
+	if displayWidth(s) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		if width+runeWidth(r) > n-1 {
+			break
+		}
+		width += runeWidth(r)
+		b.WriteRune(r)
+	}
+	return b.String() + "…"
+}
+
+// displayWidth returns the terminal columns s takes.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// runeWidth returns the terminal columns r takes: two for the East Asian wide
+// and fullwidth characters and emoji, one for the rest.
+func runeWidth(r rune) int {
+	switch {
+	case r >= 0x1100 && r <= 0x115F, // Hangul Jamo
+		r >= 0x2E80 && r <= 0xA4CF && r != 0x303F, // CJK, Kana, Yi
+		r >= 0xAC00 && r <= 0xD7A3,                // Hangul syllables
+		r >= 0xF900 && r <= 0xFAFF,                // CJK compatibility ideographs
+		r >= 0xFE30 && r <= 0xFE4F,                // CJK compatibility forms
+		r >= 0xFF00 && r <= 0xFF60,                // fullwidth forms
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F, // pictographs and emoticons
+		r >= 0x1F900 && r <= 0x1F9FF,
+		r >= 0x20000 && r <= 0x3FFFD: // CJK extensions
+		return 2
+	default:
+		return 1
+	}
+}
+
+// formatElapsed renders d to the second, e.g. "4s" or "1m20s".
+func formatElapsed(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// testClock returns a clock that reads t0 until the returned setter moves it.
+func testClock(t0 time.Time) (func() time.Time, func(time.Duration)) {
+	now := t0
+	return func() time.Time { return now }, func(d time.Duration) { now = t0.Add(d) }
+}
+
+func Test_liveProgress_Draw(t *testing.T) {
+	t.Parallel()
+
+	now, advance := testClock(time.Unix(0, 0))
+	names := testLiveNames(10)
+	names[0], names[1] = "air", "a-binary-with-a-rather-long-name"
+	lp := newLiveProgress(names, 2, now, nil)
+	air := lp.begin(names[0])
+	lp.begin(names[1])
+	for i, d := range []time.Duration{4 * time.Second, 6 * time.Second} {
+		lp.end(lp.begin(names[2+i]))
+		lp.finished(d)
+	}
+	advance(3 * time.Second)
+	lp.setPhase(air, goutil.PhaseDownloading)
+
+	var out bytes.Buffer
+	lp.Draw(&out)
+	want := "  air                           downloading  3s\n" +
+		"  a-binary-with-a-rather-long…  resolving    3s\n" +
+		"[======                        ] [ 2/10] ETA 17s\n"
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("Draw() mismatch (-want +got):\n%s", diff)
+	}
+
+	out.Reset()
+	lp.Erase(&out)
+	lp.Erase(&out)
+	if diff := cmp.Diff("\x1b[3A\r\x1b[J", out.String()); diff != "" {
+		t.Errorf("Erase() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_liveProgress_Draw_fitsTheTerminal(t *testing.T) {
+	t.Parallel()
+
+	now, _ := testClock(time.Unix(0, 0))
+	name := strings.Repeat("ツール", 10)
+	lp := newLiveProgress([]string{name}, 1, now, nil)
+	lp.width = func() int { return 24 }
+	lp.begin(name)
+
+	var out bytes.Buffer
+	lp.Draw(&out)
+	// 23 columns each: the kana take two.
+	want := "  ツールツールツールツ…\n" +
+		"[                     …\n"
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("Draw() mismatch (-want +got):\n%s", diff)
+	}
+	if !utf8.ValidString(out.String()) {
+		t.Errorf("Draw() split a character: %q", out.String())
+	}
+}
+
+// testLiveNames returns the names p1 to pN.
+func testLiveNames(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = "p" + strconv.Itoa(i+1)
+	}
+	return names
+}
+
+func Test_liveProgress_eta(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		total  int
+		cpus   int
+		builds map[string]time.Duration
+		done   []time.Duration // of p1, p2, ...
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "nothing known yet", total: 3, cpus: 1},
+		{name: "one worker", total: 3, cpus: 1, done: []time.Duration{2 * time.Second}, want: 4 * time.Second, wantOK: true},
+		{name: "workers share the rest", total: 9, cpus: 4, done: []time.Duration{time.Second, 3 * time.Second}, want: 3500 * time.Millisecond, wantOK: true},
+		{name: "all done", total: 1, cpus: 4, done: []time.Duration{time.Second}, wantOK: true},
+		{
+			name: "build history before anything is done", total: 3, cpus: 1,
+			builds: map[string]time.Duration{"p1": 10 * time.Second, "p2": 20 * time.Second},
+			want:   45 * time.Second, wantOK: true,
+		},
+		{
+			name: "the longest build left bounds the rest", total: 3, cpus: 2,
+			builds: map[string]time.Duration{"p3": 30 * time.Second},
+			done:   []time.Duration{2 * time.Second},
+			want:   30 * time.Second, wantOK: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			now, _ := testClock(time.Unix(0, 0))
+			names := testLiveNames(tt.total)
+			lp := newLiveProgress(names, tt.cpus, now, tt.builds)
+			for i, d := range tt.done {
+				lp.end(lp.begin(names[i]))
+				lp.finished(d)
+			}
+			got, ok := lp.eta()
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("eta() = %v, %v; want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func Test_liveProgress_worker(t *testing.T) {
+	t.Parallel()
+
+	lp := newLiveProgress([]string{testBinTool}, 1, time.Now, nil)
+	var phases []string
+	phase := func() string {
+		lp.mu.Lock()
+		defer lp.mu.Unlock()
+		return lp.active[0].phase
+	}
+	worker := lp.worker(func(_ context.Context, deps dependencies, pkg goutil.Package) updateResult {
+		phases = append(phases, phase())
+		deps.event(eventInstalling, pkg)
+		phases = append(phases, phase())
+		return updateResult{pkg: pkg}
+	})
+	worker(context.Background(), dependencies{}, goutil.Package{Name: testBinTool})
+
+	if diff := cmp.Diff([]string{phaseResolving, goutil.PhaseBuilding}, phases); diff != "" {
+		t.Errorf("phases mismatch (-want +got):\n%s", diff)
+	}
+	if len(lp.active) != 0 {
+		t.Errorf("%d packages still active after the worker returned", len(lp.active))
+	}
+}
+
+//nolint:paralleltest // mutates package-level stdoutIsTerminal
+func Test_executePackagesStream_liveProgress(t *testing.T) {
+	orig := stdoutIsTerminal
+	stdoutIsTerminal = func() bool { return true }
+	t.Cleanup(func() { stdoutIsTerminal = orig })
+
+	pkgs := []goutil.Package{{Name: testBinTool, ImportPath: testImportExampleTool}}
+	worker := func(_ context.Context, _ dependencies, pkg goutil.Package) updateResult {
+		return updateResult{pkg: pkg}
+	}
+	for _, live := range []bool{true, false} {
+		p, out := newTestPrinter()
+		executePackagesStream(context.Background(), p, nil, live, nil, pkgs, 1, 0, dependencies{}, worker, func(prefix string, v updateResult) {
+			p.Info(prefix + " " + v.pkg.Name)
+		})
+		drew := strings.Contains(out.String(), "\x1b[J")
+		if drew != live {
+			t.Errorf("live = %v: drew the display = %v; output:\n%q", live, drew, out.String())
+		}
+		if !strings.Contains(out.String(), "[1/1] "+testBinTool+"\n") {
+			t.Errorf("live = %v: the result line is missing:\n%q", live, out.String())
+		}
+		if live && !strings.HasSuffix(out.String(), "\x1b[J") {
+			t.Errorf("the display was not erased at the end:\n%q", out.String())
+		}
+	}
+}
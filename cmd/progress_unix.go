@@ -0,0 +1,19 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// stdoutWidth returns the column count of the terminal on stdout, or 0 when
+// it can't be read.
+func stdoutWidth() int {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ) //nolint:gosec // a file descriptor fits in an int
+	if err != nil {
+		return 0
+	}
+	return int(ws.Col)
+}
@@ -41,8 +41,8 @@ func quietMixedPkgs() []goutil.Package {
 
 func Test_updateWithChannels_quiet(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
-	deps.installLatest = func(context.Context, string) error { return nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error { return nil }
 
 	pkgs := quietMixedPkgs()
 	channelMap := map[string]goutil.UpdateChannel{
@@ -52,7 +52,7 @@ func Test_updateWithChannels_quiet(t *testing.T) {
 
 	var got int
 	out := captureCheckOutput(t, func(p *print.Printer) int {
-		got, _, _ = updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, true)
+		got, _, _ = updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, true, nil)
 		return got
 	})
 	if got != 0 {
@@ -75,8 +75,8 @@ func Test_updateWithChannels_quiet(t *testing.T) {
 
 func Test_updateWithChannels_quiet_failed(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
-	deps.installLatest = func(context.Context, string) error { return errors.New("install failed") }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error { return errors.New("install failed") }
 
 	pkgs := []goutil.Package{
 		{
@@ -91,7 +91,7 @@ func Test_updateWithChannels_quiet_failed(t *testing.T) {
 
 	var got int
 	out := captureCheckOutput(t, func(p *print.Printer) int {
-		got, _, _ = updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, true)
+		got, _, _ = updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, true, nil)
 		return got
 	})
 	if got != 1 {
@@ -109,7 +109,7 @@ func Test_updateWithChannels_quiet_failed(t *testing.T) {
 
 func Test_doCheck_quiet(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
 
 	pkgs := quietMixedPkgs()
 
@@ -144,8 +144,8 @@ func Test_doCheck_quiet(t *testing.T) {
 // --quiet: STDOUT stays a valid JSON array with no summary line mixed in.
 func Test_updateWithChannels_jsonQuiet(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
-	deps.installLatest = func(context.Context, string) error { return nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error { return nil }
 
 	pkgs := quietMixedPkgs()
 	channelMap := map[string]goutil.UpdateChannel{
@@ -154,7 +154,7 @@ func Test_updateWithChannels_jsonQuiet(t *testing.T) {
 	}
 
 	recs := readJSON(t, func(p *print.Printer) int {
-		got, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, true, true)
+		got, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, true, true, nil)
 		return got
 	})
 	if len(recs) != 2 {
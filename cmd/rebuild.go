@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/pkgselect"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+func newRebuildCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rebuild [BINARY...]",
+		Short: "Reinstall binaries at the same version with the current Go toolchain",
+		Long: `Reinstall binaries at the same version with the current Go toolchain.
+
+rebuild reads the exact 'import path@version' recorded in each binary's build
+info and reinstalls it at that version, so the binary picks up the installed
+Go toolchain's standard library (e.g. after a Go security release) without
+any tool being upgraded.
+
+Only binaries built with a Go older than --min-go are rebuilt; --min-go
+defaults to the installed Go toolchain. Development builds and binaries
+whose version is unknown are skipped, like 'gup migrate' does.
+
+If BINARY arguments are given, only those binaries are rebuilt.`,
+		Example: `  gup rebuild
+  gup rebuild --min-go 1.23.4
+  gup rebuild --dry-run gopls`,
+		ValidArgsFunction: completePathBinaries,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(runRebuild(defaultDependencies(), printerFor(cmd), cmd, args))
+		},
+	}
+
+	cmd.Flags().String("min-go", "", "rebuild binaries built with a Go older than this version (default: the installed Go)")
+	cmd.Flags().BoolP("dry-run", "n", false, "show which binaries would be rebuilt with no changes")
+	cmd.Flags().BoolP("notify", "N", false, "enable desktop notifications")
+	cmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "specify the number of CPU cores to use")
+	mustRegisterFlagCompletion(cmd, "jobs", completeNCPUs)
+	addTimeoutFlag(cmd)
+
+	return cmd
+}
+
+func runRebuild(deps dependencies, p *print.Printer, cmd *cobra.Command, args []string) int {
+	if err := ensureGoCommandAvailable(); err != nil {
+		p.Err(err)
+		return 1
+	}
+	minGoFlag, err := getFlagString(cmd, "min-go")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	dryRun, err := getFlagBool(cmd, "dry-run")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	notify, err := getFlagBool(cmd, "notify")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	cpus, err := getFlagInt(cmd, "jobs")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	cpus = clampJobs(cpus)
+	timeout, err := getTimeoutFlag(cmd)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	minGo := ""
+	if minGoFlag != "" {
+		if minGo, err = goutil.ParseGoVersion(minGoFlag); err != nil {
+			p.Err(fmt.Errorf("--min-go: %w", err))
+			return 1
+		}
+	}
+
+	pkgs, missingTargets, goVersionAvailable, err := pkgselect.PackageInfoByTargets(p, args)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	pkgselect.WarnMissing(missingTargets, func(msg string) { p.Warn(msg) })
+	if len(pkgs) == 0 {
+		return handleEmptyEnvironment(p, "", false, len(args) != 0,
+			"unable to rebuild package: no package information or no package under $GOBIN")
+	}
+	// Rebuilding only helps when the toolchain that will build is known and new
+	// enough; otherwise every binary would be reinstalled for nothing.
+	if !goVersionAvailable {
+		p.Err("can't rebuild: the installed Go version is unknown")
+		return 1
+	}
+	toolchain := pkgs[0].GoVersion.Latest
+	if minGo == "" {
+		minGo = toolchain
+	} else if !goutil.GoVersionUpToDate(toolchain, minGo) {
+		p.Err(fmt.Sprintf("the installed Go is %s, older than --min-go %s: rebuilding can't reach it; install a newer Go first", toolchain, minGo))
+		return 1
+	}
+
+	if dryRun {
+		p.Info(fmt.Sprintf("binaries built with a Go older than %s (dry run, nothing is installed)", minGo))
+	} else {
+		p.Info(fmt.Sprintf("rebuild binaries built with a Go older than %s using %s", minGo, toolchain))
+	}
+	result, _ := rebuildPackages(deps, p, pkgs, minGo, dryRun, cpus, timeout)
+	desktopNotifyIfNeeded(p, result, notify)
+	return result
+}
+
+// rebuildPackages reinstalls every package built with a Go older than minGo
+// at the exact version it records. Packages that are new enough, or whose
+// version can't be reinstalled, are skipped with the reason.
+func rebuildPackages(deps dependencies, pr *print.Printer, pkgs []goutil.Package, minGo string, dryRun bool, cpus int, timeout time.Duration) (int, []updateResult) {
+	rebuilder := func(ctx context.Context, deps dependencies, p goutil.Package) updateResult {
+		version, skip, reason := resolveMigrateVersion(p)
+		if skip {
+			return updateResult{pkg: p, skipped: true, skipReason: reason}
+		}
+		builtWith := ""
+		if p.GoVersion != nil {
+			builtWith = strings.TrimSpace(p.GoVersion.Current)
+		}
+		if builtWith == "" || builtWith == unknownVersion {
+			return updateResult{pkg: p, skipped: true, skipReason: "the Go version it was built with is unknown"}
+		}
+		if goutil.GoVersionUpToDate(builtWith, minGo) {
+			return updateResult{pkg: p, skipped: true, skipReason: "already built with " + builtWith}
+		}
+
+		if !dryRun {
+			if err := deps.installVersion(ctx, p.ImportPath, version); err != nil {
+				return updateResult{pkg: p, err: fmt.Errorf("%s: %w", p.Name, err)}
+			}
+		}
+		return updateResult{updated: true, pkg: p}
+	}
+
+	return executePackages(pr, pkgs, cpus, timeout, deps, rebuilder, func(prefix string, v updateResult) {
+		if v.skipped {
+			pr.Info(fmt.Sprintf("%s skip %s: %s", prefix, v.pkg.Name, v.skipReason))
+			return
+		}
+		currentGo, latestGo := colorVersionPair(v.pkg.GoVersion.Current, v.pkg.GoVersion.Latest, "go")
+		pr.Info(fmt.Sprintf("%s %s@%s (%s to %s)", prefix, v.pkg.ImportPath, v.pkg.Version.Current, currentGo, latestGo))
+	})
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+func Test_rebuildPackages(t *testing.T) {
+	t.Parallel()
+
+	pkg := func(name, version, builtWith string) goutil.Package {
+		return goutil.Package{
+			Name:       name,
+			ImportPath: "example.com/" + name,
+			ModulePath: "example.com/" + name,
+			Version:    &goutil.Version{Current: version},
+			GoVersion:  &goutil.Version{Current: builtWith, Latest: "go1.23.4"},
+		}
+	}
+	pkgs := []goutil.Package{
+		pkg("old", "v1.2.0", "go1.23.3"),
+		pkg("fresh", "v1.0.0", "go1.23.4"),
+		pkg("devel", "(devel)", "go1.22.0"),
+		pkg("nogo", "v1.0.0", "unknown"),
+	}
+
+	var mu sync.Mutex
+	calls := []string{}
+	deps := testDeps()
+	deps.installByVersion = func(_ context.Context, importPath, version string, _ goutil.InstallOptions) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, importPath+"@"+version)
+		return nil
+	}
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error {
+		t.Error("rebuild must reinstall the recorded version, not @latest")
+		return nil
+	}
+
+	p, buf := newTestPrinter()
+	code, results := rebuildPackages(deps, p, pkgs, "go1.23.4", false, 2, time.Minute)
+	if code != 0 {
+		t.Fatalf("rebuildPackages() = %d, want 0; output: %s", code, buf.String())
+	}
+	sort.Strings(calls)
+	if diff := cmp.Diff([]string{"example.com/old@v1.2.0"}, calls); diff != "" {
+		t.Errorf("installs mismatch (-want +got):\n%s", diff)
+	}
+	for i, want := range []bool{false, true, true, true} {
+		if results[i].skipped != want {
+			t.Errorf("%s skipped = %v, want %v", results[i].pkg.Name, results[i].skipped, want)
+		}
+	}
+	for _, want := range []string{
+		"example.com/old@v1.2.0 (go1.23.3 to go1.23.4)",
+		"skip fresh: already built with go1.23.4",
+		"skip devel: version is a development build",
+		"skip nogo: the Go version it was built with is unknown",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func Test_rebuildPackages_dryRun(t *testing.T) {
+	t.Parallel()
+
+	deps := testDeps()
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error {
+		t.Error("a dry run must not install")
+		return nil
+	}
+	pkgs := []goutil.Package{{
+		Name: "old", ImportPath: "example.com/old",
+		Version:   &goutil.Version{Current: "v1.2.0"},
+		GoVersion: &goutil.Version{Current: "go1.22.0", Latest: "go1.23.4"},
+	}}
+	p, _ := newTestPrinter()
+	if code, results := rebuildPackages(deps, p, pkgs, "go1.23.0", true, 1, time.Minute); code != 0 || !results[0].updated {
+		t.Fatalf("rebuildPackages() = %d, %+v; want the package reported as rebuilt", code, results)
+	}
+}
+
+func Test_runRebuild_invalidMinGo(t *testing.T) {
+	t.Parallel()
+
+	cmd := newRebuildCmd()
+	if err := cmd.Flags().Set("min-go", "latest"); err != nil {
+		t.Fatal(err)
+	}
+	p, buf := newTestPrinter()
+	if got := runRebuild(testDeps(), p, cmd, nil); got != 1 {
+		t.Fatalf("runRebuild() = %d, want 1", got)
+	}
+	if !strings.Contains(buf.String(), "--min-go") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
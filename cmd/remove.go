@@ -21,7 +21,10 @@ func newRemoveCmd() *cobra.Command {
 		Short:   "Remove the binary under $GOPATH/bin or $GOBIN",
 		Long: `Remove command in $GOPATH/bin or $GOBIN.
 If you want to specify multiple binaries at once, separate them with space.
-[e.g.] gup remove a_cmd b_cmd c_cmd`,
+[e.g.] gup remove a_cmd b_cmd c_cmd
+
+With the auto_export setting on, the removed binaries' entries are also
+deleted from gup.json.`,
 		Example: `  gup remove gopls
   gup remove --force air`,
 		Args: requireMinArgs(1,
@@ -45,13 +48,25 @@ func remove(p *print.Printer, cmd *cobra.Command, args []string) int {
 		return 1
 	}
 
+	autoExport, err := autoExportEnabled()
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
 	gobin, err := goutil.GoBin()
 	if err != nil {
 		p.Err(err)
 		return 1
 	}
 
-	return removeLoop(p, gobin, force, args)
+	result, removed := removeBinaries(p, gobin, force, args)
+	if autoExport && len(removed) > 0 {
+		if err := autoExportRemoved("", removed); err != nil {
+			p.Warn(err)
+		}
+	}
+	return result
 }
 
 const goosWindows = "windows"
@@ -73,7 +88,15 @@ var stdinIsTerminal = func() bool { //nolint:gochecknoglobals
 }
 
 func removeLoop(p *print.Printer, gobin string, force bool, target []string) int {
+	result, _ := removeBinaries(p, gobin, force, target)
+	return result
+}
+
+// removeBinaries removes each target binary from gobin, asking first unless
+// force, and also returns the names of the binaries it removed.
+func removeBinaries(p *print.Printer, gobin string, force bool, target []string) (int, []string) {
 	result := 0
+	removed := []string{}
 	for _, v := range target {
 		orig := v
 		v = strings.TrimSpace(v)
@@ -123,8 +146,9 @@ func removeLoop(p *print.Printer, gobin string, force bool, target []string) int
 			continue
 		}
 		p.Info("removed " + target)
+		removed = append(removed, v)
 	}
-	return result
+	return result, removed
 }
 
 func normalizeExecSuffix(goos, goExe string) string {
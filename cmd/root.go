@@ -67,17 +67,28 @@ GitHub Sponsors: https://github.com/sponsors/nao1215
 	cmd.SetVersionTemplate("{{.Version}}\n")
 	cmd.Flags().BoolP("version", "V", false, "version for gup")
 
+	cmd.AddCommand(newApplyCmd())
+	cmd.AddCommand(newBundleCmd())
+	cmd.AddCommand(newChangelogCmd())
 	cmd.AddCommand(newCheckCmd())
 	cmd.AddCommand(newCompletionCmd())
+	cmd.AddCommand(newDiffCmd())
+	cmd.AddCommand(newDiffDepsCmd())
 	cmd.AddCommand(newExportCmd())
 	cmd.AddCommand(newImportCmd())
+	cmd.AddCommand(newInstallCmd())
 	cmd.AddCommand(newListCmd())
 	cmd.AddCommand(newMigrateCmd())
 	cmd.AddCommand(newPinCmd())
+	cmd.AddCommand(newRebuildCmd())
 	cmd.AddCommand(newRemoveCmd())
+	cmd.AddCommand(newScheduleCmd())
+	cmd.AddCommand(newServeCmd())
+	cmd.AddCommand(newSyncCmd())
 	cmd.AddCommand(newUnpinCmd())
 	cmd.AddCommand(newUpdateCmd())
 	cmd.AddCommand(newVersionCmd())
+	cmd.AddCommand(newWatchCmd())
 	cmd.AddCommand(newBugReportCmd())
 
 	if !completion.IsWindows() {
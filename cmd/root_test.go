@@ -103,7 +103,7 @@ func helper_stubImportInstaller(t *testing.T) {
 	t.Helper()
 
 	orgInstallByVersion := installByVersionCtx
-	installByVersionCtx = func(context.Context, string, string) error {
+	installByVersionCtx = func(context.Context, string, string, goutil.InstallOptions) error {
 		return nil
 	}
 	t.Cleanup(func() {
@@ -164,6 +164,7 @@ func setupXDGBase(t *testing.T) {
 	origConfig := xdg.ConfigHome
 	origData := xdg.DataHome
 	origCache := xdg.CacheHome
+	origState := xdg.StateHome
 
 	// Use os.MkdirTemp instead of t.TempDir() to avoid flaky
 	// "directory not empty" failures on macOS caused by Spotlight
@@ -185,6 +186,7 @@ func setupXDGBase(t *testing.T) {
 		xdg.ConfigHome = origConfig
 		xdg.DataHome = origData
 		xdg.CacheHome = origCache
+		xdg.StateHome = origState
 		_ = os.RemoveAll(base)
 		_ = os.RemoveAll(telemetryDir)
 	})
@@ -195,8 +197,9 @@ func setupXDGBase(t *testing.T) {
 	xdg.ConfigHome = filepath.Join(base, "config")
 	xdg.DataHome = filepath.Join(base, "data")
 	xdg.CacheHome = filepath.Join(base, "cache")
+	xdg.StateHome = filepath.Join(base, "state")
 
-	for _, dir := range []string{xdg.ConfigHome, xdg.DataHome, xdg.CacheHome} {
+	for _, dir := range []string{xdg.ConfigHome, xdg.DataHome, xdg.CacheHome, xdg.StateHome} {
 		if err := os.MkdirAll(dir, 0o750); err != nil {
 			t.Fatalf("failed to create XDG directory %s: %v", dir, err)
 		}
@@ -0,0 +1,409 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/notify"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/nao1215/gup/internal/schedule"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// scheduleLogFileName is the log every scheduled run appends a summary and
+	// its diagnostics to, in gup's state directory.
+	scheduleLogFileName = "schedule.log"
+	// scheduleLastRunFileName holds the --json output of the last scheduled
+	// run, in gup's state directory.
+	scheduleLastRunFileName = "schedule-last.json"
+)
+
+// scheduleEnvKeys are the environment variables recorded in the scheduled job,
+// since neither systemd nor cron runs it with the login shell's environment.
+// The last two let the desktop notification reach the session.
+func scheduleEnvKeys() []string {
+	return []string{
+		"PATH", "GOBIN", "GOPATH", "GOROOT", "GOMODCACHE", "GOFLAGS", "GOTOOLCHAIN",
+		"GOPROXY", "GONOPROXY", "GOPRIVATE", "GONOSUMDB", "GOSUMDB", "GOINSECURE",
+		"XDG_CONFIG_HOME", "XDG_STATE_HOME", "DISPLAY", "DBUS_SESSION_BUS_ADDRESS",
+	}
+}
+
+func newScheduleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Run 'gup update' in the background on a schedule",
+		Long: `Run 'gup update' in the background on a schedule.
+
+'gup schedule install' generates and enables a systemd user service and timer,
+or a crontab entry where systemd is not running. The job runs 'gup update'
+(or 'gup check' with --check-only) with JSON output, keeps the output of the
+last run and a log in gup's state directory ($XDG_STATE_HOME/gup), and shows
+a desktop notification when something was updated or failed.
+
+'gup schedule status' shows the installed job, and 'gup schedule remove'
+removes it.`,
+		Example: `  gup schedule install --daily
+  gup schedule install --cron "30 4 * * 1-5" --check-only
+  gup schedule status
+  gup schedule remove`,
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+	}
+	cmd.AddCommand(newScheduleInstallCmd())
+	cmd.AddCommand(newScheduleStatusCmd())
+	cmd.AddCommand(newScheduleRemoveCmd())
+	cmd.AddCommand(newScheduleRunCmd())
+	return cmd
+}
+
+func newScheduleInstallCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install the scheduled job, replacing the one installed before",
+		Long: `Install the scheduled job, replacing the one installed before.
+
+--daily runs it every day at 03:00 and --weekly every Monday at 03:00;
+--cron takes a five-field cron expression instead. A systemd timer is used
+when a systemd user session is running and can express the schedule; it
+catches up on a run missed while the machine was off. Otherwise the job is
+added to your crontab, leaving your other entries as they are.
+
+PATH and the Go environment variables set now are recorded in the job, so it
+builds with the same Go and proxy settings as your shell.`,
+		Example: `  gup schedule install --daily
+  gup schedule install --weekly --check-only
+  gup schedule install --cron "0 */6 * * *"`,
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(runScheduleInstall(schedule.NewManager(), printerFor(cmd), cmd, args))
+		},
+	}
+	cmd.Flags().Bool("daily", false, "run every day at 03:00")
+	cmd.Flags().Bool("weekly", false, "run every Monday at 03:00")
+	cmd.Flags().String("cron", "", "run at the times of this cron expression (e.g. \"30 4 * * 1-5\")")
+	mustRegisterFlagCompletion(cmd, "cron", cobra.NoFileCompletions)
+	cmd.Flags().Bool("check-only", false, "run 'gup check' instead of 'gup update': report updates, install nothing")
+	return cmd
+}
+
+func newScheduleStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "status",
+		Short:             "Show the scheduled job and its last run",
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(runScheduleStatus(schedule.NewManager(), printerFor(cmd), cmd, args))
+		},
+	}
+}
+
+func newScheduleRemoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "remove",
+		Short:             "Remove the scheduled job",
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(runScheduleRemove(schedule.NewManager(), printerFor(cmd), cmd, args))
+		},
+	}
+}
+
+// newScheduleRunCmd is the command the scheduled job runs. It is hidden
+// because it is only meant to be run by systemd or cron.
+func newScheduleRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "run",
+		Short:  "Run the scheduled update once (used by the installed job)",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(runScheduledJob(defaultDependencies(), printerFor(cmd), cmd, args))
+		},
+	}
+	cmd.Flags().Bool("check-only", false, "run 'gup check' instead of 'gup update'")
+	return cmd
+}
+
+// scheduleSpecFromFlags reads the one of --daily, --weekly and --cron given.
+func scheduleSpecFromFlags(cmd *cobra.Command) (schedule.Spec, error) {
+	daily, err := getFlagBool(cmd, "daily")
+	if err != nil {
+		return schedule.Spec{}, err
+	}
+	weekly, err := getFlagBool(cmd, "weekly")
+	if err != nil {
+		return schedule.Spec{}, err
+	}
+	cron, err := getFlagString(cmd, "cron")
+	if err != nil {
+		return schedule.Spec{}, err
+	}
+	cron = strings.TrimSpace(cron)
+
+	given := 0
+	for _, set := range []bool{daily, weekly, cron != ""} {
+		if set {
+			given++
+		}
+	}
+	if given != 1 {
+		return schedule.Spec{}, errors.New("give exactly one of --daily, --weekly or --cron")
+	}
+	switch {
+	case daily:
+		return schedule.Daily(), nil
+	case weekly:
+		return schedule.Weekly(), nil
+	default:
+		return schedule.ParseCron(cron)
+	}
+}
+
+// scheduleJob builds the command line the scheduler runs, with the current
+// values of scheduleEnvKeys.
+func scheduleJob(checkOnly bool) (schedule.Job, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return schedule.Job{}, fmt.Errorf("can't find the gup executable: %w", err)
+	}
+	exe, err = filepath.Abs(exe)
+	if err != nil {
+		return schedule.Job{}, fmt.Errorf("can't find the gup executable: %w", err)
+	}
+	job := schedule.Job{Exe: exe, Args: []string{"schedule", "run"}}
+	if checkOnly {
+		job.Args = append(job.Args, "--check-only")
+	}
+	for _, key := range scheduleEnvKeys() {
+		if v, ok := os.LookupEnv(key); ok && v != "" {
+			job.Env = append(job.Env, key+"="+v)
+		}
+	}
+	return job, nil
+}
+
+func runScheduleInstall(m schedule.Manager, p *print.Printer, cmd *cobra.Command, _ []string) int {
+	spec, err := scheduleSpecFromFlags(cmd)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	checkOnly, err := getFlagBool(cmd, "check-only")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	job, err := scheduleJob(checkOnly)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	backend, err := m.Install(context.Background(), spec, job)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	what := "gup update"
+	if checkOnly {
+		what = "gup check"
+	}
+	switch backend {
+	case schedule.BackendSystemd:
+		p.Info(fmt.Sprintf("installed the systemd timer %s.timer: '%s' runs at %s", schedule.UnitName, what, spec.OnCalendar))
+	default:
+		p.Info(fmt.Sprintf("installed a crontab entry: '%s' runs at \"%s\"", what, spec.Cron))
+	}
+	p.Info("each run is logged to " + filepath.Join(config.StateDirPath(), scheduleLogFileName))
+	return 0
+}
+
+func runScheduleStatus(m schedule.Manager, p *print.Printer, _ *cobra.Command, _ []string) int {
+	st, err := m.Status(context.Background())
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if st.Backend == "" {
+		p.Info("no scheduled gup run is installed; add one with 'gup schedule install'")
+		return 0
+	}
+	out := p.Out()
+	_, _ = fmt.Fprintf(out, "scheduler: %s\n", st.Backend)
+	_, _ = fmt.Fprintf(out, "schedule:  %s\n", st.Schedule)
+	_, _ = fmt.Fprintf(out, "command:   %s\n", st.Command)
+	if st.NextRun != "" {
+		_, _ = fmt.Fprintf(out, "next run:  %s\n", st.NextRun)
+	}
+	logPath := filepath.Join(config.StateDirPath(), scheduleLogFileName)
+	last, err := lastScheduleLogEntry(logPath)
+	switch {
+	case err != nil:
+		p.Warn(err)
+	case last == "":
+		_, _ = fmt.Fprintln(out, "last run:  none yet")
+	default:
+		_, _ = fmt.Fprintf(out, "last run:  %s\n", last)
+	}
+	_, _ = fmt.Fprintf(out, "log:       %s\n", logPath)
+	return 0
+}
+
+func runScheduleRemove(m schedule.Manager, p *print.Printer, _ *cobra.Command, _ []string) int {
+	removed, err := m.Remove(context.Background())
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if len(removed) == 0 {
+		p.Info("no scheduled gup run is installed")
+		return 0
+	}
+	for _, b := range removed {
+		switch b {
+		case schedule.BackendSystemd:
+			p.Info(fmt.Sprintf("removed the systemd timer %s.timer and its service", schedule.UnitName))
+		default:
+			p.Info("removed the gup entry from your crontab")
+		}
+	}
+	return 0
+}
+
+// runScheduledJob runs 'gup update --json' (or 'gup check --json') once. The
+// JSON output replaces schedule-last.json, the diagnostics and a one-line
+// summary are appended to schedule.log, and the summary is shown as a desktop
+// notification when something was updated, is available, or failed.
+func runScheduledJob(deps dependencies, p *print.Printer, cmd *cobra.Command, _ []string) int {
+	checkOnly, err := getFlagBool(cmd, "check-only")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	stateDir := config.StateDirPath()
+	if err := os.MkdirAll(stateDir, 0o750); err != nil {
+		p.Err(fmt.Errorf("can't create %s: %w", stateDir, err))
+		return 1
+	}
+	logPath := filepath.Join(stateDir, scheduleLogFileName)
+	logFile, err := os.OpenFile(filepath.Clean(logPath), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		p.Err(fmt.Errorf("can't open %s: %w", logPath, err))
+		return 1
+	}
+	defer logFile.Close()
+
+	started := time.Now()
+	var jsonOut bytes.Buffer
+	jobPrinter := print.New(&jsonOut, logFile)
+	name := "update"
+	var code int
+	if checkOnly {
+		name = "check"
+		c := newCheckCmd()
+		if err := c.ParseFlags([]string{"--json"}); err != nil {
+			p.Err(err)
+			return 1
+		}
+		code = check(deps, jobPrinter, c, nil)
+	} else {
+		c := newUpdateCmd()
+		if err := c.ParseFlags([]string{"--json"}); err != nil {
+			p.Err(err)
+			return 1
+		}
+		code = gup(deps, jobPrinter, c, nil)
+	}
+
+	lastPath := filepath.Join(stateDir, scheduleLastRunFileName)
+	if err := writeFileAtomically(lastPath, jsonOut.Bytes()); err != nil {
+		p.Warn(err)
+	}
+	summary, news := summarizeScheduledRun(name, jsonOut.Bytes(), code)
+	if _, err := fmt.Fprintf(logFile, "%s %s\n", started.Format(time.RFC3339), summary); err != nil {
+		p.Warn(fmt.Errorf("can't write %s: %w", logPath, err))
+	}
+	p.Info(summary)
+	if code != 0 {
+		notify.Warn(p, "gup", summary)
+	} else if news {
+		notify.Info(p, "gup", summary)
+	}
+	return code
+}
+
+// summarizeScheduledRun describes a scheduled run from its --json output and
+// exit code, and reports whether it is worth a notification.
+func summarizeScheduledRun(name string, out []byte, code int) (string, bool) {
+	var pkgs []jsonPackage
+	if err := json.Unmarshal(out, &pkgs); err != nil {
+		return fmt.Sprintf("scheduled %s failed (exit %d); see %s", name, code, scheduleLogFileName), true
+	}
+	// Tally the records the way check and update tally their own results.
+	results := make([]updateResult, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		v := updateResult{status: pkg.Status}
+		if pkg.Status == statusError {
+			v.err = errors.New(pkg.Error)
+		}
+		results = append(results, v)
+	}
+	counts := countResults(results)
+	parts := []string{}
+	for _, c := range []struct {
+		n     int
+		label string
+	}{
+		{counts.Updated, "updated"},
+		{counts.UpdateAvailable, "update available"},
+		{counts.NeedsNewerGo, "needs a newer Go"},
+		{counts.Failed, "failed"},
+	} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", c.n, c.label))
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("scheduled %s: all %d binaries up to date (exit %d)", name, len(pkgs), code), code != 0
+	}
+	return fmt.Sprintf("scheduled %s: %s (exit %d)", name, strings.Join(parts, ", "), code), true
+}
+
+// lastScheduleLogEntry returns the last summary line in the schedule log, or
+// "" when no run has been logged yet. Summary lines start with the run's
+// RFC 3339 start time; the diagnostics in between do not.
+func lastScheduleLogEntry(path string) (string, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	last := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		stamp, _, ok := strings.Cut(line, " ")
+		if _, err := time.Parse(time.RFC3339, stamp); ok && err == nil {
+			last = line
+		}
+	}
+	return last, scanner.Err()
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/schedule"
+)
+
+func Test_scheduleSpecFromFlags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    schedule.Spec
+		wantErr bool
+	}{
+		{name: "daily", args: []string{"--daily"}, want: schedule.Daily()},
+		{name: "weekly", args: []string{"--weekly"}, want: schedule.Weekly()},
+		{name: "cron", args: []string{"--cron", "0 */6 * * *"}, want: schedule.Spec{Cron: "0 */6 * * *", OnCalendar: "*-*-* 00/6:00:00"}},
+		{name: "none", args: nil, wantErr: true},
+		{name: "two", args: []string{"--daily", "--weekly"}, wantErr: true},
+		{name: "invalid cron", args: []string{"--cron", "every night"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cmd := newScheduleInstallCmd()
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			got, err := scheduleSpecFromFlags(cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("scheduleSpecFromFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("scheduleSpecFromFlags() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_summarizeScheduledRun(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		out      string
+		code     int
+		want     string
+		wantNews bool
+	}{
+		{
+			name: "nothing to do",
+			out:  `[{"status":"up-to-date"},{"status":"pinned"}]`,
+			want: "scheduled update: all 2 binaries up to date (exit 0)",
+		},
+		{
+			name:     "updates and a failure",
+			out:      `[{"status":"updated"},{"status":"updated"},{"status":"error"}]`,
+			code:     1,
+			want:     "scheduled update: 2 updated, 1 failed (exit 1)",
+			wantNews: true,
+		},
+		{
+			name:     "pin mismatch is an update available",
+			out:      `[{"status":"update-available"},{"status":"pin-mismatch"},{"status":"up-to-date"}]`,
+			want:     "scheduled update: 2 update available (exit 0)",
+			wantNews: true,
+		},
+		{
+			name:     "no JSON",
+			out:      "",
+			code:     1,
+			want:     "scheduled update failed (exit 1); see schedule.log",
+			wantNews: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, news := summarizeScheduledRun("update", []byte(tt.out), tt.code)
+			if got != tt.want || news != tt.wantNews {
+				t.Errorf("summarizeScheduledRun() = %q, %v; want %q, %v", got, news, tt.want, tt.wantNews)
+			}
+		})
+	}
+}
+
+func Test_lastScheduleLogEntry(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), scheduleLogFileName)
+	if got, err := lastScheduleLogEntry(path); err != nil || got != "" {
+		t.Fatalf("lastScheduleLogEntry() of a missing log = %q, %v", got, err)
+	}
+	log := "2026-10-18T03:00:00Z scheduled update: all 3 binaries up to date (exit 0)\n" +
+		"2026-10-19T03:00:01Z scheduled update: 1 updated (exit 0)\n" +
+		"gup:WARN: a diagnostic line\n"
+	if err := os.WriteFile(path, []byte(log), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := lastScheduleLogEntry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2026-10-19T03:00:01Z scheduled update: 1 updated (exit 0)"; got != want {
+		t.Errorf("lastScheduleLogEntry() = %q, want %q", got, want)
+	}
+}
+
+//nolint:paralleltest // swaps XDG state paths
+func Test_runSchedule_installStatusRemove(t *testing.T) {
+	setupXDGBase(t)
+
+	crontab := ""
+	m := schedule.Manager{
+		UnitDir: filepath.Join(t.TempDir(), "systemd", "user"),
+		GOOS:    "linux",
+		Run: func(_ context.Context, stdin, name string, args ...string) (string, error) {
+			switch {
+			case name == "systemctl":
+				return "", errors.New("systemctl: Failed to connect to bus")
+			case args[0] == "-l" && crontab == "":
+				return "", errors.New("no crontab for me")
+			case args[0] == "-l":
+				return crontab, nil
+			default:
+				crontab = stdin
+				return "", nil
+			}
+		},
+	}
+
+	install := newScheduleInstallCmd()
+	if err := install.ParseFlags([]string{"--weekly", "--check-only"}); err != nil {
+		t.Fatal(err)
+	}
+	p, buf := newTestPrinter()
+	if got := runScheduleInstall(m, p, install, nil); got != 0 {
+		t.Fatalf("runScheduleInstall() = %d, want 0; output:\n%s", got, buf.String())
+	}
+	if !strings.Contains(crontab, "0 3 * * 1 ") || !strings.Contains(crontab, " schedule run --check-only\n") {
+		t.Errorf("crontab = %q", crontab)
+	}
+
+	p, buf = newTestPrinter()
+	if got := runScheduleStatus(m, p, newScheduleStatusCmd(), nil); got != 0 {
+		t.Fatalf("runScheduleStatus() = %d, want 0", got)
+	}
+	for _, want := range []string{"scheduler: cron\n", "schedule:  0 3 * * 1\n", "last run:  none yet\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("status output lacks %q:\n%s", want, buf.String())
+		}
+	}
+
+	p, buf = newTestPrinter()
+	if got := runScheduleRemove(m, p, newScheduleRemoveCmd(), nil); got != 0 {
+		t.Fatalf("runScheduleRemove() = %d, want 0", got)
+	}
+	if crontab != "" || !strings.Contains(buf.String(), "removed the gup entry from your crontab") {
+		t.Errorf("after remove: crontab %q, output:\n%s", crontab, buf.String())
+	}
+}
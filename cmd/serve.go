@@ -0,0 +1,529 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// defaultServeListen is the address 'gup serve' listens on by default.
+	defaultServeListen = "127.0.0.1:7979"
+	// serveTokenEnv holds the token POST requests must send. Without it, gup
+	// serve makes up a token and prints it.
+	serveTokenEnv = "GUP_SERVE_TOKEN"
+	// maxServeRequestBody limits the JSON body of a POST request.
+	maxServeRequestBody = 1 << 20
+)
+
+func newServeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve a local HTTP API for listing, checking, updating and pinning binaries",
+		Long: `Serve a local HTTP API for listing, checking, updating and pinning binaries.
+
+The API returns the same records as --json, and runs the same code as the
+commands:
+
+  GET  /list                   gup list --json
+  GET  /check?binary=NAME...   gup check --json [NAME...]
+  GET  /metrics                gup check --metrics-textfile, as Prometheus metrics
+  POST /update                 gup update --json; body {"binaries": [...], "dry_run": false}
+  POST /pin                    gup pin; body {"binary": "NAME", "version": "vX.Y.Z"}
+
+Every request must be addressed to the loopback address gup serve listens on:
+one with another Host header, such as a web page that rebinds its own domain
+to 127.0.0.1, is refused with 403 Forbidden. POST requests also need an
+"Authorization: Bearer TOKEN" header. The token is read
+from $GUP_SERVE_TOKEN, or made up and printed when gup serve starts. Only one
+update or pin runs at a time; another one is refused with 409 Conflict.
+
+With "Accept: text/event-stream", the response is a stream of Server-Sent
+Events instead: a "progress" event as each package finishes, a "log" event for
+each message, a "result" event with the records, and a final "done" event with
+the exit code.
+
+A command stops when its client disconnects. On SIGINT or SIGTERM, gup serve
+stops accepting requests, cancels the running commands and waits for a
+running update or pin to clean up (a dry run restores $GOBIN) before it exits.
+
+gup serve only listens on a loopback address.`,
+		Example: `  gup serve
+  GUP_SERVE_TOKEN=secret gup serve --listen 127.0.0.1:8080`,
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(runServe(defaultDependencies(), printerFor(cmd), cmd, args))
+		},
+	}
+	cmd.Flags().String("listen", defaultServeListen, "loopback address and port to listen on")
+	mustRegisterFlagCompletion(cmd, "listen", cobra.NoFileCompletions)
+	cmd.Flags().StringP("file", "f", "", "specify gup.json file path the endpoints use")
+	mustMarkFileFlagAsJSON(cmd)
+	return cmd
+}
+
+func runServe(deps dependencies, p *print.Printer, cmd *cobra.Command, _ []string) int {
+	listen, err := getFlagString(cmd, "listen")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	confFile, err := getFlagString(cmd, "file")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if err := checkLoopbackListen(listen); err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	token := strings.TrimSpace(os.Getenv(serveTokenEnv))
+	tokenFromEnv := token != ""
+	if !tokenFromEnv {
+		if token, err = newServeToken(); err != nil {
+			p.Err(err)
+			return 1
+		}
+	}
+
+	ln, err := (&net.ListenConfig{}).Listen(context.Background(), "tcp", listen)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	// Messages are streamed to API clients, which have no use for colors.
+	applyColorPreference(true)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	api := newAPIServer(deps, confFile, token, listenPort(ln.Addr()))
+	srv := &http.Server{
+		Handler:           api.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		// Request contexts end with the serve context, so a signal cancels
+		// the running commands too.
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx) //nolint:contextcheck // the serve context is already done here
+	}()
+
+	p.Info("serving the HTTP API on http://" + ln.Addr().String())
+	if tokenFromEnv {
+		p.Info("POST requests need 'Authorization: Bearer $" + serveTokenEnv + "'")
+	} else {
+		p.Info("POST requests need 'Authorization: Bearer " + token + "'")
+	}
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		p.Err(err)
+		return 1
+	}
+	<-shutdown
+	// Shutdown stops waiting for requests after its timeout, but a canceled
+	// update still has to restore $GOBIN and remove its dry-run directory.
+	api.waitMutation()
+	return 0
+}
+
+// checkLoopbackListen rejects a listen address that other machines could
+// reach: the read endpoints need no token.
+func checkLoopbackListen(listen string) error {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return fmt.Errorf("invalid --listen %q: %w", listen, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("invalid --listen %q: gup serve only listens on a loopback address such as 127.0.0.1", listen)
+}
+
+// listenPort returns the port of the address gup serve listens on.
+func listenPort(addr net.Addr) string {
+	_, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return ""
+	}
+	return port
+}
+
+// newServeToken makes up a random token for the POST endpoints.
+func newServeToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("can't generate a token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// apiServer serves the gup API by running the commands' own functions.
+type apiServer struct {
+	deps     dependencies
+	confFile string
+	token    string
+	// port is the port gup serve listens on, which the Host header of every
+	// request must name.
+	port string
+	// mutating is held by the running update or pin, so a second one can be
+	// refused instead of queued.
+	mutating sync.Mutex
+	// state lets reads run together but never during a mutation, which may
+	// point $GOBIN at a dry-run directory or rewrite gup.json.
+	state sync.RWMutex
+}
+
+func newAPIServer(deps dependencies, confFile, token, port string) *apiServer {
+	return &apiServer{deps: deps, confFile: confFile, token: token, port: port}
+}
+
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /list", s.handleList)
+	mux.HandleFunc("GET /check", s.handleCheck)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.HandleFunc("POST /update", s.mutation(s.handleUpdate))
+	mux.HandleFunc("POST /pin", s.mutation(s.handlePin))
+	return s.checkHost(mux)
+}
+
+// checkHost refuses requests whose Host header is not a loopback address with
+// the port gup serve listens on. Listening on loopback keeps other machines
+// out, but not a web page in a local browser whose domain is rebound to
+// 127.0.0.1: its requests name that domain as their Host.
+func (s *apiServer) checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.loopbackHost(r.Host) {
+			writeServeError(w, http.StatusForbidden, fmt.Errorf("unexpected Host %q: address gup serve as localhost:%s", r.Host, s.port))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loopbackHost reports whether host names a loopback address and the port of
+// s. A Host without a port means port 80.
+func (s *apiServer) loopbackHost(host string) bool {
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		name, port = strings.Trim(host, "[]"), "80"
+	}
+	if port != s.port {
+		return false
+	}
+	if name == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(name)
+	return ip != nil && ip.IsLoopback()
+}
+
+// fileFlag returns the --file argument the commands get.
+func (s *apiServer) fileFlag() []string {
+	if s.confFile == "" {
+		return nil
+	}
+	return []string{"--file", s.confFile}
+}
+
+func (s *apiServer) handleList(w http.ResponseWriter, r *http.Request) {
+	s.state.RLock()
+	defer s.state.RUnlock()
+
+	cmd := newListCmd()
+	if !parseServeFlags(w, cmd, append([]string{"--json"}, s.fileFlag()...)) {
+		return
+	}
+	runForAPI(w, r, true, func(p *print.Printer) int { return list(p, cmd, nil) })
+}
+
+func (s *apiServer) handleCheck(w http.ResponseWriter, r *http.Request) {
+	s.state.RLock()
+	defer s.state.RUnlock()
+
+	cmd := newCheckCmd()
+	cmd.SetContext(r.Context())
+	if !parseServeFlags(w, cmd, append([]string{"--json"}, s.fileFlag()...)) {
+		return
+	}
+	binaries := r.URL.Query()["binary"]
+	runForAPI(w, r, true, func(p *print.Printer) int { return check(s.deps, p, cmd, binaries) })
+}
+
+// handleMetrics runs a check of every binary and reports it as Prometheus
+// metrics, the same ones 'gup check --metrics-textfile' writes.
+func (s *apiServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.state.RLock()
+	defer s.state.RUnlock()
+
+	cmd := newCheckCmd()
+	cmd.SetContext(r.Context())
+	if !parseServeFlags(w, cmd, append([]string{"--json"}, s.fileFlag()...)) {
+		return
+	}
+	var out, errOut bytes.Buffer
+	code := check(s.deps, print.New(&out, &errOut), cmd, nil)
+	var recs []jsonPackage
+	if err := json.Unmarshal(out.Bytes(), &recs); err != nil {
+		writeServeJSON(w, http.StatusInternalServerError, serveError{Error: strings.TrimSpace(errOut.String()), ExitCode: code})
+		return
+	}
+	lastUpdate, err := readLastUpdate()
+	if err != nil {
+		writeServeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = writeMetrics(w, recs, lastUpdate)
+}
+
+// updateRequest is the body of POST /update. No binaries means all of them.
+type updateRequest struct {
+	Binaries []string `json:"binaries"`
+	DryRun   bool     `json:"dry_run"`
+}
+
+func (s *apiServer) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	var req updateRequest
+	if !decodeServeRequest(w, r, &req) {
+		return
+	}
+	args := append([]string{"--json"}, s.fileFlag()...)
+	if req.DryRun {
+		args = append(args, "--dry-run")
+	}
+	cmd := newUpdateCmd()
+	cmd.SetContext(r.Context())
+	if !parseServeFlags(w, cmd, args) {
+		return
+	}
+	runForAPI(w, r, true, func(p *print.Printer) int { return gup(s.deps, p, cmd, req.Binaries) })
+}
+
+// pinRequest is the body of POST /pin.
+type pinRequest struct {
+	Binary  string `json:"binary"`
+	Version string `json:"version"`
+}
+
+func (s *apiServer) handlePin(w http.ResponseWriter, r *http.Request) {
+	var req pinRequest
+	if !decodeServeRequest(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Binary) == "" {
+		writeServeError(w, http.StatusBadRequest, errors.New(`"binary" is required`))
+		return
+	}
+	args := []string{req.Binary}
+	if strings.TrimSpace(req.Version) != "" {
+		args = append(args, req.Version)
+	}
+	cmd := newPinCmd()
+	if !parseServeFlags(w, cmd, s.fileFlag()) {
+		return
+	}
+	runForAPI(w, r, false, func(p *print.Printer) int { return runPin(p, cmd, args) })
+}
+
+// mutation wraps a POST handler: it checks the token, refuses to start while
+// another mutation runs, and waits for running reads to finish.
+func (s *apiServer) mutation(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeServeError(w, http.StatusUnauthorized, errors.New("missing or wrong bearer token"))
+			return
+		}
+		if !s.mutating.TryLock() {
+			writeServeError(w, http.StatusConflict, errors.New("another update or pin is running; try again when it finishes"))
+			return
+		}
+		defer s.mutating.Unlock()
+		s.state.Lock()
+		defer s.state.Unlock()
+		next(w, r)
+	}
+}
+
+// waitMutation waits for the running update or pin, if any, to return, and
+// keeps another one from starting.
+func (s *apiServer) waitMutation() {
+	s.mutating.Lock()
+}
+
+func (s *apiServer) authorized(r *http.Request) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(got)), []byte(s.token)) == 1
+}
+
+// decodeServeRequest reads a JSON request body into v. An empty body leaves v
+// as it is.
+func decodeServeRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxServeRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+func parseServeFlags(w http.ResponseWriter, cmd *cobra.Command, args []string) bool {
+	if err := cmd.ParseFlags(args); err != nil {
+		writeServeError(w, http.StatusInternalServerError, err)
+		return false
+	}
+	return true
+}
+
+// serveError is the body of an error response.
+type serveError struct {
+	Error    string `json:"error"`
+	ExitCode int    `json:"exit_code,omitempty"`
+}
+
+func writeServeError(w http.ResponseWriter, status int, err error) {
+	writeServeJSON(w, status, serveError{Error: err.Error()})
+}
+
+func writeServeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// serveResult is the body of a successful POST /pin.
+type serveResult struct {
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output"`
+}
+
+// runForAPI runs a command function and writes its output as the response.
+// jsonRecords is true for commands run with --json, whose output is the
+// records array. With "Accept: text/event-stream" the output is streamed as
+// Server-Sent Events while the command runs.
+func runForAPI(w http.ResponseWriter, r *http.Request, jsonRecords bool, run func(*print.Printer) int) {
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		streamForAPI(w, jsonRecords, run)
+		return
+	}
+
+	var out, errOut bytes.Buffer
+	code := run(print.New(&out, &errOut))
+	w.Header().Set("X-Gup-Exit-Code", fmt.Sprint(code))
+	switch {
+	case jsonRecords && json.Valid(out.Bytes()):
+		// A partial failure still has records, with "error" statuses.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(out.Bytes())
+	case code == 0:
+		writeServeJSON(w, http.StatusOK, serveResult{ExitCode: code, Output: out.String()})
+	default:
+		writeServeJSON(w, http.StatusInternalServerError, serveError{Error: strings.TrimSpace(errOut.String()), ExitCode: code})
+	}
+}
+
+// streamForAPI runs a command function, sending its progress and messages as
+// Server-Sent Events, then its records and exit code.
+func streamForAPI(w http.ResponseWriter, jsonRecords bool, run func(*print.Printer) int) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	sse := &sseStream{w: w}
+	sse.flush()
+
+	var records bytes.Buffer
+	var out io.Writer = sse.writer("log")
+	if jsonRecords {
+		out = &records
+	}
+	p := print.New(out, sse.writer("log"))
+	p.SetProgress(sse.writer("progress"))
+	code := run(p)
+
+	if jsonRecords {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, records.Bytes()); err == nil {
+			sse.send("result", compact.String())
+		}
+	}
+	done, _ := json.Marshal(map[string]int{"exit_code": code})
+	sse.send("done", string(done))
+}
+
+// sseStream writes Server-Sent Events. Command workers write concurrently, so
+// events are serialized with mu.
+type sseStream struct {
+	mu sync.Mutex
+	w  http.ResponseWriter
+}
+
+func (s *sseStream) send(event, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = fmt.Fprintf(s.w, "event: %s\n", event)
+	for _, line := range strings.Split(data, "\n") {
+		_, _ = fmt.Fprintf(s.w, "data: %s\n", line)
+	}
+	_, _ = fmt.Fprint(s.w, "\n")
+	s.flushLocked()
+}
+
+func (s *sseStream) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushLocked()
+}
+
+func (s *sseStream) flushLocked() {
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writer returns an io.Writer that sends each line written to it as an event.
+func (s *sseStream) writer(event string) io.Writer {
+	return sseLineWriter{stream: s, event: event}
+}
+
+type sseLineWriter struct {
+	stream *sseStream
+	event  string
+}
+
+func (l sseLineWriter) Write(b []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(b), "\n"), "\n") {
+		if strings.TrimSpace(line) != "" {
+			l.stream.send(l.event, line)
+		}
+	}
+	return len(b), nil
+}
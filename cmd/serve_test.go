@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+const (
+	testServeToken = "secret"
+	testServePort  = "7979"
+)
+
+func serveRequest(t *testing.T, s *apiServer, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Host = "127.0.0.1:" + testServePort
+	for k, v := range header {
+		if k == "Host" {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func Test_checkLoopbackListen(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		listen  string
+		wantErr bool
+	}{
+		{listen: "127.0.0.1:7979"},
+		{listen: "localhost:0"},
+		{listen: "[::1]:8080"},
+		{listen: "0.0.0.0:7979", wantErr: true},
+		{listen: ":7979", wantErr: true},
+		{listen: "192.168.1.10:7979", wantErr: true},
+		{listen: "127.0.0.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.listen, func(t *testing.T) {
+			t.Parallel()
+			if err := checkLoopbackListen(tt.listen); (err != nil) != tt.wantErr {
+				t.Errorf("checkLoopbackListen(%q) error = %v, wantErr %v", tt.listen, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAPIServer_rejectsForeignHost(t *testing.T) {
+	t.Parallel()
+
+	s := newAPIServer(testDeps(), "", testServeToken, testServePort)
+	for _, host := range []string{"attacker.example:" + testServePort, "localhost:8080", "127.0.0.1", "10.0.0.1:" + testServePort} {
+		for _, target := range []string{"/list", "/check", "/metrics"} {
+			rec := serveRequest(t, s, http.MethodGet, target, "", map[string]string{"Host": host})
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("GET %s with Host %q = %d, want 403", target, host, rec.Code)
+			}
+		}
+	}
+}
+
+func Test_apiServer_loopbackHost(t *testing.T) {
+	t.Parallel()
+
+	s := newAPIServer(testDeps(), "", testServeToken, testServePort)
+	for host, want := range map[string]bool{
+		"localhost:" + testServePort: true,
+		"127.0.0.1:" + testServePort: true,
+		"[::1]:" + testServePort:     true,
+		"rebound.example:7979":       false,
+		"localhost":                  false,
+		"[::1]:80":                   false,
+	} {
+		if got := s.loopbackHost(host); got != want {
+			t.Errorf("loopbackHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestAPIServer_mutationNeedsToken(t *testing.T) {
+	t.Parallel()
+
+	s := newAPIServer(testDeps(), "", testServeToken, testServePort)
+	for _, auth := range []string{"", "Bearer wrong", testServeToken} {
+		rec := serveRequest(t, s, http.MethodPost, "/update", "", map[string]string{"Authorization": auth})
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("POST /update with Authorization %q = %d, want 401", auth, rec.Code)
+		}
+	}
+}
+
+func TestAPIServer_refusesConcurrentMutation(t *testing.T) {
+	t.Parallel()
+
+	s := newAPIServer(testDeps(), "", testServeToken, testServePort)
+	s.mutating.Lock()
+	defer s.mutating.Unlock()
+
+	rec := serveRequest(t, s, http.MethodPost, "/pin", `{"binary":"tool"}`,
+		map[string]string{"Authorization": "Bearer " + testServeToken})
+	if rec.Code != http.StatusConflict {
+		t.Errorf("POST /pin during another mutation = %d, want 409", rec.Code)
+	}
+}
+
+func TestAPIServer_waitMutation(t *testing.T) {
+	t.Parallel()
+
+	s := newAPIServer(testDeps(), "", testServeToken, testServePort)
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := s.mutation(func(http.ResponseWriter, *http.Request) {
+		close(started)
+		<-release
+	})
+	go func() {
+		req := httptest.NewRequest(http.MethodPost, "/update", nil)
+		req.Header.Set("Authorization", "Bearer "+testServeToken)
+		handler(httptest.NewRecorder(), req)
+	}()
+	<-started
+
+	waited := make(chan struct{})
+	go func() {
+		s.waitMutation()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("waitMutation() returned while an update was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitMutation() did not return after the update finished")
+	}
+
+	rec := serveRequest(t, s, http.MethodPost, "/pin", `{"binary":"tool"}`,
+		map[string]string{"Authorization": "Bearer " + testServeToken})
+	if rec.Code != http.StatusConflict {
+		t.Errorf("POST /pin after waitMutation() = %d, want 409", rec.Code)
+	}
+}
+
+func TestAPIServer_rejectsUnknownFields(t *testing.T) {
+	t.Parallel()
+
+	s := newAPIServer(testDeps(), "", testServeToken, testServePort)
+	rec := serveRequest(t, s, http.MethodPost, "/update", `{"binary":["tool"]}`,
+		map[string]string{"Authorization": "Bearer " + testServeToken})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("POST /update with an unknown field = %d, want 400", rec.Code)
+	}
+}
+
+//nolint:paralleltest // swaps package globals and XDG env; must not run in parallel
+func TestAPIServer_pin(t *testing.T) {
+	setupXDGBase(t)
+	stubPinPackageInfo(t, []goutil.Package{
+		{Name: testBinTool, ImportPath: pinnedTestImport, Version: &goutil.Version{Current: testVersionZeroNine}},
+	})
+
+	s := newAPIServer(testDeps(), "", testServeToken, testServePort)
+	rec := serveRequest(t, s, http.MethodPost, "/pin", `{"binary":"tool","version":"v1.0.0"}`,
+		map[string]string{"Authorization": "Bearer " + testServeToken})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /pin = %d, want 200; body:\n%s", rec.Code, rec.Body.String())
+	}
+	var got serveResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ExitCode != 0 {
+		t.Errorf("exit_code = %d, want 0", got.ExitCode)
+	}
+	raw, err := os.ReadFile(config.FilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), `"channel": "pinned"`) {
+		t.Errorf("gup.json was not pinned:\n%s", raw)
+	}
+}
+
+//nolint:paralleltest // swaps package globals and XDG env; must not run in parallel
+func TestAPIServer_pinStreamsEvents(t *testing.T) {
+	setupXDGBase(t)
+	stubPinPackageInfo(t, []goutil.Package{
+		{Name: testBinTool, ImportPath: pinnedTestImport, Version: &goutil.Version{Current: testVersionZeroNine}},
+	})
+
+	s := newAPIServer(testDeps(), "", testServeToken, testServePort)
+	rec := serveRequest(t, s, http.MethodPost, "/pin", `{"binary":"tool","version":"v1.0.0"}`, map[string]string{
+		"Authorization": "Bearer " + testServeToken,
+		"Accept":        "text/event-stream",
+	})
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"event: log\ndata: ", "event: done\ndata: {\"exit_code\":0}\n\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("stream lacks %q:\n%s", want, body)
+		}
+	}
+}
+
+//nolint:paralleltest // sets GOBIN; must not run in parallel
+func TestAPIServer_list(t *testing.T) {
+	setupXDGBase(t)
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	copyTestExecutable(t, filepath.Join(gobin, "cmd.test"))
+
+	s := newAPIServer(testDeps(), "", testServeToken, testServePort)
+	rec := serveRequest(t, s, http.MethodGet, "/list", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /list = %d, want 200; body:\n%s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("X-Gup-Exit-Code"); got != "0" {
+		t.Errorf("X-Gup-Exit-Code = %q, want 0", got)
+	}
+	var recs []jsonPackage
+	if err := json.Unmarshal(rec.Body.Bytes(), &recs); err != nil {
+		t.Fatalf("GET /list body is not a record array: %v\n%s", err, rec.Body.String())
+	}
+	if len(recs) != 1 || recs[0].Name != "cmd.test" {
+		t.Errorf("GET /list = %+v, want the one test binary", recs)
+	}
+}
+
+//nolint:paralleltest // sets GOBIN; must not run in parallel
+func TestAPIServer_metrics(t *testing.T) {
+	setupXDGBase(t)
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	copyTestExecutable(t, filepath.Join(gobin, "cmd.test"))
+
+	s := newAPIServer(testDeps(), "", testServeToken, testServePort)
+	rec := serveRequest(t, s, http.MethodGet, "/metrics", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d, want 200; body:\n%s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `gup_tool_info{name="cmd.test",`) {
+		t.Errorf("GET /metrics:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,377 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/configstate"
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/pkgselect"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+// installedPackageInfo lists the installed packages. It is a package-level
+// variable so tests can swap the implementation.
+var installedPackageInfo = pkgselect.PackageInfo //nolint:gochecknoglobals // swapped in tests
+
+func newSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Make $GOPATH/bin or $GOBIN match gup.json",
+		Long: `Make $GOPATH/bin or $GOBIN match gup.json.
+
+sync compares gup.json with the installed binaries and prints a plan before
+changing anything:
+  install    a gup.json entry that is not installed
+  reinstall  an installed binary whose version, channel or pin differs
+             from its gup.json entry (this can downgrade it)
+  remove     an installed binary that gup.json does not list (--prune only)
+
+An entry 'gup watch' marked as removed is not installed again: its binary
+was deleted on purpose. It is only reported.
+
+Binaries are matched to gup.json entries by import path first, then by
+name. An entry recorded as "latest" is installed when missing but is not
+compared by version. Without --prune, binaries missing from gup.json are only
+reported. --prune removes them with the same checks as 'gup remove': each
+removal is confirmed on a terminal unless --force is given.
+
+When --file names a gup.json other than gup's own, the installed binaries are
+compared with the channels gup keeps for them (the channels 'gup update'
+uses), and the channels that sync changes are saved there so the next
+'gup update' keeps them.`,
+		Example: `  gup sync --dry-run
+  gup sync --file gup.json
+  gup sync --prune --force`,
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(runSync(printerFor(cmd), cmd, args))
+		},
+	}
+
+	cmd.Flags().BoolP("dry-run", "n", false, "print the plan without changing anything")
+	cmd.Flags().Bool("prune", false, "remove installed binaries that gup.json does not list")
+	cmd.Flags().Bool("force", false, "remove without confirmation (with --prune)")
+	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to sync with")
+	mustMarkFileFlagAsJSON(cmd)
+	cmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "specify the number of CPU cores to use")
+	mustRegisterFlagCompletion(cmd, "jobs", completeNCPUs)
+	addTimeoutFlag(cmd)
+
+	return cmd
+}
+
+// syncOpts holds the parsed command-line flags for 'gup sync'.
+type syncOpts struct {
+	dryRun   bool
+	prune    bool
+	force    bool
+	confFile string
+	cpus     int
+	timeout  time.Duration
+}
+
+func parseSyncFlags(cmd *cobra.Command) (syncOpts, error) {
+	var opts syncOpts
+	var err error
+
+	if opts.dryRun, err = getFlagBool(cmd, "dry-run"); err != nil {
+		return syncOpts{}, err
+	}
+	if opts.prune, err = getFlagBool(cmd, "prune"); err != nil {
+		return syncOpts{}, err
+	}
+	if opts.force, err = getFlagBool(cmd, "force"); err != nil {
+		return syncOpts{}, err
+	}
+	if opts.force && !opts.prune {
+		return syncOpts{}, errors.New("--force requires --prune")
+	}
+	if opts.confFile, err = getFlagString(cmd, "file"); err != nil {
+		return syncOpts{}, err
+	}
+	if opts.cpus, err = getFlagInt(cmd, "jobs"); err != nil {
+		return syncOpts{}, err
+	}
+	opts.cpus = clampJobs(opts.cpus)
+	if opts.timeout, err = getTimeoutFlag(cmd); err != nil {
+		return syncOpts{}, err
+	}
+	return opts, nil
+}
+
+func runSync(p *print.Printer, cmd *cobra.Command, _ []string) int {
+	if err := ensureGoCommandAvailable(); err != nil {
+		p.Err(err)
+		return 1
+	}
+	opts, err := parseSyncFlags(cmd)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	confReadPath, confPkgs, err := readDesiredConfig(opts.confFile)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	installed, err := installedState(p, opts.confFile, confPkgs)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	steps := planSync(configstate.CompareInstalled(confPkgs, installed), opts.prune)
+	printSyncPlan(p, confReadPath, steps)
+	if opts.dryRun || !hasSyncChanges(steps) {
+		return 0
+	}
+	return applySync(p, confReadPath, steps, opts)
+}
+
+// readDesiredConfig reads the gup.json that sync and diff compare against. The
+// file must exist: comparing with "no config" would make every installed
+// binary look unmanaged.
+func readDesiredConfig(confFile string) (string, []goutil.Package, error) {
+	confReadPath, err := config.ResolveImportFilePath(confFile)
+	if err != nil {
+		return "", nil, err
+	}
+	if !fileutil.IsFile(confReadPath) {
+		return "", nil, fmt.Errorf("%s is not found", confReadPath)
+	}
+	confPkgs, err := config.ReadConfFile(confReadPath)
+	if err != nil {
+		return "", nil, err
+	}
+	return confReadPath, confPkgs, nil
+}
+
+// installedState returns the installed packages with the update channel and
+// pin gup keeps them on. Without --file that is the same gup.json sync and diff
+// compare against; with --file it is gup's own gup.json, so a channel recorded
+// differently in the named file shows up as a difference.
+func installedState(p *print.Printer, confFile string, confPkgs []goutil.Package) ([]goutil.Package, error) {
+	pkgs, err := installedPackageInfo(p)
+	if err != nil {
+		return nil, err
+	}
+	installed := validPkgInfo(p, pkgs)
+	if strings.TrimSpace(confFile) == "" {
+		return configstate.ApplySavedChannels(installed, confPkgs), nil
+	}
+	return configstate.ResolveAndApplyChannels(installed, "")
+}
+
+// syncAction is what sync does with one package.
+type syncAction string
+
+const (
+	syncInstall   syncAction = "install"
+	syncReinstall syncAction = "reinstall"
+	syncRemove    syncAction = "remove"
+	// syncSkip is an unmanaged binary (no --prune) or an entry marked as
+	// removed; either is only reported.
+	syncSkip syncAction = "skip"
+)
+
+// syncStep is one entry of the sync plan.
+type syncStep struct {
+	action syncAction
+	drift  configstate.Drift
+}
+
+// planSync turns the differences between gup.json and the installed binaries
+// into the steps sync takes, in the order the differences were given.
+func planSync(drifts []configstate.Drift, prune bool) []syncStep {
+	steps := make([]syncStep, 0, len(drifts))
+	for _, d := range drifts {
+		action := syncReinstall
+		switch {
+		case d.Has(configstate.DriftMissing):
+			action = syncInstall
+		case d.Has(configstate.DriftRemoved):
+			action = syncSkip
+		case d.Has(configstate.DriftExtra) && prune:
+			action = syncRemove
+		case d.Has(configstate.DriftExtra):
+			action = syncSkip
+		}
+		steps = append(steps, syncStep{action: action, drift: d})
+	}
+	return steps
+}
+
+// hasSyncChanges reports whether applying steps would change anything.
+func hasSyncChanges(steps []syncStep) bool {
+	for _, s := range steps {
+		if s.action != syncSkip {
+			return true
+		}
+	}
+	return false
+}
+
+func printSyncPlan(p *print.Printer, confPath string, steps []syncStep) {
+	if len(steps) == 0 {
+		p.Info("nothing to do: the installed binaries match " + confPath)
+		return
+	}
+	p.Info("sync plan for " + confPath + ":")
+	for _, s := range steps {
+		p.Info(fmt.Sprintf("  %-9s %s (%s)", s.action, s.drift.Name, describeSyncStep(s)))
+	}
+}
+
+// describeSyncStep explains why a step is in the plan.
+func describeSyncStep(s syncStep) string {
+	d := s.drift
+	switch s.action {
+	case syncInstall:
+		want := syncTarget(*d.Want)
+		return fmt.Sprintf("%s@%s", want.ImportPath, driftVersion(&want))
+	case syncRemove:
+		return "not in gup.json"
+	case syncSkip:
+		if d.Has(configstate.DriftRemoved) {
+			return "marked as removed in gup.json"
+		}
+		return "not in gup.json; use --prune to remove"
+	}
+	reasons := []string{}
+	if d.Has(configstate.DriftChannel) {
+		reasons = append(reasons, fmt.Sprintf("channel %s -> %s", driftChannel(d.Have), driftChannel(d.Want)))
+	}
+	if d.Has(configstate.DriftPin) {
+		reasons = append(reasons, fmt.Sprintf("pinned to %s, installed %s", d.Want.PinnedVersion, driftVersion(d.Have)))
+	}
+	if d.Has(configstate.DriftVersion) {
+		reasons = append(reasons, fmt.Sprintf("version %s -> %s", driftVersion(d.Have), driftVersion(d.Want)))
+	}
+	return strings.Join(reasons, ", ")
+}
+
+func driftVersion(p *goutil.Package) string {
+	if p == nil || p.Version == nil || strings.TrimSpace(p.Version.Current) == "" {
+		return "unknown"
+	}
+	return strings.TrimSpace(p.Version.Current)
+}
+
+func driftChannel(p *goutil.Package) goutil.UpdateChannel {
+	if p == nil {
+		return goutil.UpdateChannelLatest
+	}
+	return goutil.NormalizeUpdateChannel(string(p.UpdateChannel))
+}
+
+// applySync installs and reinstalls the gup.json entries in steps, then removes
+// the binaries marked for removal. A failed step makes the exit code 1 but
+// does not stop the others.
+func applySync(p *print.Printer, confReadPath string, steps []syncStep, opts syncOpts) int {
+	exitCode := 0
+
+	installs := []goutil.Package{}
+	wants := []goutil.Package{}
+	channelChanged := []bool{}
+	removals := []string{}
+	for _, s := range steps {
+		switch s.action {
+		case syncInstall, syncReinstall:
+			want := syncTarget(*s.drift.Want)
+			installs = append(installs, want)
+			wants = append(wants, *s.drift.Want)
+			// A tool that was not installed has no saved channel, which
+			// means @latest.
+			channelChanged = append(channelChanged, s.drift.Has(configstate.DriftChannel) ||
+				(s.action == syncInstall && driftChannel(&want) != goutil.UpdateChannelLatest))
+		case syncRemove:
+			removals = append(removals, s.drift.Have.Name)
+		case syncSkip:
+		}
+	}
+
+	if len(installs) > 0 {
+		result, results := executePackages(p, installs, opts.cpus, opts.timeout, dependencies{}, installConfigured, func(prefix string, v updateResult) {
+			p.Info(fmt.Sprintf("%s %s@%s", prefix, v.pkg.ImportPath, v.pkg.Version.Current))
+		})
+		exitCode = max(exitCode, result)
+
+		synced := []goutil.Package{}
+		for i, r := range results {
+			if r.err == nil && channelChanged[i] {
+				synced = append(synced, wants[i])
+			}
+		}
+		if err := recordSyncedChannels(confReadPath, synced); err != nil {
+			p.Err(err)
+			exitCode = 1
+		}
+	}
+
+	if len(removals) > 0 {
+		gobin, err := goutil.GoBin()
+		if err != nil {
+			p.Err(err)
+			return 1
+		}
+		exitCode = max(exitCode, removeLoop(p, gobin, opts.force, removals))
+	}
+	return exitCode
+}
+
+// syncTarget returns want with the version sync installs it at: an entry on
+// the main or master channel with no concrete version is installed from its
+// branch instead of @latest.
+func syncTarget(want goutil.Package) goutil.Package {
+	if configstate.ConcreteVersion(want) != "" {
+		return want
+	}
+	switch channel := driftChannel(&want); channel {
+	case goutil.UpdateChannelMain, goutil.UpdateChannelMaster:
+		want.Version = &goutil.Version{Current: string(channel)}
+	}
+	return want
+}
+
+// recordSyncedChannels saves the channels and pins of the packages synced from
+// a gup.json other than gup's own into gup's own gup.json, so the next sync
+// sees no channel difference and 'gup update' keeps the synced channels.
+func recordSyncedChannels(confReadPath string, synced []goutil.Package) error {
+	if len(synced) == 0 {
+		return nil
+	}
+	statePath, err := config.ResolveImportFilePath("")
+	if err != nil {
+		return err
+	}
+	if sameFilePath(statePath, confReadPath) {
+		return nil
+	}
+	statePkgs, err := configstate.ReadFileIfExists(statePath)
+	if err != nil {
+		return err
+	}
+	merged := configstate.MergePackages(statePkgs, synced, nil, nil)
+	return writeConfigFile(configstate.ResolveWritePath("", statePath), merged)
+}
+
+// sameFilePath reports whether a and b name the same file path once made
+// absolute.
+func sameFilePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
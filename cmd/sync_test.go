@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/configstate"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+)
+
+// syncTestConf is the gup.json the sync tests converge to.
+const syncTestConf = `{"schema_version":2,"packages":[
+{"name":"missing","import_path":"example.com/missing","version":"v1.0.0","channel":"latest"},
+{"name":"old","import_path":"example.com/old","version":"v1.2.0","channel":"latest"},
+{"name":"held","import_path":"example.com/held","version":"v0.9.0","channel":"pinned"},
+{"name":"tracked","import_path":"example.com/tracked","version":"latest","channel":"main"},
+{"name":"same","import_path":"example.com/same","version":"v3.0.0","channel":"latest"},
+{"name":"gone","import_path":"example.com/gone","version":"v1.0.0","channel":"latest","removed":true}]}
+`
+
+// syncTestInstalled is what is installed before sync runs.
+func syncTestInstalled() []goutil.Package {
+	return []goutil.Package{
+		{Name: "old", ImportPath: "example.com/old", Version: &goutil.Version{Current: "v1.3.0"}},
+		{Name: "held", ImportPath: "example.com/held", Version: &goutil.Version{Current: "v1.0.0"}},
+		{Name: "tracked", ImportPath: "example.com/tracked", Version: &goutil.Version{Current: "v1.0.0"}},
+		{Name: "same", ImportPath: "example.com/same", Version: &goutil.Version{Current: "v3.0.0"}},
+		{Name: "extra", ImportPath: "example.com/extra", Version: &goutil.Version{Current: "v1.0.0"}},
+	}
+}
+
+func stubInstalledPackageInfo(t *testing.T, pkgs []goutil.Package) {
+	t.Helper()
+	orig := installedPackageInfo
+	installedPackageInfo = func(*print.Printer) ([]goutil.Package, error) { return pkgs, nil }
+	t.Cleanup(func() { installedPackageInfo = orig })
+}
+
+// recordInstalls swaps installByVersionCtx for a stub that records every
+// "path@version" it is asked to install.
+func recordInstalls(t *testing.T) func() []string {
+	t.Helper()
+	orig := installByVersionCtx
+	t.Cleanup(func() { installByVersionCtx = orig })
+
+	var mu sync.Mutex
+	calls := []string{}
+	installByVersionCtx = func(_ context.Context, importPath, version string, _ goutil.InstallOptions) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, importPath+"@"+version)
+		return nil
+	}
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		sort.Strings(calls)
+		return calls
+	}
+}
+
+func Test_planSync(t *testing.T) {
+	t.Parallel()
+
+	want := &goutil.Package{Name: "a"}
+	have := &goutil.Package{Name: "a"}
+	drifts := []configstate.Drift{
+		{Name: "a", Kinds: []configstate.DriftKind{configstate.DriftMissing}, Want: want},
+		{Name: "b", Kinds: []configstate.DriftKind{configstate.DriftChannel, configstate.DriftPin}, Want: want, Have: have},
+		{Name: "c", Kinds: []configstate.DriftKind{configstate.DriftExtra}, Have: have},
+		{Name: "d", Kinds: []configstate.DriftKind{configstate.DriftRemoved}, Want: want},
+	}
+
+	actions := func(steps []syncStep) []syncAction {
+		got := []syncAction{}
+		for _, s := range steps {
+			got = append(got, s.action)
+		}
+		return got
+	}
+	if diff := cmp.Diff([]syncAction{syncInstall, syncReinstall, syncSkip, syncSkip}, actions(planSync(drifts, false))); diff != "" {
+		t.Errorf("planSync() without prune mismatch (-want +got):\n%s", diff)
+	}
+	pruned := planSync(drifts, true)
+	if diff := cmp.Diff([]syncAction{syncInstall, syncReinstall, syncRemove, syncSkip}, actions(pruned)); diff != "" {
+		t.Errorf("planSync() with prune mismatch (-want +got):\n%s", diff)
+	}
+	if hasSyncChanges(planSync(drifts[2:], false)) {
+		t.Error("a plan that only reports unmanaged binaries has no changes")
+	}
+	if hasSyncChanges(planSync(drifts[3:], true)) {
+		t.Error("a plan that only reports entries marked as removed has no changes")
+	}
+}
+
+func Test_runSync_forceRequiresPrune(t *testing.T) {
+	t.Parallel()
+
+	cmd := newSyncCmd()
+	if err := cmd.Flags().Set("force", "true"); err != nil {
+		t.Fatal(err)
+	}
+	p, buf := newTestPrinter()
+	if got := runSync(p, cmd, nil); got != 1 {
+		t.Fatalf("runSync() = %d, want 1", got)
+	}
+	if !strings.Contains(buf.String(), "--force requires --prune") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+}
+
+//nolint:paralleltest // swaps package globals and XDG env
+func Test_runSync_dryRunChangesNothing(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+	stubInstalledPackageInfo(t, syncTestInstalled())
+	installs := recordInstalls(t)
+	confPath := filepath.Join(t.TempDir(), "gup.json")
+	if err := os.WriteFile(confPath, []byte(syncTestConf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := newSyncCmd()
+	for name, value := range map[string]string{"file": confPath, "dry-run": "true", "prune": "true"} {
+		if err := cmd.Flags().Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, buf := newTestPrinter()
+	if got := runSync(p, cmd, nil); got != 0 {
+		t.Fatalf("runSync() = %d, want 0; output: %s", got, buf.String())
+	}
+	if len(installs()) != 0 {
+		t.Errorf("dry run installed %v", installs())
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"install   missing (example.com/missing@v1.0.0)",
+		"reinstall old (version v1.3.0 -> v1.2.0)",
+		"reinstall held (channel latest -> pinned, pinned to v0.9.0, installed v1.0.0)",
+		"reinstall tracked (channel latest -> main)",
+		"remove    extra (not in gup.json)",
+		"skip      gone (marked as removed in gup.json)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("plan is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "same") {
+		t.Errorf("an up-to-date tool must not be in the plan:\n%s", out)
+	}
+}
+
+//nolint:paralleltest // swaps package globals, GOBIN and XDG env
+func Test_runSync_appliesPlan(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+	stubInstalledPackageInfo(t, syncTestInstalled())
+	installs := recordInstalls(t)
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	extra := filepath.Join(gobin, "extra")
+	if GOOS == goosWindows {
+		extra += exeSuffix
+	}
+	if err := os.WriteFile(extra, []byte("binary"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	confPath := filepath.Join(t.TempDir(), "gup.json")
+	if err := os.WriteFile(confPath, []byte(syncTestConf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := newSyncCmd()
+	for name, value := range map[string]string{"file": confPath, "prune": "true", "force": "true"} {
+		if err := cmd.Flags().Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, buf := newTestPrinter()
+	if got := runSync(p, cmd, nil); got != 0 {
+		t.Fatalf("runSync() = %d, want 0; output: %s", got, buf.String())
+	}
+
+	want := []string{
+		"example.com/held@v0.9.0",
+		"example.com/missing@v1.0.0",
+		"example.com/old@v1.2.0",
+		"example.com/tracked@main",
+	}
+	if diff := cmp.Diff(want, installs()); diff != "" {
+		t.Errorf("installs mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := os.Stat(extra); !os.IsNotExist(err) {
+		t.Errorf("the unmanaged binary should be removed, stat error = %v", err)
+	}
+
+	// The synced channels are saved in gup's own gup.json, so a second sync
+	// against the same file sees no channel difference.
+	state, err := config.ReadConfFile(config.FilePath())
+	if err != nil {
+		t.Fatalf("gup's own gup.json was not written: %v", err)
+	}
+	channels := map[string]goutil.UpdateChannel{}
+	for _, pkg := range state {
+		channels[pkg.Name] = pkg.UpdateChannel
+	}
+	if channels["held"] != goutil.UpdateChannelPinned || channels["tracked"] != goutil.UpdateChannelMain {
+		t.Errorf("saved channels = %v", channels)
+	}
+}
+
+//nolint:paralleltest // swaps package globals and XDG env
+func Test_runSync_nothingToDo(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+	stubInstalledPackageInfo(t, []goutil.Package{
+		{Name: "same", ImportPath: "example.com/same", Version: &goutil.Version{Current: "v3.0.0"}},
+	})
+	installs := recordInstalls(t)
+	if err := os.MkdirAll(filepath.Dir(config.FilePath()), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	conf := `{"schema_version":1,"packages":[{"name":"same","import_path":"example.com/same","version":"v3.0.0","channel":"latest"}]}`
+	if err := os.WriteFile(config.FilePath(), []byte(conf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, buf := newTestPrinter()
+	if got := runSync(p, newSyncCmd(), nil); got != 0 {
+		t.Fatalf("runSync() = %d, want 0; output: %s", got, buf.String())
+	}
+	if len(installs()) != 0 || !strings.Contains(buf.String(), "nothing to do") {
+		t.Errorf("installs = %v, output: %s", installs(), buf.String())
+	}
+}
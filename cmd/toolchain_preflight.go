@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/goproxy"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// toolchainCheck is what check learned from a candidate version's go.mod
+// about the Go toolchain it needs. It is also the "toolchain" field of the
+// 'check --json' record.
+type toolchainCheck struct {
+	// Go is the candidate's go directive, e.g. "1.24.0".
+	Go string `json:"go"`
+	// Toolchain is the candidate's toolchain directive, e.g. "go1.24.2".
+	Toolchain string `json:"toolchain,omitempty"`
+	// LocalGo is the installed Go toolchain that 'gup update' would build with.
+	LocalGo string `json:"local_go"`
+	// GoToolchain is the effective GOTOOLCHAIN setting.
+	GoToolchain string `json:"go_toolchain,omitempty"`
+	// NeedsNewerGo reports that LocalGo is older than the go directive.
+	NeedsNewerGo bool `json:"needs_newer_go"`
+	// AutoSwitch reports that GOTOOLCHAIN=auto would let the go command
+	// download the Go the candidate needs.
+	AutoSwitch bool `json:"auto_switch"`
+}
+
+// blocksUpdate reports whether the update is doomed: the local Go is too old
+// and the GOTOOLCHAIN setting will not download a newer one.
+func (tc *toolchainCheck) blocksUpdate() bool {
+	return tc != nil && tc.NeedsNewerGo && !(tc.AutoSwitch && goutil.AutoToolchainEnabled(tc.GoToolchain))
+}
+
+// note describes a toolchain requirement worth reading, or returns "" when the
+// local Go can build the candidate.
+func (tc *toolchainCheck) note() string {
+	if tc == nil || !tc.NeedsNewerGo {
+		return ""
+	}
+	need := "go" + tc.Go
+	_, err := goutil.ParseGoToolchain(tc.GoToolchain)
+	pinnedGo := err == nil // GOTOOLCHAIN names one release, e.g. from gup.json's go_toolchain
+	switch {
+	case tc.blocksUpdate() && pinnedGo:
+		return fmt.Sprintf("needs %s, but GOTOOLCHAIN=%s selects an older Go; choose a newer one with 'gup update --go'", need, tc.GoToolchain)
+	case tc.blocksUpdate() && tc.AutoSwitch:
+		return fmt.Sprintf("needs %s, but the installed Go is %s (GOTOOLCHAIN=%s); GOTOOLCHAIN=auto would download it", need, tc.LocalGo, tc.GoToolchain)
+	case tc.blocksUpdate():
+		return fmt.Sprintf("needs %s, but the installed Go is %s; install a newer Go", need, tc.LocalGo)
+	default:
+		return fmt.Sprintf("needs %s; GOTOOLCHAIN=%s will download it", need, tc.GoToolchain)
+	}
+}
+
+// toolchainPreflight checks candidate versions' go.mod against the installed
+// Go before anything is built. It is best-effort: with no module proxy to
+// query, it checks nothing.
+type toolchainPreflight struct {
+	proxy       moduleProxy
+	goToolchain string
+}
+
+// newToolchainPreflight looks up the module proxy and GOTOOLCHAIN once for a
+// whole check run.
+func newToolchainPreflight(deps dependencies, timeout time.Duration) toolchainPreflight {
+	ctx, stop := newBoundedSignalContext(context.Background(), timeout)
+	defer stop()
+
+	var tp toolchainPreflight
+	proxy, err := deps.moduleProxy(ctx)
+	if err != nil {
+		return tp
+	}
+	tp.proxy = proxy
+	if env, err := deps.goEnv(ctx, "GOTOOLCHAIN"); err == nil {
+		tp.goToolchain = env["GOTOOLCHAIN"]
+	}
+	return tp
+}
+
+// check fetches the go.mod of p's candidate version (p.Version.Latest) and
+// compares it with the Go that would build it (p.GoVersion.Latest). It returns nil, nil
+// when there is nothing to check: no proxy, a private module, a go.mod the
+// proxy does not have, an unknown installed Go, or no version change.
+func (tp toolchainPreflight) check(ctx context.Context, p goutil.Package) (*toolchainCheck, error) {
+	if tp.proxy == nil || p.ModulePath == "" || p.Version == nil || p.GoVersion == nil ||
+		p.Version.Current == p.Version.Latest {
+		return nil, nil
+	}
+	localGo := strings.TrimSpace(p.GoVersion.Latest)
+	if localGo == "" || localGo == unknownVersion {
+		return nil, nil
+	}
+
+	raw, err := tp.proxy.GoMod(ctx, p.ModulePath, p.Version.Latest)
+	if err != nil {
+		if errors.Is(err, goproxy.ErrNoProxy) || errors.Is(err, goproxy.ErrPrivateModule) || errors.Is(err, goproxy.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("can't fetch go.mod of %s@%s: %w", p.ModulePath, p.Version.Latest, err)
+	}
+	mf, err := goproxy.ParseGoMod(raw)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse go.mod of %s@%s: %w", p.ModulePath, p.Version.Latest, err)
+	}
+	if mf.Go == "" {
+		return nil, nil
+	}
+	// A package with a Go toolchain saved in gup.json is built with exactly
+	// that release (p.GoVersion.Latest), which GOTOOLCHAIN then names.
+	goToolchain := tp.goToolchain
+	if p.GoToolchain != "" {
+		goToolchain = p.GoToolchain
+	}
+
+	return &toolchainCheck{
+		Go:           mf.Go,
+		Toolchain:    mf.Toolchain,
+		LocalGo:      localGo,
+		GoToolchain:  goToolchain,
+		NeedsNewerGo: !goutil.GoVersionUpToDate(localGo, "go"+mf.Go),
+		AutoSwitch:   goutil.CanSwitchToolchain(localGo),
+	}, nil
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+)
+
+// preflightDeps returns dependencies whose proxy serves a go.mod for
+// example.com/<name>@v9.9.9 declaring goDirective, and whose GOTOOLCHAIN is
+// gotoolchain. Every package is outdated (latest v9.9.9).
+func preflightDeps(goDirective, gotoolchain string) dependencies {
+	deps := stubUpdateDeps()
+	deps.moduleProxy = func(context.Context) (moduleProxy, error) {
+		return fakeProxy{mods: map[string]string{
+			"example.com/tool@" + testVersionNine: "module example.com/tool\n\ngo " + goDirective + "\n\ntoolchain go1.99.2\n",
+		}}, nil
+	}
+	deps.goEnv = func(context.Context, ...string) (map[string]string, error) {
+		return map[string]string{"GOTOOLCHAIN": gotoolchain}, nil
+	}
+	return deps
+}
+
+func Test_toolchainPreflight_check(t *testing.T) {
+	t.Parallel()
+
+	pkg := newCheckPkg("tool", testVersionOne, goutil.UpdateChannelLatest)
+	pkg.Version.Latest = testVersionNine
+
+	tests := []struct {
+		name        string
+		goDirective string
+		gotoolchain string
+		want        *toolchainCheck
+		blocks      bool
+	}{
+		{
+			name: "local Go is new enough", goDirective: "1.22.0", gotoolchain: "local",
+			want: &toolchainCheck{Go: "1.22.0", Toolchain: "go1.99.2", LocalGo: testGoVersion1224, GoToolchain: "local", AutoSwitch: true},
+		},
+		{
+			name: "local Go too old, no switching", goDirective: "1.99.0", gotoolchain: "local",
+			want: &toolchainCheck{
+				Go: "1.99.0", Toolchain: "go1.99.2", LocalGo: testGoVersion1224, GoToolchain: "local",
+				NeedsNewerGo: true, AutoSwitch: true,
+			},
+			blocks: true,
+		},
+		{
+			name: "local Go too old, GOTOOLCHAIN=auto downloads it", goDirective: "1.99.0", gotoolchain: "auto",
+			want: &toolchainCheck{
+				Go: "1.99.0", Toolchain: "go1.99.2", LocalGo: testGoVersion1224, GoToolchain: "auto",
+				NeedsNewerGo: true, AutoSwitch: true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tp := newToolchainPreflight(preflightDeps(tt.goDirective, tt.gotoolchain), time.Minute)
+			got, err := tp.check(context.Background(), pkg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("check() mismatch (-want +got):\n%s", diff)
+			}
+			if got.blocksUpdate() != tt.blocks {
+				t.Errorf("blocksUpdate() = %v, want %v", got.blocksUpdate(), tt.blocks)
+			}
+		})
+	}
+}
+
+func Test_toolchainPreflight_selectedToolchain(t *testing.T) {
+	t.Parallel()
+
+	// gup.json selects go1.23.0 for the tool; GOTOOLCHAIN=go1.23.0 then
+	// overrides the environment's auto, so no newer Go is downloaded.
+	pkg := newCheckPkg("tool", testVersionOne, goutil.UpdateChannelLatest)
+	pkg.Version.Latest = testVersionNine
+	pkg.GoToolchain = "go1.23.0"
+	pkg.GoVersion.Latest = "go1.23.0"
+
+	tp := newToolchainPreflight(preflightDeps("1.99.0", "auto"), time.Minute)
+	got, err := tp.check(context.Background(), pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LocalGo != "go1.23.0" || got.GoToolchain != "go1.23.0" || !got.blocksUpdate() {
+		t.Errorf("check() = %+v, want go1.23.0 selected and blocking", got)
+	}
+	want := "needs go1.99.0, but GOTOOLCHAIN=go1.23.0 selects an older Go; choose a newer one with 'gup update --go'"
+	if got.note() != want {
+		t.Errorf("note() = %q, want %q", got.note(), want)
+	}
+}
+
+func Test_toolchainPreflight_nothingToCheck(t *testing.T) {
+	t.Parallel()
+
+	pkg := newCheckPkg("other", testVersionOne, goutil.UpdateChannelLatest)
+	pkg.Version.Latest = testVersionNine
+	tp := newToolchainPreflight(preflightDeps("1.99.0", "local"), time.Minute)
+	if got, err := tp.check(context.Background(), pkg); got != nil || err != nil {
+		t.Errorf("check() of a go.mod the proxy lacks = %+v, %v; want nil, nil", got, err)
+	}
+
+	deps := testDeps()
+	deps.moduleProxy = func(context.Context) (moduleProxy, error) { return nil, errors.New("no go command") }
+	if got, err := newToolchainPreflight(deps, time.Minute).check(context.Background(), pkg); got != nil || err != nil {
+		t.Errorf("check() without a proxy = %+v, %v; want nil, nil", got, err)
+	}
+}
+
+func Test_doCheck_needsNewerGo(t *testing.T) {
+	t.Parallel()
+
+	pkgs := []goutil.Package{newCheckPkg("tool", testVersionOne, goutil.UpdateChannelLatest)}
+	deps := preflightDeps("1.99.0", "local")
+
+	recs := readJSON(t, func(p *print.Printer) int {
+		return doCheckJSON(deps, p, pkgs, 1, 0, true)
+	})
+	if len(recs) != 1 || recs[0].Status != statusNeedsNewerGo {
+		t.Fatalf("records = %+v, want one %q record", recs, statusNeedsNewerGo)
+	}
+	if tc := recs[0].Toolchain; tc == nil || tc.Go != "1.99.0" || tc.Toolchain != "go1.99.2" {
+		t.Errorf("toolchain = %+v, want the candidate's go and toolchain directives", recs[0].Toolchain)
+	}
+
+	out := captureCheckOutput(t, func(p *print.Printer) int {
+		return doCheck(deps, p, pkgs, 1, 0, true, true)
+	})
+	for _, want := range []string{
+		"tool needs go1.99.0, but the installed Go is " + testGoVersion1224 + " (GOTOOLCHAIN=local); GOTOOLCHAIN=auto would download it",
+		"1 need a newer Go",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "$ gup update tool") {
+		t.Errorf("an update the installed Go can't build must not be suggested:\n%s", out)
+	}
+}
@@ -27,12 +27,32 @@ func newUpdateCmd() *cobra.Command {
 		Short: "Update binaries installed by 'go install'",
 		Example: `  gup update
   gup update --dry-run
-  gup update --exclude foo,bar`,
+  gup update --exclude foo,bar
+  gup update --plan plan.json
+  gup update -i
+  gup update --resume`,
 		Long: `Update binaries installed by 'go install'
 
 If you execute '$ gup update', gup gets the package path of all commands
 under $GOPATH/bin and automatically updates commands to the latest version,
-using the current installed Go toolchain.`,
+using the current installed Go toolchain.
+
+With --plan FILE, nothing is installed: every channel and pin is resolved to
+an exact version and the updates are written to FILE for review. Run
+'gup apply FILE' to install exactly those versions.
+
+With -i, the binaries are checked first and listed with their current and
+latest versions, channel, pin and Go toolchain. At the update> prompt, type
+commands to pick what to update, change a channel or pin a binary, then press
+Enter to update the selection. The choices can be saved to gup.json, either
+holding every skipped update at the installed version or excluding those
+binaries from later updates. Binaries excluded in gup.json are skipped unless
+named, and -i lists them unselected.
+
+While an update runs, its progress is kept in gup's state directory. When it
+is interrupted (Ctrl-C, a timeout, a crash) or some binaries fail, --resume
+continues with the binaries that are not done, at the versions the
+interrupted run resolved for them.`,
 		Run: func(cmd *cobra.Command, args []string) {
 			OsExit(gup(defaultDependencies(), printerFor(cmd), cmd, args))
 		},
@@ -51,12 +71,25 @@ using the current installed Go toolchain.`,
 	// cmd.Flags().BoolP("main-all", "M", false, "update all binaries by @main or @master (delimiter: ',')")
 	cmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "specify the number of CPU cores to use")
 	mustRegisterFlagCompletion(cmd, "jobs", completeNCPUs)
+	cmd.Flags().Int("lookup-jobs", 0, "number of version lookups to run at once (default: --jobs)")
+	mustRegisterFlagCompletion(cmd, "lookup-jobs", completeNCPUs)
+	cmd.Flags().Int("build-jobs", 0, "number of go install builds to run at once, each given its share of CPUs and memory (default: --jobs)")
+	mustRegisterFlagCompletion(cmd, "build-jobs", completeNCPUs)
 	cmd.Flags().Bool("ignore-go-update", false, "ignore updates to the Go toolchain")
 	cmd.Flags().Bool("json", false, "output result as machine-readable JSON")
+	addJSONFormatFlag(cmd, "JSON layout: v1 is the bare --json array, v2 wraps it with a summary, timings and the environment (implies --json)")
+	addJSONStreamFlag(cmd)
+	addFormatFlag(cmd)
 	cmd.Flags().BoolP("quiet", "q", false, "suppress up-to-date lines; show only updated/failed binaries plus a summary")
+	cmd.Flags().BoolP("interactive", "i", false, "check first, then pick what to update from a list (needs a terminal)")
+	cmd.Flags().Bool("resume", false, "continue an interrupted update with the binaries it did not finish, at the same versions")
 	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to read/write saved update channels")
 	mustMarkFileFlagAsJSON(cmd)
+	cmd.Flags().String("plan", "", "resolve the updates and write them to this plan file for 'gup apply' instead of installing")
+	mustMarkFlagAsJSON(cmd, "plan")
+	addGoToolchainFlag(cmd, "build the updated binaries with this Go release (e.g. 1.22.5) and save it to gup.json; 'local' clears it")
 	addTimeoutFlag(cmd)
+	addRetryFlags(cmd)
 
 	return cmd
 }
@@ -66,15 +99,24 @@ type updateOpts struct {
 	dryRun         bool
 	notify         bool
 	cpus           int // already clamped to >= 1
+	lookupJobs     int // 0 when --lookup-jobs is not given
+	buildJobs      int // 0 when --build-jobs is not given
 	ignoreGoUpdate bool
-	jsonOut        bool
+	jsonOut        bool // also set by --json-format v2 and --format
+	jsonV2         bool
+	jsonStream     bool
+	format         *outputFormat
 	quiet          bool
+	interactive    bool
+	resume         bool
 	timeout        time.Duration
 	excludePkgList []string
 	mainPkgNames   []string
 	masterPkgNames []string
 	latestPkgNames []string
 	confFile       string
+	planFile       string
+	goToolchain    string // "go1.22.5", goutil.LocalToolchain, or "" when --go is not given
 }
 
 // parseUpdateFlags reads every flag of the update command in one place so gup()
@@ -93,15 +135,37 @@ func parseUpdateFlags(cmd *cobra.Command) (updateOpts, error) {
 		return updateOpts{}, err
 	}
 	opts.cpus = clampJobs(opts.cpus)
+	if opts.lookupJobs, err = getFlagInt(cmd, "lookup-jobs"); err != nil {
+		return updateOpts{}, err
+	}
+	if opts.buildJobs, err = getFlagInt(cmd, "build-jobs"); err != nil {
+		return updateOpts{}, err
+	}
+	if opts.lookupJobs < 0 || opts.buildJobs < 0 {
+		return updateOpts{}, errors.New("--lookup-jobs and --build-jobs must be >= 0 (0 means --jobs)")
+	}
 	if opts.ignoreGoUpdate, err = getFlagBool(cmd, "ignore-go-update"); err != nil {
 		return updateOpts{}, err
 	}
 	if opts.jsonOut, err = getFlagBool(cmd, "json"); err != nil {
 		return updateOpts{}, err
 	}
+	if opts.jsonV2, err = getJSONFormatV2(cmd); err != nil {
+		return updateOpts{}, err
+	}
+	opts.jsonOut = opts.jsonOut || opts.jsonV2
+	if opts.jsonStream, err = getFlagBool(cmd, jsonStreamFlagName); err != nil {
+		return updateOpts{}, err
+	}
 	if opts.quiet, err = getFlagBool(cmd, "quiet"); err != nil {
 		return updateOpts{}, err
 	}
+	if opts.interactive, err = getFlagBool(cmd, "interactive"); err != nil {
+		return updateOpts{}, err
+	}
+	if opts.resume, err = getFlagBool(cmd, "resume"); err != nil {
+		return updateOpts{}, err
+	}
 	if opts.timeout, err = getTimeoutFlag(cmd); err != nil {
 		return updateOpts{}, err
 	}
@@ -120,6 +184,35 @@ func parseUpdateFlags(cmd *cobra.Command) (updateOpts, error) {
 	if opts.confFile, err = getFlagString(cmd, "file"); err != nil {
 		return updateOpts{}, err
 	}
+	if opts.planFile, err = getFlagString(cmd, "plan"); err != nil {
+		return updateOpts{}, err
+	}
+	if opts.goToolchain, err = getGoToolchainFlag(cmd); err != nil {
+		return updateOpts{}, err
+	}
+	if opts.format, err = getFormatFlag(cmd, checkFormatFields); err != nil {
+		return updateOpts{}, err
+	}
+	if opts.jsonOut && opts.jsonStream {
+		return updateOpts{}, errors.New("--json and --json-format v2 can't be used with --json-stream")
+	}
+	if opts.format != nil && (opts.jsonOut || opts.jsonStream) {
+		return updateOpts{}, errors.New("--format can't be used with --json, --json-format v2 or --json-stream")
+	}
+	if opts.planFile != "" && (opts.dryRun || opts.jsonOut || opts.jsonStream || opts.format != nil) {
+		return updateOpts{}, errors.New("--plan can't be used with --dry-run, --json, --format or --json-stream")
+	}
+	opts.jsonOut = opts.jsonOut || opts.format != nil
+	if opts.interactive && (opts.jsonOut || opts.jsonStream || opts.format != nil || opts.planFile != "") {
+		return updateOpts{}, errors.New("-i can't be used with --json, --format, --json-stream or --plan")
+	}
+	if opts.resume && (opts.interactive || opts.dryRun || opts.planFile != "" || opts.goToolchain != "" || len(opts.excludePkgList) != 0 ||
+		len(opts.mainPkgNames) != 0 || len(opts.masterPkgNames) != 0 || len(opts.latestPkgNames) != 0) {
+		return updateOpts{}, errors.New("--resume continues the interrupted update as it was: it can't be used with -i, --dry-run, --plan, --go, --exclude, --main, --master or --latest")
+	}
+	if opts.planFile != "" && opts.goToolchain != "" {
+		return updateOpts{}, errors.New("--plan can't be used with --go: save the Go toolchain with 'gup update --go' first")
+	}
 	return opts, nil
 }
 
@@ -140,6 +233,34 @@ func gup(deps dependencies, p *print.Printer, cmd *cobra.Command, args []string)
 		p.Err(err)
 		return 1
 	}
+	if deps.retry, err = getRetryPolicy(cmd); err != nil {
+		p.Err(err)
+		return 1
+	}
+	if opts.interactive && !stdinIsTerminal() {
+		p.Err(errors.New("gup update -i needs a terminal, but stdin is not a TTY"))
+		return 1
+	}
+	autoExport, err := autoExportEnabled()
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	report, p := newJSONReport(p, "update", opts.jsonStream, opts.jsonV2, opts.confFile)
+	if opts.format != nil {
+		report = &jsonReport{format: opts.format}
+	}
+	pool, lookupJobs, buildJobs, hints := jobLimits(opts.cpus, opts.lookupJobs, opts.buildJobs, runtime.NumCPU(), memoryBudget())
+	opts.cpus = pool
+	deps = deps.limitJobs(pool, lookupJobs, buildJobs, hints)
+	deps.builds = loadBuildTimes(p, buildHistoryPath())
+	if opts.resume {
+		if len(args) != 0 {
+			p.Err(errors.New("--resume continues the interrupted update as it was: it takes no binary names"))
+			return 1
+		}
+		return resumeUpdate(commandContext(cmd), deps, p, opts, report, autoExport)
+	}
 
 	pkgs, missingTargets, goVersionAvailable, err := pkgselect.PackageInfoByTargets(p, args)
 	if err != nil {
@@ -160,14 +281,17 @@ func gup(deps dependencies, p *print.Printer, cmd *cobra.Command, args []string)
 		excludeNotify = func(msg string) { p.Info(msg) }
 	}
 	pkgs = pkgselect.Exclude(pkgs, opts.excludePkgList, excludeNotify)
+	// Only a run over every installed binary leaves the whole tool set up to
+	// date, which is what the time of the last update tells.
+	fullRun := len(args) == 0 && len(opts.excludePkgList) == 0
 
 	if len(pkgs) == 0 {
 		// With explicit targets or --exclude, an empty result means the user
 		// narrowed everything out: that is a usage error. Otherwise it is a normal
 		// first-run condition handled the same way as check.
-		return handleEmptyEnvironment(p, opts.confFile, opts.jsonOut,
+		return handleEmptyEnvironmentReport(p, opts.confFile, opts.jsonOut,
 			len(args) != 0 || len(opts.excludePkgList) != 0,
-			"unable to update package: no package information or no package under $GOBIN")
+			"unable to update package: no package information or no package under $GOBIN", report)
 	}
 
 	// When both the user-level config and ./gup.json exist and no --file is
@@ -188,6 +312,23 @@ func gup(deps dependencies, p *print.Printer, cmd *cobra.Command, args []string)
 		p.Err(err)
 		return 1
 	}
+	// Binaries excluded in gup.json are left out like --exclude, unless they
+	// are named. -i lists them unselected instead, so they can be picked.
+	excludedNames := configstate.ExcludedNames(confPkgs)
+	if len(args) == 0 && !opts.interactive {
+		pkgs = pkgselect.Exclude(pkgs, excludedNames, excludeNotify)
+		if len(pkgs) == 0 {
+			if opts.jsonOut {
+				if err := encodeJSONResults(p, report, nil, nil); err != nil {
+					p.Err(err)
+					return 1
+				}
+				return 0
+			}
+			p.Info("every binary is excluded in " + confReadPath + ": name one to update it")
+			return 0
+		}
+	}
 
 	// missingTargets were already reported as "not found ... in $GOBIN" above;
 	// pass them so ResolveChannels does not emit a second, redundant notice for a
@@ -198,10 +339,68 @@ func gup(deps dependencies, p *print.Printer, cmd *cobra.Command, args []string)
 		p.Err(err)
 		return 1
 	}
+	// Each package is built with the Go toolchain saved for it in gup.json,
+	// unless --go selects another one for this run (and saves it).
+	pkgs = configstate.ApplyGoToolchains(pkgs, confPkgs, opts.goToolchain)
+
+	if opts.planFile != "" {
+		return writeUpdatePlan(deps, p, pkgs, opts, ignoreGoUpdate, channelMap, pinnedMap)
+	}
+
+	// -i narrows pkgs to the selection and may change channels and pins for
+	// this run; they are persisted only when the user saves them.
+	runChannels, runPins := channelMap, pinnedMap
+	saveChoices := false
+	if opts.interactive {
+		sel, ok, err := chooseUpdates(deps, p, cmd.InOrStdin(), pkgs, channelMap, pinnedMap, excludedNames, opts, ignoreGoUpdate)
+		if err != nil {
+			p.Err(err)
+			return 1
+		}
+		if !ok {
+			p.Info("nothing updated")
+			return 0
+		}
+		pkgs, runChannels, runPins = sel.pkgs, sel.channels, sel.pins
+		fullRun = fullRun && !sel.partial
+		switch {
+		case sel.save && opts.dryRun:
+			p.Warn("a dry run does not save the choices")
+		case sel.save:
+			for _, v := range sel.pinned {
+				if confPkgs, err = configstate.SetPin(confPkgs, v, v.PinnedVersion); err != nil {
+					p.Err(err)
+					return 1
+				}
+			}
+			confPkgs = configstate.SetExcluded(confPkgs, sel.excluded, true)
+			confPkgs = configstate.SetExcluded(confPkgs, sel.included, false)
+			channelMap, saveChoices = sel.channels, true
+		}
+		if len(pkgs) == 0 {
+			p.Info("no binary selected")
+			if saveChoices {
+				return writeUpdateChoices(p, confWritePath, configstate.MergePackages(confPkgs, nil, channelMap, nil))
+			}
+			return 0
+		}
+	}
 
-	result, succeededPkgs, renamedPkgs := updateWithChannels(deps, p, pkgs, opts.dryRun, opts.notify, opts.cpus, ignoreGoUpdate, channelMap, pinnedMap, opts.timeout, opts.jsonOut, opts.quiet)
+	var run *updateRun
+	if !opts.dryRun {
+		run = startUpdateRun(p, pkgs, runChannels, runPins,
+			configstate.ShouldPersistChannels(opts.mainPkgNames, opts.masterPkgNames, opts.latestPkgNames) || saveChoices, fullRun)
+	}
+	result, succeededPkgs, renamedPkgs := updateWithRun(commandContext(cmd), deps, p, pkgs, opts.dryRun, opts.notify, opts.cpus, ignoreGoUpdate, runChannels, runPins, opts.timeout, opts.jsonOut, opts.quiet, report, run)
+	run.finish(p)
+	if !opts.dryRun && result == 0 && fullRun {
+		if err := recordLastUpdate(time.Now()); err != nil {
+			p.Warn("failed to record the time of this update: " + err.Error())
+		}
+	}
 
-	if !opts.dryRun && (configstate.ShouldPersistChannels(opts.mainPkgNames, opts.masterPkgNames, opts.latestPkgNames) || len(renamedPkgs) > 0) {
+	if !opts.dryRun && (configstate.ShouldPersistChannels(opts.mainPkgNames, opts.masterPkgNames, opts.latestPkgNames) ||
+		len(renamedPkgs) > 0 || opts.goToolchain != "" || autoExport || saveChoices) {
 		merged := configstate.MergePackages(confPkgs, succeededPkgs, channelMap, renamedPkgs)
 		if err := writeConfigFile(confWritePath, merged); err != nil {
 			p.Warn("failed to write " + confWritePath + ": " + err.Error())
@@ -211,20 +410,38 @@ func gup(deps dependencies, p *print.Printer, cmd *cobra.Command, args []string)
 	return result
 }
 
+// writeUpdateChoices writes the choices saved in 'update -i' when nothing was
+// selected to update.
+func writeUpdateChoices(p *print.Printer, path string, pkgs []goutil.Package) int {
+	if err := writeConfigFile(path, pkgs); err != nil {
+		p.Err(err)
+		return 1
+	}
+	p.Info("saved the choices to " + path)
+	return 0
+}
+
 type updateResult struct {
 	updated     bool
 	pkg         goutil.Package
 	err         error
-	renamedFrom string // original binary name if renamed during update
-	skipped     bool   // true when the package was intentionally skipped (no error)
-	skipReason  string // human-readable reason when skipped is true
-	status      string // machine-readable status for --json output (see jsonout.go)
+	renamedFrom string          // original binary name if renamed during update
+	skipped     bool            // true when the package was intentionally skipped (no error)
+	skipReason  string          // human-readable reason when skipped is true
+	status      string          // machine-readable status for --json output (see jsonout.go)
+	toolchain   *toolchainCheck // check's go.mod pre-flight of the candidate version, if any
+	elapsed     time.Duration   // how long the worker took; set by executePackages
+	attempts    int             // 1 plus the network retries the package took; 0 when retrying is off
 }
 
-func updateWithChannels(deps dependencies, pr *print.Printer, pkgs []goutil.Package, dryRun, notification bool, cpus int, ignoreGoUpdate bool, channelMap map[string]goutil.UpdateChannel, pinnedMap map[string]string, timeout time.Duration, jsonOut, quiet bool) (exitCode int, succeeded []goutil.Package, renamed map[string]string) {
-	dryRunManager := goutil.NewGoPaths()
+func updateWithChannels(deps dependencies, pr *print.Printer, pkgs []goutil.Package, dryRun, notification bool, cpus int, ignoreGoUpdate bool, channelMap map[string]goutil.UpdateChannel, pinnedMap map[string]string, timeout time.Duration, jsonOut, quiet bool, report *jsonReport) (exitCode int, succeeded []goutil.Package, renamed map[string]string) {
+	return updateWithRun(context.Background(), deps, pr, pkgs, dryRun, notification, cpus, ignoreGoUpdate, channelMap, pinnedMap, timeout, jsonOut, quiet, report, nil)
+}
 
-	verCache := deps.newVerCache()
+// updateWithRun is updateWithChannels that runs under ctx and also records the
+// run in run, when it is not nil, so 'gup update --resume' can continue it.
+func updateWithRun(ctx context.Context, deps dependencies, pr *print.Printer, pkgs []goutil.Package, dryRun, notification bool, cpus int, ignoreGoUpdate bool, channelMap map[string]goutil.UpdateChannel, pinnedMap map[string]string, timeout time.Duration, jsonOut, quiet bool, report *jsonReport, run *updateRun) (exitCode int, succeeded []goutil.Package, renamed map[string]string) {
+	dryRunManager := goutil.NewGoPaths()
 
 	if !jsonOut && !quiet {
 		pr.Info("update binary under $GOPATH/bin or $GOBIN")
@@ -245,29 +462,65 @@ func updateWithChannels(deps dependencies, pr *print.Printer, pkgs []goutil.Pack
 		}()
 	}
 
-	updater := func(ctx context.Context, p goutil.Package) updateResult {
+	updater := newUpdateWorker(deps, updateWorkerOpts{ignoreGoUpdate: ignoreGoUpdate, jsonOut: jsonOut, channels: channelMap, pins: pinnedMap})
+
+	return runUpdates(ctx, pr, deps, pkgs, cpus, timeout, run.worker(deps.retryWorker(updater)), notification, jsonOut, quiet, report)
+}
+
+// updateWorkerOpts configures the update of one package by newUpdateWorker.
+type updateWorkerOpts struct {
+	ignoreGoUpdate bool
+	jsonOut        bool
+	channels       map[string]goutil.UpdateChannel
+	pins           map[string]string
+	// resolved holds, by binary name, the version to install instead of
+	// looking the channel up: the version an interrupted run resolved.
+	resolved map[string]string
+	// moved names the binaries whose module moved before the version in
+	// resolved was resolved; they may install under a new name.
+	moved map[string]bool
+}
+
+// newUpdateWorker returns the update of one package as 'gup update' does it:
+// resolve the version of its channel (or install its pin), skip it when it is
+// up to date, else install it, following a module that moved and removing the
+// binary it renamed.
+func newUpdateWorker(deps dependencies, opts updateWorkerOpts) pkgWorker {
+	verCache := deps.newVerCache()
+
+	return func(ctx context.Context, deps dependencies, p goutil.Package) updateResult {
 		originalName := p.Name
+		deps.toolchain = p.GoToolchain
 		// Resolve the update channel up front so the skip/update decision is
 		// derived from the version the selected channel would install, not from
 		// @latest. Without this, a package tracked on @main/@master would
 		// piggyback on the @latest lookup and could be skipped or updated
 		// incorrectly (see issue #292).
-		channel := configstate.PackageChannel(p.Name, p.UpdateChannel, channelMap)
+		channel := configstate.PackageChannel(p.Name, p.UpdateChannel, opts.channels)
 		p.UpdateChannel = channel
 
 		// A pinned package is installed at its exact recorded version and never
 		// resolves @latest/@main/@master, so it is handled entirely separately from
 		// the channel-version lookup below.
 		if channel == goutil.UpdateChannelPinned {
-			p.PinnedVersion = pinnedMap[p.Name]
-			return updatePinned(deps, ctx, p, ignoreGoUpdate)
+			p.PinnedVersion = opts.pins[p.Name]
+			return updatePinned(deps, ctx, p, opts.ignoreGoUpdate)
 		}
 
 		// Collect online channel version if possible; else always update
 		shouldUpdate := true
-		modulePathChanged := false
-		if p.ModulePath != "" {
-			ver, err := verCache.Get(ctx, p.ModulePath, channel)
+		modulePathChanged := opts.moved[originalName]
+		resolved, isResolved := opts.resolved[originalName]
+		switch {
+		case isResolved:
+			// Install the version resolved before, without looking the channel up
+			// again. A binary that is not installed any more is installed again.
+			p.Version.Latest = resolved
+			deps.event(eventResolved, p)
+			shouldUpdate = p.Version.Current == "" || modulePathChanged || !p.IsPackageUpToDate() ||
+				(!opts.ignoreGoUpdate && p.GoVersion != nil && !p.IsGoUpToDate())
+		case p.ModulePath != "":
+			ver, err := deps.lookup(ctx, verCache, p.ModulePath, channel)
 			if err != nil {
 				newPkg, changed := resolveModulePathChange(p, err)
 				if !changed {
@@ -280,7 +533,7 @@ func updateWithChannels(deps dependencies, pr *print.Printer, pkgs []goutil.Pack
 				modulePathChanged = true
 				p = newPkg
 
-				ver, err = verCache.Get(ctx, p.ModulePath, channel)
+				ver, err = deps.lookup(ctx, verCache, p.ModulePath, channel)
 				if err != nil {
 					return updateResult{
 						updated: false,
@@ -290,16 +543,17 @@ func updateWithChannels(deps dependencies, pr *print.Printer, pkgs []goutil.Pack
 				}
 			}
 			p.Version.Latest = ver
+			deps.event(eventResolved, p)
 
 			// Check if we should update the package
-			shouldUpdate = modulePathChanged || !p.IsPackageUpToDate() || (!ignoreGoUpdate && !p.IsGoUpToDate())
+			shouldUpdate = modulePathChanged || !p.IsPackageUpToDate() || (!opts.ignoreGoUpdate && !p.IsGoUpToDate())
 		}
 
 		if !shouldUpdate {
 			// Up to date once the ignored Go delta is set aside: hide that delta so
 			// the rendered line reads "Already up-to-date" instead of a phantom
 			// "goX to goY" for a package that is not being reinstalled.
-			hideIgnoredGoDelta(&p, ignoreGoUpdate, jsonOut)
+			hideIgnoredGoDelta(&p, opts.ignoreGoUpdate, opts.jsonOut)
 			return updateResult{
 				updated: false,
 				pkg:     p,
@@ -314,14 +568,21 @@ func updateWithChannels(deps dependencies, pr *print.Printer, pkgs []goutil.Pack
 		if p.ImportPath == "" {
 			updateErr = fmt.Errorf("%s is not installed by 'go install' (or permission incorrect)", p.Name)
 		} else {
-			if err := installWithSelectedVersion(deps, ctx, p.ImportPath, channel); err != nil {
+			deps.event(eventInstalling, p)
+			install := func(importPath string) error {
+				if isResolved {
+					return deps.installVersion(ctx, importPath, resolved)
+				}
+				return installWithSelectedVersion(deps, ctx, importPath, channel)
+			}
+			if err := install(p.ImportPath); err != nil {
 				newPkg, changed := resolveModulePathChange(p, err)
 				if !changed {
 					updateErr = fmt.Errorf("%s: %w", p.Name, err)
 				} else {
 					installedViaRetry = true
 					p = newPkg
-					if retryErr := installWithSelectedVersion(deps, ctx, p.ImportPath, channel); retryErr != nil {
+					if retryErr := install(p.ImportPath); retryErr != nil {
 						updateErr = fmt.Errorf("%s: %w", originalName, retryErr)
 					} else {
 						newName := binaryNameFromImportPath(p.ImportPath)
@@ -332,6 +593,14 @@ func updateWithChannels(deps dependencies, pr *print.Printer, pkgs []goutil.Pack
 						p.UpdateChannel = channel
 					}
 				}
+			} else if modulePathChanged && isResolved {
+				// The module moved before this run, which installed it under its
+				// new path: the binary may have a new name, like above.
+				newName := binaryNameFromImportPath(p.ImportPath)
+				if err := removeOldBinaryIfRenamed(originalName, newName); err != nil {
+					updateErr = fmt.Errorf("%s: %w", originalName, err)
+				}
+				p.Name = newName
 			}
 		}
 
@@ -358,7 +627,12 @@ func updateWithChannels(deps dependencies, pr *print.Printer, pkgs []goutil.Pack
 			status:      status,
 		}
 	}
+}
 
+// runUpdates runs worker, the update of one package, over pkgs under ctx with
+// deps and reports the results as 'gup update' does.
+func runUpdates(ctx context.Context, pr *print.Printer, deps dependencies, pkgs []goutil.Package, cpus int, timeout time.Duration,
+	worker pkgWorker, notification, jsonOut, quiet bool, report *jsonReport) (int, []goutil.Package, map[string]string) {
 	var onResult func(prefix string, v updateResult)
 	if !jsonOut {
 		// In quiet mode show only binaries that were actually updated.
@@ -367,11 +641,16 @@ func updateWithChannels(deps dependencies, pr *print.Printer, pkgs []goutil.Pack
 			updateResultStr)
 	}
 
-	// update all packages
-	result, results := executePackages(pr, pkgs, cpus, timeout, updater, onResult)
+	// update all packages, starting the longest builds first; the results
+	// are put back in the order of pkgs.
+	builds := deps.builds
+	order := builds.order(pkgs)
+	result, results := executePackagesStream(ctx, pr, report.events(), !jsonOut && !quiet, builds, inOrder(pkgs, order), cpus, timeout, deps, builds.worker(worker), onResult)
+	results = fromOrder(results, order)
+	builds.save(pr)
 
 	if jsonOut {
-		if err := encodeJSONPackages(pr, resultsToJSONPackages(results)); err != nil {
+		if err := encodeJSONResults(pr, report, resultsToJSONPackages(results), results); err != nil {
 			pr.Err(err)
 			result = 1
 		}
@@ -450,6 +729,7 @@ func updatePinned(deps dependencies, ctx context.Context, p goutil.Package, igno
 		p.Version = &goutil.Version{}
 	}
 	p.Version.Latest = pinnedVer
+	deps.event(eventResolved, p)
 
 	goOutdated := !ignoreGoUpdate && p.GoVersion != nil && !p.IsGoUpToDate()
 	if p.PinSatisfied() && !goOutdated {
@@ -476,7 +756,8 @@ func updatePinned(deps dependencies, ctx context.Context, p goutil.Package, igno
 		}
 	}
 
-	if err := deps.installByVersion(ctx, p.ImportPath, pinnedVer); err != nil {
+	deps.event(eventInstalling, p)
+	if err := deps.installVersion(ctx, p.ImportPath, pinnedVer); err != nil {
 		return updateResult{
 			updated: false,
 			pkg:     p,
@@ -499,19 +780,24 @@ func updatePinned(deps dependencies, ctx context.Context, p goutil.Package, igno
 }
 
 func installWithSelectedVersion(deps dependencies, ctx context.Context, importPath string, channel goutil.UpdateChannel) error {
+	installLatest := func(ctx context.Context, opts goutil.InstallOptions) error {
+		return deps.installLatest(ctx, importPath, opts)
+	}
 	switch goutil.NormalizeUpdateChannel(string(channel)) {
 	case goutil.UpdateChannelLatest:
-		return deps.installLatest(ctx, importPath)
+		return deps.install(ctx, installLatest)
 	case goutil.UpdateChannelMain:
-		return deps.installMainOrMaster(ctx, importPath)
+		return deps.install(ctx, func(ctx context.Context, opts goutil.InstallOptions) error {
+			return deps.installMainOrMaster(ctx, importPath, opts)
+		})
 	case goutil.UpdateChannelMaster:
-		return deps.installByVersion(ctx, importPath, "master")
+		return deps.installVersion(ctx, importPath, "master")
 	case goutil.UpdateChannelPinned:
 		// Pinned packages are installed via updatePinned, never here; never silently
 		// degrade a pin to @latest.
 		return fmt.Errorf("pinned package %s must be installed at its recorded version, not via channel install", importPath)
 	default:
-		return deps.installLatest(ctx, importPath)
+		return deps.install(ctx, installLatest)
 	}
 }
 
@@ -0,0 +1,320 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nao1215/gup/internal/binname"
+	"github.com/nao1215/gup/internal/configstate"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+)
+
+// updatePickHelp lists the commands of 'gup update -i'.
+const updatePickHelp = `commands:
+  1,3-5           select or deselect these binaries
+  a / n           select all / none
+  c N CHANNEL     update N from latest, main or master
+  p N [VERSION]   pin N to VERSION (default: its installed version)
+  u or Enter      update the selected binaries
+  q               quit without updating
+  ?               show this help`
+
+// updatePick is one binary in the 'update -i' list.
+type updatePick struct {
+	pkg      goutil.Package // as gup update received it
+	result   updateResult   // its check result
+	selected bool
+	channel  goutil.UpdateChannel // channel the update uses
+	pin      string               // pinned version when channel is pinned
+	changed  bool                 // channel or pin was changed in the list
+	excluded bool                 // excluded from 'gup update' in gup.json
+}
+
+// skipsUpdate reports whether the user deselected an update check found.
+func (v updatePick) skipsUpdate() bool {
+	return !v.selected && v.result.err == nil &&
+		(v.result.status == statusUpdateAvailable || v.result.status == statusPinMismatch || v.result.status == statusNeedsNewerGo)
+}
+
+// updateSelection is what the user chose in 'update -i'.
+type updateSelection struct {
+	pkgs []goutil.Package
+	// channels and pins hold the choice for every selected binary, and with
+	// save also the holds of the skipped updates.
+	channels map[string]goutil.UpdateChannel
+	pins     map[string]string
+	// save reports that the choices should be written to gup.json.
+	save bool
+	// pinned are the binaries to pin in gup.json when save is set.
+	pinned []goutil.Package
+	// excluded are the binaries to exclude from 'gup update' in gup.json, and
+	// included the excluded ones that were selected, when save is set.
+	excluded, included []goutil.Package
+	// partial reports that an update the check found was left out.
+	partial bool
+}
+
+// chooseUpdates checks pkgs like 'gup check' and lets the user pick, on in,
+// what to update. The binaries named in excluded start unselected. It returns
+// false when the user quit.
+func chooseUpdates(deps dependencies, p *print.Printer, in io.Reader, pkgs []goutil.Package, channelMap map[string]goutil.UpdateChannel, pinnedMap map[string]string, excluded []string, opts updateOpts, ignoreGoUpdate bool) (updateSelection, bool, error) {
+	isExcluded := make(map[string]bool, len(excluded))
+	for _, name := range excluded {
+		isExcluded[binname.NormalizeForMatch(name)] = true
+	}
+	candidates := make([]goutil.Package, 0, len(pkgs))
+	for _, v := range pkgs {
+		v.UpdateChannel = configstate.PackageChannel(v.Name, v.UpdateChannel, channelMap)
+		v.PinnedVersion = pinnedMap[v.Name]
+		if v.Version != nil {
+			ver := *v.Version
+			v.Version = &ver
+		}
+		candidates = append(candidates, v)
+	}
+	p.Info(fmt.Sprintf("checking %d binaries for updates", len(candidates)))
+	worker := newCheckWorker(deps, p, checkOpts{ignoreGoUpdate: ignoreGoUpdate, timeout: opts.timeout})
+	_, results := executePackages(p, candidates, opts.cpus, opts.timeout, deps, worker, nil)
+
+	picks := make([]updatePick, 0, len(results))
+	for i, v := range results {
+		excluded := isExcluded[binname.NormalizeForMatch(pkgs[i].Name)]
+		picks = append(picks, updatePick{
+			pkg:      pkgs[i],
+			result:   v,
+			selected: !excluded && v.err == nil && (v.status == statusUpdateAvailable || v.status == statusPinMismatch),
+			channel:  candidates[i].UpdateChannel,
+			pin:      candidates[i].PinnedVersion,
+			excluded: excluded,
+		})
+	}
+	return pickUpdates(p, bufio.NewScanner(in), picks)
+}
+
+// pickUpdates runs the 'update -i' prompt over picks.
+func pickUpdates(p *print.Printer, in *bufio.Scanner, picks []updatePick) (updateSelection, bool, error) {
+	printUpdatePicks(p, picks)
+	p.Info(updatePickHelp)
+	for {
+		_, _ = fmt.Fprint(p.Out(), "update> ")
+		if !in.Scan() {
+			if err := in.Err(); err != nil {
+				return updateSelection{}, false, err
+			}
+			return updateSelection{}, false, errors.New("no answer: the input was closed")
+		}
+		line := strings.TrimSpace(in.Text())
+		switch line {
+		case "", "u":
+			return finishUpdatePicks(p, in, picks)
+		case "q":
+			return updateSelection{}, false, nil
+		case "?":
+			p.Info(updatePickHelp)
+			continue
+		case "a", "n":
+			for i := range picks {
+				picks[i].selected = line == "a"
+			}
+		default:
+			if err := applyUpdatePickCommand(picks, line); err != nil {
+				p.Warn(err)
+				continue
+			}
+		}
+		printUpdatePicks(p, picks)
+	}
+}
+
+// applyUpdatePickCommand applies one c, p or selection command to picks.
+func applyUpdatePickCommand(picks []updatePick, line string) error {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case "c":
+		if len(fields) != 3 {
+			return errors.New("usage: c N CHANNEL")
+		}
+		i, err := updatePickIndex(picks, fields[1])
+		if err != nil {
+			return err
+		}
+		channel := goutil.UpdateChannel(fields[2])
+		switch channel {
+		case goutil.UpdateChannelLatest, goutil.UpdateChannelMain, goutil.UpdateChannelMaster:
+		default:
+			return fmt.Errorf("%q is not latest, main or master", fields[2])
+		}
+		picks[i].channel, picks[i].pin = channel, ""
+		picks[i].selected, picks[i].changed = true, true
+		return nil
+	case "p":
+		if len(fields) != 2 && len(fields) != 3 {
+			return errors.New("usage: p N [VERSION]")
+		}
+		i, err := updatePickIndex(picks, fields[1])
+		if err != nil {
+			return err
+		}
+		version := ""
+		if picks[i].pkg.Version != nil {
+			version = picks[i].pkg.Version.Current
+		}
+		if len(fields) == 3 {
+			version = fields[2]
+		}
+		if err := goutil.ValidatePinnedVersion(version); err != nil {
+			return fmt.Errorf("can't pin %s: %w", picks[i].pkg.Name, err)
+		}
+		picks[i].channel, picks[i].pin = goutil.UpdateChannelPinned, version
+		picks[i].selected, picks[i].changed = true, true
+		return nil
+	}
+	var toggle []int
+	for _, part := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' }) {
+		from, to, isRange := strings.Cut(part, "-")
+		if !isRange {
+			to = from
+		}
+		first, err := updatePickIndex(picks, from)
+		if err != nil {
+			return err
+		}
+		last, err := updatePickIndex(picks, to)
+		if err != nil {
+			return err
+		}
+		for i := first; i <= last; i++ {
+			toggle = append(toggle, i)
+		}
+	}
+	for _, i := range toggle {
+		picks[i].selected = !picks[i].selected
+	}
+	return nil
+}
+
+// updatePickIndex parses the 1-based number of a pick.
+func updatePickIndex(picks []updatePick, s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 || n > len(picks) {
+		return 0, fmt.Errorf("%q is not a number from 1 to %d (? for help)", s, len(picks))
+	}
+	return n - 1, nil
+}
+
+// finishUpdatePicks turns picks into the selection, asking first whether to
+// save the choices when there is anything to save.
+func finishUpdatePicks(p *print.Printer, in *bufio.Scanner, picks []updatePick) (updateSelection, bool, error) {
+	sel := updateSelection{
+		channels: map[string]goutil.UpdateChannel{},
+		pins:     map[string]string{},
+	}
+	worthSaving := false
+	for _, v := range picks {
+		worthSaving = worthSaving || v.changed || v.skipsUpdate() || (v.selected && v.excluded)
+		sel.partial = sel.partial || v.skipsUpdate()
+		if !v.selected {
+			continue
+		}
+		sel.pkgs = append(sel.pkgs, v.pkg)
+		sel.channels[v.pkg.Name] = v.channel
+		if v.pin != "" {
+			sel.pins[v.pkg.Name] = v.pin
+		}
+	}
+	if !worthSaving {
+		return sel, true, nil
+	}
+
+	_, _ = fmt.Fprint(p.Out(), "save these choices to gup.json? Unselected updates are (h)eld at the installed version or (e)xcluded from 'gup update' [h/e/N] ")
+	if !in.Scan() {
+		return sel, true, nil
+	}
+	exclude := false
+	switch strings.ToLower(strings.TrimSpace(in.Text())) {
+	case "h", "hold", "y", "yes":
+	case "e", "exclude":
+		exclude = true
+	default:
+		return sel, true, nil
+	}
+	sel.save = true
+	for _, v := range picks {
+		if v.selected && v.excluded {
+			sel.included = append(sel.included, v.pkg)
+		}
+		switch {
+		case v.selected && v.pin != "" && v.changed:
+			sel.pinned = append(sel.pinned, withPin(v.pkg, v.pin))
+		case v.skipsUpdate() && exclude:
+			sel.excluded = append(sel.excluded, v.pkg)
+		case v.skipsUpdate():
+			if v.pkg.Version == nil || goutil.ValidatePinnedVersion(v.pkg.Version.Current) != nil {
+				p.Warn("can't hold " + v.pkg.Name + ": its installed version can't be pinned")
+				continue
+			}
+			sel.pinned = append(sel.pinned, withPin(v.pkg, v.pkg.Version.Current))
+			sel.channels[v.pkg.Name] = goutil.UpdateChannelPinned
+			sel.pins[v.pkg.Name] = v.pkg.Version.Current
+		}
+	}
+	return sel, true, nil
+}
+
+// withPin returns pkg pinned to version.
+func withPin(pkg goutil.Package, version string) goutil.Package {
+	pkg.UpdateChannel, pkg.PinnedVersion = goutil.UpdateChannelPinned, version
+	return pkg
+}
+
+// printUpdatePicks prints the 'update -i' list.
+func printUpdatePicks(p *print.Printer, picks []updatePick) {
+	tw := tabwriter.NewWriter(p.Out(), 0, 0, 2, ' ', 0)
+	for i, v := range picks {
+		mark := "[ ]"
+		if v.selected {
+			mark = "[x]"
+		}
+		channel := string(v.channel)
+		if v.channel == goutil.UpdateChannelPinned {
+			channel = "pinned " + v.pin
+		}
+		if v.excluded {
+			channel += " (excluded)"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n", mark, i+1, v.pkg.Name, updatePickVersions(v.result), channel, updatePickGo(v.result))
+	}
+	_ = tw.Flush()
+}
+
+// updatePickVersions describes the version change a check result found.
+func updatePickVersions(v updateResult) string {
+	if v.err != nil {
+		return "error: " + v.err.Error()
+	}
+	if v.pkg.Version == nil {
+		return ""
+	}
+	cur, latest := v.pkg.Version.Current, v.pkg.Version.Latest
+	switch {
+	case v.status == statusNeedsNewerGo:
+		return cur + " -> " + latest + " (needs a newer Go)"
+	case latest == "" || cur == latest:
+		return cur + " (up to date)"
+	}
+	return cur + " -> " + latest
+}
+
+// updatePickGo describes the Go toolchain change a check result found.
+func updatePickGo(v updateResult) string {
+	if v.err != nil || v.pkg.GoVersion == nil || v.pkg.GoVersion.Latest == "" || v.pkg.GoVersion.Current == v.pkg.GoVersion.Latest {
+		return ""
+	}
+	return v.pkg.GoVersion.Current + " -> " + v.pkg.GoVersion.Latest
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// testUpdatePicks returns three picks: an available update, an up-to-date
+// binary and another available update.
+func testUpdatePicks() []updatePick {
+	pick := func(name, cur, latest, status string) updatePick {
+		pkg := goutil.Package{Name: name, ImportPath: "example.com/" + name, Version: &goutil.Version{Current: cur, Latest: latest}}
+		return updatePick{
+			pkg:      pkg,
+			result:   updateResult{pkg: pkg, status: status},
+			selected: status == statusUpdateAvailable,
+			channel:  goutil.UpdateChannelLatest,
+		}
+	}
+	return []updatePick{
+		pick("air", testVersionOne, testVersionTwo, statusUpdateAvailable),
+		pick("gopls", testVersionTwo, testVersionTwo, statusUpToDate),
+		pick("lazygit", testVersionOne, testVersionTwo, statusUpdateAvailable),
+	}
+}
+
+func Test_pickUpdates(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		wantOK   bool
+		wantPkgs []string
+		wantSave bool
+		want     map[string]goutil.UpdateChannel
+		wantPins map[string]string
+	}{
+		{
+			name:     "accept the preselection",
+			input:    "\n",
+			wantOK:   true,
+			wantPkgs: []string{"air", "lazygit"},
+			want:     map[string]goutil.UpdateChannel{"air": goutil.UpdateChannelLatest, "lazygit": goutil.UpdateChannelLatest},
+			wantPins: map[string]string{},
+		},
+		{
+			name:     "toggle, change a channel and save a hold",
+			input:    "3\nc 2 main\nbogus\nu\ny\n",
+			wantOK:   true,
+			wantPkgs: []string{"air", "gopls"},
+			wantSave: true,
+			want: map[string]goutil.UpdateChannel{
+				"air":     goutil.UpdateChannelLatest,
+				"gopls":   goutil.UpdateChannelMain,
+				"lazygit": goutil.UpdateChannelPinned,
+			},
+			wantPins: map[string]string{"lazygit": testVersionOne},
+		},
+		{
+			name:     "pin inline without saving",
+			input:    "n\np 1 " + testVersionTwo + "\n\nn\n",
+			wantOK:   true,
+			wantPkgs: []string{"air"},
+			want:     map[string]goutil.UpdateChannel{"air": goutil.UpdateChannelPinned},
+			wantPins: map[string]string{"air": testVersionTwo},
+		},
+		{
+			name:  "quit",
+			input: "1-3\nq\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p, out := newTestPrinter()
+			sel, ok, err := pickUpdates(p, bufio.NewScanner(strings.NewReader(tt.input)), testUpdatePicks())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("pickUpdates() ok = %v, want %v; output:\n%s", ok, tt.wantOK, out.String())
+			}
+			if !ok {
+				return
+			}
+			var names []string
+			for _, v := range sel.pkgs {
+				names = append(names, v.Name)
+			}
+			if diff := cmp.Diff(tt.wantPkgs, names); diff != "" {
+				t.Errorf("selected mismatch (-want +got):\n%s", diff)
+			}
+			if sel.save != tt.wantSave {
+				t.Errorf("save = %v, want %v", sel.save, tt.wantSave)
+			}
+			if diff := cmp.Diff(tt.want, sel.channels); diff != "" {
+				t.Errorf("channels mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantPins, sel.pins); diff != "" {
+				t.Errorf("pins mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_pickUpdates_savesExcludes(t *testing.T) {
+	t.Parallel()
+
+	picks := testUpdatePicks()
+	picks[2].selected, picks[2].excluded = false, true
+	p, out := newTestPrinter()
+	sel, ok, err := pickUpdates(p, bufio.NewScanner(strings.NewReader("1,3\n\ne\n")), picks)
+	if err != nil || !ok {
+		t.Fatalf("pickUpdates() = %v, %v; output:\n%s", ok, err, out.String())
+	}
+	if !sel.save || len(sel.pinned) != 0 {
+		t.Errorf("save = %v, pinned = %v; want saved without holds", sel.save, sel.pinned)
+	}
+	names := func(pkgs []goutil.Package) []string {
+		var s []string
+		for _, v := range pkgs {
+			s = append(s, v.Name)
+		}
+		return s
+	}
+	if diff := cmp.Diff([]string{"lazygit"}, names(sel.pkgs)); diff != "" {
+		t.Errorf("selected mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"air"}, names(sel.excluded)); diff != "" {
+		t.Errorf("excluded mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"lazygit"}, names(sel.included)); diff != "" {
+		t.Errorf("included mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_pickUpdates_closedInput(t *testing.T) {
+	t.Parallel()
+
+	p, _ := newTestPrinter()
+	if _, _, err := pickUpdates(p, bufio.NewScanner(strings.NewReader("1\n")), testUpdatePicks()); err == nil {
+		t.Error("pickUpdates() on closed input succeeded")
+	}
+}
+
+func Test_printUpdatePicks(t *testing.T) {
+	t.Parallel()
+
+	picks := testUpdatePicks()
+	picks[0].result.pkg.GoVersion = &goutil.Version{Current: testGoVersion1224, Latest: testGoVersionNoDwarf5}
+	picks[1].channel, picks[1].pin = goutil.UpdateChannelPinned, testVersionTwo
+	p, out := newTestPrinter()
+	printUpdatePicks(p, picks)
+	for _, want := range []string{
+		"[x]  1  air      " + testVersionOne + " -> " + testVersionTwo,
+		testGoVersion1224 + " -> " + testGoVersionNoDwarf5,
+		"[ ]  2  gopls    " + testVersionTwo + " (up to date)",
+		"pinned " + testVersionTwo,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("list lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func Test_chooseUpdates_preselectsUpdates(t *testing.T) {
+	t.Parallel()
+
+	deps := testDeps()
+	deps.getLatestVer = func(_ context.Context, modulePath, _ string) (string, error) {
+		if modulePath == testImportExampleUpToDate {
+			return testVersionOne, nil
+		}
+		return testVersionTwo, nil
+	}
+	pkgs := []goutil.Package{
+		{Name: testBinTool, ImportPath: testImportExampleTool, ModulePath: testImportExampleTool, Version: &goutil.Version{Current: testVersionOne}},
+		{Name: "uptodate", ImportPath: testImportExampleUpToDate, ModulePath: testImportExampleUpToDate, Version: &goutil.Version{Current: testVersionOne}},
+	}
+	p, out := newTestPrinter()
+	sel, ok, err := chooseUpdates(deps, p, strings.NewReader("\n"), pkgs, nil, nil, nil, updateOpts{cpus: 1}, true)
+	if err != nil || !ok {
+		t.Fatalf("chooseUpdates() = %v, %v; output:\n%s", ok, err, out.String())
+	}
+	if len(sel.pkgs) != 1 || sel.pkgs[0].Name != testBinTool {
+		t.Errorf("selected %v, want only %s", sel.pkgs, testBinTool)
+	}
+	if pkgs[0].Version.Latest != "" {
+		t.Errorf("the check changed the packages given: latest = %q", pkgs[0].Version.Latest)
+	}
+}
+
+func Test_parseUpdateFlags_interactiveConflicts(t *testing.T) {
+	t.Parallel()
+
+	for _, args := range [][]string{
+		{"-i", "--json"},
+		{"-i", "--format", "csv"},
+		{"-i", "--json-stream"},
+		{"-i", "--plan", "plan.json"},
+	} {
+		cmd := newUpdateCmd()
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseUpdateFlags(cmd); err == nil {
+			t.Errorf("parseUpdateFlags(%v) succeeded", args)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/configstate"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/nao1215/gup/internal/updateplan"
+)
+
+// writeUpdatePlan is 'gup update --plan': it resolves what update would install
+// and writes it to opts.planFile without installing anything. A plan is only
+// written when every package was resolved, so a reviewed plan never silently
+// leaves a binary out.
+func writeUpdatePlan(deps dependencies, p *print.Printer, pkgs []goutil.Package, opts updateOpts, ignoreGoUpdate bool,
+	channelMap map[string]goutil.UpdateChannel, pinnedMap map[string]string) int {
+	if !opts.quiet {
+		p.Info("plan updates of binaries under $GOPATH/bin or $GOBIN")
+	}
+	exitCode, results := planUpdates(deps, p, pkgs, ignoreGoUpdate, channelMap, pinnedMap, opts.cpus, opts.timeout, opts.quiet)
+	if exitCode != 0 {
+		p.Err("not writing " + opts.planFile + ": some binaries could not be resolved")
+		return exitCode
+	}
+
+	plan := updateplan.New(time.Now())
+	for _, r := range results {
+		if r.status != statusUpdateAvailable && r.status != statusPinMismatch {
+			continue
+		}
+		plan.Updates = append(plan.Updates, updateplan.Entry{
+			Binary:     r.pkg.Name,
+			ImportPath: r.pkg.ImportPath,
+			From:       r.pkg.Version.Current,
+			To:         r.pkg.Version.Latest,
+			Channel:    string(r.pkg.UpdateChannel),
+		})
+	}
+	if configstate.ShouldPersistChannels(opts.mainPkgNames, opts.masterPkgNames, opts.latestPkgNames) {
+		plan.Channels = make(map[string]string, len(channelMap))
+		for name, channel := range channelMap {
+			plan.Channels[name] = string(channel)
+		}
+	}
+	if err := updateplan.Write(opts.planFile, plan); err != nil {
+		p.Err(err)
+		return 1
+	}
+	p.Info(fmt.Sprintf("wrote %d update(s) to %s; run 'gup apply %s' to install them", len(plan.Updates), opts.planFile, opts.planFile))
+	return 0
+}
+
+// planUpdates resolves the exact version update would install for every
+// package, without installing anything. A package that needs an update gets
+// status statusUpdateAvailable (statusPinMismatch when pinned) and its target in
+// Version.Latest.
+func planUpdates(deps dependencies, pr *print.Printer, pkgs []goutil.Package, ignoreGoUpdate bool,
+	channelMap map[string]goutil.UpdateChannel, pinnedMap map[string]string,
+	cpus int, timeout time.Duration, quiet bool) (int, []updateResult) {
+	verCache := deps.newVerCache()
+
+	planner := func(ctx context.Context, deps dependencies, p goutil.Package) updateResult {
+		deps.toolchain = p.GoToolchain
+		channel := configstate.PackageChannel(p.Name, p.UpdateChannel, channelMap)
+		p.UpdateChannel = channel
+		if p.ImportPath == "" {
+			return updateResult{pkg: p, err: fmt.Errorf("%s is not installed by 'go install' (or permission incorrect)", p.Name)}
+		}
+		if p.Version == nil {
+			p.Version = &goutil.Version{}
+		}
+		goOutdated := !ignoreGoUpdate && p.GoVersion != nil && !p.IsGoUpToDate()
+
+		if channel == goutil.UpdateChannelPinned {
+			p.PinnedVersion = strings.TrimSpace(pinnedMap[p.Name])
+			if p.PinnedVersion == "" {
+				return updateResult{pkg: p, err: fmt.Errorf("%s: pinned package has no recorded version", p.Name)}
+			}
+			p.Version.Latest = p.PinnedVersion
+			if p.PinSatisfied() && !goOutdated {
+				return updateResult{pkg: p, status: statusPinned}
+			}
+			return updateResult{pkg: p, status: statusPinMismatch}
+		}
+
+		// A plan must name an exact version, so a package whose module path is
+		// unknown can't be planned (update would install it blindly).
+		if p.ModulePath == "" {
+			return updateResult{pkg: p, err: fmt.Errorf("%s: can't resolve the version to install: module path is unknown", p.Name)}
+		}
+		ver, err := deps.lookup(ctx, verCache, p.ModulePath, channel)
+		if err != nil {
+			newPkg, changed := resolveModulePathChange(p, err)
+			if !changed {
+				return updateResult{pkg: p, err: fmt.Errorf("%s: %w", p.Name, err)}
+			}
+			p = newPkg
+			if ver, err = deps.lookup(ctx, verCache, p.ModulePath, channel); err != nil {
+				return updateResult{pkg: p, err: fmt.Errorf("%s: %w", p.Name, err)}
+			}
+		}
+		p.Version.Latest = ver
+		if p.IsPackageUpToDate() && !goOutdated {
+			hideIgnoredGoDelta(&p, ignoreGoUpdate, false)
+			return updateResult{pkg: p, status: statusUpToDate}
+		}
+		return updateResult{pkg: p, status: statusUpdateAvailable}
+	}
+
+	return executePackages(pr, pkgs, cpus, timeout, deps, planner, resultLineRenderer(pr, quiet,
+		func(v updateResult) bool { return v.status == statusUpdateAvailable || v.status == statusPinMismatch },
+		updateResultStr))
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/updateplan"
+)
+
+// planTestGo returns the Go version of a binary built with the running toolchain.
+func planTestGo() *goutil.Version {
+	return &goutil.Version{Current: "go1.25.0", Latest: "go1.25.0"}
+}
+
+func Test_planUpdates(t *testing.T) {
+	t.Parallel()
+
+	deps := testDeps()
+	deps.getLatestVer = func(_ context.Context, modulePath, _ string) (string, error) {
+		if modulePath == "example.com/current" {
+			return "v1.0.0", nil
+		}
+		return "v1.2.0", nil
+	}
+	installed := false
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error { installed = true; return nil }
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error { installed = true; return nil }
+
+	pkgs := []goutil.Package{
+		{
+			Name: "old", ImportPath: "example.com/old", ModulePath: "example.com/old",
+			Version: &goutil.Version{Current: "v1.0.0"}, GoVersion: planTestGo(),
+		},
+		{
+			Name: "current", ImportPath: "example.com/current", ModulePath: "example.com/current",
+			Version: &goutil.Version{Current: "v1.0.0"}, GoVersion: planTestGo(),
+		},
+		{
+			Name: "held", ImportPath: "example.com/held", ModulePath: "example.com/held",
+			Version: &goutil.Version{Current: "v1.1.0"}, GoVersion: planTestGo(), UpdateChannel: goutil.UpdateChannelPinned,
+		},
+	}
+	p, _ := newTestPrinter()
+	code, results := planUpdates(deps, p, pkgs, true, nil, map[string]string{"held": "v0.9.0"}, 2, time.Minute, false)
+	if code != 0 {
+		t.Fatalf("planUpdates() = %d, want 0", code)
+	}
+	if installed {
+		t.Fatal("planUpdates() must not install anything")
+	}
+
+	got := map[string]string{}
+	for _, r := range results {
+		got[r.pkg.Name] = string(r.status) + " " + r.pkg.Version.Latest
+	}
+	want := map[string]string{
+		"old":     string(statusUpdateAvailable) + " v1.2.0",
+		"current": string(statusUpToDate) + " v1.0.0",
+		"held":    string(statusPinMismatch) + " v0.9.0",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("planUpdates() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_planUpdates_unknownModulePath(t *testing.T) {
+	t.Parallel()
+
+	pkgs := []goutil.Package{{Name: "tool", ImportPath: "example.com/tool", Version: &goutil.Version{Current: "v1.0.0"}, GoVersion: planTestGo()}}
+	p, _ := newTestPrinter()
+	if code, _ := planUpdates(testDeps(), p, pkgs, true, nil, nil, 1, time.Minute, false); code == 0 {
+		t.Fatal("planUpdates() = 0, want failure for a package without a module path")
+	}
+}
+
+//nolint:paralleltest // swaps XDG env
+func Test_writeUpdatePlan(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+
+	deps := testDeps()
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return "v1.2.0", nil }
+	pkgs := []goutil.Package{{
+		Name: "tool", ImportPath: "example.com/tool/cmd/tool", ModulePath: "example.com/tool",
+		Version: &goutil.Version{Current: "v1.0.0"}, GoVersion: planTestGo(),
+	}}
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	opts := updateOpts{planFile: planFile, cpus: 1, timeout: time.Minute, latestPkgNames: []string{"tool"}}
+	channelMap := map[string]goutil.UpdateChannel{"tool": goutil.UpdateChannelLatest}
+
+	p, buf := newTestPrinter()
+	if got := writeUpdatePlan(deps, p, pkgs, opts, true, channelMap, nil); got != 0 {
+		t.Fatalf("writeUpdatePlan() = %d, want 0; output: %s", got, buf.String())
+	}
+	plan, err := updateplan.Read(planFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []updateplan.Entry{{
+		Binary: "tool", ImportPath: "example.com/tool/cmd/tool", From: "v1.0.0", To: "v1.2.0", Channel: "latest",
+	}}
+	if diff := cmp.Diff(want, plan.Updates); diff != "" {
+		t.Errorf("plan updates mismatch (-want +got):\n%s", diff)
+	}
+	if plan.Channels["tool"] != "latest" {
+		t.Errorf("plan channels = %v, want tool: latest", plan.Channels)
+	}
+}
+
+func Test_plannedPackages_drift(t *testing.T) {
+	t.Parallel()
+
+	plan := updateplan.New(time.Now())
+	plan.Updates = []updateplan.Entry{
+		{Binary: "moved", ImportPath: "example.com/moved", From: "v1.0.0", To: "v1.2.0", Channel: "latest"},
+		{Binary: "gone", ImportPath: "example.com/gone", From: "v1.0.0", To: "v1.2.0", Channel: "latest"},
+		{Binary: "same", ImportPath: "example.com/same", From: "v1.0.0", To: "v1.2.0", Channel: "latest"},
+	}
+	installed := []goutil.Package{
+		{Name: "moved", ImportPath: "example.com/moved", Version: &goutil.Version{Current: "v1.1.0"}, GoVersion: planTestGo()},
+		{Name: "same", ImportPath: "example.com/same", Version: &goutil.Version{Current: "v1.0.0"}, GoVersion: planTestGo()},
+	}
+	_, _, err := plannedPackages(plan, installed)
+	if err == nil {
+		t.Fatal("plannedPackages() error = nil, want drift error")
+	}
+	for _, want := range []string{
+		"moved is installed at v1.1.0, but the plan was made against v1.0.0",
+		"gone is no longer installed",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error is missing %q: %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "same") {
+		t.Errorf("an unchanged binary must not be reported: %v", err)
+	}
+}
+
+func Test_plannedPackages_moved(t *testing.T) {
+	t.Parallel()
+
+	plan := updateplan.New(time.Now())
+	plan.Updates = []updateplan.Entry{
+		{Binary: "tool", ImportPath: "example.com/new/tool", From: "v1.0.0", To: "v2.0.0", Channel: "latest"},
+		{Binary: "same", ImportPath: "example.com/same", From: "v1.0.0", To: "v1.2.0", Channel: "latest"},
+	}
+	installed := []goutil.Package{
+		{Name: "tool", ImportPath: "example.com/old/tool", ModulePath: "example.com/old/tool", Version: &goutil.Version{Current: "v1.0.0"}, GoVersion: planTestGo()},
+		{Name: "same", ImportPath: "example.com/same", ModulePath: "example.com/same", Version: &goutil.Version{Current: "v1.0.0"}, GoVersion: planTestGo()},
+	}
+	pkgs, moved, err := plannedPackages(plan, installed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{"tool": "example.com/old/tool"}, moved); diff != "" {
+		t.Errorf("moved mismatch (-want +got):\n%s", diff)
+	}
+	if pkgs[0].ModulePath != "" {
+		t.Errorf("ModulePath of the moved tool = %q, want it unknown until it installs", pkgs[0].ModulePath)
+	}
+	if pkgs[1].ModulePath != "example.com/same" {
+		t.Errorf("ModulePath of same = %q, want the installed module path", pkgs[1].ModulePath)
+	}
+}
+
+func Test_applyPlan(t *testing.T) {
+	t.Parallel()
+
+	plan := updateplan.New(time.Now())
+	plan.Updates = []updateplan.Entry{
+		{Binary: "tool", ImportPath: "example.com/tool/cmd/tool", From: "v1.0.0", To: "v1.2.0", Channel: "latest"},
+		{Binary: "held", ImportPath: "example.com/held", From: "v1.1.0", To: "v0.9.0", Channel: "pinned"},
+	}
+	installed := []goutil.Package{
+		{Name: "held", ImportPath: "example.com/held", Version: &goutil.Version{Current: "v1.1.0"}, GoVersion: planTestGo()},
+		{Name: "tool", ImportPath: "example.com/tool/cmd/tool", Version: &goutil.Version{Current: "v1.0.0"}, GoVersion: planTestGo()},
+	}
+	pkgs, moved, err := plannedPackages(plan, installed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 0 {
+		t.Errorf("moved = %v, want none", moved)
+	}
+
+	var mu sync.Mutex
+	calls := []string{}
+	deps := testDeps()
+	deps.installByVersion = func(_ context.Context, importPath, version string, _ goutil.InstallOptions) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, importPath+"@"+version)
+		return nil
+	}
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error {
+		t.Error("apply must install the planned version, not @latest")
+		return nil
+	}
+
+	p, _ := newTestPrinter()
+	code, results := applyPlan(deps, p, pkgs, moved, 2, time.Minute)
+	if code != 0 {
+		t.Fatalf("applyPlan() = %d, want 0", code)
+	}
+	sort.Strings(calls)
+	want := []string{"example.com/held@v0.9.0", "example.com/tool/cmd/tool@v1.2.0"}
+	if diff := cmp.Diff(want, calls); diff != "" {
+		t.Errorf("installs mismatch (-want +got):\n%s", diff)
+	}
+	if got := results[1].pkg; !got.IsPinned() || got.Version.Current != "v0.9.0" {
+		t.Errorf("pinned result = %+v, want pinned at v0.9.0", got)
+	}
+}
@@ -0,0 +1,341 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nao1215/gup/internal/binname"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/configstate"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/pkgselect"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/nao1215/gup/internal/updaterun"
+)
+
+// updateRunPath returns where 'gup update' keeps the state of its run.
+func updateRunPath() string {
+	return filepath.Join(config.StateDirPath(), updaterun.FileName)
+}
+
+// updateRun records a 'gup update' run in the state file as it goes: every
+// selected package, the version each one resolved to, and which are done. A run
+// that ends with packages left keeps the file for 'gup update --resume'.
+// A nil *updateRun records nothing.
+type updateRun struct {
+	mu      sync.Mutex
+	path    string
+	state   updaterun.State
+	index   map[string]int // binary name -> entry in state.Packages
+	saveErr error          // the first failed save, reported by finish
+}
+
+// newUpdateRun returns the record of a run over pkgs, installing into gobin.
+// channels and pins are the ones the run uses; save says whether the run saves
+// the channels to gup.json.
+func newUpdateRun(path, gobin string, now time.Time, pkgs []goutil.Package, channels map[string]goutil.UpdateChannel, pins map[string]string, save, full bool) *updateRun {
+	r := &updateRun{path: path, state: updaterun.New(now, gobin), index: make(map[string]int, len(pkgs))}
+	r.state.Full = full
+	for _, v := range pkgs {
+		channel := configstate.PackageChannel(v.Name, v.UpdateChannel, channels)
+		e := updaterun.Entry{Binary: v.Name, ImportPath: v.ImportPath, ModulePath: v.ModulePath, Channel: string(channel)}
+		if v.Version != nil {
+			e.From = v.Version.Current
+		}
+		if channel == goutil.UpdateChannelPinned {
+			e.To = pins[v.Name]
+		}
+		r.index[v.Name] = len(r.state.Packages)
+		r.state.Packages = append(r.state.Packages, e)
+	}
+	if save {
+		r.state.Channels = make(map[string]string, len(channels))
+		for name, channel := range channels {
+			r.state.Channels[name] = string(channel)
+		}
+	}
+	return r
+}
+
+// resumedUpdateRun returns the record of a resumed run, which goes on updating
+// the state file of the interrupted one.
+func resumedUpdateRun(path string, state updaterun.State) *updateRun {
+	r := &updateRun{path: path, state: state, index: make(map[string]int, len(state.Packages))}
+	for i, e := range state.Packages {
+		r.index[e.Binary] = i
+	}
+	return r
+}
+
+// start writes the state file before any package is touched.
+func (r *updateRun) start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return updaterun.Write(r.path, r.state)
+}
+
+// worker wraps worker so every package is recorded when it resolved and when
+// it finished. It returns worker as it is on a nil run.
+func (r *updateRun) worker(worker pkgWorker) pkgWorker {
+	if r == nil {
+		return worker
+	}
+	return func(ctx context.Context, deps dependencies, pkg goutil.Package) updateResult {
+		name := pkg.Name
+		v := worker(ctx, deps.observe(func(event string, pkg goutil.Package) {
+			r.resolved(name, event, pkg)
+		}), pkg)
+		r.finished(name, v)
+		return v
+	}
+}
+
+// resolved records the version the package named name resolved to, when
+// event is the resolved step of pkg.
+func (r *updateRun) resolved(name, event string, pkg goutil.Package) {
+	if event != eventResolved || pkg.Version == nil {
+		return
+	}
+	r.update(name, func(e *updaterun.Entry) {
+		e.To = pkg.Version.Latest
+		e.ImportPath, e.ModulePath = pkg.ImportPath, pkg.ModulePath
+	})
+}
+
+// finished records the result of the package named name.
+func (r *updateRun) finished(name string, v updateResult) {
+	r.update(name, func(e *updaterun.Entry) {
+		switch {
+		case v.err != nil:
+			e.Status = updaterun.StatusError
+		case v.status != "":
+			e.Status = v.status
+		case v.updated:
+			e.Status = statusUpdated
+		default:
+			e.Status = statusUpToDate
+		}
+	})
+}
+
+// update changes the entry of name and saves the state file.
+func (r *updateRun) update(name string, change func(*updaterun.Entry)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, ok := r.index[name]
+	if !ok {
+		return
+	}
+	change(&r.state.Packages[i])
+	if err := updaterun.Write(r.path, r.state); err != nil && r.saveErr == nil {
+		r.saveErr = err
+	}
+}
+
+// finish removes the state file when every package is done. Otherwise it keeps
+// it and tells how to continue. It does nothing on a nil run.
+func (r *updateRun) finish(p *print.Printer) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.saveErr != nil {
+		p.Warn("failed to record the progress of this update: " + r.saveErr.Error())
+	}
+	left := len(r.state.Remaining())
+	if left == 0 {
+		if err := updaterun.Remove(r.path); err != nil {
+			p.Warn(err)
+		}
+		return
+	}
+	if r.saveErr == nil {
+		p.Hint(fmt.Sprintf("%d of %d binaries were not updated; run 'gup update --resume' to continue with them at the same versions",
+			left, len(r.state.Packages)))
+	}
+}
+
+// startUpdateRun records a run over pkgs, or returns nil (with a warning) when
+// the state file can't be written: the update then runs without --resume.
+func startUpdateRun(p *print.Printer, pkgs []goutil.Package, channels map[string]goutil.UpdateChannel, pins map[string]string, save, full bool) *updateRun {
+	gobin, err := goutil.GoBin()
+	if err != nil {
+		p.Warn("this update can't be resumed: " + err.Error())
+		return nil
+	}
+	run := newUpdateRun(updateRunPath(), gobin, time.Now(), pkgs, channels, pins, save, full)
+	if err := run.start(); err != nil {
+		p.Warn("this update can't be resumed: " + err.Error())
+		return nil
+	}
+	return run
+}
+
+// resumeUpdate is 'gup update --resume': it continues the interrupted run with
+// the packages that are not done, each at the version the run resolved for it.
+// A package the run had not resolved yet is resolved now.
+func resumeUpdate(ctx context.Context, deps dependencies, p *print.Printer, opts updateOpts, report *jsonReport, autoExport bool) int {
+	path := updateRunPath()
+	state, err := updaterun.Read(path)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	gobin, err := goutil.GoBin()
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	if filepath.Clean(gobin) != filepath.Clean(state.GOBIN) {
+		p.Err(fmt.Errorf("the interrupted update installed into %s, but $GOBIN is now %s", state.GOBIN, gobin))
+		return 1
+	}
+
+	left := state.Remaining()
+	started := state.StartedAt.Local().Format(time.DateTime)
+	if !opts.jsonOut {
+		p.Info(fmt.Sprintf("resume the update started %s: %d of %d binaries left", started, len(left), len(state.Packages)))
+		if done := doneSummary(state); done != "" {
+			p.Info("already done: " + done)
+		}
+	}
+	if len(left) == 0 {
+		if err := updaterun.Remove(path); err != nil {
+			p.Warn(err)
+		}
+		return encodeEmptyResults(p, report, opts.jsonOut)
+	}
+
+	confReadPath, err := config.ResolveImportFilePath(opts.confFile)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	confPkgs, err := configstate.ReadFileIfExists(confReadPath)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	names := make([]string, 0, len(left))
+	for _, e := range left {
+		names = append(names, e.Binary)
+	}
+	installed, _, goVersionAvailable, err := pkgselect.PackageInfoByTargets(p, names)
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	pkgs, moved := resumedPackages(left, installed)
+	pkgs = configstate.ApplyGoToolchains(pkgs, confPkgs, "")
+	resolved, pins := resumedVersions(left)
+
+	run := resumedUpdateRun(path, state)
+	worker := newUpdateWorker(deps, updateWorkerOpts{
+		ignoreGoUpdate: opts.ignoreGoUpdate || !goVersionAvailable,
+		jsonOut:        opts.jsonOut,
+		pins:           pins,
+		resolved:       resolved,
+		moved:          moved,
+	})
+	result, succeededPkgs, renamedPkgs := runUpdates(ctx, p, deps, pkgs, opts.cpus, opts.timeout, run.worker(deps.retryWorker(worker)), opts.notify, opts.jsonOut, opts.quiet, report)
+	run.finish(p)
+	if result == 0 && state.Full {
+		if err := recordLastUpdate(time.Now()); err != nil {
+			p.Warn("failed to record the time of this update: " + err.Error())
+		}
+	}
+
+	if state.Channels != nil || len(renamedPkgs) > 0 || autoExport {
+		channelMap := make(map[string]goutil.UpdateChannel, len(state.Channels))
+		for name, channel := range state.Channels {
+			channelMap[name] = goutil.NormalizeUpdateChannel(channel)
+		}
+		confWritePath := configstate.ResolveWritePath(opts.confFile, confReadPath)
+		merged := configstate.MergePackages(confPkgs, succeededPkgs, channelMap, renamedPkgs)
+		if err := writeConfigFile(confWritePath, merged); err != nil {
+			p.Warn("failed to write " + confWritePath + ": " + err.Error())
+		}
+	}
+	return result
+}
+
+// encodeEmptyResults writes the empty result of a resume with nothing left.
+func encodeEmptyResults(p *print.Printer, report *jsonReport, jsonOut bool) int {
+	if s := report.events(); s != nil {
+		s.summary(nil, 0)
+		return 0
+	}
+	if !jsonOut {
+		p.Info("nothing to resume: every binary of that update is done")
+		return 0
+	}
+	if err := encodeJSONResults(p, report, []jsonPackage{}, nil); err != nil {
+		p.Err(err)
+		return 1
+	}
+	return 0
+}
+
+// doneSummary lists the packages of state that are done, with their result.
+func doneSummary(state updaterun.State) string {
+	done := []string{}
+	for _, e := range state.Packages {
+		if e.Done() {
+			done = append(done, fmt.Sprintf("%s (%s)", e.Binary, e.Status))
+		}
+	}
+	return strings.Join(done, ", ")
+}
+
+// resumedPackages builds the packages to update from the entries left, taking
+// the installed version and Go build of each from installed. A binary that is
+// not installed any more is installed again.
+//
+// moved names the binaries the interrupted run resolved under another import
+// path than the installed one: their module moved, and they may install under
+// a new name.
+func resumedPackages(left []updaterun.Entry, installed []goutil.Package) (pkgs []goutil.Package, moved map[string]bool) {
+	byName := make(map[string]goutil.Package, len(installed))
+	for _, v := range installed {
+		byName[binname.NormalizeForMatch(v.Name)] = v
+	}
+	pkgs = make([]goutil.Package, 0, len(left))
+	moved = map[string]bool{}
+	for _, e := range left {
+		pkg, ok := byName[binname.NormalizeForMatch(e.Binary)]
+		if !ok {
+			pkg = goutil.Package{Name: e.Binary, Version: &goutil.Version{}}
+		}
+		if pkg.Version == nil {
+			pkg.Version = &goutil.Version{}
+		}
+		if pkg.ImportPath != "" && pkg.ImportPath != e.ImportPath {
+			moved[pkg.Name] = true
+		}
+		pkg.ImportPath, pkg.ModulePath = e.ImportPath, e.ModulePath
+		pkg.UpdateChannel = goutil.NormalizeUpdateChannel(e.Channel)
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, moved
+}
+
+// resumedVersions returns, by binary name, the versions the interrupted run
+// resolved for the entries left: resolved for the channels, pins for the
+// pinned binaries. An entry the run had not resolved is in neither.
+func resumedVersions(left []updaterun.Entry) (resolved, pins map[string]string) {
+	resolved, pins = map[string]string{}, map[string]string{}
+	for _, e := range left {
+		switch {
+		case goutil.NormalizeUpdateChannel(e.Channel) == goutil.UpdateChannelPinned:
+			pins[e.Binary] = e.To
+		case e.To != "":
+			resolved[e.Binary] = e.To
+		}
+	}
+	return resolved, pins
+}
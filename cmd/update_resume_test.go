@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/updaterun"
+)
+
+func Test_updateRun_recordsTheRun(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), updaterun.FileName)
+	pkgs := []goutil.Package{
+		{Name: "air", ImportPath: "example.com/air", ModulePath: "example.com/air", Version: &goutil.Version{Current: testVersionOne}},
+		{Name: "gopls", ImportPath: "example.com/gopls", ModulePath: "example.com/gopls", Version: &goutil.Version{Current: testVersionOne}},
+		{Name: "lazygit", ImportPath: "example.com/lazygit", Version: &goutil.Version{Current: testVersionOne}},
+	}
+	channels := map[string]goutil.UpdateChannel{"lazygit": goutil.UpdateChannelPinned}
+	run := newUpdateRun(path, "/gobin", time.Now(), pkgs, channels, map[string]string{"lazygit": testVersionTwo}, false, false)
+	if err := run.start(); err != nil {
+		t.Fatal(err)
+	}
+
+	worker := run.worker(func(_ context.Context, deps dependencies, pkg goutil.Package) updateResult {
+		if pkg.Name == "lazygit" {
+			// Interrupted before it finished.
+			return updateResult{pkg: pkg, err: context.Canceled}
+		}
+		pkg.Version.Latest = testVersionTwo
+		deps.event(eventResolved, pkg)
+		if pkg.Name == "gopls" {
+			return updateResult{pkg: pkg, status: statusUpToDate}
+		}
+		return updateResult{pkg: pkg, updated: true, status: statusUpdated}
+	})
+	for _, v := range pkgs {
+		worker(context.Background(), dependencies{}, v)
+	}
+
+	p, out := newTestPrinter()
+	run.finish(p)
+	if !strings.Contains(out.String(), "1 of 3 binaries were not updated") {
+		t.Errorf("finish() did not tell how to resume:\n%s", out.String())
+	}
+	got, err := updaterun.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []updaterun.Entry{
+		{Binary: "air", ImportPath: "example.com/air", ModulePath: "example.com/air", From: testVersionOne, To: testVersionTwo, Channel: "latest", Status: statusUpdated},
+		{Binary: "gopls", ImportPath: "example.com/gopls", ModulePath: "example.com/gopls", From: testVersionOne, To: testVersionTwo, Channel: "latest", Status: statusUpToDate},
+		{Binary: "lazygit", ImportPath: "example.com/lazygit", From: testVersionOne, To: testVersionTwo, Channel: "pinned", Status: updaterun.StatusError},
+	}
+	if diff := cmp.Diff(want, got.Packages); diff != "" {
+		t.Errorf("recorded packages mismatch (-want +got):\n%s", diff)
+	}
+
+	// Resuming finishes the last one, which removes the state file.
+	resumed := resumedUpdateRun(path, got)
+	resumed.finished("lazygit", updateResult{updated: true, status: statusUpdated})
+	resumed.finish(p)
+	if _, err := updaterun.Read(path); !errors.Is(err, updaterun.ErrNoRun) {
+		t.Errorf("the state file is left after every package is done: %v", err)
+	}
+}
+
+func Test_resumedPackages(t *testing.T) {
+	t.Parallel()
+
+	left := []updaterun.Entry{
+		{Binary: "air", ImportPath: "example.com/air/cmd/air", ModulePath: "example.com/air", From: testVersionOne, To: testVersionTwo, Channel: "latest"},
+		{Binary: "gone", ImportPath: "example.com/gone", From: testVersionOne, To: testVersionTwo, Channel: "pinned"},
+		{Binary: "old", ImportPath: "example.com/new", ModulePath: "example.com/new", From: testVersionOne, Channel: "main"},
+	}
+	installed := []goutil.Package{
+		{Name: "air", ImportPath: "example.com/air/cmd/air", Version: &goutil.Version{Current: testVersionOne}, GoVersion: &goutil.Version{Current: testGoVersion1224}},
+		{Name: "old", ImportPath: "example.com/old", Version: &goutil.Version{Current: testVersionOne}},
+	}
+
+	got, moved := resumedPackages(left, installed)
+	if len(got) != 3 {
+		t.Fatalf("got %d packages, want 3", len(got))
+	}
+	if got[0].Version.Current != testVersionOne || got[0].GoVersion == nil || got[0].ModulePath != "example.com/air" {
+		t.Errorf("air = %+v, want the installed binary with the recorded module", got[0])
+	}
+	if got[1].Version.Current != "" || got[1].UpdateChannel != goutil.UpdateChannelPinned {
+		t.Errorf("gone = %+v, want a pinned install from nothing", got[1])
+	}
+	if got[2].ImportPath != "example.com/new" {
+		t.Errorf("old = %+v, want the import path the run resolved", got[2])
+	}
+	if diff := cmp.Diff(map[string]bool{"old": true}, moved); diff != "" {
+		t.Errorf("moved mismatch (-want +got):\n%s", diff)
+	}
+
+	resolved, pins := resumedVersions(left)
+	if diff := cmp.Diff(map[string]string{"air": testVersionTwo}, resolved); diff != "" {
+		t.Errorf("resolved mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]string{"gone": testVersionTwo}, pins); diff != "" {
+		t.Errorf("pins mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_newUpdateWorker_resolved(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	installs := map[string]string{}
+	record := func(importPath, version string) {
+		mu.Lock()
+		defer mu.Unlock()
+		installs[importPath] = version
+	}
+	deps := testDeps()
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
+	deps.installLatest = func(_ context.Context, importPath string, _ goutil.InstallOptions) error {
+		record(importPath, "latest")
+		return nil
+	}
+	deps.installByVersion = func(_ context.Context, importPath, version string, _ goutil.InstallOptions) error {
+		record(importPath, version)
+		return nil
+	}
+	goVersion := func() *goutil.Version { return &goutil.Version{Current: testGoVersion1224, Latest: testGoVersion1224} }
+	worker := newUpdateWorker(deps, updateWorkerOpts{resolved: map[string]string{"air": testVersionTwo, "gopls": testVersionTwo}})
+
+	tests := []struct {
+		name        string
+		pkg         goutil.Package
+		wantStatus  string
+		wantInstall string
+	}{
+		{
+			name:        "installs the resolved version, not the newest one",
+			pkg:         goutil.Package{Name: "air", ImportPath: "example.com/air", ModulePath: "example.com/air", Version: &goutil.Version{Current: testVersionOne}, GoVersion: goVersion()},
+			wantStatus:  statusUpdated,
+			wantInstall: testVersionTwo,
+		},
+		{
+			name:       "keeps a binary the interrupted run already installed",
+			pkg:        goutil.Package{Name: "gopls", ImportPath: "example.com/gopls", ModulePath: "example.com/gopls", Version: &goutil.Version{Current: testVersionTwo}, GoVersion: goVersion()},
+			wantStatus: statusUpToDate,
+		},
+		{
+			name:        "resolves a binary the run had not resolved",
+			pkg:         goutil.Package{Name: "lazygit", ImportPath: "example.com/lazygit", ModulePath: "example.com/lazygit", Version: &goutil.Version{Current: testVersionOne}, GoVersion: goVersion()},
+			wantStatus:  statusUpdated,
+			wantInstall: "latest",
+		},
+	}
+	for _, tt := range tests {
+		v := worker(context.Background(), deps, tt.pkg)
+		if v.err != nil || v.status != tt.wantStatus {
+			t.Errorf("%s: status = %q, err = %v; want %q", tt.name, v.status, v.err, tt.wantStatus)
+		}
+		mu.Lock()
+		got := installs[tt.pkg.ImportPath]
+		mu.Unlock()
+		if got != tt.wantInstall {
+			t.Errorf("%s: installed %q, want %q", tt.name, got, tt.wantInstall)
+		}
+	}
+}
+
+func Test_newUpdateWorker_resolvedMovedModule(t *testing.T) {
+	gobin := t.TempDir()
+	t.Setenv("GOBIN", gobin)
+	oldPath := filepath.Join(gobin, "old")
+	if err := os.WriteFile(oldPath, []byte("binary"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	var installed string
+	deps := testDeps()
+	deps.installByVersion = func(_ context.Context, importPath, version string, _ goutil.InstallOptions) error {
+		installed = importPath + "@" + version
+		return nil
+	}
+	worker := newUpdateWorker(deps, updateWorkerOpts{
+		resolved: map[string]string{"old": testVersionTwo},
+		moved:    map[string]bool{"old": true},
+	})
+	pkg := goutil.Package{Name: "old", ImportPath: "example.com/new", ModulePath: "example.com/new", Version: &goutil.Version{Current: testVersionOne}}
+	v := worker(context.Background(), deps, pkg)
+	if v.err != nil || v.status != statusUpdated {
+		t.Fatalf("status = %q, err = %v; want %q", v.status, v.err, statusUpdated)
+	}
+	if installed != "example.com/new@"+testVersionTwo {
+		t.Errorf("installed %q, want the moved module at the resolved version", installed)
+	}
+	if v.pkg.Name != "new" || v.renamedFrom != "old" {
+		t.Errorf("name = %q, renamed from %q; want new, renamed from old", v.pkg.Name, v.renamedFrom)
+	}
+	if _, err := os.Stat(oldPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the old binary was not removed: %v", err)
+	}
+}
+
+func Test_parseUpdateFlags_resumeConflicts(t *testing.T) {
+	t.Parallel()
+
+	for _, args := range [][]string{
+		{"--resume", "-i"},
+		{"--resume", "--dry-run"},
+		{"--resume", "--plan", "plan.json"},
+		{"--resume", "--exclude", "air"},
+		{"--resume", "--main", "air"},
+	} {
+		cmd := newUpdateCmd()
+		if err := cmd.ParseFlags(args); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseUpdateFlags(cmd); err == nil {
+			t.Errorf("parseUpdateFlags(%v) succeeded", args)
+		}
+	}
+}
+
+//nolint:paralleltest // setupXDGBase changes the process environment
+func Test_resumeUpdate_nothingToResume(t *testing.T) {
+	setupXDGBase(t)
+
+	p, out := newTestPrinter()
+	if got := resumeUpdate(context.Background(), testDeps(), p, updateOpts{cpus: 1}, nil, false); got != 1 {
+		t.Errorf("resumeUpdate() = %d, want 1", got)
+	}
+	if !strings.Contains(out.String(), updaterun.ErrNoRun.Error()) {
+		t.Errorf("resumeUpdate() did not say there is nothing to resume:\n%s", out.String())
+	}
+}
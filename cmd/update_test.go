@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"testing"
@@ -17,6 +18,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/google/go-cmp/cmp"
 	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/configstate"
 	"github.com/nao1215/gup/internal/goutil"
 	"github.com/nao1215/gup/internal/print"
 	"github.com/spf13/cobra"
@@ -270,7 +272,7 @@ func Test_gup_ignoreGoUpdateFlag(t *testing.T) {
 	}
 
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionZero, nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionZero, nil }
 
 	OsExit = func(code int) {}
 	defer func() {
@@ -447,16 +449,16 @@ func Test_gup_dryRun(t *testing.T) {
 
 	var installCalled atomic.Bool
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
-	deps.installLatest = func(context.Context, string) error {
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error {
 		installCalled.Store(true)
 		return nil
 	}
-	deps.installMainOrMaster = func(context.Context, string) error {
+	deps.installMainOrMaster = func(context.Context, string, goutil.InstallOptions) error {
 		installCalled.Store(true)
 		return nil
 	}
-	deps.installByVersion = func(context.Context, string, string) error {
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error {
 		installCalled.Store(true)
 		return nil
 	}
@@ -546,7 +548,7 @@ func Test_gup_jobsClamp(t *testing.T) {
 	}
 
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionZero, nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionZero, nil }
 
 	OsExit = func(code int) {}
 	defer func() {
@@ -649,7 +651,7 @@ func Test_update_modulePathChangedOnGetLatest(t *testing.T) {
 
 	deps := testDeps()
 	var latestCalls []string
-	deps.getLatestVer = func(_ context.Context, modulePath string) (string, error) {
+	deps.getLatestVer = func(_ context.Context, modulePath, _ string) (string, error) {
 		latestCalls = append(latestCalls, modulePath)
 		if modulePath == oldModule {
 			return "", modulePathMismatchErr(oldModule, newModule)
@@ -661,15 +663,15 @@ func Test_update_modulePathChangedOnGetLatest(t *testing.T) {
 	}
 
 	var installCalls []string
-	deps.installLatest = func(_ context.Context, importPath string) error {
+	deps.installLatest = func(_ context.Context, importPath string, _ goutil.InstallOptions) error {
 		installCalls = append(installCalls, importPath)
 		return nil
 	}
-	deps.installMainOrMaster = func(context.Context, string) error {
+	deps.installMainOrMaster = func(context.Context, string, goutil.InstallOptions) error {
 		t.Fatal("installMainOrMaster should not be called")
 		return nil
 	}
-	deps.installByVersion = func(context.Context, string, string) error {
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error {
 		t.Fatal("installByVersionUpd should not be called")
 		return nil
 	}
@@ -691,7 +693,7 @@ func Test_update_modulePathChangedOnGetLatest(t *testing.T) {
 
 	channelMap := map[string]goutil.UpdateChannel{testBinAir: goutil.UpdateChannelLatest}
 	p, _ := newTestPrinter()
-	if got, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false); got != 0 {
+	if got, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false, nil); got != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", got)
 	}
 	if diff := cmp.Diff([]string{oldModule, newModule}, latestCalls); diff != "" {
@@ -711,18 +713,18 @@ func Test_update_modulePathChangedOnInstall(t *testing.T) {
 	)
 
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
-	deps.installMainOrMaster = func(context.Context, string) error {
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
+	deps.installMainOrMaster = func(context.Context, string, goutil.InstallOptions) error {
 		t.Fatal("installMainOrMaster should not be called")
 		return nil
 	}
-	deps.installByVersion = func(context.Context, string, string) error {
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error {
 		t.Fatal("installByVersionUpd should not be called")
 		return nil
 	}
 
 	var installCalls []string
-	deps.installLatest = func(_ context.Context, importPath string) error {
+	deps.installLatest = func(_ context.Context, importPath string, _ goutil.InstallOptions) error {
 		installCalls = append(installCalls, importPath)
 		switch len(installCalls) {
 		case 1:
@@ -751,7 +753,7 @@ func Test_update_modulePathChangedOnInstall(t *testing.T) {
 
 	channelMap := map[string]goutil.UpdateChannel{testBinAir: goutil.UpdateChannelLatest}
 	p, _ := newTestPrinter()
-	if got, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false); got != 0 {
+	if got, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false, nil); got != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", got)
 	}
 	if diff := cmp.Diff([]string{oldImport, newImport}, installCalls); diff != "" {
@@ -772,9 +774,12 @@ func Test_installWithSelectedVersion(t *testing.T) {
 	// globals, so this test owns its dependencies and runs in parallel.
 	var called string
 	deps := dependencies{
-		installLatest:       func(context.Context, string) error { called = latestKeyword; return nil },
-		installMainOrMaster: func(context.Context, string) error { called = "main"; return nil },
-		installByVersion:    func(_ context.Context, _, v string) error { called = "version:" + v; return nil },
+		installLatest:       func(context.Context, string, goutil.InstallOptions) error { called = latestKeyword; return nil },
+		installMainOrMaster: func(context.Context, string, goutil.InstallOptions) error { called = "main"; return nil },
+		installByVersion: func(_ context.Context, _, v string, _ goutil.InstallOptions) error {
+			called = "version:" + v
+			return nil
+		},
 	}
 
 	tests := []struct {
@@ -800,7 +805,7 @@ func Test_installWithSelectedVersion(t *testing.T) {
 func Test_installWithSelectedVersion_contextCanceled(t *testing.T) {
 	t.Parallel()
 	deps := testDeps()
-	deps.installLatest = func(ctx context.Context, _ string) error {
+	deps.installLatest = func(ctx context.Context, _ string, _ goutil.InstallOptions) error {
 		<-ctx.Done()
 		return fmt.Errorf("can't install %s:\n%w", testImportExampleTool, ctx.Err())
 	}
@@ -896,7 +901,7 @@ func Test_removeOldBinaryIfRenamed_emptyNames(t *testing.T) {
 
 func Test_updateWithChannels_emptyImportPath(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
 
 	pkgs := []goutil.Package{
 		{
@@ -910,7 +915,7 @@ func Test_updateWithChannels_emptyImportPath(t *testing.T) {
 
 	channelMap := map[string]goutil.UpdateChannel{testBinTool: goutil.UpdateChannelLatest}
 	p, _ := newTestPrinter()
-	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false)
+	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false, nil)
 	if result != 1 {
 		t.Fatalf("updateWithChannels() = %d, want 1 (empty import path)", result)
 	}
@@ -918,7 +923,7 @@ func Test_updateWithChannels_emptyImportPath(t *testing.T) {
 
 func Test_updateWithChannels_alreadyUpToDate(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionOne, nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionOne, nil }
 
 	pkgs := []goutil.Package{
 		{
@@ -932,7 +937,7 @@ func Test_updateWithChannels_alreadyUpToDate(t *testing.T) {
 
 	channelMap := map[string]goutil.UpdateChannel{testBinTool: goutil.UpdateChannelLatest}
 	p, _ := newTestPrinter()
-	result, succeeded, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false)
+	result, succeeded, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false, nil)
 	if result != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", result)
 	}
@@ -944,18 +949,18 @@ func Test_updateWithChannels_alreadyUpToDate(t *testing.T) {
 
 func Test_updateWithChannels_alreadyUpToDate_customGoBuildTag(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionOne, nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionOne, nil }
 
 	var installCalled atomic.Bool
-	deps.installLatest = func(context.Context, string) error {
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error {
 		installCalled.Store(true)
 		return nil
 	}
-	deps.installMainOrMaster = func(context.Context, string) error {
+	deps.installMainOrMaster = func(context.Context, string, goutil.InstallOptions) error {
 		t.Fatal("installMainOrMaster should not be called")
 		return nil
 	}
-	deps.installByVersion = func(context.Context, string, string) error {
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error {
 		t.Fatal("installByVersionUpd should not be called")
 		return nil
 	}
@@ -973,7 +978,7 @@ func Test_updateWithChannels_alreadyUpToDate_customGoBuildTag(t *testing.T) {
 	}
 
 	channelMap := map[string]goutil.UpdateChannel{testBinTool: goutil.UpdateChannelLatest}
-	result, succeeded, _ := updateWithChannels(deps, p, pkgs, false, false, 1, false, channelMap, nil, 0, false, false)
+	result, succeeded, _ := updateWithChannels(deps, p, pkgs, false, false, 1, false, channelMap, nil, 0, false, false, nil)
 
 	if result != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", result)
@@ -1000,16 +1005,16 @@ func Test_updateWithChannels_alreadyUpToDate_customGoBuildTag(t *testing.T) {
 // of a phantom "goX to goY".
 func Test_updateWithChannels_ignoreGoUpdate_hidesGoOnlyDelta(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionOne, nil }
-	deps.installLatest = func(context.Context, string) error {
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionOne, nil }
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error {
 		t.Fatal("installLatest should not be called when only an ignored Go delta exists")
 		return nil
 	}
-	deps.installMainOrMaster = func(context.Context, string) error {
+	deps.installMainOrMaster = func(context.Context, string, goutil.InstallOptions) error {
 		t.Fatal("installMainOrMaster should not be called")
 		return nil
 	}
-	deps.installByVersion = func(context.Context, string, string) error {
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error {
 		t.Fatal("installByVersion should not be called")
 		return nil
 	}
@@ -1028,7 +1033,7 @@ func Test_updateWithChannels_ignoreGoUpdate_hidesGoOnlyDelta(t *testing.T) {
 
 	channelMap := map[string]goutil.UpdateChannel{testBinTool: goutil.UpdateChannelLatest}
 	// 7th positional arg = ignoreGoUpdate = true.
-	result, succeeded, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false)
+	result, succeeded, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false, nil)
 
 	if result != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", result)
@@ -1051,13 +1056,13 @@ func Test_updateWithChannels_customGoBuildTag_goVersionDiffColor(t *testing.T) {
 	t.Cleanup(func() { color.NoColor = oldNoColor })
 
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionOne, nil }
-	deps.installLatest = func(context.Context, string) error { return nil }
-	deps.installMainOrMaster = func(context.Context, string) error {
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionOne, nil }
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error { return nil }
+	deps.installMainOrMaster = func(context.Context, string, goutil.InstallOptions) error {
 		t.Fatal("installMainOrMaster should not be called")
 		return nil
 	}
-	deps.installByVersion = func(context.Context, string, string) error {
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error {
 		t.Fatal("installByVersionUpd should not be called")
 		return nil
 	}
@@ -1075,7 +1080,7 @@ func Test_updateWithChannels_customGoBuildTag_goVersionDiffColor(t *testing.T) {
 	}
 
 	channelMap := map[string]goutil.UpdateChannel{testBinTool: goutil.UpdateChannelLatest}
-	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, false, channelMap, nil, 0, false, false)
+	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, false, channelMap, nil, 0, false, false, nil)
 
 	if result != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", result)
@@ -1090,7 +1095,7 @@ func Test_updateWithChannels_customGoBuildTag_goVersionDiffColor(t *testing.T) {
 
 func Test_updateWithChannels_emptyModulePath(t *testing.T) {
 	deps := testDeps()
-	deps.installLatest = func(context.Context, string) error { return nil }
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error { return nil }
 
 	pkgs := []goutil.Package{
 		{
@@ -1104,7 +1109,7 @@ func Test_updateWithChannels_emptyModulePath(t *testing.T) {
 
 	channelMap := map[string]goutil.UpdateChannel{testBinTool: goutil.UpdateChannelLatest}
 	p, _ := newTestPrinter()
-	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false)
+	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false, nil)
 	if result != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", result)
 	}
@@ -1112,7 +1117,7 @@ func Test_updateWithChannels_emptyModulePath(t *testing.T) {
 
 func Test_updateWithChannels_getLatestVerError(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) {
+	deps.getLatestVer = func(context.Context, string, string) (string, error) {
 		return "", errors.New("network error")
 	}
 
@@ -1128,7 +1133,7 @@ func Test_updateWithChannels_getLatestVerError(t *testing.T) {
 
 	channelMap := map[string]goutil.UpdateChannel{testBinTool: goutil.UpdateChannelLatest}
 	p, _ := newTestPrinter()
-	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false)
+	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false, nil)
 	if result != 1 {
 		t.Fatalf("updateWithChannels() = %d, want 1", result)
 	}
@@ -1136,11 +1141,11 @@ func Test_updateWithChannels_getLatestVerError(t *testing.T) {
 
 func Test_updateWithChannels_masterChannel(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
 	// The skip/update decision resolves the version via the @master ref.
-	deps.getVerByRef = func(_ context.Context, _ string, _ string) (string, error) { return testVersionNine, nil }
+	deps.getVerByRef = func(_ context.Context, _, _, _ string) (string, error) { return testVersionNine, nil }
 	var calledVersion string
-	deps.installByVersion = func(_ context.Context, _, ver string) error { calledVersion = ver; return nil }
+	deps.installByVersion = func(_ context.Context, _, ver string, _ goutil.InstallOptions) error { calledVersion = ver; return nil }
 
 	pkgs := []goutil.Package{
 		{
@@ -1154,7 +1159,7 @@ func Test_updateWithChannels_masterChannel(t *testing.T) {
 
 	channelMap := map[string]goutil.UpdateChannel{testBinTool: goutil.UpdateChannelMaster}
 	p, _ := newTestPrinter()
-	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false)
+	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false, nil)
 	if result != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", result)
 	}
@@ -1170,8 +1175,8 @@ func Test_updateWithChannels_masterChannel(t *testing.T) {
 // @master has moved forward and must trigger an update.
 func Test_updateWithChannels_masterChannel_skipDecisionUsesChannel(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionOne, nil }
-	deps.getVerByRef = func(_ context.Context, _ string, ref string) (string, error) {
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionOne, nil }
+	deps.getVerByRef = func(_ context.Context, _, ref, _ string) (string, error) {
 		if ref == string(goutil.UpdateChannelMaster) {
 			return testVersionNine, nil
 		}
@@ -1180,7 +1185,7 @@ func Test_updateWithChannels_masterChannel_skipDecisionUsesChannel(t *testing.T)
 
 	var installCalled atomic.Bool
 	var calledVersion string
-	deps.installByVersion = func(_ context.Context, _, ver string) error {
+	deps.installByVersion = func(_ context.Context, _, ver string, _ goutil.InstallOptions) error {
 		installCalled.Store(true)
 		calledVersion = ver
 		return nil
@@ -1198,7 +1203,7 @@ func Test_updateWithChannels_masterChannel_skipDecisionUsesChannel(t *testing.T)
 
 	channelMap := map[string]goutil.UpdateChannel{testBinTool: goutil.UpdateChannelMaster}
 	p, _ := newTestPrinter()
-	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false)
+	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false, nil)
 	if result != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", result)
 	}
@@ -1216,15 +1221,15 @@ func Test_updateWithChannels_masterChannel_skipDecisionUsesChannel(t *testing.T)
 // package must be skipped.
 func Test_updateWithChannels_masterChannel_latestMovedButMasterSame(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
-	deps.getVerByRef = func(_ context.Context, _ string, ref string) (string, error) {
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
+	deps.getVerByRef = func(_ context.Context, _, ref, _ string) (string, error) {
 		if ref == string(goutil.UpdateChannelMaster) {
 			return testVersionOne, nil
 		}
 		return "", fmt.Errorf("unexpected ref %q", ref)
 	}
 
-	deps.installByVersion = func(context.Context, string, string) error {
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error {
 		t.Fatal("install must not run: @master is unchanged")
 		return nil
 	}
@@ -1241,7 +1246,7 @@ func Test_updateWithChannels_masterChannel_latestMovedButMasterSame(t *testing.T
 
 	channelMap := map[string]goutil.UpdateChannel{testBinTool: goutil.UpdateChannelMaster}
 	p, _ := newTestPrinter()
-	result, succeeded, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false)
+	result, succeeded, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false, nil)
 	if result != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", result)
 	}
@@ -1255,8 +1260,8 @@ func Test_updateWithChannels_masterChannel_latestMovedButMasterSame(t *testing.T
 // installed version, but @main has moved and must trigger an update.
 func Test_updateWithChannels_mainChannel_skipDecisionUsesChannel(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionOne, nil }
-	deps.getVerByRef = func(_ context.Context, _ string, ref string) (string, error) {
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionOne, nil }
+	deps.getVerByRef = func(_ context.Context, _, ref, _ string) (string, error) {
 		if ref == string(goutil.UpdateChannelMain) {
 			return testVersionNine, nil
 		}
@@ -1264,7 +1269,7 @@ func Test_updateWithChannels_mainChannel_skipDecisionUsesChannel(t *testing.T) {
 	}
 
 	var installCalled atomic.Bool
-	deps.installMainOrMaster = func(context.Context, string) error {
+	deps.installMainOrMaster = func(context.Context, string, goutil.InstallOptions) error {
 		installCalled.Store(true)
 		return nil
 	}
@@ -1281,7 +1286,7 @@ func Test_updateWithChannels_mainChannel_skipDecisionUsesChannel(t *testing.T) {
 
 	channelMap := map[string]goutil.UpdateChannel{testBinTool: goutil.UpdateChannelMain}
 	p, _ := newTestPrinter()
-	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false)
+	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false, nil)
 	if result != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", result)
 	}
@@ -1324,8 +1329,8 @@ func Test_removeOldBinaryIfRenamed_notExist(t *testing.T) {
 
 func Test_updateWithChannels_notify(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
-	deps.installLatest = func(context.Context, string) error { return nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error { return nil }
 
 	pkgs := []goutil.Package{
 		{
@@ -1339,7 +1344,7 @@ func Test_updateWithChannels_notify(t *testing.T) {
 
 	channelMap := map[string]goutil.UpdateChannel{testBinTool: goutil.UpdateChannelLatest}
 	p, _ := newTestPrinter()
-	result, _, _ := updateWithChannels(deps, p, pkgs, false, true, 1, true, channelMap, nil, 0, false, false)
+	result, _, _ := updateWithChannels(deps, p, pkgs, false, true, 1, true, channelMap, nil, 0, false, false, nil)
 	if result != 0 {
 		t.Fatalf("updateWithChannels() with notify = %d, want 0", result)
 	}
@@ -1347,8 +1352,8 @@ func Test_updateWithChannels_notify(t *testing.T) {
 
 func Test_updateWithChannels_installError(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
-	deps.installLatest = func(context.Context, string) error { return errors.New("install failed") }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error { return errors.New("install failed") }
 
 	pkgs := []goutil.Package{
 		{
@@ -1362,7 +1367,7 @@ func Test_updateWithChannels_installError(t *testing.T) {
 
 	channelMap := map[string]goutil.UpdateChannel{testBinTool: goutil.UpdateChannelLatest}
 	p, _ := newTestPrinter()
-	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false)
+	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false, nil)
 	if result != 1 {
 		t.Fatalf("updateWithChannels() = %d, want 1", result)
 	}
@@ -1370,15 +1375,15 @@ func Test_updateWithChannels_installError(t *testing.T) {
 
 func Test_updateWithChannels_mainChannel(t *testing.T) {
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return testVersionNine, nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return testVersionNine, nil }
 	// The skip/update decision resolves the version via the @main ref.
-	deps.getVerByRef = func(_ context.Context, _ string, _ string) (string, error) { return testVersionNine, nil }
-	deps.installLatest = func(context.Context, string) error {
+	deps.getVerByRef = func(_ context.Context, _, _, _ string) (string, error) { return testVersionNine, nil }
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error {
 		t.Fatal("installLatest should not be called for main channel")
 		return nil
 	}
-	deps.installMainOrMaster = func(context.Context, string) error { return nil }
-	deps.installByVersion = func(context.Context, string, string) error { return nil }
+	deps.installMainOrMaster = func(context.Context, string, goutil.InstallOptions) error { return nil }
+	deps.installByVersion = func(context.Context, string, string, goutil.InstallOptions) error { return nil }
 
 	pkgs := []goutil.Package{
 		{
@@ -1392,7 +1397,7 @@ func Test_updateWithChannels_mainChannel(t *testing.T) {
 
 	channelMap := map[string]goutil.UpdateChannel{testBinTool: goutil.UpdateChannelMain}
 	p, _ := newTestPrinter()
-	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false)
+	result, _, _ := updateWithChannels(deps, p, pkgs, false, false, 1, true, channelMap, nil, 0, false, false, nil)
 	if result != 0 {
 		t.Fatalf("updateWithChannels() = %d, want 0", result)
 	}
@@ -1473,9 +1478,9 @@ func TestRemoveOldBinaryIfRenamed_missingOldBinaryIsNoError(t *testing.T) {
 func TestUpdateWithChannels_modulePathRenameRetry(t *testing.T) {
 	t.Setenv("GOBIN", t.TempDir())
 	deps := testDeps()
-	deps.getLatestVer = func(context.Context, string) (string, error) { return "v1.1.0", nil }
+	deps.getLatestVer = func(context.Context, string, string) (string, error) { return "v1.1.0", nil }
 	calls := 0
-	deps.installLatest = func(context.Context, string) error {
+	deps.installLatest = func(context.Context, string, goutil.InstallOptions) error {
 		calls++
 		if calls == 1 {
 			return errors.New("module declares its path as: example.com/new\n\tbut was required as: example.com/old")
@@ -1494,7 +1499,7 @@ func TestUpdateWithChannels_modulePathRenameRetry(t *testing.T) {
 
 	// jsonOut=true keeps output machine-readable and avoids the human renderer,
 	// which would dereference the (test-omitted) GoVersion for display.
-	code, succeeded, renamed := updateWithChannels(deps, discardPrinter(), pkgs, false, false, 1, true, channelMap, nil, 0, true, false)
+	code, succeeded, renamed := updateWithChannels(deps, discardPrinter(), pkgs, false, false, 1, true, channelMap, nil, 0, true, false, nil)
 	if code != 0 {
 		t.Fatalf("updateWithChannels() exit = %d, want 0", code)
 	}
@@ -1511,3 +1516,82 @@ func TestUpdateWithChannels_modulePathRenameRetry(t *testing.T) {
 		t.Fatalf("renamed = %v, want %s->%s", renamed, testNameOld, wantNew)
 	}
 }
+
+func Test_updateWithChannels_goToolchain(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	got := map[string]string{}
+	deps := stubUpdateDeps()
+	deps.installLatest = func(_ context.Context, importPath string, opts goutil.InstallOptions) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got[importPath] = opts.Toolchain
+		return nil
+	}
+
+	// Both tools are at the latest version. "old" was built with go1.22.4 but
+	// gup.json selects go1.23.0, so it is rebuilt with that toolchain.
+	pkgs := configstate.ApplyGoToolchains([]goutil.Package{
+		newCheckPkg("old", testVersionNine, goutil.UpdateChannelLatest),
+		newCheckPkg("same", testVersionNine, goutil.UpdateChannelLatest),
+	}, []goutil.Package{
+		{Name: "old", GoToolchain: "go1.23.0"},
+		{Name: "same", GoToolchain: testGoVersion1224},
+	}, "")
+
+	p, _ := newTestPrinter()
+	if code, _, _ := updateWithChannels(deps, p, pkgs, false, false, 2, false, nil, nil, 0, false, false, nil); code != 0 {
+		t.Fatalf("updateWithChannels() = %d, want 0", code)
+	}
+	if diff := cmp.Diff(map[string]string{"example.com/old/cmd/old": "go1.23.0"}, got); diff != "" {
+		t.Errorf("installs mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_gup_skipsExcludedBinaries(t *testing.T) {
+	gobin, err := filepath.Abs(filepath.Join("testdata", "check_success"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOBIN", gobin)
+	setupXDGBase(t)
+	chdirToTemp(t)
+	conf := `{"schema_version": 1, "packages": [
+  {"name": "gal", "import_path": "github.com/nao1215/gal/cmd/gal", "version": "latest", "channel": "latest", "excluded": true}
+]}`
+	if err := os.WriteFile(config.ConfigFileName, []byte(conf), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	installed := map[string]bool{}
+	deps := stubUpdateDeps()
+	record := func(importPath string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		installed[importPath] = true
+		return nil
+	}
+	deps.installLatest = func(_ context.Context, importPath string, _ goutil.InstallOptions) error { return record(importPath) }
+	deps.installByVersion = func(_ context.Context, importPath, _ string, _ goutil.InstallOptions) error {
+		return record(importPath)
+	}
+
+	p, buf := newTestPrinter()
+	if got := gup(deps, p, newUpdateCmd(), nil); got != 0 {
+		t.Fatalf("gup() = %d, want 0; output:\n%s", got, buf.String())
+	}
+	if installed["github.com/nao1215/gal/cmd/gal"] || !installed["github.com/nao1215/subaru"] {
+		t.Errorf("installed %v, want subaru but not the excluded gal", installed)
+	}
+
+	// Naming an excluded binary updates it.
+	p, buf = newTestPrinter()
+	if got := gup(deps, p, newUpdateCmd(), []string{"gal"}); got != 0 {
+		t.Fatalf("gup(gal) = %d, want 0; output:\n%s", got, buf.String())
+	}
+	if !installed["github.com/nao1215/gal/cmd/gal"] {
+		t.Errorf("gup(gal) did not update the named excluded binary; output:\n%s", buf.String())
+	}
+}
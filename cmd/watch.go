@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/print"
+	"github.com/spf13/cobra"
+)
+
+// defaultWatchDebounce is how long a binary in $GOBIN must go unchanged before
+// 'gup watch' reads it.
+const defaultWatchDebounce = 2 * time.Second
+
+// watchLogFileName is the log of a 'gup watch --daemon' process, kept next to
+// the user-level gup.json.
+const watchLogFileName = "watch.log"
+
+func newWatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Watch $GOBIN and record go-installed binaries in gup.json",
+		Long: `Watch $GOBIN and record go-installed binaries in gup.json.
+
+gup watch keeps gup.json accurate when binaries are installed with 'go install'
+instead of gup. Whenever a binary in $GOBIN is added or replaced, its build
+info is read and its entry in gup.json is added or updated; the channel, pin
+and Go toolchain gup.json already records for it are kept, and a new tool is
+recorded on @latest. When a binary is deleted, its entry is marked as removed
+but kept, so gup.json still records the tool; import, bundle and sync leave
+it out, diff reports it as removed, and installing it again clears the mark. Files that are not go-installed binaries, and Go's own
+commands, are ignored.
+
+The watch is notified of changes by the operating system (inotify, kqueue or
+ReadDirectoryChangesW), so an idle $GOBIN costs nothing. A binary is only read
+once it has gone --debounce without a change, so a binary 'go install' is
+still writing is never recorded half-written. Binaries already in $GOBIN when
+the watch starts are left alone; use 'gup export' to record them.
+
+gup watch runs in the foreground until interrupted. With --daemon it starts
+itself in the background, prints the process ID, and logs to --log.`,
+		Example: `  gup watch
+  gup watch --file ./gup.json --debounce 5s
+  gup watch --daemon`,
+		Args:              cobra.NoArgs,
+		ValidArgsFunction: cobra.NoFileCompletions,
+		Run: func(cmd *cobra.Command, args []string) {
+			OsExit(runWatch(printerFor(cmd), cmd, args))
+		},
+	}
+
+	cmd.Flags().StringP("file", "f", "", "specify gup.json file path to record the binaries in")
+	mustMarkFileFlagAsJSON(cmd)
+	cmd.Flags().Duration("debounce", defaultWatchDebounce, "how long a binary must go unchanged before it is read (e.g. 2s, 1m)")
+	mustRegisterFlagCompletion(cmd, "debounce", cobra.NoFileCompletions)
+	cmd.Flags().Bool("daemon", false, "run the watch in the background")
+	cmd.Flags().String("log", "", "log file of the background watch (default: "+watchLogFileName+" next to the user-level gup.json)")
+
+	return cmd
+}
+
+func runWatch(p *print.Printer, cmd *cobra.Command, _ []string) int {
+	confFile, err := getFlagString(cmd, "file")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	debounce, err := cmd.Flags().GetDuration("debounce")
+	if err != nil {
+		p.Err(fmt.Errorf("can not parse command line argument (--debounce): %w", err))
+		return 1
+	}
+	if debounce <= 0 {
+		p.Err("can not parse command line argument (--debounce): must be > 0")
+		return 1
+	}
+	daemon, err := getFlagBool(cmd, "daemon")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+	logFile, err := getFlagString(cmd, "log")
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	// Resolve the config path up front, so an ambiguous or broken gup.json
+	// fails the command instead of every later write.
+	if _, err := config.ResolveImportFilePath(confFile); err != nil {
+		p.Err(err)
+		return 1
+	}
+	gobin, err := goutil.GoBin()
+	if err != nil {
+		p.Err(err)
+		return 1
+	}
+
+	if daemon {
+		return startWatchDaemon(p, confFile, debounce, logFile)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return watchGobin(ctx, p, gobin, confFile, debounce)
+}
+
+// watchGobin watches gobin until ctx is done, recording the changes it sees
+// in gup.json.
+func watchGobin(ctx context.Context, p *print.Printer, gobin, confFile string, debounce time.Duration) int {
+	w, err := newGobinWatcher(gobin, debounce)
+	if err != nil {
+		p.Err(fmt.Errorf("can't watch %s: %w", gobin, err))
+		return 1
+	}
+	defer w.close()
+	p.Info(fmt.Sprintf("watching %s for go-installed binaries", gobin))
+
+	w.watch(ctx, func(changed, removed []string) {
+		recordWatchChanges(p, confFile, changed, removed)
+	}, func(err error) {
+		p.Warn(fmt.Errorf("watching %s: %w", gobin, err))
+	})
+	p.Info("stopped watching " + gobin)
+	return 0
+}
+
+// recordWatchChanges upserts the go-installed binaries among the changed
+// paths into gup.json and marks the entries of the removed binaries as
+// removed. A failed write is reported, and the watch goes on.
+func recordWatchChanges(p *print.Printer, confFile string, changed, removed []string) {
+	if len(changed) > 0 {
+		pkgs := goutil.GetPackageInformationWithoutGoVersion(p, changed)
+		if len(pkgs) > 0 {
+			if err := autoExportInstalled(confFile, pkgs); err != nil {
+				p.Warn(err)
+			} else {
+				for _, pkg := range pkgs {
+					p.Info(fmt.Sprintf("recorded %s (%s@%s)", pkg.Name, pkg.ImportPath, pkg.Version.Current))
+				}
+			}
+		}
+	}
+	if len(removed) > 0 {
+		marked, err := autoExportMarkRemoved(confFile, removed)
+		if err != nil {
+			p.Warn(err)
+		}
+		for _, name := range marked {
+			p.Info(fmt.Sprintf("%s was deleted: marked as removed in gup.json", name))
+		}
+	}
+}
+
+// gobinWatcher reports the binaries added to, replaced in and deleted from a
+// directory, from the file system events of the directory.
+type gobinWatcher struct {
+	dir      string
+	debounce time.Duration
+	events   *fsnotify.Watcher
+	// pending maps each file whose events have not settled yet to the time of
+	// its last event.
+	pending map[string]time.Time
+}
+
+// newGobinWatcher starts watching dir, so only the changes from now on are
+// reported.
+func newGobinWatcher(dir string, debounce time.Duration) (*gobinWatcher, error) {
+	events, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := events.Add(dir); err != nil {
+		_ = events.Close()
+		return nil, err
+	}
+	return &gobinWatcher{dir: dir, debounce: debounce, events: events, pending: map[string]time.Time{}}, nil
+}
+
+// close stops watching.
+func (w *gobinWatcher) close() {
+	_ = w.events.Close()
+}
+
+// watch calls report until ctx is done with the paths of the files that were
+// added or replaced, once each went debounce without another event, and the
+// names of the files that were deleted. Errors of the watch go to warn, and
+// the watch goes on.
+func (w *gobinWatcher) watch(ctx context.Context, report func(changed, removed []string), warn func(error)) {
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-w.events.Events:
+			if !ok {
+				return
+			}
+			name := filepath.Base(ev.Name)
+			// A change of mode alone leaves the binary as it is; dotfiles are
+			// skipped like goutil.BinaryPathList does.
+			if ev.Op == fsnotify.Chmod || strings.HasPrefix(name, ".") {
+				continue
+			}
+			if len(w.pending) == 0 {
+				timer.Reset(w.debounce)
+			}
+			w.pending[name] = time.Now()
+		case err, ok := <-w.events.Errors:
+			if !ok {
+				return
+			}
+			warn(err)
+		case now := <-timer.C:
+			changed, removed, next := w.settle(now, warn)
+			if next > 0 {
+				timer.Reset(next)
+			}
+			if len(changed) > 0 || len(removed) > 0 {
+				report(changed, removed)
+			}
+		}
+	}
+}
+
+// settle takes the files that went debounce without an event by now out of
+// pending, and sorts them into the changed and the removed ones by what is
+// in the directory now. next is the wait until the next pending file
+// settles, or 0 when none is left.
+func (w *gobinWatcher) settle(now time.Time, warn func(error)) (changed, removed []string, next time.Duration) {
+	for name, last := range w.pending {
+		if wait := last.Add(w.debounce).Sub(now); wait > 0 {
+			if next == 0 || wait < next {
+				next = wait
+			}
+			continue
+		}
+		delete(w.pending, name)
+		path := filepath.Join(w.dir, name)
+		info, err := os.Lstat(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			removed = append(removed, name)
+		case err != nil:
+			warn(err)
+		case info.Mode().IsRegular():
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return changed, removed, next
+}
+
+// startWatchDaemon starts 'gup watch' again as a background process, detached
+// from the terminal, with its output appended to logFile.
+func startWatchDaemon(p *print.Printer, confFile string, debounce time.Duration, logFile string) int {
+	if logFile == "" {
+		logFile = filepath.Join(config.DirPath(), watchLogFileName)
+	}
+	if err := os.MkdirAll(filepath.Dir(logFile), 0o750); err != nil {
+		p.Err(fmt.Errorf("can't create the log directory: %w", err))
+		return 1
+	}
+	log, err := os.OpenFile(filepath.Clean(logFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		p.Err(fmt.Errorf("can't open the log file: %w", err))
+		return 1
+	}
+	defer log.Close()
+
+	exe, err := os.Executable()
+	if err != nil {
+		p.Err(fmt.Errorf("can't find the gup executable: %w", err))
+		return 1
+	}
+	args := []string{"watch", "--" + noColorFlagName, "--debounce", debounce.String()}
+	if confFile != "" {
+		args = append(args, "--file", confFile)
+	}
+	child := exec.CommandContext(context.Background(), exe, args...) //nolint:gosec // re-runs this gup binary
+	child.Stdout = log
+	child.Stderr = log
+	child.SysProcAttr = detachedProcAttr()
+	if err := child.Start(); err != nil {
+		p.Err(fmt.Errorf("can't start the background watch: %w", err))
+		return 1
+	}
+	pid := child.Process.Pid
+	if err := child.Process.Release(); err != nil {
+		p.Warn(err)
+	}
+	p.Info(fmt.Sprintf("gup watch is running in the background (pid %d), logging to %s", pid, logFile))
+	return 0
+}
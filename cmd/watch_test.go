@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/nao1215/gup/internal/config"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+func Test_gobinWatcher_watch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("old", "v1")
+
+	const debounce = 100 * time.Millisecond
+	w, err := newGobinWatcher(dir, debounce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.close()
+
+	type change struct{ changed, removed []string }
+	reports := make(chan change, 8)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.watch(ctx, func(changed, removed []string) {
+		reports <- change{changed, removed}
+	}, func(err error) { t.Error(err) })
+	next := func() change {
+		t.Helper()
+		select {
+		case c := <-reports:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("no change reported")
+			return change{}
+		}
+	}
+
+	// A binary written in several steps is reported once, after the last
+	// write settled; dotfiles are ignored.
+	begin := time.Now()
+	write("new", "v1")
+	write(".hidden", "x")
+	write("new", "v1 grown")
+	got := next()
+	if waited := time.Since(begin); waited < debounce {
+		t.Errorf("reported after %v, before the binary went %v unchanged", waited, debounce)
+	}
+	if diff := cmp.Diff(change{changed: []string{filepath.Join(dir, "new")}}, got,
+		cmp.AllowUnexported(change{}), cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("report mismatch (-want +got):\n%s", diff)
+	}
+
+	// A replaced binary and a deleted one are reported.
+	write("old", "v2 is longer")
+	if err := os.Remove(filepath.Join(dir, "new")); err != nil {
+		t.Fatal(err)
+	}
+	// The two may settle in one report or in two.
+	got = next()
+	if len(got.changed) == 0 || len(got.removed) == 0 {
+		other := next()
+		got.changed = append(got.changed, other.changed...)
+		got.removed = append(got.removed, other.removed...)
+	}
+	want := change{changed: []string{filepath.Join(dir, "old")}, removed: []string{"new"}}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(change{}), cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("report mismatch (-want +got):\n%s", diff)
+	}
+	select {
+	case c := <-reports:
+		t.Errorf("unexpected report %+v", c)
+	case <-time.After(3 * debounce):
+	}
+}
+
+//nolint:paralleltest // swaps XDG env and the working directory
+func Test_recordWatchChanges(t *testing.T) {
+	setupXDGBase(t)
+	chdirToTemp(t)
+
+	writePath, err := config.ResolveImportFilePath("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeConfigFile(writePath, []goutil.Package{
+		{Name: "gone", ImportPath: "example.com/gone", Version: &goutil.Version{Current: "v1.0.0"}, UpdateChannel: goutil.UpdateChannelLatest},
+		{Name: "kept", ImportPath: "example.com/kept", Version: &goutil.Version{Current: "v0.1.0"}, UpdateChannel: goutil.UpdateChannelPinned, PinnedVersion: "v0.1.0"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The test binary itself is a module binary with build info, so it stands
+	// in for one 'go install' just wrote; a text file is not a binary at all.
+	dir := t.TempDir()
+	tool := filepath.Join(dir, "tool")
+	copyTestExecutable(t, tool)
+	notes := filepath.Join(dir, "notes")
+	if err := os.WriteFile(notes, []byte("not a binary"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, buf := newTestPrinter()
+	recordWatchChanges(p, "", []string{notes, tool}, []string{"gone"})
+
+	pkgs, err := config.ReadConfFile(writePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]goutil.UpdateChannel{}
+	for _, pkg := range pkgs {
+		got[pkg.Name] = pkg.UpdateChannel
+	}
+	want := map[string]goutil.UpdateChannel{"gone": goutil.UpdateChannelLatest, "kept": goutil.UpdateChannelPinned, "tool": goutil.UpdateChannelLatest}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("gup.json mismatch (-want +got):\n%s\noutput:\n%s", diff, buf.String())
+	}
+	for _, pkg := range pkgs {
+		if pkg.Removed != (pkg.Name == "gone") {
+			t.Errorf("%s removed = %v, want only the deleted binary marked", pkg.Name, pkg.Removed)
+		}
+	}
+}
+
+func Test_watchGobin_stopsWhenCanceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p, buf := newTestPrinter()
+	if got := watchGobin(ctx, p, t.TempDir(), "", time.Hour); got != 0 {
+		t.Errorf("watchGobin() = %d, want 0; output:\n%s", got, buf.String())
+	}
+}
+
+func Test_watchGobin_missingDir(t *testing.T) {
+	t.Parallel()
+
+	p, _ := newTestPrinter()
+	if got := watchGobin(context.Background(), p, filepath.Join(t.TempDir(), "none"), "", time.Hour); got != 1 {
+		t.Errorf("watchGobin() = %d, want 1", got)
+	}
+}
+
+// copyTestExecutable copies the running test binary to dst.
+func copyTestExecutable(t *testing.T, dst string) {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := os.Open(filepath.Clean(exe))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	out, err := os.Create(filepath.Clean(dst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
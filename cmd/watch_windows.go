@@ -0,0 +1,15 @@
+//go:build windows
+
+package cmd
+
+import "syscall"
+
+// detachedProcess is the DETACHED_PROCESS process creation flag, which the
+// syscall package does not define.
+const detachedProcess = 0x00000008
+
+// detachedProcAttr starts the background watch without a console, in its own
+// process group, so closing the console does not stop it.
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess}
+}
@@ -0,0 +1,118 @@
+// Package buildhist keeps how long the last 'go install' of each binary took,
+// in gup's state directory. 'gup update' starts the binaries that took longest
+// first, so a long build never starts last and stretches the whole run.
+package buildhist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/nao1215/gup/internal/fileutil"
+)
+
+// schemaVersion is the history schema written by this gup.
+const schemaVersion = 1
+
+// FileName is the history file in gup's state directory.
+const FileName = "build-times.json"
+
+// History maps binary names to how long their last build took.
+type History struct {
+	SchemaVersion int `json:"schema_version"`
+	// BuildsMS maps a binary name to the duration of its last build, in
+	// milliseconds.
+	BuildsMS map[string]int64 `json:"builds_ms"`
+}
+
+// New returns an empty history.
+func New() History {
+	return History{SchemaVersion: schemaVersion, BuildsMS: map[string]int64{}}
+}
+
+// Duration returns how long the last build of name took, or 0 when it is not
+// known.
+func (h History) Duration(name string) time.Duration {
+	return time.Duration(h.BuildsMS[name]) * time.Millisecond
+}
+
+// Record sets d as the duration of the last build of name.
+func (h History) Record(name string, d time.Duration) {
+	h.BuildsMS[name] = d.Milliseconds()
+}
+
+// Order returns the indexes of names with the longest known build first.
+// Names with no known build follow in their own order.
+func (h History) Order(names []string) []int {
+	order := make([]int, len(names))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return h.BuildsMS[names[order[a]]] > h.BuildsMS[names[order[b]]]
+	})
+	return order
+}
+
+// Read reads the history file at path. A missing file is an empty history.
+func Read(path string) (History, error) {
+	raw, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return History{}, fmt.Errorf("can't read %s: %w", path, err)
+	}
+	var h History
+	if err := json.Unmarshal(raw, &h); err != nil {
+		return History{}, fmt.Errorf("%s is not valid JSON: %w", path, err)
+	}
+	if h.SchemaVersion != schemaVersion {
+		return History{}, fmt.Errorf("%s has unsupported schema_version: %d (supported: %d)", path, h.SchemaVersion, schemaVersion)
+	}
+	if h.BuildsMS == nil {
+		h.BuildsMS = map[string]int64{}
+	}
+	return h, nil
+}
+
+// Write replaces the history file at path with h. The file is written to a
+// temporary file and renamed, so an interrupted write leaves the last history
+// intact.
+func Write(path string, h History) (err error) {
+	raw, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't marshal the build history: %w", err)
+	}
+	raw = append(raw, '\n')
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, fileutil.FileModeCreatingDir); err != nil {
+		return fmt.Errorf("can't create %s: %w", dir, err)
+	}
+	file, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("can't create temp file for %s: %w", path, err)
+	}
+	tmpPath := file.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+	if _, err = file.Write(raw); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("can't write %s: %w", tmpPath, err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("can't write %s: %w", tmpPath, err)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("can't write %s: %w", path, err)
+	}
+	return nil
+}
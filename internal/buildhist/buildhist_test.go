@@ -0,0 +1,79 @@
+package buildhist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteRead(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state", FileName)
+	got, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() of a missing file error = %v", err)
+	}
+	if diff := cmp.Diff(New(), got); diff != "" {
+		t.Errorf("Read() of a missing file mismatch (-want +got):\n%s", diff)
+	}
+
+	h := New()
+	h.Record("gopls", 81*time.Second)
+	h.Record("air", 1500*time.Millisecond)
+	if err := Write(path, h); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	got, err = Read(path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if diff := cmp.Diff(h, got); diff != "" {
+		t.Errorf("Read() mismatch (-want +got):\n%s", diff)
+	}
+	if d := got.Duration("air"); d != 1500*time.Millisecond {
+		t.Errorf("Duration(air) = %v, want 1.5s", d)
+	}
+	if d := got.Duration("lazygit"); d != 0 {
+		t.Errorf("Duration(lazygit) = %v, want 0 for an unknown binary", d)
+	}
+}
+
+func TestRead_invalid(t *testing.T) {
+	t.Parallel()
+
+	for name, content := range map[string]string{
+		"not JSON":       "{",
+		"unknown schema": `{"schema_version": 9, "builds_ms": {}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), FileName)
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Read(path); err == nil {
+				t.Error("Read() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestHistory_Order(t *testing.T) {
+	t.Parallel()
+
+	h := New()
+	h.Record("air", 2*time.Second)
+	h.Record("gopls", 80*time.Second)
+	h.Record("golangci-lint", 120*time.Second)
+
+	got := h.Order([]string{"air", "new-tool", "gopls", "other-tool", "golangci-lint"})
+	// The known builds come longest first, then the unknown ones in their
+	// own order.
+	if diff := cmp.Diff([]int{4, 2, 0, 1, 3}, got); diff != "" {
+		t.Errorf("Order() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,150 @@
+// Package buildmanifest reads and writes gup-manifest.json, the manifest that
+// 'gup export --build' writes next to the binaries it cross-compiles. It
+// records the target platform and, for every binary, the package it was built
+// from and its SHA-256, so 'gup migrate' can install the prebuilt binaries on
+// the target machine after checking they arrived intact.
+package buildmanifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// FileName is the manifest's file name inside the build directory.
+const FileName = "gup-manifest.json"
+
+// schemaVersion is the manifest schema written by this gup.
+const schemaVersion = 1
+
+// Manifest is the content of gup-manifest.json. Its field names are part of
+// the public contract.
+type Manifest struct {
+	SchemaVersion int     `json:"schema_version"`
+	GOOS          string  `json:"goos"`
+	GOARCH        string  `json:"goarch"`
+	Binaries      []Entry `json:"binaries"`
+}
+
+// Entry is one binary in the build directory.
+type Entry struct {
+	// File is the binary's file name inside the build directory.
+	File       string `json:"file"`
+	ImportPath string `json:"import_path"`
+	Version    string `json:"version"`
+	Channel    string `json:"channel"`
+	SHA256     string `json:"sha256"`
+}
+
+// New returns an empty manifest for platform.
+func New(platform goutil.Platform) Manifest {
+	return Manifest{SchemaVersion: schemaVersion, GOOS: platform.OS, GOARCH: platform.Arch, Binaries: []Entry{}}
+}
+
+// Platform returns the platform the binaries were built for.
+func (m Manifest) Platform() goutil.Platform {
+	return goutil.Platform{OS: m.GOOS, Arch: m.GOARCH}
+}
+
+// Read reads the manifest in dir. The second return value is false when dir
+// has no manifest, which is not an error: dir is then an ordinary GOBIN.
+func Read(dir string) (Manifest, bool, error) {
+	path := filepath.Join(dir, FileName)
+	raw, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return Manifest{}, false, nil
+	}
+	if err != nil {
+		return Manifest{}, false, fmt.Errorf("can't read %s: %w", path, err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return Manifest{}, false, fmt.Errorf("%s is not valid JSON: %w", path, err)
+	}
+	if m.SchemaVersion != schemaVersion {
+		return Manifest{}, false, fmt.Errorf("%s has unsupported schema_version: %d (supported: %d)", path, m.SchemaVersion, schemaVersion)
+	}
+	for i, e := range m.Binaries {
+		if e.File == "" || e.File != filepath.Base(e.File) || strings.ContainsAny(e.File, `/\`) || e.File == ".." {
+			return Manifest{}, false, fmt.Errorf("%s contains an invalid file name at index %d", path, i)
+		}
+		if e.SHA256 == "" {
+			return Manifest{}, false, fmt.Errorf("%s is missing the sha256 of %s", path, e.File)
+		}
+	}
+	return m, true, nil
+}
+
+// Write writes m to dir atomically.
+func Write(dir string, m Manifest) (err error) {
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't marshal %s: %w", FileName, err)
+	}
+	raw = append(raw, '\n')
+
+	file, err := os.CreateTemp(dir, FileName+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("can't create temp file for %s: %w", FileName, err)
+	}
+	tmpPath := file.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+	if _, err = file.Write(raw); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("can't write %s: %w", tmpPath, err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("can't write %s: %w", tmpPath, err)
+	}
+	if err = os.Chmod(tmpPath, fileutil.FileModeCreatingFile); err != nil {
+		return fmt.Errorf("can't write %s: %w", tmpPath, err)
+	}
+	if err = os.Rename(tmpPath, filepath.Join(dir, FileName)); err != nil {
+		return fmt.Errorf("can't write %s: %w", filepath.Join(dir, FileName), err)
+	}
+	return nil
+}
+
+// HashFile returns the hex SHA-256 of the file at path.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("can't read %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Verify checks that e's binary in dir matches the recorded hash.
+func Verify(dir string, e Entry) error {
+	got, err := HashFile(filepath.Join(dir, e.File))
+	if err != nil {
+		return fmt.Errorf("can't verify %s: %w", e.File, err)
+	}
+	return e.CheckSum(got)
+}
+
+// CheckSum checks that got, the hex SHA-256 of e's binary, is the recorded hash.
+func (e Entry) CheckSum(got string) error {
+	if !strings.EqualFold(got, e.SHA256) {
+		return fmt.Errorf("%s does not match its sha256 in %s; the file was modified or corrupted", e.File, FileName)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package buildmanifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+func TestWriteRead(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "tool"), []byte("binary"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := HashFile(filepath.Join(dir, "tool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := New(goutil.Platform{OS: "linux", Arch: "arm64"})
+	m.Binaries = append(m.Binaries, Entry{
+		File: "tool", ImportPath: "example.com/tool", Version: "v1.0.0", Channel: "latest", SHA256: sum,
+	})
+	if err := Write(dir, m); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	got, ok, err := Read(dir)
+	if err != nil || !ok {
+		t.Fatalf("Read() = %v, %v, want a manifest", ok, err)
+	}
+	if diff := cmp.Diff(m, got); diff != "" {
+		t.Errorf("Read() mismatch (-want +got):\n%s", diff)
+	}
+	if got.Platform() != (goutil.Platform{OS: "linux", Arch: "arm64"}) {
+		t.Errorf("Platform() = %v", got.Platform())
+	}
+	if err := Verify(dir, got.Binaries[0]); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "tool"), []byte("tampered"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(dir, got.Binaries[0]); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Verify() of a modified binary error = %v, want mismatch", err)
+	}
+}
+
+func TestReadMissing(t *testing.T) {
+	t.Parallel()
+
+	_, ok, err := Read(t.TempDir())
+	if err != nil || ok {
+		t.Fatalf("Read() of a plain directory = %v, %v, want no manifest and no error", ok, err)
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "invalid JSON", content: "{"},
+		{name: "unsupported schema", content: `{"schema_version":9,"binaries":[]}`},
+		{name: "path in file name", content: `{"schema_version":1,"binaries":[{"file":"../evil","sha256":"00"}]}`},
+		{name: "missing hash", content: `{"schema_version":1,"binaries":[{"file":"tool"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, FileName), []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := Read(dir); err == nil {
+				t.Fatal("Read() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+// Package bundle reads and writes gup's offline bundle: a tar.gz archive that
+// carries a gup.json together with every module file 'go install' needs for it,
+// laid out as a GOPROXY tree, so the tools can be installed on a machine with no
+// network access.
+//
+// Archive layout:
+//
+//	gup.json      the configuration to import, with concrete versions
+//	proxy/...     module .info/.mod/.zip files and @v/list, in GOPROXY layout
+//	proxy/sumdb/  the checksum database records and tiles 'go install' looked
+//	              up, served to the go command through the proxy
+//	bundle.json   the manifest: the SHA-256 of every other file
+//
+// Extract verifies the manifest before anything is used: a file missing from
+// it, a hash mismatch, or an entry that is not a plain file under the layout
+// above (absolute paths, "..", links, devices) rejects the whole archive. The
+// manifest only tells that the archive is whole; the bundled checksum database
+// records, signed by the database, are what let the go command verify the
+// modules offline.
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nao1215/gup/internal/fileutil"
+)
+
+const (
+	// ConfigName is the gup.json entry of a bundle.
+	ConfigName = "gup.json"
+	// ProxyDir is the directory holding the GOPROXY tree.
+	ProxyDir = "proxy"
+	// manifestName is the manifest entry.
+	manifestName = "bundle.json"
+	// sumDBDir is the checksum database cache in the download cache, and in
+	// the proxy tree.
+	sumDBDir = "sumdb"
+	// schemaVersion is the manifest schema written by this gup.
+	schemaVersion = 1
+	// maxManifestSize bounds the manifest, which only lists file hashes.
+	maxManifestSize = 64 << 20
+)
+
+// errNoManifest is returned for an archive without bundle.json.
+var errNoManifest = errors.New("not a gup bundle: bundle.json is missing")
+
+// manifest is the bundle.json entry.
+type manifest struct {
+	SchemaVersion int               `json:"schema_version"`
+	GupVersion    string            `json:"gup_version,omitempty"`
+	Files         map[string]string `json:"files"`
+}
+
+// Write writes a bundle to w: confJSON as gup.json and every regular file under
+// downloadDir (a GOMODCACHE's cache/download directory, which already uses the
+// GOPROXY layout) below proxy/. The checksum database cache there
+// (sumdb/<name>/lookup and tile) has the layout a proxy serves it in, and each
+// database gets the sumdb/<name>/supported file that tells the go command the
+// proxy serves it. The go command's lock and temporary files are skipped;
+// they are not part of the proxy protocol.
+func Write(w io.Writer, downloadDir string, confJSON []byte, gupVersion string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	m := manifest{SchemaVersion: schemaVersion, GupVersion: gupVersion, Files: map[string]string{}}
+
+	if err := addBytes(tw, m.Files, ConfigName, confJSON); err != nil {
+		return err
+	}
+
+	var files, sumDBs []string
+	err := filepath.WalkDir(downloadDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(downloadDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if dir, name := path.Split(rel); dir == sumDBDir+"/" {
+				sumDBs = append(sumDBs, name)
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || skipDownloadFile(rel) {
+			return nil
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can't read module download cache %s: %w", downloadDir, err)
+	}
+	sort.Strings(files)
+	for _, rel := range files {
+		if err := addFile(tw, m.Files, ProxyDir+"/"+rel, filepath.Join(downloadDir, filepath.FromSlash(rel))); err != nil {
+			return err
+		}
+	}
+	for _, name := range sumDBs {
+		supported := ProxyDir + "/" + sumDBDir + "/" + name + "/supported"
+		if _, ok := m.Files[supported]; ok {
+			continue
+		}
+		if err := addBytes(tw, m.Files, supported, nil); err != nil {
+			return err
+		}
+	}
+
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't marshal bundle manifest: %w", err)
+	}
+	if err := addBytes(tw, nil, manifestName, raw); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("can't finish bundle archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("can't finish bundle archive: %w", err)
+	}
+	return nil
+}
+
+// skipDownloadFile reports whether a file in the download cache is go-command
+// bookkeeping rather than proxy content.
+func skipDownloadFile(rel string) bool {
+	for _, suffix := range []string{".lock", ".partial", ".tmp", ".ziphash"} {
+		if strings.HasSuffix(rel, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func addBytes(tw *tar.Writer, sums map[string]string, name string, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("can't write %s to bundle: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("can't write %s to bundle: %w", name, err)
+	}
+	if sums != nil {
+		sum := sha256.Sum256(data)
+		sums[name] = hex.EncodeToString(sum[:])
+	}
+	return nil
+}
+
+func addFile(tw *tar.Writer, sums map[string]string, name, src string) error {
+	f, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return fmt.Errorf("can't open %s: %w", src, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("can't stat %s: %w", src, err)
+	}
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: info.Size(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("can't write %s to bundle: %w", name, err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tw, h), f); err != nil {
+		return fmt.Errorf("can't write %s to bundle: %w", name, err)
+	}
+	sums[name] = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// Extract unpacks the bundle read from r into dest, which must exist, and
+// verifies every file against the manifest. On error dest may hold a partial
+// extraction; the caller owns (and removes) it.
+func Extract(r io.Reader, dest string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("not a gup bundle (gzip): %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	got := map[string]string{}
+	var m *manifest
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("can't read bundle: %w", err)
+		}
+		name, err := entryName(hdr)
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		if name == manifestName {
+			if m, err = readManifest(tr); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, dup := got[name]; dup {
+			return fmt.Errorf("bundle contains %s twice", name)
+		}
+		sum, err := extractFile(tr, filepath.Join(dest, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		got[name] = sum
+	}
+
+	if m == nil {
+		return errNoManifest
+	}
+	return verify(m, got)
+}
+
+// entryName validates a tar entry and returns its cleaned slash path.
+func entryName(hdr *tar.Header) (string, error) {
+	name := hdr.Name
+	clean := path.Clean(name)
+	if name == "" || path.IsAbs(name) || strings.Contains(name, `\`) || clean == ".." ||
+		strings.HasPrefix(clean, "../") || strings.Contains(name, ":") {
+		return "", fmt.Errorf("bundle entry %q escapes the bundle directory", name)
+	}
+	switch hdr.Typeflag {
+	case tar.TypeReg, tar.TypeDir:
+	default:
+		return "", fmt.Errorf("bundle entry %q is not a regular file", name)
+	}
+	if clean != ConfigName && clean != manifestName && clean != ProxyDir && !strings.HasPrefix(clean, ProxyDir+"/") {
+		return "", fmt.Errorf("unexpected bundle entry %q", name)
+	}
+	return clean, nil
+}
+
+func readManifest(r io.Reader) (*manifest, error) {
+	raw, err := io.ReadAll(io.LimitReader(r, maxManifestSize))
+	if err != nil {
+		return nil, fmt.Errorf("can't read bundle manifest: %w", err)
+	}
+	var m manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("bundle manifest is not valid JSON: %w", err)
+	}
+	if m.SchemaVersion != schemaVersion {
+		return nil, fmt.Errorf("bundle has unsupported schema_version: %d (supported: %d)", m.SchemaVersion, schemaVersion)
+	}
+	return &m, nil
+}
+
+func extractFile(r io.Reader, dst string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), fileutil.FileModeCreatingDir); err != nil {
+		return "", fmt.Errorf("can't create %s: %w", filepath.Dir(dst), err)
+	}
+	f, err := os.OpenFile(filepath.Clean(dst), os.O_CREATE|os.O_EXCL|os.O_WRONLY, fileutil.FileModeCreatingFile)
+	if err != nil {
+		return "", fmt.Errorf("can't create %s: %w", dst, err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil { //nolint:gosec // module zips are large by design; every byte is checked against the manifest
+		_ = f.Close()
+		return "", fmt.Errorf("can't extract %s: %w", dst, err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("can't extract %s: %w", dst, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verify checks that the extracted files are exactly the files the manifest
+// lists, with matching hashes.
+func verify(m *manifest, got map[string]string) error {
+	for name, sum := range got {
+		want, ok := m.Files[name]
+		if !ok {
+			return fmt.Errorf("bundle file %s is not listed in bundle.json", name)
+		}
+		if !strings.EqualFold(want, sum) {
+			return fmt.Errorf("bundle file %s does not match its checksum in bundle.json", name)
+		}
+	}
+	for name := range m.Files {
+		if _, ok := got[name]; !ok {
+			return fmt.Errorf("bundle file %s listed in bundle.json is missing", name)
+		}
+	}
+	if _, ok := got[ConfigName]; !ok {
+		return fmt.Errorf("not a gup bundle: %s is missing", ConfigName)
+	}
+	return nil
+}
+
+// ProxyURL returns the GOPROXY value that serves the proxy/ tree of a bundle
+// extracted into dir. dir must be absolute. Windows paths ("C:\x") become
+// "file:///C:/x".
+func ProxyURL(dir string) string {
+	p := filepath.ToSlash(filepath.Join(dir, ProxyDir))
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return "file://" + p
+}
@@ -0,0 +1,253 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files (slash paths relative to root) with their contents.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+type entry struct {
+	name     string
+	typeflag byte
+	body     string
+}
+
+// rawBundle builds a tar.gz from entries, for archives Write would never produce.
+func rawBundle(t *testing.T, entries []entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Mode: 0o644, Size: int64(len(e.body))}
+		if e.typeflag == tar.TypeSymlink {
+			hdr.Size = 0
+			hdr.Linkname = "/etc/passwd"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size > 0 {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func sum(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func manifestJSON(t *testing.T, files map[string]string) string {
+	t.Helper()
+	raw, err := json.Marshal(manifest{SchemaVersion: schemaVersion, Files: files})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(raw)
+}
+
+func TestWriteExtractRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	download := t.TempDir()
+	writeTree(t, download, map[string]string{
+		"gup.test/tool/@v/list":           "v1.0.0\n",
+		"gup.test/tool/@v/v1.0.0.info":    `{"Version":"v1.0.0"}`,
+		"gup.test/tool/@v/v1.0.0.mod":     "module gup.test/tool\n",
+		"gup.test/tool/@v/v1.0.0.zip":     "zipdata",
+		"gup.test/tool/@v/v1.0.0.lock":    "",
+		"gup.test/tool/@v/v1.0.0.ziphash": "h1:xyz",
+		"sumdb/sum.golang.org/lookup/x":   "tile",
+	})
+	conf := []byte(`{"schema_version":1,"packages":[]}`)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, download, conf, "v1.2.3"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	dest := t.TempDir()
+	if err := Extract(bytes.NewReader(buf.Bytes()), dest); err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+
+	for _, name := range []string{
+		"gup.json",
+		"proxy/gup.test/tool/@v/list",
+		"proxy/gup.test/tool/@v/v1.0.0.info",
+		"proxy/gup.test/tool/@v/v1.0.0.mod",
+		"proxy/gup.test/tool/@v/v1.0.0.zip",
+		"proxy/sumdb/sum.golang.org/lookup/x",
+		"proxy/sumdb/sum.golang.org/supported",
+	} {
+		if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name))); err != nil {
+			t.Errorf("%s missing after Extract: %v", name, err)
+		}
+	}
+	for _, name := range []string{
+		"proxy/gup.test/tool/@v/v1.0.0.lock",
+		"proxy/gup.test/tool/@v/v1.0.0.ziphash",
+		"bundle.json",
+	} {
+		if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name))); err == nil {
+			t.Errorf("%s should not be extracted", name)
+		}
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "gup.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, conf) {
+		t.Errorf("gup.json = %s, want %s", got, conf)
+	}
+}
+
+func TestExtractRejects(t *testing.T) {
+	t.Parallel()
+
+	conf := `{"schema_version":1,"packages":[]}`
+	tests := []struct {
+		name    string
+		entries []entry
+		wantErr string
+	}{
+		{
+			name: "path traversal",
+			entries: []entry{
+				{name: "proxy/../../evil", typeflag: tar.TypeReg, body: "x"},
+			},
+			wantErr: "escapes",
+		},
+		{
+			name: "absolute path",
+			entries: []entry{
+				{name: "/tmp/evil", typeflag: tar.TypeReg, body: "x"},
+			},
+			wantErr: "escapes",
+		},
+		{
+			name: "symlink",
+			entries: []entry{
+				{name: "proxy/link", typeflag: tar.TypeSymlink},
+			},
+			wantErr: "not a regular file",
+		},
+		{
+			name: "entry outside the layout",
+			entries: []entry{
+				{name: "bin/tool", typeflag: tar.TypeReg, body: "x"},
+			},
+			wantErr: "unexpected bundle entry",
+		},
+		{
+			name: "missing manifest",
+			entries: []entry{
+				{name: "gup.json", typeflag: tar.TypeReg, body: conf},
+			},
+			wantErr: "bundle.json is missing",
+		},
+		{
+			name: "checksum mismatch",
+			entries: []entry{
+				{name: "gup.json", typeflag: tar.TypeReg, body: conf},
+				{name: "proxy/m/@v/v1.0.0.zip", typeflag: tar.TypeReg, body: "tampered"},
+				{name: "bundle.json", typeflag: tar.TypeReg, body: manifestJSON(t, map[string]string{
+					"gup.json":              sum(conf),
+					"proxy/m/@v/v1.0.0.zip": sum("original"),
+				})},
+			},
+			wantErr: "does not match its checksum",
+		},
+		{
+			name: "file not in manifest",
+			entries: []entry{
+				{name: "gup.json", typeflag: tar.TypeReg, body: conf},
+				{name: "proxy/m/@v/v1.0.0.zip", typeflag: tar.TypeReg, body: "extra"},
+				{name: "bundle.json", typeflag: tar.TypeReg, body: manifestJSON(t, map[string]string{
+					"gup.json": sum(conf),
+				})},
+			},
+			wantErr: "not listed in bundle.json",
+		},
+		{
+			name: "file missing from archive",
+			entries: []entry{
+				{name: "gup.json", typeflag: tar.TypeReg, body: conf},
+				{name: "bundle.json", typeflag: tar.TypeReg, body: manifestJSON(t, map[string]string{
+					"gup.json":              sum(conf),
+					"proxy/m/@v/v1.0.0.zip": sum("gone"),
+				})},
+			},
+			wantErr: "is missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dest := t.TempDir()
+			err := Extract(bytes.NewReader(rawBundle(t, tt.entries)), dest)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Extract() error = %v, want containing %q", err, tt.wantErr)
+			}
+			if _, statErr := os.Stat(filepath.Join(filepath.Dir(dest), "evil")); statErr == nil {
+				t.Fatal("Extract() wrote outside the destination directory")
+			}
+		})
+	}
+}
+
+func TestExtractNotGzip(t *testing.T) {
+	t.Parallel()
+
+	if err := Extract(strings.NewReader("plain text"), t.TempDir()); err == nil {
+		t.Fatal("Extract() of a non-gzip file should fail")
+	}
+}
+
+func TestProxyURL(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		got := ProxyURL(`C:\bundles\x`)
+		if want := "file:///C:/bundles/x/proxy"; got != want {
+			t.Errorf("ProxyURL() = %q, want %q", got, want)
+		}
+		return
+	}
+	if got, want := ProxyURL("/tmp/x"), "file:///tmp/x/proxy"; got != want {
+		t.Errorf("ProxyURL() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,313 @@
+// Package changelog extracts the release notes between two versions of a module
+// from its module zip, and selects the intermediate releases from a module's
+// version list.
+//
+// Projects keep release notes in many shapes, so extraction is best effort: a
+// root CHANGELOG.md, CHANGELOG, CHANGES.md or CHANGES file is split into
+// per-release sections by headings that name a version, and a release-notes/
+// directory is read as one file per release. Anything that does not name a
+// version is ignored rather than guessed at.
+package changelog
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// ErrNoChangelog is returned when the module zip contains no release notes gup
+// recognizes.
+var ErrNoChangelog = errors.New("no CHANGELOG.md, CHANGES or release-notes/ found in the module")
+
+// maxNotesFileSize bounds a single release-notes file read from the zip. Real
+// changelogs are far smaller; the bound keeps a hostile zip from exhausting
+// memory.
+const maxNotesFileSize = 4 << 20
+
+// releaseNotesDir is the directory read as one file per release.
+const releaseNotesDir = "release-notes"
+
+// changelogFiles are the root files recognized as changelogs, in priority
+// order. Matching is case-insensitive.
+var changelogFiles = []string{"changelog.md", "changelog", "changes.md", "changes"} //nolint:gochecknoglobals // read-only table
+
+var (
+	// versionToken finds a semantic version such as "v1.2.3", "1.2" or
+	// "v2.0.0-rc.1" in a heading.
+	versionToken = regexp.MustCompile(`(?:^|[^\w.])v?(\d+\.\d+(?:\.\d+)?(?:-[0-9A-Za-z.]+)?)(?:$|[^\w.])`)
+	// atxHeading matches a markdown "#"-style heading.
+	atxHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	// plainHeading matches a release line in a plain-text changelog: a version at
+	// the start of the line, optionally introduced by "[", "Version" or "Release".
+	plainHeading = regexp.MustCompile(`(?i)^(?:\[|version\s+|release\s+)?v?\d+\.\d+`)
+)
+
+// Section is the release notes of one version.
+type Section struct {
+	// Version is the version named by the section heading, with a "v" prefix.
+	Version string
+	// Title is the heading line (or file name for release-notes/).
+	Title string
+	// Body is the section text, without the heading.
+	Body string
+}
+
+// Notes is the result of Extract.
+type Notes struct {
+	// Source is the file or directory the notes were read from, relative to the
+	// module root.
+	Source string
+	// Sections holds the sections between the two versions, newest first.
+	Sections []Section
+}
+
+// Extract reads the release notes from the module zip of modulePath@to (as
+// served by a module proxy) and returns the sections for the versions after
+// from, up to and including to.
+func Extract(zipData []byte, modulePath, from, to string) (Notes, error) {
+	zr, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return Notes{}, fmt.Errorf("can't open module zip of %s@%s: %w", modulePath, to, err)
+	}
+
+	prefix := modulePath + "@" + to + "/"
+	rootFiles := map[string]*zip.File{}
+	var notesDir []*zip.File
+	for _, f := range zr.File {
+		name, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok || f.FileInfo().IsDir() {
+			continue
+		}
+		if !strings.Contains(name, "/") {
+			rootFiles[strings.ToLower(name)] = f
+			continue
+		}
+		if dir, _ := path.Split(name); strings.EqualFold(strings.TrimSuffix(dir, "/"), releaseNotesDir) {
+			notesDir = append(notesDir, f)
+		}
+	}
+
+	for _, want := range changelogFiles {
+		f, ok := rootFiles[want]
+		if !ok {
+			continue
+		}
+		text, err := readZipFile(f)
+		if err != nil {
+			return Notes{}, err
+		}
+		return Notes{
+			Source:   strings.TrimPrefix(f.Name, prefix),
+			Sections: filterSections(SplitSections(text), from, to),
+		}, nil
+	}
+
+	if len(notesDir) > 0 {
+		sections := make([]Section, 0, len(notesDir))
+		for _, f := range notesDir {
+			base := path.Base(f.Name)
+			v := findVersion(trimTextExt(base))
+			if v == "" {
+				continue
+			}
+			text, err := readZipFile(f)
+			if err != nil {
+				return Notes{}, err
+			}
+			sections = append(sections, Section{Version: v, Title: base, Body: strings.TrimSpace(text)})
+		}
+		sections = filterSections(sections, from, to)
+		sort.SliceStable(sections, func(i, j int) bool { return newer(sections[i].Version, sections[j].Version) })
+		return Notes{Source: releaseNotesDir + "/", Sections: sections}, nil
+	}
+
+	return Notes{}, ErrNoChangelog
+}
+
+func readZipFile(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", fmt.Errorf("can't open %s in module zip: %w", f.Name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxNotesFileSize))
+	if err != nil {
+		return "", fmt.Errorf("can't read %s in module zip: %w", f.Name, err)
+	}
+	return string(data), nil
+}
+
+// SplitSections splits a changelog into per-release sections, in file order.
+// Markdown "#" headings that name a version start a section, which runs until
+// the next heading of the same or a higher level. A file without such headings
+// is treated as plain text, where a line starting with a version starts a
+// section.
+func SplitSections(text string) []Section {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if sections := splitATX(lines); len(sections) > 0 {
+		return sections
+	}
+	return splitPlain(lines)
+}
+
+func splitATX(lines []string) []Section {
+	var (
+		sections []Section
+		cur      *Section
+		level    int
+		body     []string
+	)
+	flush := func() {
+		if cur != nil {
+			cur.Body = strings.TrimSpace(strings.Join(body, "\n"))
+			sections = append(sections, *cur)
+		}
+		cur, body = nil, nil
+	}
+
+	inFence := false
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		m := atxHeading.FindStringSubmatch(line)
+		if inFence || m == nil {
+			if cur != nil {
+				body = append(body, line)
+			}
+			continue
+		}
+		headingLevel := len(m[1])
+		if cur != nil && headingLevel > level {
+			body = append(body, line)
+			continue
+		}
+		flush()
+		if v := findVersion(m[2]); v != "" {
+			cur = &Section{Version: v, Title: strings.TrimSpace(line)}
+			level = headingLevel
+		}
+	}
+	flush()
+	return sections
+}
+
+func splitPlain(lines []string) []Section {
+	var (
+		sections []Section
+		cur      *Section
+		body     []string
+	)
+	flush := func() {
+		if cur != nil {
+			cur.Body = strings.TrimSpace(strings.Join(body, "\n"))
+			sections = append(sections, *cur)
+		}
+		cur, body = nil, nil
+	}
+	for _, line := range lines {
+		if plainHeading.MatchString(line) {
+			if v := findVersion(line); v != "" {
+				flush()
+				cur = &Section{Version: v, Title: strings.TrimSpace(line)}
+				continue
+			}
+		}
+		if cur != nil {
+			body = append(body, line)
+		}
+	}
+	flush()
+	return sections
+}
+
+// trimTextExt drops a textual file extension such as ".md" so the version in a
+// release-notes file name ("v1.2.0.md") is not read as running into it. A
+// numeric suffix is part of the version and is kept.
+func trimTextExt(name string) string {
+	ext := path.Ext(name)
+	if ext == "" || strings.ContainsAny(ext, "0123456789") {
+		return name
+	}
+	return strings.TrimSuffix(name, ext)
+}
+
+// findVersion returns the first version token in s with a "v" prefix, or "".
+func findVersion(s string) string {
+	m := versionToken.FindStringSubmatch(s)
+	if m == nil {
+		return ""
+	}
+	return "v" + m[1]
+}
+
+// filterSections keeps the sections with from < version <= to. When from is
+// not a comparable version (e.g. "(devel)"), every section up to to is kept.
+func filterSections(sections []Section, from, to string) []Section {
+	out := make([]Section, 0, len(sections))
+	for _, s := range sections {
+		if InRange(s.Version, from, to) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// InRange reports whether from < version <= to.
+func InRange(version, from, to string) bool {
+	if !atMost(version, to) {
+		return false
+	}
+	if !comparable(from) {
+		return true
+	}
+	return newer(version, from)
+}
+
+// Between returns the versions in list with from < version <= to, oldest
+// first. Pre-releases are dropped unless they are to itself, so "intermediate
+// releases" counts what users would actually have been offered by @latest.
+func Between(list []string, from, to string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, v := range list {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[v] || (isPrerelease(v) && v != to) || !InRange(v, from, to) {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return newer(out[j], out[i]) })
+	return out
+}
+
+func comparable(v string) bool {
+	return goutil.VersionUpToDate(trimV(v), trimV(v))
+}
+
+func isPrerelease(v string) bool {
+	return strings.Contains(trimV(v), "-")
+}
+
+// newer reports whether a > b.
+func newer(a, b string) bool {
+	return comparable(a) && comparable(b) && !goutil.VersionUpToDate(trimV(b), trimV(a))
+}
+
+// atMost reports whether a <= b.
+func atMost(a, b string) bool {
+	return comparable(a) && comparable(b) && goutil.VersionUpToDate(trimV(b), trimV(a))
+}
+
+func trimV(v string) string {
+	return strings.TrimPrefix(strings.TrimSpace(v), "v")
+}
@@ -0,0 +1,134 @@
+package changelog
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testModule = "example.com/tool"
+
+func buildZip(t *testing.T, version string, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range files {
+		w, err := zw.Create(testModule + "@" + version + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSplitSections(t *testing.T) {
+	t.Parallel()
+
+	t.Run("markdown headings", func(t *testing.T) {
+		t.Parallel()
+		text := "# Changelog\n\n## [Unreleased]\n- wip\n\n## [1.5.0] - 2024-05-01\n### Added\n- feature\n\n" +
+			"```\n## 0.0.1 inside a fence\n```\n\n## v1.4.0\n- fix\n"
+		want := []Section{
+			{Version: "v1.5.0", Title: "## [1.5.0] - 2024-05-01", Body: "### Added\n- feature\n\n```\n## 0.0.1 inside a fence\n```"},
+			{Version: "v1.4.0", Title: "## v1.4.0", Body: "- fix"},
+		}
+		if diff := cmp.Diff(want, SplitSections(text)); diff != "" {
+			t.Errorf("SplitSections() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("plain text", func(t *testing.T) {
+		t.Parallel()
+		text := "Release history\n\nVersion 2.0.0\n  * breaking\n\n1.9.1\n  * fix\n"
+		want := []Section{
+			{Version: "v2.0.0", Title: "Version 2.0.0", Body: "* breaking"},
+			{Version: "v1.9.1", Title: "1.9.1", Body: "* fix"},
+		}
+		if diff := cmp.Diff(want, SplitSections(text)); diff != "" {
+			t.Errorf("SplitSections() mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+
+	t.Run("changelog file", func(t *testing.T) {
+		t.Parallel()
+		data := buildZip(t, "v1.3.0", map[string]string{
+			"CHANGELOG.md": "## v1.3.0\n- three\n## v1.2.0\n- two\n## v1.1.0\n- one\n",
+			"main.go":      "package main\n",
+		})
+		got, err := Extract(data, testModule, "v1.1.0", "v1.3.0")
+		if err != nil {
+			t.Fatalf("Extract() error = %v", err)
+		}
+		if got.Source != "CHANGELOG.md" {
+			t.Errorf("Source = %q", got.Source)
+		}
+		if len(got.Sections) != 2 || got.Sections[0].Version != "v1.3.0" || got.Sections[1].Version != "v1.2.0" {
+			t.Errorf("Sections = %+v, want v1.3.0 and v1.2.0", got.Sections)
+		}
+	})
+
+	t.Run("release-notes directory", func(t *testing.T) {
+		t.Parallel()
+		data := buildZip(t, "v2.1.0", map[string]string{
+			"release-notes/v2.0.0.md": "two-zero",
+			"release-notes/v2.1.0.md": "two-one",
+			"release-notes/v1.0.0.md": "old",
+			"release-notes/README.md": "index",
+		})
+		got, err := Extract(data, testModule, "v1.0.0", "v2.1.0")
+		if err != nil {
+			t.Fatalf("Extract() error = %v", err)
+		}
+		want := Notes{Source: "release-notes/", Sections: []Section{
+			{Version: "v2.1.0", Title: "v2.1.0.md", Body: "two-one"},
+			{Version: "v2.0.0", Title: "v2.0.0.md", Body: "two-zero"},
+		}}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Extract() mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("no changelog", func(t *testing.T) {
+		t.Parallel()
+		data := buildZip(t, "v1.0.0", map[string]string{"main.go": "package main\n"})
+		if _, err := Extract(data, testModule, "v0.9.0", "v1.0.0"); !errors.Is(err, ErrNoChangelog) {
+			t.Fatalf("Extract() error = %v, want ErrNoChangelog", err)
+		}
+	})
+
+	t.Run("not a zip", func(t *testing.T) {
+		t.Parallel()
+		if _, err := Extract([]byte("nope"), testModule, "v0.9.0", "v1.0.0"); err == nil {
+			t.Fatal("Extract() error = nil, want error")
+		}
+	})
+}
+
+func TestBetween(t *testing.T) {
+	t.Parallel()
+
+	list := []string{"v1.5.0", "v1.2.0", "v1.3.0", "v1.4.0-rc.1", "v1.4.0", "v1.1.0", "v1.6.0-rc.1"}
+	want := []string{"v1.3.0", "v1.4.0", "v1.5.0"}
+	if diff := cmp.Diff(want, Between(list, "v1.2.0", "v1.5.0")); diff != "" {
+		t.Errorf("Between() mismatch (-want +got):\n%s", diff)
+	}
+	if got := Between(list, "v1.5.0", "v1.6.0-rc.1"); len(got) != 1 || got[0] != "v1.6.0-rc.1" {
+		t.Errorf("Between() to a pre-release = %v", got)
+	}
+	if got := Between(list, "(devel)", "v1.2.0"); len(got) != 2 {
+		t.Errorf("Between() from devel = %v, want every release up to v1.2.0", got)
+	}
+}
@@ -27,12 +27,14 @@ const ConfigFileName = "gup.json"
 
 // Config schema versions. v1 is the original format (latest/main/master
 // channels only). v2 adds the "pinned" channel, whose entries carry a concrete
-// target version. A gup.json is written as v2 only when it actually contains a
-// pinned package, so environments with no pins keep producing the v1 format an
-// older gup can still read. Writing "channel": "pinned" into a v1 file would be
-// unsafe because an older gup normalizes unknown channels to @latest; emitting
-// v2 instead makes an older gup fail fast on the unsupported schema_version
-// rather than silently unpin the package.
+// target version, and the "go_toolchain", "removed" and "excluded" fields. A
+// gup.json is written as v2 only when it actually uses one of them, so
+// environments without them keep producing the v1 format an older gup can
+// still read. Writing them into a v1 file would be unsafe because an older gup
+// ignores what it does not know: it normalizes unknown channels to @latest,
+// builds with the installed Go, reinstalls removed tools and updates excluded
+// ones. Emitting v2 instead makes an older gup fail fast on the unsupported
+// schema_version.
 const (
 	configSchemaVersionV1 = 1
 	configSchemaVersionV2 = 2
@@ -57,6 +59,16 @@ type configPackage struct {
 	ImportPath string `json:"import_path"`
 	Version    string `json:"version"`
 	Channel    string `json:"channel"`
+	// GoToolchain is the Go release the package is built with, e.g.
+	// "go1.22.5". It is omitted for the installed Go, so a gup.json that
+	// selects no toolchain is unchanged.
+	GoToolchain string `json:"go_toolchain,omitempty"`
+	// Removed marks a tool whose binary was deleted from $GOBIN. It is
+	// omitted while the binary is installed.
+	Removed bool `json:"removed,omitempty"`
+	// Excluded marks a tool 'gup update' leaves out unless it is named. It is
+	// omitted for every other tool.
+	Excluded bool `json:"excluded,omitempty"`
 }
 
 // FilePath return configuration-file path.
@@ -75,6 +87,12 @@ func DirPath() string {
 	return filepath.Join(xdg.ConfigHome, cmdinfo.Name)
 }
 
+// StateDirPath returns the directory that stores gup's logs and other state
+// that is not configuration. Default path is $HOME/.local/state/gup.
+func StateDirPath() string {
+	return filepath.Join(xdg.StateHome, cmdinfo.Name)
+}
+
 // ResolveImportFilePath resolves the gup.json path used by import.
 // Priority:
 //   - an explicit path always wins.
@@ -188,6 +206,13 @@ func ReadConfFile(path string) ([]goutil.Package, error) {
 			pinnedVersion = version
 		}
 
+		goToolchain := ""
+		if strings.TrimSpace(v.GoToolchain) != "" {
+			if goToolchain, err = goutil.ParseGoToolchain(v.GoToolchain); err != nil {
+				return nil, fmt.Errorf("%s package %q: %w", path, name, err)
+			}
+		}
+
 		binVer := goutil.Version{Current: version, Latest: ""}
 		goVer := goutil.Version{Current: "<from gup.json>", Latest: ""}
 		pkgs = append(pkgs, goutil.Package{
@@ -197,6 +222,9 @@ func ReadConfFile(path string) ([]goutil.Package, error) {
 			GoVersion:     ptr(goVer),
 			UpdateChannel: channel,
 			PinnedVersion: pinnedVersion,
+			GoToolchain:   goToolchain,
+			Removed:       v.Removed,
+			Excluded:      v.Excluded,
 		})
 	}
 
@@ -217,10 +245,13 @@ func WriteConfFile(file io.Writer, pkgs []goutil.Package) error {
 			return fmt.Errorf("can't write package %q: %w", v.Name, err)
 		}
 		conf.Packages = append(conf.Packages, configPackage{
-			Name:       v.Name,
-			ImportPath: v.ImportPath,
-			Version:    version,
-			Channel:    string(channel),
+			Name:        v.Name,
+			ImportPath:  v.ImportPath,
+			Version:     version,
+			Channel:     string(channel),
+			GoToolchain: v.GoToolchain,
+			Removed:     v.Removed,
+			Excluded:    v.Excluded,
 		})
 	}
 
@@ -253,12 +284,13 @@ func normalizeConfVersion(version string) string {
 }
 
 // schemaVersionFor picks the schema version to write: v2 when any package is
-// pinned (so the "pinned" channel is only ever emitted under a schema that
-// understands it), otherwise v1 so environments without pins keep producing a
-// file an older gup can read unchanged.
+// pinned or sets go_toolchain, removed or excluded (so those are only ever
+// emitted under a schema that understands them), otherwise v1 so environments
+// without them keep producing a file an older gup can read unchanged.
 func schemaVersionFor(pkgs []goutil.Package) int {
 	for _, v := range pkgs {
-		if goutil.NormalizeUpdateChannel(string(v.UpdateChannel)) == goutil.UpdateChannelPinned {
+		if goutil.NormalizeUpdateChannel(string(v.UpdateChannel)) == goutil.UpdateChannelPinned ||
+			v.GoToolchain != "" || v.Removed || v.Excluded {
 			return configSchemaVersionV2
 		}
 	}
@@ -214,6 +214,116 @@ func TestReadConfFile(t *testing.T) { //nolint:paralleltest // modifies xdg glob
 	}
 }
 
+func TestConfFile_goToolchainRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	confPath := filepath.Join(t.TempDir(), "gup.json")
+	content := `{"schema_version": 1, "packages": [
+  {"name": "foo", "import_path": "example.com/foo", "version": "v1.2.3", "channel": "latest", "go_toolchain": "1.22.5"},
+  {"name": "bar", "import_path": "example.com/bar", "version": "v4.5.6", "channel": "latest"}
+]}`
+	if err := os.WriteFile(confPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write temp conf file: %v", err)
+	}
+	pkgs, err := ReadConfFile(confPath)
+	if err != nil {
+		t.Fatalf("ReadConfFile() error = %v", err)
+	}
+	if pkgs[0].GoToolchain != "go1.22.5" || pkgs[1].GoToolchain != "" {
+		t.Fatalf("go toolchains = %q, %q; want go1.22.5 and none", pkgs[0].GoToolchain, pkgs[1].GoToolchain)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteConfFile(&buf, pkgs); err != nil {
+		t.Fatalf("WriteConfFile() error = %v", err)
+	}
+	if got := strings.Count(buf.String(), `"go_toolchain"`); got != 1 {
+		t.Errorf("go_toolchain written %d times, want only for foo:\n%s", got, buf.String())
+	}
+	if !strings.Contains(buf.String(), `"go_toolchain": "go1.22.5"`) {
+		t.Errorf("go_toolchain not written:\n%s", buf.String())
+	}
+	assertSchemaV2RoundTrip(t, buf.Bytes(), len(pkgs))
+}
+
+// assertSchemaV2RoundTrip checks that raw, written by WriteConfFile, declares
+// schema v2 and reads back with n packages.
+func assertSchemaV2RoundTrip(t *testing.T, raw []byte, n int) {
+	t.Helper()
+	if !strings.Contains(string(raw), `"schema_version": 2`) {
+		t.Errorf("a file using v2 fields must be written as schema_version 2:\n%s", raw)
+	}
+	confPath := filepath.Join(t.TempDir(), "gup.json")
+	if err := os.WriteFile(confPath, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pkgs, err := ReadConfFile(confPath)
+	if err != nil {
+		t.Fatalf("ReadConfFile() of the written file error = %v", err)
+	}
+	if len(pkgs) != n {
+		t.Errorf("read back %d packages, want %d", len(pkgs), n)
+	}
+}
+
+func TestConfFile_removedRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	confPath := filepath.Join(t.TempDir(), "gup.json")
+	content := `{"schema_version": 1, "packages": [
+  {"name": "foo", "import_path": "example.com/foo", "version": "v1.2.3", "channel": "latest", "removed": true},
+  {"name": "bar", "import_path": "example.com/bar", "version": "v4.5.6", "channel": "latest"}
+]}`
+	if err := os.WriteFile(confPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write temp conf file: %v", err)
+	}
+	pkgs, err := ReadConfFile(confPath)
+	if err != nil {
+		t.Fatalf("ReadConfFile() error = %v", err)
+	}
+	if !pkgs[0].Removed || pkgs[1].Removed {
+		t.Fatalf("removed = %v, %v; want only foo", pkgs[0].Removed, pkgs[1].Removed)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteConfFile(&buf, pkgs); err != nil {
+		t.Fatalf("WriteConfFile() error = %v", err)
+	}
+	if got := strings.Count(buf.String(), `"removed": true`); got != 1 {
+		t.Errorf("removed written %d times, want only for foo:\n%s", got, buf.String())
+	}
+	assertSchemaV2RoundTrip(t, buf.Bytes(), len(pkgs))
+}
+
+func TestConfFile_excludedRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	confPath := filepath.Join(t.TempDir(), "gup.json")
+	content := `{"schema_version": 1, "packages": [
+  {"name": "foo", "import_path": "example.com/foo", "version": "v1.2.3", "channel": "latest", "excluded": true},
+  {"name": "bar", "import_path": "example.com/bar", "version": "v4.5.6", "channel": "latest"}
+]}`
+	if err := os.WriteFile(confPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write temp conf file: %v", err)
+	}
+	pkgs, err := ReadConfFile(confPath)
+	if err != nil {
+		t.Fatalf("ReadConfFile() error = %v", err)
+	}
+	if !pkgs[0].Excluded || pkgs[1].Excluded {
+		t.Fatalf("excluded = %v, %v; want only foo", pkgs[0].Excluded, pkgs[1].Excluded)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteConfFile(&buf, pkgs); err != nil {
+		t.Fatalf("WriteConfFile() error = %v", err)
+	}
+	if got := strings.Count(buf.String(), `"excluded": true`); got != 1 {
+		t.Errorf("excluded written %d times, want only for foo:\n%s", got, buf.String())
+	}
+	assertSchemaV2RoundTrip(t, buf.Bytes(), len(pkgs))
+}
+
 func TestReadConfFile_Empty(t *testing.T) {
 	t.Parallel()
 
@@ -246,6 +356,10 @@ func TestReadConfFile_InvalidFormat(t *testing.T) {
 			name:    "unsupported schema",
 			content: `{"schema_version": 99, "packages": []}`,
 		},
+		{
+			name:    "invalid go toolchain",
+			content: `{"schema_version": 1, "packages": [{"name": "foo", "import_path": "example.com/foo", "version": "v1.2.3", "channel": "latest", "go_toolchain": "1.22"}]}`,
+		},
 		{
 			name: "invalid package entry",
 			content: `{
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SettingsFileName is the file that holds gup's own settings, next to the
+// user-level gup.json.
+const SettingsFileName = "settings.json"
+
+// AutoExportEnv overrides the auto_export setting when set: "1"/"true" turns it
+// on and "0"/"false" turns it off, whatever settings.json says.
+const AutoExportEnv = "GUP_AUTO_EXPORT"
+
+// Settings is gup's behavior configuration. Unlike gup.json, which records the
+// tool set, it changes how gup commands behave.
+type Settings struct {
+	// AutoExport makes the commands that change $GOBIN (update, install,
+	// remove, migrate) merge their results back into gup.json, so gup.json
+	// never goes stale.
+	AutoExport bool `json:"auto_export"`
+	// Retries is the default of --retries: how many more times a version
+	// lookup or 'go install' that failed with a network error is tried.
+	Retries int `json:"retries,omitempty"`
+	// RetryBackoff is the default of --retry-backoff, a Go duration such as
+	// "2s": the wait before the first retry, doubled before each next one.
+	RetryBackoff string `json:"retry_backoff,omitempty"`
+}
+
+// Backoff returns RetryBackoff as a duration, or 0 when it is not set.
+// ReadSettings has already checked that it parses.
+func (s Settings) Backoff() time.Duration {
+	d, _ := time.ParseDuration(s.RetryBackoff)
+	return d
+}
+
+// SettingsFilePath returns the path of settings.json.
+func SettingsFilePath() string {
+	return filepath.Join(DirPath(), SettingsFileName)
+}
+
+// ReadSettings reads settings.json and applies the environment overrides. A
+// missing settings.json means the defaults. A malformed file or an invalid
+// override is an error, so a typo never silently falls back to a default.
+func ReadSettings() (Settings, error) {
+	var s Settings
+	path := SettingsFilePath()
+	raw, err := os.ReadFile(filepath.Clean(path))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return Settings{}, fmt.Errorf("can't read %s: %w", path, err)
+	case len(bytes.TrimSpace(raw)) != 0:
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&s); err != nil {
+			return Settings{}, fmt.Errorf("%s is not valid: %w", path, err)
+		}
+	}
+	if s.Retries < 0 {
+		return Settings{}, fmt.Errorf("%s is not valid: retries must not be negative: %d", path, s.Retries)
+	}
+	if s.RetryBackoff != "" {
+		if d, err := time.ParseDuration(s.RetryBackoff); err != nil || d <= 0 {
+			return Settings{}, fmt.Errorf("%s is not valid: retry_backoff must be a positive duration such as \"2s\": %q", path, s.RetryBackoff)
+		}
+	}
+
+	if v, ok := os.LookupEnv(AutoExportEnv); ok && strings.TrimSpace(v) != "" {
+		on, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return Settings{}, fmt.Errorf("%s=%q is not a boolean (use 1, 0, true or false)", AutoExportEnv, v)
+		}
+		s.AutoExport = on
+	}
+	return s, nil
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadSettings(t *testing.T) { //nolint:paralleltest // modifies xdg globals and env
+	tests := []struct {
+		name    string
+		file    string // settings.json content; "" writes no file
+		env     string // GUP_AUTO_EXPORT; "" leaves it unset
+		want    Settings
+		wantErr bool
+	}{
+		{name: "no settings file", want: Settings{}},
+		{name: "auto_export on", file: `{"auto_export": true}`, want: Settings{AutoExport: true}},
+		{name: "env turns it off", file: `{"auto_export": true}`, env: "0", want: Settings{}},
+		{name: "env turns it on", env: "true", want: Settings{AutoExport: true}},
+		{name: "invalid env", env: "yes please", wantErr: true},
+		{name: "unknown setting", file: `{"auto_exprot": true}`, wantErr: true},
+		{name: "malformed file", file: `{`, wantErr: true},
+		{name: "retries", file: `{"retries": 3, "retry_backoff": "2s"}`, want: Settings{Retries: 3, RetryBackoff: "2s"}},
+		{name: "negative retries", file: `{"retries": -1}`, wantErr: true},
+		{name: "invalid retry_backoff", file: `{"retries": 3, "retry_backoff": "soon"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(withTempXDG(t))
+			t.Setenv(AutoExportEnv, tt.env)
+			if tt.file != "" {
+				if err := os.MkdirAll(DirPath(), 0o750); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(DirPath(), SettingsFileName), []byte(tt.file), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := ReadSettings()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ReadSettings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,10 +9,12 @@ import (
 )
 
 // savedEntry is the per-package state recovered from gup.json: the update
-// channel and, for a pinned package, the concrete target version.
+// channel, the concrete target version for a pinned package, and the Go
+// toolchain the package is built with.
 type savedEntry struct {
 	channel       goutil.UpdateChannel
 	pinnedVersion string
+	goToolchain   string
 }
 
 // channelIndex maps saved packages to their saved state under the shared
@@ -43,6 +45,7 @@ func indexSavedChannels(confPkgs []goutil.Package) channelIndex {
 		entry := savedEntry{
 			channel:       goutil.NormalizeUpdateChannel(string(p.UpdateChannel)),
 			pinnedVersion: savedPinnedVersion(p),
+			goToolchain:   strings.TrimSpace(p.GoToolchain),
 		}
 		for _, k := range identityKeys(p) {
 			idx[k] = entry
@@ -63,7 +66,7 @@ func (idx channelIndex) entryFor(p goutil.Package) (savedEntry, bool) {
 }
 
 // ApplySavedChannels copies each package's saved update channel (and pinned
-// target version, when pinned) from confPkgs, matching by the shared package
+// target version, when pinned) and Go toolchain from confPkgs, matching by the shared package
 // identity (import_path first, then cross-OS normalized name), so a channel is
 // not silently reset to @latest across binary renames, hand-edited configs, or
 // cross-OS name differences (#341). Packages with no saved entry default to
@@ -74,15 +77,53 @@ func ApplySavedChannels(pkgs, confPkgs []goutil.Package) []goutil.Package {
 	for _, p := range pkgs {
 		p.UpdateChannel = goutil.UpdateChannelLatest
 		p.PinnedVersion = ""
+		toolchain := ""
 		if entry, ok := saved.entryFor(p); ok {
 			p.UpdateChannel = entry.channel
 			p.PinnedVersion = entry.pinnedVersion
+			toolchain = entry.goToolchain
 		}
-		result = append(result, p)
+		result = append(result, withGoToolchain(p, toolchain))
 	}
 	return result
 }
 
+// ApplyGoToolchains sets each package's Go toolchain to the one saved for it
+// in confPkgs, matched by the shared package identity. A non-empty override
+// (the --go flag) replaces the saved toolchain of every package; the override
+// "local" clears it, so the package is built with the installed Go again.
+func ApplyGoToolchains(pkgs, confPkgs []goutil.Package, override string) []goutil.Package {
+	saved := indexSavedChannels(confPkgs)
+	result := make([]goutil.Package, 0, len(pkgs))
+	for _, p := range pkgs {
+		toolchain := ""
+		switch override = strings.TrimSpace(override); override {
+		case "":
+			if entry, ok := saved.entryFor(p); ok {
+				toolchain = entry.goToolchain
+			}
+		case goutil.LocalToolchain:
+		default:
+			toolchain = override
+		}
+		result = append(result, withGoToolchain(p, toolchain))
+	}
+	return result
+}
+
+// withGoToolchain returns p built with toolchain. The toolchain is the Go that
+// a reinstall would build with, so it also becomes p's latest Go version; the
+// GoVersion is copied so the caller's package is left untouched.
+func withGoToolchain(p goutil.Package, toolchain string) goutil.Package {
+	p.GoToolchain = toolchain
+	if toolchain != "" && p.GoVersion != nil {
+		goVersion := *p.GoVersion
+		goVersion.Latest = toolchain
+		p.GoVersion = &goVersion
+	}
+	return p
+}
+
 // ResolveChannels computes the effective update channel for every installed
 // package. The precedence, lowest to highest, is:
 //  1. @latest by default,
@@ -16,6 +16,7 @@
 //   - merge.go:    merging resolved packages back into the list persisted to
 //     gup.json, and normalizing each persisted entry/version.
 //   - pin.go:      adding and removing concrete version pins.
+//   - drift.go:    comparing gup.json with the installed binaries.
 //   - configstate.go (this file): the read/validate/resolve entry points the
 //     cmd/ layer calls.
 package configstate
@@ -3,6 +3,7 @@ package configstate
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -917,3 +918,156 @@ func TestResolveChannels_mainAndLatestConflict(t *testing.T) {
 		t.Fatal("ResolveChannels() err = nil, want conflict error for --main and --latest")
 	}
 }
+
+func TestApplyGoToolchains(t *testing.T) {
+	t.Parallel()
+
+	conf := []goutil.Package{
+		{Name: testToolName, ImportPath: testToolPath, Version: &goutil.Version{Current: testVer100}, GoToolchain: "go1.22.5"},
+	}
+	installed := func() []goutil.Package {
+		return []goutil.Package{
+			{Name: testToolName, ImportPath: testToolPath, GoVersion: &goutil.Version{Current: "go1.22.5", Latest: "go1.25.0"}},
+			{Name: testFoo, ImportPath: testFooPath, GoVersion: &goutil.Version{Current: "go1.25.0", Latest: "go1.25.0"}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		override string
+		want     map[string]string // name -> "toolchain goVersion.Latest"
+	}{
+		{
+			name: "saved toolchain",
+			want: map[string]string{testToolName: "go1.22.5 go1.22.5", testFoo: " go1.25.0"},
+		},
+		{
+			name:     "override selects for every package",
+			override: "go1.23.1",
+			want:     map[string]string{testToolName: "go1.23.1 go1.23.1", testFoo: "go1.23.1 go1.23.1"},
+		},
+		{
+			name:     "local clears the saved toolchain",
+			override: goutil.LocalToolchain,
+			want:     map[string]string{testToolName: " go1.25.0", testFoo: " go1.25.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pkgs := installed()
+			got := map[string]string{}
+			for _, p := range ApplyGoToolchains(pkgs, conf, tt.override) {
+				got[p.Name] = p.GoToolchain + " " + p.GoVersion.Latest
+			}
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("%s = %q, want %q", name, got[name], want)
+				}
+			}
+			if pkgs[0].GoVersion.Latest != "go1.25.0" {
+				t.Errorf("ApplyGoToolchains() modified the caller's GoVersion: %+v", pkgs[0].GoVersion)
+			}
+		})
+	}
+}
+
+func TestMergePackages_keepsGoToolchain(t *testing.T) {
+	t.Parallel()
+
+	conf := []goutil.Package{
+		{Name: testKeptTool, ImportPath: "github.com/example/kept-tool", Version: &goutil.Version{Current: testVer100}, GoToolchain: "go1.22.5"},
+	}
+	succeeded := []goutil.Package{
+		{Name: testNewTool, ImportPath: "github.com/example/new-tool", Version: &goutil.Version{Current: testVer100}, GoToolchain: "go1.23.1"},
+	}
+	got := MergePackages(conf, succeeded, nil, nil)
+	if len(got) != 2 {
+		t.Fatalf("MergePackages() returned %d packages, want 2", len(got))
+	}
+	if got[0].GoToolchain != "go1.22.5" || got[1].GoToolchain != "go1.23.1" {
+		t.Errorf("go toolchains = %q, %q, want go1.22.5, go1.23.1", got[0].GoToolchain, got[1].GoToolchain)
+	}
+}
+
+func TestRemovePackages(t *testing.T) {
+	t.Parallel()
+
+	conf := []goutil.Package{
+		{Name: testToolB + ".exe", ImportPath: "example.com/tool-b", Version: &goutil.Version{Current: testVer100}, UpdateChannel: goutil.UpdateChannelLatest},
+		{Name: testToolA, ImportPath: "example.com/tool-a", Version: &goutil.Version{Current: testVer100}, UpdateChannel: goutil.UpdateChannelPinned, PinnedVersion: testVer100},
+		{Name: testToolC, ImportPath: "example.com/tool-c", Version: &goutil.Version{Current: testVer200}, UpdateChannel: goutil.UpdateChannelMain},
+	}
+	got := RemovePackages(conf, []string{testToolB, testNope})
+	if len(got) != 2 {
+		t.Fatalf("RemovePackages() returned %d packages, want 2: %+v", len(got), got)
+	}
+	if got[0].Name != testToolA || !got[0].IsPinned() || got[0].PinnedVersion != testVer100 {
+		t.Errorf("first = %+v, want tool-a still pinned at v1.0.0", got[0])
+	}
+	if got[1].Name != testToolC || got[1].UpdateChannel != goutil.UpdateChannelMain {
+		t.Errorf("second = %+v, want tool-c on main", got[1])
+	}
+}
+
+func TestMarkRemoved(t *testing.T) {
+	t.Parallel()
+
+	conf := []goutil.Package{
+		{Name: testToolB + ".exe", ImportPath: "example.com/tool-b", Version: &goutil.Version{Current: testVer100}, UpdateChannel: goutil.UpdateChannelLatest},
+		{Name: testToolA, ImportPath: "example.com/tool-a", Version: &goutil.Version{Current: testVer100}, UpdateChannel: goutil.UpdateChannelPinned, PinnedVersion: testVer100},
+	}
+	got, marked := MarkRemoved(conf, []string{testToolB, testNope})
+	if len(got) != 2 {
+		t.Fatalf("MarkRemoved() returned %d packages, want both kept: %+v", len(got), got)
+	}
+	if got[0].Name != testToolA || got[0].Removed || !got[0].IsPinned() {
+		t.Errorf("first = %+v, want tool-a unmarked and still pinned", got[0])
+	}
+	if got[1].Name != testToolB+".exe" || !got[1].Removed {
+		t.Errorf("second = %+v, want tool-b marked removed", got[1])
+	}
+	if len(marked) != 1 || marked[0] != testToolB+".exe" {
+		t.Errorf("marked = %v, want only tool-b", marked)
+	}
+
+	// Installing the tool again clears the mark.
+	merged := MergePackages(got, []goutil.Package{
+		{Name: testToolB + ".exe", ImportPath: "example.com/tool-b", Version: &goutil.Version{Current: testVer200}, UpdateChannel: goutil.UpdateChannelLatest},
+	}, nil, nil)
+	for _, p := range merged {
+		if p.Removed {
+			t.Errorf("%s is still marked removed after it was installed again", p.Name)
+		}
+	}
+}
+
+func TestSetExcluded(t *testing.T) {
+	t.Parallel()
+
+	conf := []goutil.Package{
+		{Name: testToolB + ".exe", ImportPath: "example.com/tool-b", Version: &goutil.Version{Current: testVer100}, UpdateChannel: goutil.UpdateChannelLatest},
+		{Name: testToolA, ImportPath: "example.com/tool-a", Version: &goutil.Version{Current: testVer100}, UpdateChannel: goutil.UpdateChannelPinned, PinnedVersion: testVer100},
+	}
+	newTool := goutil.Package{Name: testNope, ImportPath: "example.com/nope", Version: &goutil.Version{Current: testVer200}}
+	got := SetExcluded(conf, []goutil.Package{{Name: testToolB, ImportPath: "example.com/tool-b"}, newTool}, true)
+	if got, want := ExcludedNames(got), []string{testNope, testToolB + ".exe"}; !slices.Equal(got, want) {
+		t.Errorf("ExcludedNames() = %v, want %v", got, want)
+	}
+	if len(got) != 3 || !got[1].IsPinned() {
+		t.Errorf("SetExcluded() = %+v, want every entry kept and tool-a still pinned", got)
+	}
+
+	// Updating an excluded tool by name keeps it excluded.
+	merged := MergePackages(got, []goutil.Package{
+		{Name: testToolB + ".exe", ImportPath: "example.com/tool-b", Version: &goutil.Version{Current: testVer200}, UpdateChannel: goutil.UpdateChannelLatest},
+	}, nil, nil)
+	if got, want := ExcludedNames(merged), []string{testNope, testToolB + ".exe"}; !slices.Equal(got, want) {
+		t.Errorf("excluded after an update = %v, want %v", got, want)
+	}
+
+	cleared := SetExcluded(merged, []goutil.Package{newTool}, false)
+	if got, want := ExcludedNames(cleared), []string{testToolB + ".exe"}; !slices.Equal(got, want) {
+		t.Errorf("excluded after clearing = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,140 @@
+package configstate
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// DriftKind names one way an installed binary can differ from its gup.json
+// entry.
+type DriftKind string
+
+const (
+	// DriftMissing is a gup.json entry with no installed binary.
+	DriftMissing DriftKind = "missing"
+	// DriftRemoved is a gup.json entry marked as removed ('gup watch' saw its
+	// binary deleted) with no installed binary.
+	DriftRemoved DriftKind = "removed"
+	// DriftExtra is an installed binary with no gup.json entry.
+	DriftExtra DriftKind = "extra"
+	// DriftVersion is an installed binary whose version differs from the
+	// concrete version recorded in gup.json.
+	DriftVersion DriftKind = "version"
+	// DriftChannel is an installed binary tracked on a different update
+	// channel than the one recorded in gup.json.
+	DriftChannel DriftKind = "channel"
+	// DriftPin is an installed binary that does not match its gup.json pin.
+	DriftPin DriftKind = "pin"
+)
+
+// Drift is one package that differs between gup.json and the installed
+// binaries. Want is the gup.json entry (nil for DriftExtra) and Have is the
+// installed binary (nil for DriftMissing and DriftRemoved).
+type Drift struct {
+	Name  string
+	Kinds []DriftKind
+	Want  *goutil.Package
+	Have  *goutil.Package
+}
+
+// Has reports whether d includes kind.
+func (d Drift) Has(kind DriftKind) bool {
+	return slices.Contains(d.Kinds, kind)
+}
+
+// CompareInstalled compares the gup.json entries confPkgs with the installed
+// packages, matching them by the shared package identity (import_path first,
+// then cross-OS normalized name). The installed packages must carry their
+// effective update channel and pin (see ApplySavedChannels). A gup.json entry
+// without a concrete version ("latest", a devel build) never reports a version
+// difference: which version that means is only known to the network. The
+// result holds only the packages that differ, sorted by name.
+func CompareInstalled(confPkgs, installed []goutil.Package) []Drift {
+	index := make(map[string]int, len(installed)*maxIdentityKeys)
+	for i := len(installed) - 1; i >= 0; i-- {
+		for _, k := range identityKeys(installed[i]) {
+			index[k] = i
+		}
+	}
+
+	matched := make([]bool, len(installed))
+	drifts := []Drift{}
+	for _, want := range confPkgs {
+		have, ok := lookupUnmatched(index, matched, want)
+		if !ok {
+			kind := DriftMissing
+			if want.Removed {
+				kind = DriftRemoved
+			}
+			drifts = append(drifts, Drift{Name: want.Name, Kinds: []DriftKind{kind}, Want: &want})
+			continue
+		}
+		matched[have] = true
+		got := installed[have]
+		if kinds := compareEntry(want, got); len(kinds) > 0 {
+			drifts = append(drifts, Drift{Name: want.Name, Kinds: kinds, Want: &want, Have: &got})
+		}
+	}
+	for i, got := range installed {
+		if matched[i] {
+			continue
+		}
+		drifts = append(drifts, Drift{Name: got.Name, Kinds: []DriftKind{DriftExtra}, Have: &got})
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool { return drifts[i].Name < drifts[j].Name })
+	return drifts
+}
+
+// lookupUnmatched returns the index of the installed package matching want
+// under the identity rule, skipping packages already matched to another entry.
+func lookupUnmatched(index map[string]int, matched []bool, want goutil.Package) (int, bool) {
+	for _, k := range identityKeys(want) {
+		if i, ok := index[k]; ok && !matched[i] {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// compareEntry returns how the installed package got differs from its gup.json
+// entry want.
+func compareEntry(want, got goutil.Package) []DriftKind {
+	kinds := []DriftKind{}
+	wantChannel := goutil.NormalizeUpdateChannel(string(want.UpdateChannel))
+	if wantChannel != goutil.NormalizeUpdateChannel(string(got.UpdateChannel)) {
+		kinds = append(kinds, DriftChannel)
+	}
+
+	installedVersion := ""
+	if got.Version != nil {
+		installedVersion = strings.TrimSpace(got.Version.Current)
+	}
+	if wantChannel == goutil.UpdateChannelPinned {
+		if pin := savedPinnedVersion(want); pin != "" && installedVersion != pin {
+			kinds = append(kinds, DriftPin)
+		}
+		return kinds
+	}
+	if v := ConcreteVersion(want); v != "" && installedVersion != v {
+		kinds = append(kinds, DriftVersion)
+	}
+	return kinds
+}
+
+// ConcreteVersion returns the version recorded for p, or "" when it names no
+// concrete version ("latest", a devel build, "unknown" or nothing at all).
+func ConcreteVersion(p goutil.Package) string {
+	if p.Version == nil {
+		return ""
+	}
+	v := strings.TrimSpace(p.Version.Current)
+	switch v {
+	case "", latestKeyword, "devel", "(devel)", "unknown":
+		return ""
+	}
+	return v
+}
@@ -0,0 +1,80 @@
+package configstate
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+func TestCompareInstalled(t *testing.T) {
+	t.Parallel()
+
+	conf := []goutil.Package{
+		{Name: testToolA, ImportPath: "example.com/a", Version: &goutil.Version{Current: testVer100}, UpdateChannel: goutil.UpdateChannelLatest},
+		{Name: testToolB, ImportPath: "example.com/b", Version: &goutil.Version{Current: testVer200}, UpdateChannel: goutil.UpdateChannelLatest},
+		{Name: testToolC, ImportPath: "example.com/c", Version: &goutil.Version{Current: testVer100}, UpdateChannel: goutil.UpdateChannelPinned, PinnedVersion: testVer100},
+		{Name: testNewTool, ImportPath: "example.com/new", Version: &goutil.Version{Current: latestKeyword}},
+		// Matched by import path although the binary was renamed.
+		{Name: testOldName, ImportPath: testFooPath, Version: &goutil.Version{Current: latestKeyword}, UpdateChannel: goutil.UpdateChannelMain},
+		{Name: testKeptTool, ImportPath: "example.com/kept", Version: &goutil.Version{Current: latestKeyword}},
+		// Marked removed by 'gup watch': reported as removed while it is gone,
+		// compared as usual once it is installed again.
+		{Name: "gone", ImportPath: "example.com/gone", Version: &goutil.Version{Current: testVer100}, Removed: true},
+		{Name: "back", ImportPath: "example.com/back", Version: &goutil.Version{Current: testVer100}, Removed: true},
+	}
+	installed := []goutil.Package{
+		{Name: testToolA, ImportPath: "example.com/a", Version: &goutil.Version{Current: testVer100}, UpdateChannel: goutil.UpdateChannelLatest},
+		{Name: testToolB, ImportPath: "example.com/b", Version: &goutil.Version{Current: testVer100}, UpdateChannel: goutil.UpdateChannelLatest},
+		{Name: testToolC, ImportPath: "example.com/c", Version: &goutil.Version{Current: testVer200}, UpdateChannel: goutil.UpdateChannelLatest},
+		{Name: testNewName, ImportPath: testFooPath, Version: &goutil.Version{Current: testVer100}, UpdateChannel: goutil.UpdateChannelLatest},
+		{Name: testKeptTool + ".exe", ImportPath: "example.com/kept", Version: &goutil.Version{Current: testVer100}},
+		{Name: testNope, ImportPath: "example.com/nope", Version: &goutil.Version{Current: testVer100}},
+		{Name: "back", ImportPath: "example.com/back", Version: &goutil.Version{Current: testVer100}},
+	}
+
+	got := map[string][]DriftKind{}
+	for _, d := range CompareInstalled(conf, installed) {
+		got[d.Name] = d.Kinds
+		if (d.Has(DriftMissing) || d.Has(DriftRemoved)) != (d.Have == nil) || d.Has(DriftExtra) != (d.Want == nil) {
+			t.Errorf("%s: Want/Have do not match the kinds %v", d.Name, d.Kinds)
+		}
+	}
+	want := map[string][]DriftKind{
+		testToolB:   {DriftVersion},
+		testToolC:   {DriftChannel, DriftPin},
+		testNewTool: {DriftMissing},
+		testOldName: {DriftChannel},
+		testNope:    {DriftExtra},
+		"gone":      {DriftRemoved},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CompareInstalled() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCompareInstalled_inSync(t *testing.T) {
+	t.Parallel()
+
+	pkgs := []goutil.Package{
+		{Name: testToolA, ImportPath: "example.com/a", Version: &goutil.Version{Current: testVer100}, UpdateChannel: goutil.UpdateChannelPinned, PinnedVersion: testVer100},
+	}
+	if got := CompareInstalled(pkgs, pkgs); len(got) != 0 {
+		t.Errorf("CompareInstalled() of identical lists = %+v, want no drift", got)
+	}
+}
+
+func TestConcreteVersion(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		"": "", latestKeyword: "", "(devel)": "", "unknown": "", " v1.2.3 ": testVersion123,
+	} {
+		if got := ConcreteVersion(goutil.Package{Version: &goutil.Version{Current: in}}); got != want {
+			t.Errorf("ConcreteVersion(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := ConcreteVersion(goutil.Package{}); got != "" {
+		t.Errorf("ConcreteVersion(nil version) = %q", got)
+	}
+}
@@ -74,12 +74,22 @@ func MergePackages(confPkgs []goutil.Package, succeededPkgs []goutil.Package, ch
 		persistSource := p
 		persistSource.UpdateChannel = channel
 		persistSource.PinnedVersion = pinnedVersion
+		// An update of a named binary keeps it excluded from the next ones.
+		excluded := false
+		for _, k := range identityKeys(p) {
+			if canonical, ok := aliasToKey[k]; ok {
+				excluded = byKey[canonical].Excluded
+				break
+			}
+		}
 		upsert(goutil.Package{
 			Name:          p.Name,
 			ImportPath:    p.ImportPath,
 			Version:       &goutil.Version{Current: PersistedVersion(persistSource)},
 			UpdateChannel: channel,
 			PinnedVersion: pinnedVersion,
+			GoToolchain:   p.GoToolchain,
+			Excluded:      excluded,
 		})
 	}
 
@@ -124,11 +134,99 @@ func MergePackages(confPkgs []goutil.Package, succeededPkgs []goutil.Package, ch
 	return merged
 }
 
+// RemovePackages returns confPkgs without the entries of the removed binaries,
+// matched by cross-OS normalized name. Every other entry is kept, pins
+// included, so removing one tool never changes how the rest are tracked. The
+// result is sorted by name like MergePackages's.
+func RemovePackages(confPkgs []goutil.Package, removedNames []string) []goutil.Package {
+	removed := make(map[string]struct{}, len(removedNames))
+	for _, name := range removedNames {
+		removed[nameIdentityKey(name)] = struct{}{}
+	}
+	kept := make([]goutil.Package, 0, len(confPkgs))
+	for _, p := range confPkgs {
+		if _, ok := removed[nameIdentityKey(p.Name)]; ok {
+			continue
+		}
+		kept = append(kept, SanitizePackage(p))
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].Name < kept[j].Name
+	})
+	return kept
+}
+
+// MarkRemoved returns confPkgs with the entries of the removed binaries, matched
+// by cross-OS normalized name, marked as removed, and the names of the entries
+// it marked. Unlike RemovePackages it keeps every entry, so gup.json still
+// lists the tools that are expected. The result is sorted by name like
+// MergePackages's.
+func MarkRemoved(confPkgs []goutil.Package, removedNames []string) (pkgs []goutil.Package, marked []string) {
+	removed := make(map[string]struct{}, len(removedNames))
+	for _, name := range removedNames {
+		removed[nameIdentityKey(name)] = struct{}{}
+	}
+	pkgs = make([]goutil.Package, 0, len(confPkgs))
+	for _, p := range confPkgs {
+		p = SanitizePackage(p)
+		if _, ok := removed[nameIdentityKey(p.Name)]; ok && !p.Removed {
+			p.Removed = true
+			marked = append(marked, p.Name)
+		}
+		pkgs = append(pkgs, p)
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Name < pkgs[j].Name
+	})
+	sort.Strings(marked)
+	return pkgs, marked
+}
+
+// SetExcluded returns confPkgs with the entries of targets, matched by package
+// identity, marked as excluded from 'gup update' or, when excluded is false,
+// cleared of the mark. An excluded target without an entry gets one. The
+// result is sorted by name like MergePackages's.
+func SetExcluded(confPkgs []goutil.Package, targets []goutil.Package, excluded bool) []goutil.Package {
+	pkgs := make([]goutil.Package, 0, len(confPkgs)+len(targets))
+	found := make([]bool, len(targets))
+	for _, p := range confPkgs {
+		p = SanitizePackage(p)
+		for i, t := range targets {
+			if sameIdentity(p, t) {
+				p.Excluded, found[i] = excluded, true
+			}
+		}
+		pkgs = append(pkgs, p)
+	}
+	for i, t := range targets {
+		if !found[i] && excluded {
+			t.Excluded = true
+			pkgs = append(pkgs, SanitizePackage(t))
+		}
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].Name < pkgs[j].Name
+	})
+	return pkgs
+}
+
+// ExcludedNames returns the names of the entries of confPkgs excluded from
+// 'gup update'.
+func ExcludedNames(confPkgs []goutil.Package) []string {
+	names := []string{}
+	for _, p := range confPkgs {
+		if p.Excluded {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
 // SanitizePackage returns a trimmed, channel-normalized copy of p suitable for
-// writing to gup.json. A missing/blank version is normalized to "latest". A
-// pinned package keeps its concrete pin target in both PinnedVersion and the
-// version field so the pin survives the merge/write cycle and never degrades to
-// "latest".
+// writing to gup.json, keeping its Go toolchain. A missing/blank version is
+// normalized to "latest". A pinned package keeps its concrete pin target in
+// both PinnedVersion and the version field so the pin survives the merge/write
+// cycle and never degrades to "latest".
 func SanitizePackage(p goutil.Package) goutil.Package {
 	channel := goutil.NormalizeUpdateChannel(string(p.UpdateChannel))
 
@@ -154,6 +252,9 @@ func SanitizePackage(p goutil.Package) goutil.Package {
 		Version:       &goutil.Version{Current: version},
 		UpdateChannel: channel,
 		PinnedVersion: pinnedVersion,
+		GoToolchain:   strings.TrimSpace(p.GoToolchain),
+		Removed:       p.Removed,
+		Excluded:      p.Excluded,
 	}
 }
 
@@ -0,0 +1,160 @@
+// Package depdiff compares two dependency sets of a Go binary - typically the
+// modules linked into the installed binary and the requirements of a candidate
+// version's go.mod - and classifies each difference.
+//
+// It is pure logic with no I/O, so the rules for what counts as an upgrade or a
+// security-relevant module are tested here independently of the proxy fetches
+// and rendering in the cmd layer.
+package depdiff
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// Kind classifies a dependency change.
+type Kind string
+
+const (
+	// Added means the module is required only by the candidate version.
+	Added Kind = "added"
+	// Removed means the module is no longer required by the candidate version.
+	Removed Kind = "removed"
+	// Upgraded means the candidate version requires a newer version.
+	Upgraded Kind = "upgraded"
+	// Downgraded means the candidate version requires an older version.
+	Downgraded Kind = "downgraded"
+)
+
+// Change is one dependency difference between two module sets.
+type Change struct {
+	// Path is the module path.
+	Path string
+	// Kind classifies the change.
+	Kind Kind
+	// From is the installed version; empty for Added.
+	From string
+	// To is the candidate version; empty for Removed.
+	To string
+	// Security reports whether the module is on the security-relevant list.
+	Security bool
+}
+
+// securityRelevantPrefixes lists modules whose version changes deserve a closer
+// look before updating: cryptography, TLS/HTTP stacks, authentication tokens and
+// parsers that routinely receive security fixes. A module matches when its path
+// equals an entry or lies below it.
+var securityRelevantPrefixes = []string{ //nolint:gochecknoglobals // read-only table
+	"golang.org/x/crypto",
+	"golang.org/x/net",
+	"golang.org/x/oauth2",
+	"golang.org/x/text",
+	"google.golang.org/grpc",
+	"google.golang.org/protobuf",
+	"github.com/golang/protobuf",
+	"github.com/golang-jwt/jwt",
+	"github.com/dgrijalva/jwt-go",
+	"github.com/go-jose/go-jose",
+	"gopkg.in/square/go-jose.v2",
+	"github.com/ProtonMail/go-crypto",
+	"github.com/cloudflare/circl",
+	"github.com/go-git/go-git",
+	"github.com/docker/docker",
+	"github.com/containerd/containerd",
+	"github.com/opencontainers/runc",
+	"github.com/hashicorp/go-getter",
+	"github.com/quic-go/quic-go",
+	"golang.org/x/image",
+	"gopkg.in/yaml.v2",
+	"gopkg.in/yaml.v3",
+}
+
+// IsSecurityRelevant reports whether modulePath is on the curated list of
+// modules whose updates commonly carry security fixes.
+func IsSecurityRelevant(modulePath string) bool {
+	for _, prefix := range securityRelevantPrefixes {
+		if modulePath == prefix || strings.HasPrefix(modulePath, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Overlay returns base with every module also present in override replaced by
+// override's version, plus the modules only in override. It is used to build
+// the installed dependency set: the installed version's go.mod requirements
+// (base) corrected by the versions actually linked into the binary (override),
+// since minimal version selection can pick a newer version than go.mod names.
+func Overlay(base, override []goutil.Module) []goutil.Module {
+	idx := make(map[string]int, len(base))
+	out := make([]goutil.Module, 0, len(base)+len(override))
+	for _, m := range base {
+		if i, ok := idx[m.Path]; ok {
+			out[i] = m
+			continue
+		}
+		idx[m.Path] = len(out)
+		out = append(out, m)
+	}
+	for _, m := range override {
+		if i, ok := idx[m.Path]; ok {
+			out[i] = m
+			continue
+		}
+		idx[m.Path] = len(out)
+		out = append(out, m)
+	}
+	return out
+}
+
+// Compare returns the differences between the installed and candidate module
+// sets, sorted by module path. Modules with identical versions are omitted.
+func Compare(installed, candidate []goutil.Module) []Change {
+	from := toMap(installed)
+	to := toMap(candidate)
+
+	changes := make([]Change, 0)
+	for path, toVer := range to {
+		fromVer, ok := from[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: path, Kind: Added, To: toVer})
+		case fromVer == toVer:
+			continue
+		case isNewer(toVer, fromVer):
+			changes = append(changes, Change{Path: path, Kind: Upgraded, From: fromVer, To: toVer})
+		default:
+			changes = append(changes, Change{Path: path, Kind: Downgraded, From: fromVer, To: toVer})
+		}
+	}
+	for path, fromVer := range from {
+		if _, ok := to[path]; !ok {
+			changes = append(changes, Change{Path: path, Kind: Removed, From: fromVer})
+		}
+	}
+
+	for i := range changes {
+		changes[i].Security = IsSecurityRelevant(changes[i].Path)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func toMap(mods []goutil.Module) map[string]string {
+	m := make(map[string]string, len(mods))
+	for _, v := range mods {
+		m[v.Path] = v.Version
+	}
+	return m
+}
+
+// isNewer reports whether a is a strictly newer version than b. Versions that
+// are not comparable (e.g. "(devel)") are treated as an upgrade, since the
+// candidate is what the user would install next.
+func isNewer(a, b string) bool {
+	a = strings.TrimPrefix(a, "v")
+	b = strings.TrimPrefix(b, "v")
+	return !goutil.VersionUpToDate(b, a)
+}
@@ -0,0 +1,81 @@
+package depdiff
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	installed := []goutil.Module{
+		{Path: "golang.org/x/crypto", Version: "v0.20.0"},
+		{Path: "github.com/spf13/cobra", Version: "v1.8.1"},
+		{Path: "github.com/old/dep", Version: "v1.0.0"},
+		{Path: "example.com/pinned", Version: "v2.0.0"},
+		{Path: "example.com/pseudo", Version: "v0.0.0-20230101000000-aaaaaaaaaaaa"},
+	}
+	candidate := []goutil.Module{
+		{Path: "golang.org/x/crypto", Version: "v0.31.0"},
+		{Path: "github.com/spf13/cobra", Version: "v1.8.1"},
+		{Path: "github.com/new/dep", Version: "v0.1.0"},
+		{Path: "example.com/pinned", Version: "v1.9.0"},
+		{Path: "example.com/pseudo", Version: "v0.0.0-20240101000000-bbbbbbbbbbbb"},
+	}
+
+	want := []Change{
+		{Path: "example.com/pinned", Kind: Downgraded, From: "v2.0.0", To: "v1.9.0"},
+		{Path: "example.com/pseudo", Kind: Upgraded, From: "v0.0.0-20230101000000-aaaaaaaaaaaa", To: "v0.0.0-20240101000000-bbbbbbbbbbbb"},
+		{Path: "github.com/new/dep", Kind: Added, To: "v0.1.0"},
+		{Path: "github.com/old/dep", Kind: Removed, From: "v1.0.0"},
+		{Path: "golang.org/x/crypto", Kind: Upgraded, From: "v0.20.0", To: "v0.31.0", Security: true},
+	}
+	if diff := cmp.Diff(want, Compare(installed, candidate)); diff != "" {
+		t.Errorf("Compare() mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := Compare(installed, installed); len(got) != 0 {
+		t.Errorf("Compare() of identical sets = %v, want none", got)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	t.Parallel()
+
+	base := []goutil.Module{
+		{Path: "a", Version: "v1.0.0"},
+		{Path: "b", Version: "v1.0.0"},
+	}
+	override := []goutil.Module{
+		{Path: "b", Version: "v1.2.0"},
+		{Path: "c", Version: "v0.1.0"},
+	}
+	want := []goutil.Module{
+		{Path: "a", Version: "v1.0.0"},
+		{Path: "b", Version: "v1.2.0"},
+		{Path: "c", Version: "v0.1.0"},
+	}
+	if diff := cmp.Diff(want, Overlay(base, override)); diff != "" {
+		t.Errorf("Overlay() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestIsSecurityRelevant(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]bool{
+		"golang.org/x/crypto":             true,
+		"golang.org/x/net":                true,
+		"github.com/golang-jwt/jwt/v5":    true,
+		"google.golang.org/grpc":          true,
+		"golang.org/x/cryptography-extra": false,
+		"github.com/spf13/cobra":          false,
+	}
+	for path, want := range tests {
+		if got := IsSecurityRelevant(path); got != want {
+			t.Errorf("IsSecurityRelevant(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
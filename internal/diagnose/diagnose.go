@@ -11,6 +11,8 @@
 package diagnose
 
 import (
+	"context"
+	"errors"
 	"regexp"
 	"strings"
 
@@ -52,6 +54,14 @@ func (m matcher) matches(lower string) bool {
 // "note: module requires Go 1.23" or "requires go >= 1.23".
 var goToolchainRegex = regexp.MustCompile(`requires go ?>?=? ?\d`)
 
+// networkNeedles recognize a failure to reach the module proxy or repository.
+var networkNeedles = []string{"dial tcp", "i/o timeout", "connection refused", "tls handshake", "proxyconnect", "no such host", "network is unreachable", "could not connect"} //nolint:gochecknoglobals
+
+// serverNeedles recognize a proxy or repository that answered but could not
+// serve the request right now. They are transient, but not worth a hint of
+// their own.
+var serverNeedles = []string{"connection reset by peer", "unexpected eof", "500 internal server error", "502 bad gateway", "503 service unavailable", "504 gateway timeout"} //nolint:gochecknoglobals
+
 // matchers is consulted in order; keep the most specific failure modes first so
 // they win over the broader network/permission fallbacks.
 var matchers = []matcher{ //nolint:gochecknoglobals
@@ -112,7 +122,7 @@ var matchers = []matcher{ //nolint:gochecknoglobals
 		hint: "Permission denied while installing. Check write access to your install directory (`go env GOBIN` / `go env GOPATH`) and the module cache.",
 	},
 	{
-		needles: []string{"dial tcp", "i/o timeout", "connection refused", "tls handshake", "proxyconnect", "no such host", "network is unreachable", "could not connect"},
+		needles: networkNeedles,
 		hint:    "Network error reaching the module proxy or repository. Check your connection and the GOPROXY setting (`go env GOPROXY`).",
 	},
 }
@@ -154,3 +164,26 @@ func Hint(err error) string {
 	}
 	return ""
 }
+
+// Transient reports whether err is a network or proxy failure that may well
+// succeed when tried again, so 'gup update --retries' retries it. A timeout or
+// cancellation of gup's own context is never transient: retrying it would
+// overrun the --timeout budget or ignore Ctrl-C.
+func Transient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	lower := strings.ToLower(err.Error())
+	if strings.Contains(lower, "timed out") || strings.Contains(lower, "canceled") ||
+		strings.Contains(lower, "deadline exceeded") {
+		return false
+	}
+	for _, needles := range [][]string{networkNeedles, serverNeedles} {
+		for _, n := range needles {
+			if strings.Contains(lower, n) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package diagnose
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
@@ -286,3 +287,31 @@ func TestMatcherNeedlesAreLowercase(t *testing.T) {
 		}
 	}
 }
+
+func TestTransient(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "no such host", err: errors.New("can't check x:\ndial tcp: lookup proxy.golang.org: no such host"), want: true},
+		{name: "connection refused", err: errors.New("can't check x:\ndial tcp 127.0.0.1:443: connect: connection refused"), want: true},
+		{name: "proxy bad gateway", err: errors.New("can't install x:\ngo: x@v1.0.0: reading https://proxy.golang.org/x/@v/v1.0.0.zip: 502 Bad Gateway"), want: true},
+		{name: "connection reset", err: errors.New("can't install x:\nread tcp 10.0.0.2:50312->142.250.72.17:443: read: connection reset by peer"), want: true},
+		{name: "module not found", err: errors.New("can't install x:\ngo: x@latest: reading https://proxy.golang.org/x/@v/list: 404 Not Found"), want: false},
+		{name: "build failure", err: errors.New("can't install x:\n# x\n./main.go:3:2: undefined: y"), want: false},
+		{name: "timed out", err: errors.New("install of x timed out; run `go install x@latest` manually or raise --timeout (0 disables it): dial tcp: i/o timeout"), want: false},
+		{name: "context canceled", err: context.Canceled, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := Transient(tt.err); got != tt.want {
+				t.Errorf("Transient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package goproxy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// EscapePath returns the case-encoded form of a module path used in proxy URLs:
+// every upper-case letter is replaced by "!" followed by its lower-case form, so
+// the path is safe on case-insensitive file systems.
+func EscapePath(modulePath string) (string, error) {
+	if strings.TrimSpace(modulePath) == "" {
+		return "", errors.New("empty module path")
+	}
+	if strings.HasPrefix(modulePath, "/") || strings.Contains(modulePath, "..") || strings.Contains(modulePath, "!") {
+		return "", fmt.Errorf("invalid module path %q", modulePath)
+	}
+	return escapeString(modulePath)
+}
+
+// EscapeVersion returns the case-encoded form of a module version.
+func EscapeVersion(version string) (string, error) {
+	if strings.TrimSpace(version) == "" || strings.ContainsAny(version, "/\\!") || strings.Contains(version, "..") {
+		return "", fmt.Errorf("invalid module version %q", version)
+	}
+	return escapeString(version)
+}
+
+func escapeString(s string) (string, error) {
+	var b strings.Builder
+	for _, r := range s {
+		if r == utf8.RuneError || r >= utf8.RuneSelf {
+			return "", fmt.Errorf("invalid character in %q", s)
+		}
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,286 @@
+// Package goproxy fetches module metadata (go.mod files, version lists and
+// module zips) from the module proxies configured by GOPROXY, following the
+// GOPROXY protocol the go command itself speaks.
+//
+// gup uses it for read-only inspection that the go command has no direct
+// subcommand for, such as comparing a candidate version's go.mod against the
+// installed binary. It deliberately implements only the proxy side of the
+// protocol: "direct" (VCS) access is never attempted, and modules matched by
+// GOPRIVATE/GONOPROXY are refused, so gup never leaks a private module path to a
+// public proxy.
+package goproxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// ErrNoProxy is returned when GOPROXY names no proxy gup can query: it is empty,
+// "off", or only "direct".
+var ErrNoProxy = errors.New("no module proxy available (GOPROXY is off or direct-only)")
+
+// ErrPrivateModule is returned for a module matched by GOPRIVATE/GONOPROXY.
+var ErrPrivateModule = errors.New("module is private (GOPRIVATE/GONOPROXY); gup does not query proxies for it")
+
+// ErrNotFound is returned when every proxy reports the requested file as not
+// found.
+var ErrNotFound = errors.New("not found on the module proxy")
+
+// maxResponseSize bounds a single proxy response. go.mod and .info files are
+// tiny; the limit exists for module zips, which the go command itself caps at
+// 500 MiB.
+const maxResponseSize = 500 << 20
+
+// defaultTimeout bounds a single proxy request when the caller's context has no
+// deadline of its own.
+const defaultTimeout = 60 * time.Second
+
+// Info is the JSON metadata a proxy serves at <module>/@v/<version>.info.
+type Info struct {
+	// Version is the canonical module version.
+	Version string `json:"Version"` //nolint:tagliatelle // GOPROXY protocol field name
+	// Time is the commit time of the version.
+	Time time.Time `json:"Time"` //nolint:tagliatelle // GOPROXY protocol field name
+}
+
+// proxyEntry is one element of the GOPROXY list. fallbackOnAnyError is true
+// when the entry was followed by "|": the go command then tries the next proxy
+// after any error, whereas "," only falls through on "not found".
+type proxyEntry struct {
+	url                string
+	fallbackOnAnyError bool
+}
+
+// Client queries the configured module proxies.
+type Client struct {
+	proxies    []proxyEntry
+	noProxy    []string
+	httpClient *http.Client
+}
+
+// New builds a Client from GOPROXY- and GONOPROXY-formatted values. When
+// noProxy is empty, GOPRIVATE is used, matching the go command's defaulting.
+func New(goproxy, noProxy, private string) *Client {
+	if strings.TrimSpace(noProxy) == "" {
+		noProxy = private
+	}
+	return &Client{
+		proxies:    parseProxyList(goproxy),
+		noProxy:    splitPatterns(noProxy),
+		httpClient: &http.Client{},
+	}
+}
+
+// FromEnv builds a Client from the go command's effective environment.
+func FromEnv(ctx context.Context) (*Client, error) {
+	env, err := goutil.GoEnv(ctx, "GOPROXY", "GONOPROXY", "GOPRIVATE")
+	if err != nil {
+		return nil, err
+	}
+	return New(env["GOPROXY"], env["GONOPROXY"], env["GOPRIVATE"]), nil
+}
+
+// parseProxyList splits a GOPROXY value into the proxies gup can query. The
+// "direct" and "off" keywords end the list: anything after them would only be
+// reached by the go command after a VCS fetch, which gup never performs.
+func parseProxyList(goproxy string) []proxyEntry {
+	var entries []proxyEntry
+	for goproxy != "" {
+		var elem string
+		sep := strings.IndexAny(goproxy, ",|")
+		fallbackOnAnyError := false
+		if sep < 0 {
+			elem, goproxy = goproxy, ""
+		} else {
+			elem = goproxy[:sep]
+			fallbackOnAnyError = goproxy[sep] == '|'
+			goproxy = goproxy[sep+1:]
+		}
+		elem = strings.TrimSpace(elem)
+		switch elem {
+		case "":
+			continue
+		case "direct", "off":
+			return entries
+		}
+		entries = append(entries, proxyEntry{url: strings.TrimSuffix(elem, "/"), fallbackOnAnyError: fallbackOnAnyError})
+	}
+	return entries
+}
+
+// GoMod returns the go.mod file of modulePath at version.
+func (c *Client) GoMod(ctx context.Context, modulePath, version string) ([]byte, error) {
+	return c.fetchVersionFile(ctx, modulePath, version, ".mod")
+}
+
+// Zip returns the module zip of modulePath at version.
+func (c *Client) Zip(ctx context.Context, modulePath, version string) ([]byte, error) {
+	return c.fetchVersionFile(ctx, modulePath, version, ".zip")
+}
+
+// Info returns the version metadata of modulePath at version.
+func (c *Client) Info(ctx context.Context, modulePath, version string) (Info, error) {
+	raw, err := c.fetchVersionFile(ctx, modulePath, version, ".info")
+	if err != nil {
+		return Info{}, err
+	}
+	var info Info
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return Info{}, fmt.Errorf("malformed %s@%s info from the module proxy: %w", modulePath, version, err)
+	}
+	return info, nil
+}
+
+// List returns the tagged versions of modulePath known to the proxy, in the
+// order the proxy reports them (which is unspecified).
+func (c *Client) List(ctx context.Context, modulePath string) ([]string, error) {
+	escPath, err := EscapePath(modulePath)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := c.fetch(ctx, modulePath, escPath+"/@v/list")
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, line := range strings.Split(string(raw), "\n") {
+		if v := strings.TrimSpace(line); v != "" {
+			versions = append(versions, v)
+		}
+	}
+	return versions, nil
+}
+
+func (c *Client) fetchVersionFile(ctx context.Context, modulePath, version, suffix string) ([]byte, error) {
+	escPath, err := EscapePath(modulePath)
+	if err != nil {
+		return nil, err
+	}
+	escVer, err := EscapeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	return c.fetch(ctx, modulePath, escPath+"/@v/"+escVer+suffix)
+}
+
+// fetch requests file (a path relative to the proxy root) from each proxy in
+// turn, applying the GOPROXY fallback rules.
+func (c *Client) fetch(ctx context.Context, modulePath, file string) ([]byte, error) {
+	if c.isPrivate(modulePath) {
+		return nil, fmt.Errorf("%s: %w", modulePath, ErrPrivateModule)
+	}
+	if len(c.proxies) == 0 {
+		return nil, ErrNoProxy
+	}
+
+	var lastErr error
+	for _, p := range c.proxies {
+		data, err := c.fetchFrom(ctx, p.url, file)
+		if err == nil {
+			return data, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+		if !p.fallbackOnAnyError && !errors.Is(err, ErrNotFound) {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+func (c *Client) fetchFrom(ctx context.Context, base, file string) ([]byte, error) {
+	if strings.HasPrefix(base, "file://") {
+		return readFileProxy(base, file)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/"+file, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", base, err)
+	}
+	resp, err := c.httpClient.Do(req) //#nosec G704 -- the URL comes from the user's own GOPROXY setting
+	if err != nil {
+		return nil, fmt.Errorf("can't reach module proxy %s: %w", base, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, fmt.Errorf("%s/%s: %w", base, file, ErrNotFound)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("module proxy %s returned %s for %s", base, resp.Status, file)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("can't read response from module proxy %s: %w", base, err)
+	}
+	return data, nil
+}
+
+// readFileProxy serves a GOPROXY=file:// tree, which has exactly the same layout
+// as an HTTP proxy.
+func readFileProxy(base, file string) ([]byte, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", base, err)
+	}
+	root := filepath.FromSlash(u.Path)
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(path.Clean("/"+file)))) //#nosec G304 -- path is rooted in the user's own GOPROXY directory
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s/%s: %w", base, file, ErrNotFound)
+		}
+		return nil, fmt.Errorf("can't read %s from %s: %w", file, base, err)
+	}
+	return data, nil
+}
+
+// isPrivate reports whether modulePath matches a GONOPROXY/GOPRIVATE pattern.
+// Like the go command, a pattern matches a path prefix of whole elements, with
+// path.Match glob syntax within each element.
+func (c *Client) isPrivate(modulePath string) bool {
+	for _, pattern := range c.noProxy {
+		if matchPrefix(pattern, modulePath) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitPatterns(list string) []string {
+	var patterns []string
+	for _, p := range strings.Split(list, ",") {
+		if p = strings.TrimSuffix(strings.TrimSpace(p), "/"); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+func matchPrefix(pattern, target string) bool {
+	n := strings.Count(pattern, "/") + 1
+	elems := strings.Split(target, "/")
+	if len(elems) < n {
+		return false
+	}
+	ok, err := path.Match(pattern, strings.Join(elems[:n], "/"))
+	return err == nil && ok
+}
@@ -0,0 +1,183 @@
+package goproxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testGoMod = "module example.com/Tool\n\ngo 1.22\n"
+
+func newTestServer(t *testing.T, files map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient_GoMod(t *testing.T) {
+	t.Parallel()
+
+	srv := newTestServer(t, map[string]string{
+		"/example.com/!tool/@v/v1.0.0.mod": testGoMod,
+	})
+
+	t.Run("fetches an escaped module path", func(t *testing.T) {
+		t.Parallel()
+		c := New(srv.URL, "", "")
+		got, err := c.GoMod(context.Background(), "example.com/Tool", "v1.0.0")
+		if err != nil {
+			t.Fatalf("GoMod() error = %v", err)
+		}
+		if string(got) != testGoMod {
+			t.Errorf("GoMod() = %q", got)
+		}
+	})
+
+	t.Run("comma falls through on not found", func(t *testing.T) {
+		t.Parallel()
+		empty := newTestServer(t, nil)
+		c := New(empty.URL+","+srv.URL, "", "")
+		if _, err := c.GoMod(context.Background(), "example.com/Tool", "v1.0.0"); err != nil {
+			t.Fatalf("GoMod() error = %v", err)
+		}
+	})
+
+	t.Run("comma stops on other errors", func(t *testing.T) {
+		t.Parallel()
+		broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		t.Cleanup(broken.Close)
+
+		c := New(broken.URL+","+srv.URL, "", "")
+		if _, err := c.GoMod(context.Background(), "example.com/Tool", "v1.0.0"); err == nil {
+			t.Fatal("GoMod() error = nil, want server error")
+		}
+		c = New(broken.URL+"|"+srv.URL, "", "")
+		if _, err := c.GoMod(context.Background(), "example.com/Tool", "v1.0.0"); err != nil {
+			t.Fatalf("GoMod() with | fallback error = %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		c := New(srv.URL, "", "")
+		_, err := c.GoMod(context.Background(), "example.com/Tool", "v9.9.9")
+		if !errors.Is(err, ErrNotFound) {
+			t.Fatalf("GoMod() error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("direct and off are not queried", func(t *testing.T) {
+		t.Parallel()
+		for _, v := range []string{"", "off", "direct", "direct," + srv.URL} {
+			c := New(v, "", "")
+			if _, err := c.GoMod(context.Background(), "example.com/Tool", "v1.0.0"); !errors.Is(err, ErrNoProxy) {
+				t.Errorf("GOPROXY=%q: error = %v, want ErrNoProxy", v, err)
+			}
+		}
+	})
+
+	t.Run("private modules are refused", func(t *testing.T) {
+		t.Parallel()
+		c := New(srv.URL, "", "example.com/*")
+		if _, err := c.GoMod(context.Background(), "example.com/Tool", "v1.0.0"); !errors.Is(err, ErrPrivateModule) {
+			t.Fatalf("GoMod() error = %v, want ErrPrivateModule", err)
+		}
+		// GONOPROXY takes precedence over GOPRIVATE.
+		c = New(srv.URL, "other.org", "example.com")
+		if _, err := c.GoMod(context.Background(), "example.com/Tool", "v1.0.0"); err != nil {
+			t.Fatalf("GoMod() error = %v", err)
+		}
+	})
+}
+
+func TestClient_FileProxy(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "example.com", "tool", "@v")
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"list":        "v1.0.0\nv1.1.0\n",
+		"v1.1.0.info": `{"Version":"v1.1.0","Time":"2024-05-01T00:00:00Z"}`,
+		"v1.1.0.mod":  testGoMod,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := New("file://"+filepath.ToSlash(root), "", "")
+	ctx := context.Background()
+
+	list, err := c.List(ctx, "example.com/tool")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if diff := cmp.Diff([]string{"v1.0.0", "v1.1.0"}, list); diff != "" {
+		t.Errorf("List() mismatch (-want +got):\n%s", diff)
+	}
+
+	info, err := c.Info(ctx, "example.com/tool", "v1.1.0")
+	if err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+	if info.Version != "v1.1.0" || info.Time.Year() != 2024 {
+		t.Errorf("Info() = %+v", info)
+	}
+
+	if _, err := c.GoMod(ctx, "example.com/tool", "v1.0.0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GoMod() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "github.com/BurntSushi/toml", want: "github.com/!burnt!sushi/toml"},
+		{in: "golang.org/x/tools", want: "golang.org/x/tools"},
+		{in: "", wantErr: true},
+		{in: "../etc", wantErr: true},
+		{in: "example.com/!x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := EscapePath(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("EscapePath(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("EscapePath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := EscapeVersion("v1.0.0/../x"); err == nil {
+		t.Error("EscapeVersion() accepted a path separator")
+	}
+	if got, _ := EscapeVersion("v1.0.0-RC1"); got != "v1.0.0-!r!c1" {
+		t.Errorf("EscapeVersion() = %q", got)
+	}
+}
@@ -0,0 +1,94 @@
+package goproxy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// ModFile is the subset of a go.mod file gup inspects.
+type ModFile struct {
+	// Module is the declared module path.
+	Module string
+	// Go is the version on the "go" directive, e.g. "1.22.0". It is empty when
+	// the file has no go directive.
+	Go string
+	// Toolchain is the version on the "toolchain" directive, e.g. "go1.23.4".
+	Toolchain string
+	// Require lists the required modules in file order.
+	Require []goutil.Module
+}
+
+// ParseGoMod parses the module, go, toolchain and require directives of a
+// go.mod file. Every other directive is ignored; gup only reads go.mod files
+// served by a module proxy, which the go command has already validated.
+func ParseGoMod(data []byte) (*ModFile, error) {
+	mf := &ModFile{}
+	block := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		if j := strings.Index(line, "//"); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			if block == "require" {
+				m, err := parseRequire(fields)
+				if err != nil {
+					return nil, fmt.Errorf("go.mod line %d: %w", i+1, err)
+				}
+				mf.Require = append(mf.Require, m)
+			}
+			continue
+		}
+
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		switch fields[0] {
+		case "module":
+			if len(fields) == 2 {
+				mf.Module = unquote(fields[1])
+			}
+		case "go":
+			if len(fields) == 2 {
+				mf.Go = fields[1]
+			}
+		case "toolchain":
+			if len(fields) == 2 {
+				mf.Toolchain = fields[1]
+			}
+		case "require":
+			m, err := parseRequire(fields[1:])
+			if err != nil {
+				return nil, fmt.Errorf("go.mod line %d: %w", i+1, err)
+			}
+			mf.Require = append(mf.Require, m)
+		}
+	}
+	return mf, nil
+}
+
+func parseRequire(fields []string) (goutil.Module, error) {
+	if len(fields) != 2 { //nolint:mnd // a require line is exactly "path version"
+		return goutil.Module{}, fmt.Errorf("malformed require %q", strings.Join(fields, " "))
+	}
+	return goutil.Module{Path: unquote(fields[0]), Version: unquote(fields[1])}, nil
+}
+
+func unquote(s string) string {
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return s
+}
@@ -0,0 +1,54 @@
+package goproxy
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+func TestParseGoMod(t *testing.T) {
+	t.Parallel()
+
+	src := `// Tool module.
+module "example.com/tool"
+
+go 1.22.0
+
+toolchain go1.23.4
+
+require golang.org/x/mod v0.20.0
+
+require (
+	golang.org/x/net v0.30.0 // indirect
+	github.com/spf13/cobra v1.8.1
+)
+
+replace golang.org/x/net => golang.org/x/net v0.31.0
+
+exclude (
+	golang.org/x/text v0.1.0
+)
+`
+	got, err := ParseGoMod([]byte(src))
+	if err != nil {
+		t.Fatalf("ParseGoMod() error = %v", err)
+	}
+	want := &ModFile{
+		Module:    "example.com/tool",
+		Go:        "1.22.0",
+		Toolchain: "go1.23.4",
+		Require: []goutil.Module{
+			{Path: "golang.org/x/mod", Version: "v0.20.0"},
+			{Path: "golang.org/x/net", Version: "v0.30.0"},
+			{Path: "github.com/spf13/cobra", Version: "v1.8.1"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseGoMod() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := ParseGoMod([]byte("require (\n\tbroken\n)\n")); err == nil {
+		t.Error("ParseGoMod() accepted a malformed require")
+	}
+}
@@ -0,0 +1,39 @@
+package goutil
+
+import (
+	"debug/buildinfo"
+	"fmt"
+)
+
+// Module is a module path and version, as recorded in a binary's build info or
+// in a go.mod require directive.
+type Module struct {
+	// Path is the module path.
+	Path string
+	// Version is the module version.
+	Version string
+}
+
+// ReadBuildDeps returns the dependency modules linked into the binary at path,
+// as recorded in its build info. A replaced module is reported with the
+// replacement's version, because that is the code actually compiled in; a
+// local (directory) replacement has no version and is reported as "(devel)".
+func ReadBuildDeps(path string) ([]Module, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read build info of %s: %w", path, err)
+	}
+
+	deps := make([]Module, 0, len(info.Deps))
+	for _, d := range info.Deps {
+		m := Module{Path: d.Path, Version: d.Version}
+		if d.Replace != nil {
+			m.Version = d.Replace.Version
+			if m.Version == "" {
+				m.Version = develVersionParen
+			}
+		}
+		deps = append(deps, m)
+	}
+	return deps, nil
+}
@@ -0,0 +1,39 @@
+package goutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadBuildDeps(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reads deps of a module-aware binary", func(t *testing.T) {
+		t.Parallel()
+		// The test binary itself carries build info listing this module's deps.
+		deps, err := ReadBuildDeps(os.Args[0])
+		if err != nil {
+			t.Fatalf("ReadBuildDeps() error = %v", err)
+		}
+		found := false
+		for _, d := range deps {
+			if d.Path == "github.com/hashicorp/go-version" {
+				found = true
+				if d.Version == "" {
+					t.Errorf("go-version dep has empty version")
+				}
+			}
+		}
+		if !found {
+			t.Errorf("ReadBuildDeps() = %v, want github.com/hashicorp/go-version", deps)
+		}
+	})
+
+	t.Run("rejects a file without build info", func(t *testing.T) {
+		t.Parallel()
+		if _, err := ReadBuildDeps(filepath.Join("testdata", "normal.txt")); err == nil {
+			t.Fatal("ReadBuildDeps() error = nil, want error")
+		}
+	})
+}
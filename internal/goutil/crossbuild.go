@@ -0,0 +1,76 @@
+package goutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// Platform is a GOOS/GOARCH build target.
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// HostPlatform returns the platform gup itself runs on.
+func HostPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// String returns the platform in "goos/goarch" form.
+func (p Platform) String() string {
+	return p.OS + "/" + p.Arch
+}
+
+// BuildForPlatformWithContext executes
+// "$ GOOS=<os> GOARCH=<arch> go install <importPath>@<version>" with GOPATH set
+// to workDir, and returns the path of the built binary.
+//
+// 'go install' refuses to cross-compile into GOBIN, so GOBIN is cleared and the
+// binary lands in workDir/bin (or workDir/bin/<os>_<arch> for a foreign
+// platform). workDir must be a fresh, empty directory per call, so the single
+// file found there is the binary. modCache is passed as GOMODCACHE so moving
+// GOPATH does not also move (and re-download) the module cache; empty keeps the
+// go command's default for workDir.
+func BuildForPlatformWithContext(ctx context.Context, importPath, version string, platform Platform, workDir, modCache string) (string, error) {
+	if importPath == "command-line-arguments" {
+		return "", errors.New("is devel-binary copied from local environment")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var stderr bytes.Buffer
+	cmd := goCommandContext(ctx, "install", fmt.Sprintf("%s@%s", importPath, version))
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	env = append(env, "GOOS="+platform.OS, "GOARCH="+platform.Arch, "GOBIN=", "GOPATH="+workDir)
+	if modCache != "" {
+		env = append(env, "GOMODCACHE="+modCache)
+	}
+	cmd.Env = env
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", installError(ctx, importPath, version, err, stderr.String())
+	}
+
+	binDir := filepath.Join(workDir, "bin")
+	if platform != HostPlatform() {
+		binDir = filepath.Join(binDir, platform.OS+"_"+platform.Arch)
+	}
+	list, err := BinaryPathList(binDir)
+	if err != nil {
+		return "", fmt.Errorf("can't find the binary built for %s: %w", importPath, err)
+	}
+	if len(list) != 1 {
+		return "", fmt.Errorf("can't find the binary built for %s: %d files in %s", importPath, len(list), binDir)
+	}
+	return list[0], nil
+}
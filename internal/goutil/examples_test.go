@@ -26,9 +26,13 @@ func ExampleBinaryPathList() {
 	}
 
 	want := []string{
+		"builddeps.go",
+		"builddeps_test.go",
 		"channel.go",
 		"channel_test.go",
+		"crossbuild.go",
 		"examples_test.go",
+		"goenv.go",
 		"goutil.go",
 		"goutil_test.go",
 		"helperprocess_test.go",
@@ -0,0 +1,55 @@
+package goutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GoEnv executes "$ go env -json <keys...>" and returns the reported values.
+// Reading the settings through the go command (rather than os.Getenv) honors
+// the user's go.env/GOENV file, so gup sees the same GOPROXY, GOPRIVATE, etc.
+// that 'go install' would use.
+func GoEnv(ctx context.Context, keys ...string) (map[string]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := goCommandContext(ctx, append([]string{"env", "-json"}, keys...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		detail := stderr.String()
+		if strings.TrimSpace(detail) == "" {
+			detail = err.Error()
+		}
+		return nil, fmt.Errorf("can't read go env:\n%s", detail)
+	}
+
+	env := map[string]string{}
+	if err := json.Unmarshal(stdout.Bytes(), &env); err != nil {
+		return nil, fmt.Errorf("can't parse 'go env -json' output: %w", err)
+	}
+	return env, nil
+}
+
+// minToolchainSwitchGo is the first Go release that can switch to a newer
+// toolchain when a module requires one.
+const minToolchainSwitchGo = "go1.21"
+
+// CanSwitchToolchain reports whether a local Go toolchain can switch to a newer
+// toolchain (GOTOOLCHAIN=auto) when a module requires one.
+func CanSwitchToolchain(localGo string) bool {
+	return goVersionUpToDate(localGo, minToolchainSwitchGo)
+}
+
+// AutoToolchainEnabled reports whether a GOTOOLCHAIN setting, as reported by
+// 'go env GOTOOLCHAIN', downloads a newer toolchain when a module requires
+// one: "auto" or "<name>+auto". "local", a fixed toolchain, and "+path" (which
+// only uses toolchains already in PATH) never download.
+func AutoToolchainEnabled(gotoolchain string) bool {
+	gotoolchain = strings.TrimSpace(gotoolchain)
+	return gotoolchain == "auto" || strings.HasSuffix(gotoolchain, "+auto")
+}
@@ -37,3 +37,18 @@ var (
 		return exec.CommandContext(ctx, goExe, args...) //#nosec G204 -- args are built internally, not from untrusted input
 	}
 )
+
+// goCommand builds a go command like goCommandContext that uses toolchain,
+// e.g. "go1.22.5", through GOTOOLCHAIN. An empty toolchain leaves the go
+// command's own selection.
+func goCommand(ctx context.Context, toolchain string, args ...string) *exec.Cmd {
+	cmd := goCommandContext(ctx, args...)
+	if toolchain != "" {
+		env := cmd.Env
+		if env == nil {
+			env = os.Environ()
+		}
+		cmd.Env = append(env, "GOTOOLCHAIN="+toolchain)
+	}
+	return cmd
+}
@@ -11,6 +11,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -75,7 +76,7 @@ func TestGetLatestVer_unknown_module(t *testing.T) {
 }
 
 func TestGetVerWithContext_unknown_module(t *testing.T) {
-	out, err := GetVerWithContext(context.Background(), ".", "master")
+	out, err := GetVerWithContext(context.Background(), ".", "master", "")
 
 	// Require to be error
 	if err == nil {
@@ -93,7 +94,7 @@ func TestGetVerWithContext_golden(t *testing.T) {
 	// requested ref is echoed back as the resolved version.
 	withGoExecutable(t, "echo")
 
-	out, err := GetVerWithContext(context.Background(), "github.com/nao1215/gup", "master")
+	out, err := GetVerWithContext(context.Background(), "github.com/nao1215/gup", "master", "")
 	if err != nil {
 		t.Fatalf("GetVerWithContext() should not return error. got: %v", err)
 	}
@@ -1057,6 +1058,104 @@ func TestNormalizeGoVersionForCompare(t *testing.T) {
 	}
 }
 
+func TestParseGoVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{name: "without prefix", in: "1.23.4", want: "go1.23.4"},
+		{name: "with prefix", in: "go1.23.4", want: "go1.23.4"},
+		{name: "minor only", in: "1.24", want: "go1.24"},
+		{name: "surrounding space", in: " 1.23.4 ", want: "go1.23.4"},
+		{name: "empty", in: "", wantErr: true},
+		{name: "not a version", in: "latest", wantErr: true},
+		{name: "module version", in: "v1.2.3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGoVersion(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGoVersion(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseGoVersion(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGoToolchain(t *testing.T) {
+	for in, want := range map[string]string{"1.22.5": "go1.22.5", "go1.23rc1": "go1.23rc1", " go1.21.0 ": "go1.21.0"} {
+		got, err := ParseGoToolchain(in)
+		if err != nil || got != want {
+			t.Errorf("ParseGoToolchain(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "1.22", "go1.22", "local", "auto", "1.22.5+auto"} {
+		if _, err := ParseGoToolchain(in); err == nil {
+			t.Errorf("ParseGoToolchain(%q) error = nil, want error", in)
+		}
+	}
+}
+
+func TestGoCommand_toolchain(t *testing.T) {
+	withGoExecutable(t, "true")
+
+	cmd := goCommand(context.Background(), "go1.22.5", "version")
+	if !slices.Contains(cmd.Env, "GOTOOLCHAIN=go1.22.5") {
+		t.Errorf("env does not select the toolchain: %v", cmd.Env)
+	}
+	if cmd := goCommand(context.Background(), "", "version"); cmd.Env != nil {
+		t.Errorf("no toolchain must keep the inherited environment, got %v", cmd.Env)
+	}
+}
+
+func TestPackage_IsGoUpToDate_selectedToolchain(t *testing.T) {
+	pkg := Package{GoVersion: &Version{Current: "go1.23.4", Latest: "go1.22.5"}, GoToolchain: "go1.22.5"}
+	if pkg.IsGoUpToDate() {
+		t.Error("a binary built with a newer Go than its selected toolchain must be rebuilt")
+	}
+	pkg.GoVersion.Current = "go1.22.5"
+	if !pkg.IsGoUpToDate() {
+		t.Error("a binary built with its selected toolchain is up to date")
+	}
+}
+
+func TestAutoToolchainEnabled(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "auto", want: true},
+		{in: "go1.22.0+auto", want: true},
+		{in: "local+auto", want: true},
+		{in: "local", want: false},
+		{in: "go1.22.0", want: false},
+		{in: "path", want: false},
+		{in: "go1.22.0+path", want: false},
+		{in: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := AutoToolchainEnabled(tt.in); got != tt.want {
+				t.Fatalf("AutoToolchainEnabled(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanSwitchToolchain(t *testing.T) {
+	for in, want := range map[string]bool{"go1.20.14": false, "go1.21.0": true, "go1.23.4": true, "unknown": false} {
+		if got := CanSwitchToolchain(in); got != want {
+			t.Errorf("CanSwitchToolchain(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
 func TestPackage_IsGoUpToDate_customBuildTag(t *testing.T) {
 	pkgInfo := Package{
 		Name:       "foo",
@@ -1157,7 +1256,7 @@ func canceledContext(t *testing.T) context.Context {
 }
 
 func TestInstallWithContext_Timeout(t *testing.T) {
-	err := InstallWithContext(expiredContext(t), timeoutTestImportPath, "latest")
+	err := InstallWithContext(expiredContext(t), timeoutTestImportPath, "latest", InstallOptions{})
 	if err == nil {
 		t.Fatal("InstallWithContext should fail when the context deadline is exceeded")
 	}
@@ -1178,7 +1277,7 @@ func TestInstallWithContext_Timeout(t *testing.T) {
 }
 
 func TestInstallWithContext_Cancel(t *testing.T) {
-	err := InstallWithContext(canceledContext(t), timeoutTestImportPath, "latest")
+	err := InstallWithContext(canceledContext(t), timeoutTestImportPath, "latest", InstallOptions{})
 	if err == nil {
 		t.Fatal("InstallWithContext should fail when the context is canceled")
 	}
@@ -1188,7 +1287,7 @@ func TestInstallWithContext_Cancel(t *testing.T) {
 }
 
 func TestGetLatestVerWithContext_Timeout(t *testing.T) {
-	_, err := GetLatestVerWithContext(expiredContext(t), timeoutTestImportPath)
+	_, err := GetLatestVerWithContext(expiredContext(t), timeoutTestImportPath, "")
 	if err == nil {
 		t.Fatal("GetLatestVerWithContext should fail when the context deadline is exceeded")
 	}
@@ -1209,7 +1308,7 @@ func TestGetLatestVerWithContext_Timeout(t *testing.T) {
 }
 
 func TestGetLatestVerWithContext_Cancel(t *testing.T) {
-	_, err := GetLatestVerWithContext(canceledContext(t), timeoutTestImportPath)
+	_, err := GetLatestVerWithContext(canceledContext(t), timeoutTestImportPath, "")
 	if err == nil {
 		t.Fatal("GetLatestVerWithContext should fail when the context is canceled")
 	}
@@ -1233,7 +1332,7 @@ func TestInstallWithContext_EmptyStderrFallback(t *testing.T) {
 	// context.Background() ctx.Err() is nil so the fallback branch is reached.
 	withGoExecutable(t, "gup-nonexistent-go-command-for-test")
 
-	err := InstallWithContext(context.Background(), timeoutTestImportPath, "latest")
+	err := InstallWithContext(context.Background(), timeoutTestImportPath, "latest", InstallOptions{})
 	if err == nil {
 		t.Fatal("InstallWithContext should fail when the go command is missing")
 	}
@@ -1251,7 +1350,7 @@ func TestInstallWithContext_EmptyStderrFallback(t *testing.T) {
 func TestGetVerWithContext_EmptyStderrFallback(t *testing.T) {
 	withGoExecutable(t, "gup-nonexistent-go-command-for-test")
 
-	_, err := GetVerWithContext(context.Background(), timeoutTestImportPath, "latest")
+	_, err := GetVerWithContext(context.Background(), timeoutTestImportPath, "latest", "")
 	if err == nil {
 		t.Fatal("GetVerWithContext should fail when the go command is missing")
 	}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"testing"
@@ -166,7 +168,7 @@ func TestGetVerWithContext_helperProcess_versionString(t *testing.T) {
 	// which GetVerWithContext must trim.
 	withHelperProcess(t, helperProcessConfig{stdout: testVer123 + "\n"})
 
-	out, err := GetVerWithContext(context.Background(), "github.com/nao1215/gup", "latest")
+	out, err := GetVerWithContext(context.Background(), "github.com/nao1215/gup", "latest", "")
 	if err != nil {
 		t.Fatalf("GetVerWithContext() unexpected error: %v", err)
 	}
@@ -178,7 +180,7 @@ func TestGetVerWithContext_helperProcess_versionString(t *testing.T) {
 func TestGetLatestVerWithContext_helperProcess_versionString(t *testing.T) {
 	withHelperProcess(t, helperProcessConfig{stdout: "v0.9.0\n\n"})
 
-	out, err := GetLatestVerWithContext(context.Background(), "github.com/nao1215/gup")
+	out, err := GetLatestVerWithContext(context.Background(), "github.com/nao1215/gup", "")
 	if err != nil {
 		t.Fatalf("GetLatestVerWithContext() unexpected error: %v", err)
 	}
@@ -196,7 +198,7 @@ func TestGetVerWithContext_helperProcess_stderrBranch(t *testing.T) {
 		exit:   1,
 	})
 
-	out, err := GetVerWithContext(context.Background(), "github.com/nao1215/gup", "main")
+	out, err := GetVerWithContext(context.Background(), "github.com/nao1215/gup", "main", "")
 	if err == nil {
 		t.Fatalf("GetVerWithContext() should fail when the subprocess exits non-zero. got out=%q", out)
 	}
@@ -219,7 +221,7 @@ func TestInstallWithContext_helperProcess_success(t *testing.T) {
 	// Exit code 0 with no output: install succeeds.
 	withHelperProcess(t, helperProcessConfig{})
 
-	if err := InstallWithContext(context.Background(), "github.com/nao1215/gup", "latest"); err != nil {
+	if err := InstallWithContext(context.Background(), "github.com/nao1215/gup", "latest", InstallOptions{}); err != nil {
 		t.Fatalf("InstallWithContext() unexpected error: %v", err)
 	}
 }
@@ -233,7 +235,7 @@ func TestInstallWithContext_helperProcess_stderrBranch(t *testing.T) {
 		exit:   2,
 	})
 
-	err := InstallWithContext(context.Background(), "github.com/nao1215/gup", "latest")
+	err := InstallWithContext(context.Background(), "github.com/nao1215/gup", "latest", InstallOptions{})
 	if err == nil {
 		t.Fatal("InstallWithContext() should fail when the subprocess exits non-zero")
 	}
@@ -245,6 +247,31 @@ func TestInstallWithContext_helperProcess_stderrBranch(t *testing.T) {
 	}
 }
 
+func TestInstallWithContext_helperProcess_reportsPhases(t *testing.T) {
+	// With a phase reporter, 'go install -v' runs: the download lines and the
+	// package names of -v move the phase, and only the package names are left
+	// out of the error.
+	withHelperProcess(t, helperProcessConfig{
+		stderr: "go: downloading example.com/dep v1.0.0\nexample.com/dep\nexample.com/tool\n# example.com/tool\n./main.go:3:2: undefined: x",
+		exit:   1,
+	})
+
+	var phases []string
+	err := InstallWithContext(context.Background(), "example.com/tool", "latest", InstallOptions{
+		Phase: func(phase string) { phases = append(phases, phase) },
+	})
+	if err == nil {
+		t.Fatal("InstallWithContext() should fail when the subprocess exits non-zero")
+	}
+	if got, want := strings.Join(phases, ","), PhaseDownloading+","+PhaseBuilding; got != want {
+		t.Errorf("phases = %s, want %s", got, want)
+	}
+	want := "can't install example.com/tool:\ngo: downloading example.com/dep v1.0.0\n# example.com/tool\n./main.go:3:2: undefined: x"
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err.Error(), want)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // InstallMainOrMasterWithContext: @main failure -> @master fallback
 // ---------------------------------------------------------------------------
@@ -254,7 +281,7 @@ func TestInstallMainOrMasterWithContext_helperProcess_masterFallbackSucceeds(t *
 	// overall call must succeed (the @main error is swallowed).
 	withHelperProcessMainMasterFallback(t, "go: unknown revision main\n")
 
-	if err := InstallMainOrMasterWithContext(context.Background(), "github.com/example/tool"); err != nil {
+	if err := InstallMainOrMasterWithContext(context.Background(), "github.com/example/tool", InstallOptions{}); err != nil {
 		t.Fatalf("InstallMainOrMasterWithContext() should succeed via @master fallback. got: %v", err)
 	}
 }
@@ -268,7 +295,7 @@ func TestInstallMainOrMasterWithContext_helperProcess_bothFail(t *testing.T) {
 		exit:   1,
 	})
 
-	err := InstallMainOrMasterWithContext(context.Background(), "github.com/example/tool")
+	err := InstallMainOrMasterWithContext(context.Background(), "github.com/example/tool", InstallOptions{})
 	if err == nil {
 		t.Fatal("InstallMainOrMasterWithContext() should fail when both @main and @master fail")
 	}
@@ -288,7 +315,7 @@ func TestInstallMainOrMasterWithContext_helperProcess_bothFail(t *testing.T) {
 func TestInstallMainOrMasterWithContext_noFallbackOnGenericMainError(t *testing.T) {
 	withHelperProcessMainMasterFallback(t, "go: build failed: some compile error\n")
 
-	err := InstallMainOrMasterWithContext(context.Background(), "github.com/example/tool")
+	err := InstallMainOrMasterWithContext(context.Background(), "github.com/example/tool", InstallOptions{})
 	if err == nil {
 		t.Fatal("InstallMainOrMasterWithContext() must not fall back to @master when @main fails for non-branch reasons")
 	}
@@ -379,3 +406,105 @@ func TestGetInstalledGoVersion_helperProcess_noVersionToken(t *testing.T) {
 		t.Errorf("error should report the missing version token. got: %v", err)
 	}
 }
+
+func TestGoEnv(t *testing.T) {
+	t.Run("parses go env -json output", func(t *testing.T) {
+		withHelperProcess(t, helperProcessConfig{stdout: `{"GOPROXY":"https://proxy.golang.org,direct","GOPRIVATE":""}`})
+
+		got, err := GoEnv(context.Background(), "GOPROXY", "GOPRIVATE")
+		if err != nil {
+			t.Fatalf("GoEnv() error = %v", err)
+		}
+		if got["GOPROXY"] != "https://proxy.golang.org,direct" {
+			t.Errorf("GOPROXY = %q", got["GOPROXY"])
+		}
+		if v, ok := got["GOPRIVATE"]; !ok || v != "" {
+			t.Errorf("GOPRIVATE = %q, %v", v, ok)
+		}
+	})
+
+	t.Run("reports go command failure", func(t *testing.T) {
+		withHelperProcess(t, helperProcessConfig{stderr: "go: broken env", exit: 1})
+
+		_, err := GoEnv(context.Background(), "GOPROXY")
+		if err == nil || !strings.Contains(err.Error(), "go: broken env") {
+			t.Fatalf("GoEnv() error = %v, want stderr detail", err)
+		}
+	})
+
+	t.Run("rejects malformed output", func(t *testing.T) {
+		withHelperProcess(t, helperProcessConfig{stdout: "not json"})
+
+		if _, err := GoEnv(context.Background(), "GOPROXY"); err == nil {
+			t.Fatal("GoEnv() error = nil, want parse error")
+		}
+	})
+}
+
+func TestBuildForPlatformWithContext(t *testing.T) {
+	foreign := Platform{OS: "plan9", Arch: "arm"}
+	if foreign == HostPlatform() {
+		t.Skip("host is the foreign test platform")
+	}
+
+	t.Run("returns the binary go install wrote for a foreign platform", func(t *testing.T) {
+		withHelperProcess(t, helperProcessConfig{})
+		work := t.TempDir()
+		binDir := filepath.Join(work, "bin", "plan9_arm")
+		if err := os.MkdirAll(binDir, 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(binDir, "tool"), []byte("bin"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := BuildForPlatformWithContext(context.Background(), "example.com/tool", testVer123, foreign, work, "")
+		if err != nil {
+			t.Fatalf("BuildForPlatformWithContext() error = %v", err)
+		}
+		if want := filepath.Join(binDir, "tool"); got != want {
+			t.Errorf("BuildForPlatformWithContext() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("reports go install failure", func(t *testing.T) {
+		withHelperProcess(t, helperProcessConfig{stderr: "build constraints exclude all Go files", exit: 1})
+
+		_, err := BuildForPlatformWithContext(context.Background(), "example.com/tool", testVer123, foreign, t.TempDir(), "")
+		if err == nil || !strings.Contains(err.Error(), "build constraints exclude all Go files") {
+			t.Fatalf("BuildForPlatformWithContext() error = %v, want stderr detail", err)
+		}
+	})
+
+	t.Run("reports a missing binary", func(t *testing.T) {
+		withHelperProcess(t, helperProcessConfig{})
+
+		if _, err := BuildForPlatformWithContext(context.Background(), "example.com/tool", testVer123, foreign, t.TempDir(), ""); err == nil {
+			t.Fatal("BuildForPlatformWithContext() error = nil, want missing binary error")
+		}
+	})
+}
+
+func TestInstallWithContext_buildLimits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh to echo the environment of the go command")
+	}
+	// The stand-in go command fails with its arguments, the hints and the
+	// toolchain it was given, so they show in the error.
+	old := goCommandContext
+	t.Cleanup(func() { goCommandContext = old })
+	goCommandContext = func(ctx context.Context, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "sh", append([]string{"-c", `echo "$GOTOOLCHAIN $GOMAXPROCS $GOMEMLIMIT $*" >&2; exit 1`, "sh"}, args...)...)
+	}
+
+	err := InstallWithContext(context.Background(), "example.com/tool", "latest", InstallOptions{
+		Toolchain: "go1.22.5",
+		Limits:    BuildLimits{Procs: 2, MemLimit: 1 << 30},
+	})
+	if err == nil {
+		t.Fatal("InstallWithContext() should fail when the subprocess exits non-zero")
+	}
+	if want := "go1.22.5 2 1073741824 install -p 2 example.com/tool@latest"; !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %q, want it to contain %q", err.Error(), want)
+	}
+}
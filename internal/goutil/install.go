@@ -5,8 +5,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+
+	"github.com/nao1215/gup/internal/retry"
 )
 
 // CanUseGoCmd check whether go command install in the system.
@@ -17,28 +21,30 @@ func CanUseGoCmd() error {
 
 // InstallLatest execute "$ go install <importPath>@latest".
 func InstallLatest(importPath string) error {
-	return InstallLatestWithContext(context.Background(), importPath)
+	return InstallLatestWithContext(context.Background(), importPath, InstallOptions{})
 }
 
-// InstallLatestWithContext executes "$ go install <importPath>@latest".
-func InstallLatestWithContext(ctx context.Context, importPath string) error {
-	return InstallWithContext(ctx, importPath, "latest")
+// InstallLatestWithContext executes "$ go install <importPath>@latest" with
+// opts.
+func InstallLatestWithContext(ctx context.Context, importPath string, opts InstallOptions) error {
+	return InstallWithContext(ctx, importPath, "latest", opts)
 }
 
 // InstallMainOrMaster execute "$ go install <importPath>@main" or "$ go install <importPath>@master".
 func InstallMainOrMaster(importPath string) error {
-	return InstallMainOrMasterWithContext(context.Background(), importPath)
+	return InstallMainOrMasterWithContext(context.Background(), importPath, InstallOptions{})
 }
 
 // InstallMainOrMasterWithContext executes "$ go install <importPath>@main"
-// or "$ go install <importPath>@master" with context cancellation support.
+// or "$ go install <importPath>@master" with opts and context cancellation
+// support.
 //
 // The @master fallback is taken only when @main fails because the main branch
 // does not exist. Build failures, network/proxy/auth errors, timeouts, and
 // cancellations on @main are returned as-is and never trigger a @master install
 // (#340).
-func InstallMainOrMasterWithContext(ctx context.Context, importPath string) error {
-	mainErr := InstallWithContext(ctx, importPath, "main")
+func InstallMainOrMasterWithContext(ctx context.Context, importPath string, opts InstallOptions) error {
+	mainErr := InstallWithContext(ctx, importPath, "main", opts)
 	if mainErr == nil {
 		return nil
 	}
@@ -52,7 +58,7 @@ func InstallMainOrMasterWithContext(ctx context.Context, importPath string) erro
 		return mainErr
 	}
 
-	masterErr := InstallWithContext(ctx, importPath, "master")
+	masterErr := InstallWithContext(ctx, importPath, "master", opts)
 	if masterErr == nil {
 		return nil
 	}
@@ -62,11 +68,31 @@ func InstallMainOrMasterWithContext(ctx context.Context, importPath string) erro
 
 // Install executes "$ go install <importPath>@<version>".
 func Install(importPath, version string) error {
-	return InstallWithContext(context.Background(), importPath, version)
+	return InstallWithContext(context.Background(), importPath, version, InstallOptions{})
+}
+
+// InstallOptions are the settings of an install beside what to install. The
+// zero value runs a plain 'go install' that is not retried.
+type InstallOptions struct {
+	// Toolchain is the Go release to build with through GOTOOLCHAIN, e.g.
+	// "go1.22.5". Empty leaves the go command's own selection.
+	Toolchain string
+	// Limits are the hints given to 'go install' about the share of the
+	// machine it may use.
+	Limits BuildLimits
+	// Phase is called with PhaseDownloading or PhaseBuilding whenever
+	// 'go install' moves to another phase, for a live progress display. A nil
+	// Phase reports nothing.
+	Phase func(phase string)
+	// Retry is how a transient failure is retried.
+	Retry retry.Policy
+	// Retries counts the retries made, unless it is nil.
+	Retries *retry.Counter
 }
 
-// InstallWithContext executes "$ go install <importPath>@<version>".
-func InstallWithContext(ctx context.Context, importPath, version string) error {
+// InstallWithContext executes "$ go install <importPath>@<version>" with
+// opts. A transient failure is retried by opts.Retry.
+func InstallWithContext(ctx context.Context, importPath, version string, opts InstallOptions) error {
 	if importPath == "command-line-arguments" {
 		return errors.New("is devel-binary copied from local environment")
 	}
@@ -74,25 +100,141 @@ func InstallWithContext(ctx context.Context, importPath, version string) error {
 		ctx = context.Background()
 	}
 
+	return retry.Do(ctx, opts.Retry, opts.Retries, func(ctx context.Context) error {
+		return install(ctx, importPath, version, opts)
+	})
+}
+
+// install runs one "$ go install <importPath>@<version>".
+func install(ctx context.Context, importPath, version string, opts InstallOptions) error {
 	var stderr bytes.Buffer
-	cmd := goCommandContext(ctx, "install", fmt.Sprintf("%s@%s", importPath, version))
+	args := []string{"install", fmt.Sprintf("%s@%s", importPath, version)}
+	if opts.Phase != nil {
+		// -v names each package as it is compiled, which tells the building
+		// phase apart from the downloads.
+		args = []string{"install", "-v", args[1]}
+	}
+	if opts.Limits.Procs > 0 {
+		args = append([]string{args[0], "-p", strconv.Itoa(opts.Limits.Procs)}, args[1:]...)
+	}
+	cmd := goCommand(ctx, opts.Toolchain, args...)
+	if env := opts.Limits.env(); len(env) != 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, env...)
+	}
 	cmd.Stderr = &stderr
+	phases := &phaseWriter{report: opts.Phase, detail: &stderr}
+	if opts.Phase != nil {
+		cmd.Stderr = phases
+	}
 
 	err := cmd.Run()
+	phases.flush()
 	if err != nil {
-		if ctxErr := ctx.Err(); ctxErr != nil {
-			if errors.Is(ctxErr, context.DeadlineExceeded) {
-				return fmt.Errorf("install of %s timed out; run `go install %s@%s` manually or raise --timeout (0 disables it): %w", importPath, importPath, version, ctxErr)
-			}
-			return fmt.Errorf("install of %s canceled: %w", importPath, ctxErr)
+		return installError(ctx, importPath, version, err, stderr.String())
+	}
+	return nil
+}
+
+// BuildLimits are the hints InstallWithContext gives 'go install' so that
+// several installs running at once share the machine instead of each sizing
+// itself to all of it.
+type BuildLimits struct {
+	// Procs is passed as -p and GOMAXPROCS. 0 leaves the go command's default.
+	Procs int
+	// MemLimit is passed as GOMEMLIMIT, in bytes. 0 sets none.
+	MemLimit int64
+}
+
+// env returns the environment entries of l.
+func (l BuildLimits) env() []string {
+	var env []string
+	if l.Procs > 0 {
+		env = append(env, "GOMAXPROCS="+strconv.Itoa(l.Procs))
+	}
+	if l.MemLimit > 0 {
+		env = append(env, "GOMEMLIMIT="+strconv.FormatInt(l.MemLimit, 10))
+	}
+	return env
+}
+
+// The phases of 'go install' reported to InstallOptions.Phase.
+const (
+	// PhaseDownloading means the go command is downloading modules.
+	PhaseDownloading = "downloading"
+	// PhaseBuilding means the go command is compiling packages.
+	PhaseBuilding = "building"
+)
+
+// phaseWriter is the stderr of 'go install -v'. It reports the phase each line
+// shows and keeps every line but the package names of -v in detail, so a
+// failure is described as it is without -v.
+type phaseWriter struct {
+	report  func(phase string)
+	detail  *bytes.Buffer
+	partial []byte
+	phase   string
+}
+
+// Write splits p into lines, holding back an unfinished last line.
+func (w *phaseWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			return len(p), nil
 		}
-		// A killed subprocess (e.g. SIGKILL) often writes nothing to stderr, so
-		// fall back to err (e.g. "signal: killed") to always name a cause.
-		detail := stderr.String()
-		if strings.TrimSpace(detail) == "" {
-			detail = err.Error()
+		w.line(string(w.partial[:i+1]))
+		w.partial = w.partial[i+1:]
+	}
+}
+
+// flush handles an unfinished last line once the go command exited.
+func (w *phaseWriter) flush() {
+	if len(w.partial) > 0 {
+		w.line(string(w.partial))
+		w.partial = nil
+	}
+}
+
+// line handles one line of stderr. A -v package line is an import path alone;
+// the go command's own messages and compiler errors always hold a space or a
+// colon.
+func (w *phaseWriter) line(line string) {
+	text := strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(text, "go: downloading "):
+		w.enter(PhaseDownloading)
+	case text != "" && !strings.ContainsAny(text, " :"):
+		w.enter(PhaseBuilding)
+		return
+	}
+	w.detail.WriteString(line)
+}
+
+// enter reports phase unless the go command is already in it.
+func (w *phaseWriter) enter(phase string) {
+	if w.report != nil && phase != w.phase {
+		w.phase = phase
+		w.report(phase)
+	}
+}
+
+// installError describes a failed "go install <importPath>@<version>", naming
+// a timeout or cancellation of ctx before the go command's own output.
+func installError(ctx context.Context, importPath, version string, err error, detail string) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			return fmt.Errorf("install of %s timed out; run `go install %s@%s` manually or raise --timeout (0 disables it): %w", importPath, importPath, version, ctxErr)
 		}
-		return fmt.Errorf("can't install %s:\n%s", importPath, detail)
+		return fmt.Errorf("install of %s canceled: %w", importPath, ctxErr)
 	}
-	return nil
+	// A killed subprocess (e.g. SIGKILL) often writes nothing to stderr, so
+	// fall back to err (e.g. "signal: killed") to always name a cause.
+	if strings.TrimSpace(detail) == "" {
+		detail = err.Error()
+	}
+	return fmt.Errorf("can't install %s:\n%s", importPath, detail)
 }
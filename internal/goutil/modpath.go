@@ -14,24 +14,25 @@ var requiredAsPathRegex = regexp.MustCompile(`(?m)but was required as:\s*(\S+)`)
 
 // GetLatestVer execute "$ go list -m -f {{.Version}} <importPath>@latest".
 func GetLatestVer(modulePath string) (string, error) {
-	return GetLatestVerWithContext(context.Background(), modulePath)
+	return GetLatestVerWithContext(context.Background(), modulePath, "")
 }
 
 // GetLatestVerWithContext execute "$ go list -m -f {{.Version}} <importPath>@latest"
-// with context cancellation support.
-func GetLatestVerWithContext(ctx context.Context, modulePath string) (string, error) {
-	return GetVerWithContext(ctx, modulePath, "latest")
+// with context cancellation support, using toolchain like GetVerWithContext.
+func GetLatestVerWithContext(ctx context.Context, modulePath, toolchain string) (string, error) {
+	return GetVerWithContext(ctx, modulePath, "latest", toolchain)
 }
 
 // GetVerWithContext execute "$ go list -m -f {{.Version}} <modulePath>@<ref>"
 // with context cancellation support. ref is the version selector understood by
 // the go toolchain, such as "latest", "main", "master" or a concrete version.
-func GetVerWithContext(ctx context.Context, modulePath, ref string) (string, error) {
+// toolchain is the Go release to run it with, as in InstallOptions.Toolchain.
+func GetVerWithContext(ctx context.Context, modulePath, ref, toolchain string) (string, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 	var stderr bytes.Buffer
-	cmd := goCommandContext(ctx, "list", "-m", "-f", "{{.Version}}", modulePath+"@"+ref)
+	cmd := goCommand(ctx, toolchain, "list", "-m", "-f", "{{.Version}}", modulePath+"@"+ref)
 	cmd.Stderr = &stderr
 	out, err := cmd.Output()
 	if err != nil {
@@ -1,6 +1,8 @@
 package goutil
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/hashicorp/go-version"
@@ -21,12 +23,22 @@ type Package struct {
 	GoVersion *Version
 	// UpdateChannel stores preferred update channel.
 	UpdateChannel UpdateChannel
+	// GoToolchain is the Go toolchain release (e.g. "go1.22.5") the package is
+	// built with, set through GOTOOLCHAIN. It is empty for the installed Go.
+	GoToolchain string
 	// PinnedVersion is the concrete target version when UpdateChannel is
 	// "pinned". It is empty for every other channel. It is kept separate from
 	// Version.Current (the installed version) so a pin can downgrade a binary and
 	// so check/update compare the installed version against the pin target
 	// without consulting @latest.
 	PinnedVersion string
+	// Removed marks a gup.json entry whose binary was deleted from $GOBIN
+	// ('gup watch' records it). The entry is kept, so the tool is still
+	// expected, and installing it again clears the mark.
+	Removed bool
+	// Excluded marks a gup.json entry that 'gup update' skips unless the
+	// binary is named, like --exclude does ('gup update -i' saves it).
+	Excluded bool
 }
 
 // IsPinned reports whether the package is pinned to a concrete version.
@@ -82,8 +94,13 @@ func (p *Package) IsPackageUpToDate() bool {
 }
 
 // IsGoUpToDate checks if the Golang runtime version is up to date.
-// Returns true if current >= available.
+// Returns true if current >= available. A package with a selected GoToolchain
+// is up to date only when it was built with exactly that toolchain, so a tool
+// held at an older Go is rebuilt back to it.
 func (p *Package) IsGoUpToDate() bool {
+	if p.GoToolchain != "" {
+		return normalizeGoVersionForCompare(p.GoVersion.Current) == normalizeGoVersionForCompare(p.GoToolchain)
+	}
 	return goVersionUpToDate(
 		p.GoVersion.Current,
 		p.GoVersion.Latest,
@@ -160,3 +177,38 @@ func VersionUpToDate(current, available string) bool {
 func GoVersionUpToDate(current, available string) bool {
 	return goVersionUpToDate(current, available)
 }
+
+// ParseGoVersion normalizes a Go release version given by a user, with or
+// without the "go" prefix ("1.23.4", "go1.23.4"), to the "go1.23.4" form that
+// 'go version' and build info report. It rejects anything that is not a Go
+// release version.
+func ParseGoVersion(ver string) (string, error) {
+	ver = strings.TrimPrefix(strings.TrimSpace(ver), "go")
+	if ver == "" || ver[0] < '1' || ver[0] > '9' {
+		return "", fmt.Errorf("invalid Go version %q (want e.g. 1.23.4)", ver)
+	}
+	if _, err := version.NewVersion(ver); err != nil {
+		return "", fmt.Errorf("invalid Go version %q (want e.g. 1.23.4)", ver)
+	}
+	return "go" + ver, nil
+}
+
+// LocalToolchain is the GOTOOLCHAIN value that selects the installed Go. As a
+// --go flag value it clears a package's selected toolchain.
+const LocalToolchain = "local"
+
+// goToolchainRegex matches a Go toolchain release name: a patch release
+// (go1.22.5) or a release candidate (go1.23rc1).
+var goToolchainRegex = regexp.MustCompile(`^go[1-9][0-9]*\.[0-9]+(\.[0-9]+|rc[0-9]+)$`)
+
+// ParseGoToolchain normalizes a Go toolchain release given by a user, with or
+// without the "go" prefix ("1.22.5", "go1.22.5"), to the name GOTOOLCHAIN
+// accepts ("go1.22.5"). A language version such as "1.22" names no release and
+// is rejected.
+func ParseGoToolchain(toolchain string) (string, error) {
+	name, err := ParseGoVersion(toolchain)
+	if err != nil || !goToolchainRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid Go toolchain %q (want a release such as 1.22.5)", strings.TrimSpace(toolchain))
+	}
+	return name, nil
+}
@@ -18,7 +18,8 @@ import (
 // Run executes fn for each item using a pool of at most `workers` goroutines.
 // Each item runs under its own deadline derived from ctx when timeout > 0,
 // so a single stuck item fails instead of hanging the rest; cancellation of ctx
-// aborts all in-flight and pending work.
+// aborts all in-flight and pending work. Time an item spends paused (see Pause)
+// does not count toward its timeout.
 //
 // `workers` is clamped to the range [1, len(items)]. For an item that is
 // skipped because ctx is already done when it would be dispatched or started,
@@ -115,7 +116,116 @@ func runWithTimeout[T, R any](ctx context.Context, timeout time.Duration, item T
 	if timeout <= 0 {
 		return fn(ctx, item)
 	}
-	opCtx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
+	opCtx := newItemContext(ctx, timeout)
+	defer opCtx.stop()
 	return fn(opCtx, item)
 }
+
+// itemKey is the context key under which an itemContext finds itself.
+type itemKey struct{}
+
+// itemContext is the context of one item run under a timeout. It is done when
+// its parent is, or when the timeout is over, not counting the time the item
+// was paused; its deadline moves back by every pause.
+type itemContext struct {
+	context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	deadline time.Time
+	timer    *time.Timer
+	pauses   int
+	pausedAt time.Time
+	timedOut bool
+}
+
+// newItemContext returns the context of an item that times out after timeout.
+func newItemContext(parent context.Context, timeout time.Duration) *itemContext {
+	ctx, cancel := context.WithCancel(parent)
+	c := &itemContext{Context: ctx, cancel: cancel, deadline: time.Now().Add(timeout)}
+	c.timer = time.AfterFunc(timeout, c.expire)
+	return c
+}
+
+// expire ends the item when its timeout is over, unless it is paused.
+func (c *itemContext) expire() {
+	c.mu.Lock()
+	if c.pauses > 0 || c.Context.Err() != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.timedOut = true
+	c.mu.Unlock()
+	c.cancel()
+}
+
+// stop releases the timer and the context once the item is done.
+func (c *itemContext) stop() {
+	c.timer.Stop()
+	c.cancel()
+}
+
+// Deadline returns when the item times out, as of now, or the deadline of its
+// parent when that comes first.
+func (c *itemContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	deadline := c.deadline
+	c.mu.Unlock()
+	if parent, ok := c.Context.Deadline(); ok && parent.Before(deadline) {
+		return parent, true
+	}
+	return deadline, true
+}
+
+// Err returns context.DeadlineExceeded once the item timed out, like a context
+// with a deadline does.
+func (c *itemContext) Err() error {
+	err := c.Context.Err()
+	if err == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timedOut {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// Value returns c itself for itemKey, so Pause finds it below other values.
+func (c *itemContext) Value(key any) any {
+	if key == (itemKey{}) {
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+// Pause stops the timeout Run set for the item of ctx until the returned resume
+// is called, so time the item spends waiting for its turn at a shared resource
+// does not count toward it. ctx is still done when its parent is. Pause does
+// nothing when the item has no timeout.
+func Pause(ctx context.Context) (resume func()) {
+	c, ok := ctx.Value(itemKey{}).(*itemContext)
+	if !ok {
+		return func() {}
+	}
+	c.mu.Lock()
+	if c.pauses == 0 {
+		c.timer.Stop()
+		c.pausedAt = time.Now()
+	}
+	c.pauses++
+	c.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if c.pauses--; c.pauses == 0 {
+				c.deadline = c.deadline.Add(time.Since(c.pausedAt))
+				c.timer.Reset(time.Until(c.deadline))
+			}
+		})
+	}
+}
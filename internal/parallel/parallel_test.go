@@ -95,6 +95,45 @@ func TestRun_appliesPerItemTimeout(t *testing.T) {
 	}
 }
 
+func TestRun_pausedTimeDoesNotCount(t *testing.T) {
+	t.Parallel()
+
+	const timeout = 40 * time.Millisecond
+	got := Run(context.Background(), []string{"a"}, 1, timeout,
+		func(ctx context.Context, name string) res {
+			before, _ := ctx.Deadline()
+			resume := Pause(ctx)
+			time.Sleep(2 * timeout)
+			resume()
+			resume() // a second resume does nothing
+			if err := ctx.Err(); err != nil {
+				return res{name: name, err: fmt.Errorf("timed out while paused: %w", err)}
+			}
+			if after, _ := ctx.Deadline(); !after.After(before.Add(timeout)) {
+				return res{name: name, err: fmt.Errorf("deadline %v did not move back from %v", after, before)}
+			}
+			<-ctx.Done()
+			return res{name: name, err: ctx.Err()}
+		},
+		onCancel, nil)
+
+	if len(got) != 1 || !errors.Is(got[0].err, context.DeadlineExceeded) {
+		t.Fatalf("results = %+v, want the item to time out only after the pause", got)
+	}
+}
+
+func TestPause_withoutTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	resume := Pause(ctx)
+	cancel()
+	resume()
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("ctx.Err() = %v, want the parent's cancellation", ctx.Err())
+	}
+}
+
 func TestRun_noDeadlineWhenTimeoutZero(t *testing.T) {
 	t.Parallel()
 
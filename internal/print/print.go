@@ -30,6 +30,20 @@ type Printer struct {
 	err    io.Writer
 	scanln func(a ...any) (n int, err error)
 	exit   func(code int)
+	// progress receives Progress lines; nil discards them.
+	progress io.Writer
+	// live is drawn below the normal output; nil draws nothing.
+	live Live
+}
+
+// Live is a display kept below the normal output, such as the live progress of
+// 'gup update' on a terminal. The Printer erases it before each message and
+// draws it again after, so messages scroll by above it.
+type Live interface {
+	// Draw writes the display to w, ending with a newline.
+	Draw(w io.Writer)
+	// Erase removes from w what the last Draw wrote.
+	Erase(w io.Writer)
 }
 
 // New returns a Printer that writes normal output to out and warnings/errors to
@@ -44,6 +58,10 @@ func New(out, err io.Writer) *Printer {
 func (p *Printer) printf(w io.Writer, format string, args ...any) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	if p.live != nil {
+		p.live.Erase(p.out)
+		defer p.live.Draw(p.out)
+	}
 	_, _ = fmt.Fprintf(w, format, args...)
 }
 
@@ -69,6 +87,50 @@ func (p *Printer) Out() io.Writer { return p.out }
 // ErrOut returns the writer used for warnings and errors.
 func (p *Printer) ErrOut() io.Writer { return p.err }
 
+// SetProgress makes Progress write to w. Without it, Progress writes nothing.
+func (p *Printer) SetProgress(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.progress = w
+}
+
+// SetLive draws l below the normal output until SetLive is called again. A nil
+// l erases the display and leaves the output as it is.
+func (p *Printer) SetLive(l Live) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.live != nil {
+		p.live.Erase(p.out)
+	}
+	p.live = l
+	if l != nil {
+		l.Draw(p.out)
+	}
+}
+
+// RedrawLive draws the display set with SetLive again, for a display that
+// changes between messages.
+func (p *Printer) RedrawLive() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.live != nil {
+		p.live.Erase(p.out)
+		p.live.Draw(p.out)
+	}
+}
+
+// Progress reports that one step of a long-running command, such as one
+// package of 'gup update', finished. It writes only to the writer set with
+// SetProgress, so commands can report progress unconditionally without
+// changing their normal or --json output.
+func (p *Printer) Progress(msg string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.progress != nil {
+		_, _ = fmt.Fprintf(p.progress, "%s\n", msg)
+	}
+}
+
 // Info prints an information message to the normal output in green.
 //
 // NOTE: When we executed gup update, the standard output became quite wide.
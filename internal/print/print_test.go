@@ -4,6 +4,7 @@ package print
 import (
 	"bytes"
 	"errors"
+	"io"
 	"os"
 	"runtime"
 	"strings"
@@ -176,6 +177,51 @@ func TestPrinter_Question(t *testing.T) {
 // happens when goutil reports unreadable binaries from parallel.Run workers)
 // serializes its writes: under -race this fails if the methods drop the lock,
 // and every emitted line stays whole.
+func TestPrinter_Progress(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	p := New(buf, buf)
+	p.Progress("dropped")
+	if buf.Len() != 0 {
+		t.Errorf("Progress() without SetProgress wrote %q", buf.String())
+	}
+
+	progress := &bytes.Buffer{}
+	p.SetProgress(progress)
+	p.Progress(testMessage)
+	if diff := cmp.Diff(testMessage+"\n", progress.String()); diff != "" {
+		t.Errorf("value is mismatch (-want +got):\n%s", diff)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Progress() wrote to the normal output: %q", buf.String())
+	}
+}
+
+// testLive is a Live display that draws one marker line.
+type testLive struct{}
+
+func (testLive) Draw(w io.Writer)  { _, _ = io.WriteString(w, "<live>\n") }
+func (testLive) Erase(w io.Writer) { _, _ = io.WriteString(w, "<erase>") }
+
+func TestPrinter_SetLive(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	p := New(buf, buf)
+	p.SetLive(testLive{})
+	p.Info(testMessage)
+	p.RedrawLive()
+	p.SetLive(nil)
+	p.RedrawLive()
+	p.Info(testMessage)
+
+	want := "<live>\n<erase>" + testMessage + "\n<live>\n<erase><live>\n<erase>" + testMessage + "\n"
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("value is mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestPrinter_ConcurrentWrites(t *testing.T) {
 	t.Parallel()
 	buf := &bytes.Buffer{}
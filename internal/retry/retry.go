@@ -0,0 +1,116 @@
+// Package retry runs a network operation again when it fails with a transient
+// error, waiting twice as long before each new attempt. A flaky module proxy
+// then costs a few seconds instead of failing the whole package on the first
+// hiccup.
+//
+// The version lookup in vercache and 'go install' in goutil take the policy of
+// the command and pass it to Do. What counts as transient is injected with the
+// policy, keeping this package free of the error classification in diagnose.
+package retry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBackoff is the wait before the first retry when none is configured.
+const DefaultBackoff = time.Second
+
+// Policy tells Do how to retry.
+type Policy struct {
+	// Retries is the number of attempts after the first. 0 disables retrying.
+	Retries int
+	// Backoff is the wait before the first retry. It doubles before each
+	// next one.
+	Backoff time.Duration
+	// Transient reports whether an error is worth another attempt. A nil
+	// Transient retries nothing.
+	Transient func(error) bool
+}
+
+// Enabled reports whether p retries at all.
+func (p Policy) Enabled() bool {
+	return p.Retries > 0 && p.Transient != nil
+}
+
+// Counter counts the retries Do made, e.g. for one package. The zero value
+// is ready to use.
+type Counter struct {
+	n atomic.Int64
+}
+
+// Retries returns the retries counted so far.
+func (c *Counter) Retries() int {
+	return int(c.n.Load())
+}
+
+// Do calls op, and calls it again while it fails with an error p classifies
+// as transient, up to p.Retries more times. The wait before a retry starts at
+// p.Backoff and doubles each time. Every retry is added to counter, unless it
+// is nil.
+//
+// A retry never outlives ctx: Do stops when ctx is done, and does not wait
+// for a retry that would start after the deadline of ctx (the per-package
+// --timeout budget). The last error is returned, noting the attempts made when
+// there was more than one.
+func Do(ctx context.Context, p Policy, counter *Counter, op func(ctx context.Context) error) error {
+	err := op(ctx)
+	if err == nil || !p.Enabled() {
+		return err
+	}
+	wait := p.Backoff
+	attempts := 1
+	for ; attempts <= p.Retries; attempts++ {
+		if ctx.Err() != nil || !p.Transient(err) || !sleep(ctx, wait) {
+			break
+		}
+		if counter != nil {
+			counter.n.Add(1)
+		}
+		if err = op(ctx); err == nil {
+			return nil
+		}
+		wait *= 2
+	}
+	if attempts == 1 {
+		return err
+	}
+	return &Error{Attempts: attempts, Err: err}
+}
+
+// sleep waits d, and reports false without waiting when ctx is done first or
+// its deadline comes before d is over.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d {
+		return false
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// Error is the error of an operation that still failed after Attempts
+// attempts.
+type Error struct {
+	Attempts int
+	Err      error
+}
+
+// Error keeps the message of the last failure first, so callers matching on
+// it (diagnose.Hint, IsBranchNotFound) see it as it is.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (gave up after %d attempts)", strings.TrimRight(e.Err.Error(), "\n"), e.Attempts)
+}
+
+// Unwrap returns the last failure.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,107 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	errFlaky     = errors.New("dial tcp: connection refused")
+	errPermanent = errors.New("404 not found")
+)
+
+func isFlaky(err error) bool { return errors.Is(err, errFlaky) }
+
+// failing returns an op that fails with the given errors in turn, then
+// succeeds, and a pointer to the number of calls.
+func failing(errs ...error) (func(context.Context) error, *int) {
+	calls := 0
+	return func(context.Context) error {
+		calls++
+		if calls <= len(errs) {
+			return errs[calls-1]
+		}
+		return nil
+	}, &calls
+}
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	policy := Policy{Retries: 2, Backoff: time.Millisecond, Transient: isFlaky}
+	tests := []struct {
+		name        string
+		policy      Policy
+		errs        []error
+		wantCalls   int
+		wantRetries int
+		wantErr     error
+		wantMsg     string
+	}{
+		{name: "succeeds at once", policy: policy, wantCalls: 1},
+		{name: "no policy", errs: []error{errFlaky}, wantCalls: 1, wantErr: errFlaky},
+		{name: "recovers after a retry", policy: policy, errs: []error{errFlaky}, wantCalls: 2, wantRetries: 1},
+		{name: "gives up after the retries", policy: policy, errs: []error{errFlaky, errFlaky, errFlaky}, wantCalls: 3, wantRetries: 2, wantErr: errFlaky, wantMsg: "gave up after 3 attempts"},
+		{name: "permanent error is not retried", policy: policy, errs: []error{errPermanent}, wantCalls: 1, wantErr: errPermanent},
+		{name: "stops at a permanent error", policy: policy, errs: []error{errFlaky, errPermanent}, wantCalls: 2, wantRetries: 1, wantErr: errPermanent, wantMsg: "gave up after 2 attempts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			counter := &Counter{}
+			op, calls := failing(tt.errs...)
+			err := Do(context.Background(), tt.policy, counter, op)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Fatalf("Do() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && tt.wantMsg != "" && !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("Do() error = %q, want it to contain %q", err, tt.wantMsg)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("op called %d times, want %d", *calls, tt.wantCalls)
+			}
+			if counter.Retries() != tt.wantRetries {
+				t.Errorf("Retries() = %d, want %d", counter.Retries(), tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestDo_respectsDeadline(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	op, calls := failing(errFlaky)
+
+	start := time.Now()
+	if err := Do(ctx, Policy{Retries: 3, Backoff: time.Hour, Transient: isFlaky}, nil, op); !errors.Is(err, errFlaky) {
+		t.Fatalf("Do() error = %v, want %v", err, errFlaky)
+	}
+	if *calls != 1 {
+		t.Errorf("op called %d times, want 1: a backoff past the deadline must not be waited", *calls)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Do() took %v, want it to give up at once", elapsed)
+	}
+}
+
+func TestDo_backoffDoubles(t *testing.T) {
+	t.Parallel()
+
+	var starts []time.Time
+	policy := Policy{Retries: 2, Backoff: 20 * time.Millisecond, Transient: isFlaky}
+	_ = Do(context.Background(), policy, nil, func(context.Context) error {
+		starts = append(starts, time.Now())
+		return errFlaky
+	})
+	if len(starts) != 3 {
+		t.Fatalf("op called %d times, want 3", len(starts))
+	}
+	if first, second := starts[1].Sub(starts[0]), starts[2].Sub(starts[1]); first < 20*time.Millisecond || second < 40*time.Millisecond {
+		t.Errorf("waits = %v, %v; want at least 20ms, then 40ms", first, second)
+	}
+}
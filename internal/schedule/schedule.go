@@ -0,0 +1,590 @@
+// Package schedule installs, reports on and removes the job that runs gup in
+// the background on a schedule. It uses a systemd user timer where systemd is
+// running, and a crontab entry otherwise.
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/adrg/xdg"
+)
+
+// UnitName is the name of the systemd service and timer units.
+const UnitName = "gup-update"
+
+const (
+	cronBegin = "# BEGIN gup schedule (managed by 'gup schedule'; do not edit)"
+	cronEnd   = "# END gup schedule"
+)
+
+// Backend is the scheduler that runs the job.
+type Backend string
+
+const (
+	// BackendSystemd is a systemd user service started by a timer.
+	BackendSystemd Backend = "systemd"
+	// BackendCron is an entry in the user's crontab.
+	BackendCron Backend = "cron"
+)
+
+// Spec is when the job runs.
+type Spec struct {
+	// Cron is the five-field cron expression.
+	Cron string
+	// OnCalendar is the systemd calendar event for the same times. It is
+	// empty when the cron expression has no systemd equivalent, and then the
+	// job is always installed in the crontab.
+	OnCalendar string
+}
+
+// Daily runs the job every day at 03:00.
+func Daily() Spec {
+	return Spec{Cron: "0 3 * * *", OnCalendar: "*-*-* 03:00:00"}
+}
+
+// Weekly runs the job every Monday at 03:00.
+func Weekly() Spec {
+	return Spec{Cron: "0 3 * * 1", OnCalendar: "Mon *-*-* 03:00:00"}
+}
+
+// cronItem is one comma-separated item of a cron field: "*", "N", "N-M", and
+// any of them with a "/STEP".
+var cronItem = regexp.MustCompile(`^(\*|\d+(?:-\d+)?)(?:/(\d+))?$`)
+
+// cronField is the valid range of one cron field.
+type cronField struct {
+	name     string
+	min, max int
+}
+
+// cronFields returns the five cron fields in order. Day of week accepts 7 as
+// well as 0 for Sunday.
+func cronFields() []cronField {
+	return []cronField{
+		{name: "minute", min: 0, max: 59},
+		{name: "hour", min: 0, max: 23},
+		{name: "day of month", min: 1, max: 31},
+		{name: "month", min: 1, max: 12},
+		{name: "day of week", min: 0, max: 7},
+	}
+}
+
+// ParseCron validates a five-field cron expression and works out its systemd
+// calendar event. Only numeric fields are accepted; names such as "mon" and
+// macros such as "@daily" are not.
+func ParseCron(expr string) (Spec, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields()) {
+		return Spec{}, fmt.Errorf("cron expression %q must have 5 fields (minute hour day-of-month month day-of-week)", expr)
+	}
+	for i, f := range cronFields() {
+		if err := validateCronField(fields[i], f); err != nil {
+			return Spec{}, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+	}
+	return Spec{Cron: strings.Join(fields, " "), OnCalendar: onCalendar(fields)}, nil
+}
+
+func validateCronField(field string, f cronField) error {
+	for _, item := range strings.Split(field, ",") {
+		m := cronItem.FindStringSubmatch(item)
+		if m == nil {
+			return fmt.Errorf("invalid %s %q", f.name, item)
+		}
+		if m[2] != "" {
+			if step, _ := strconv.Atoi(m[2]); step == 0 {
+				return fmt.Errorf("invalid %s %q: the step must be > 0", f.name, item)
+			}
+		}
+		if m[1] == "*" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(m[1], "-")
+		if !isRange {
+			hi = lo
+		}
+		from, errFrom := strconv.Atoi(lo)
+		to, errTo := strconv.Atoi(hi)
+		if errFrom != nil || errTo != nil || from < f.min || to > f.max || from > to {
+			return fmt.Errorf("invalid %s %q: must be within %d-%d", f.name, item, f.min, f.max)
+		}
+	}
+	return nil
+}
+
+// weekdays are systemd's day names, indexed by cron's day-of-week number.
+func weekdays() []string {
+	return []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+}
+
+// onCalendar converts validated cron fields to a systemd calendar event, or
+// returns "" when systemd can't express them: a range with a step, a stepped
+// day of week, or both day of month and day of week restricted (cron runs the
+// job on either, systemd only on both).
+func onCalendar(fields []string) string {
+	minute, hour, dom, month, dow := fields[0], fields[1], fields[2], fields[3], fields[4]
+	if dom != "*" && dow != "*" {
+		return ""
+	}
+	calendarField := func(field string, first int) (string, bool) {
+		if field == "*" {
+			return "*", true
+		}
+		items := strings.Split(field, ",")
+		for i, item := range items {
+			m := cronItem.FindStringSubmatch(item)
+			value, step := m[1], m[2]
+			if step != "" && strings.Contains(value, "-") {
+				return "", false
+			}
+			if value == "*" {
+				value = strconv.Itoa(first)
+			}
+			lo, hi, isRange := strings.Cut(value, "-")
+			lo, hi = padTwo(lo), padTwo(hi)
+			switch {
+			case isRange:
+				items[i] = lo + ".." + hi
+			case step != "":
+				items[i] = lo + "/" + step
+			default:
+				items[i] = lo
+			}
+		}
+		return strings.Join(items, ","), true
+	}
+
+	parts := make([]string, 0, 4)
+	for _, f := range []struct {
+		field string
+		first int
+	}{{minute, 0}, {hour, 0}, {dom, 1}, {month, 1}} {
+		v, ok := calendarField(f.field, f.first)
+		if !ok {
+			return ""
+		}
+		parts = append(parts, v)
+	}
+	event := fmt.Sprintf("*-%s-%s %s:%s:00", parts[3], parts[2], parts[1], parts[0])
+	if dow == "*" {
+		return event
+	}
+	days := strings.Split(dow, ",")
+	for i, item := range days {
+		if strings.Contains(item, "/") || item == "*" {
+			return ""
+		}
+		lo, hi, isRange := strings.Cut(item, "-")
+		from, _ := strconv.Atoi(lo)
+		days[i] = weekdays()[from]
+		if isRange {
+			to, _ := strconv.Atoi(hi)
+			days[i] += ".." + weekdays()[to]
+		}
+	}
+	return strings.Join(days, ",") + " " + event
+}
+
+func padTwo(s string) string {
+	if len(s) == 1 {
+		return "0" + s
+	}
+	return s
+}
+
+// Job is the command the scheduler runs.
+type Job struct {
+	// Exe is the absolute path of the gup executable.
+	Exe string
+	// Args are the arguments given to Exe.
+	Args []string
+	// Env holds KEY=VALUE pairs set for the job. Neither systemd nor cron
+	// gives a job the login shell's environment, so PATH and the Go variables
+	// are recorded when the job is installed.
+	Env []string
+}
+
+// Runner runs an external command with stdin as its input and returns what it
+// printed to STDOUT.
+type Runner func(ctx context.Context, stdin, name string, args ...string) (string, error)
+
+// execRunner runs the command with os/exec. The error includes STDERR.
+func execRunner(ctx context.Context, stdin, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...) //#nosec G204 -- only systemctl and crontab with arguments built here
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.String(), fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, msg)
+		}
+		return stdout.String(), fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return stdout.String(), nil
+}
+
+// Manager installs, removes and reports on the scheduled job.
+type Manager struct {
+	// Run runs systemctl and crontab.
+	Run Runner
+	// UnitDir is the systemd user unit directory.
+	UnitDir string
+	// GOOS is the operating system; systemd is only tried on linux.
+	GOOS string
+}
+
+// NewManager returns a Manager for the running system.
+func NewManager() Manager {
+	return Manager{
+		Run:     execRunner,
+		UnitDir: filepath.Join(xdg.ConfigHome, "systemd", "user"),
+		GOOS:    runtime.GOOS,
+	}
+}
+
+// Status describes the installed job.
+type Status struct {
+	// Backend is "" when no job is installed.
+	Backend Backend
+	// Schedule is the timer's OnCalendar or the crontab entry's cron
+	// expression.
+	Schedule string
+	// Command is the command line the job runs.
+	Command string
+	// NextRun is when systemd will next run the job; it is empty for cron.
+	NextRun string
+}
+
+func (m Manager) servicePath() string { return filepath.Join(m.UnitDir, UnitName+".service") }
+func (m Manager) timerPath() string   { return filepath.Join(m.UnitDir, UnitName+".timer") }
+
+// systemdAvailable reports whether a systemd user manager is running.
+func (m Manager) systemdAvailable(ctx context.Context) bool {
+	if m.GOOS != "linux" {
+		return false
+	}
+	_, err := m.Run(ctx, "", "systemctl", "--user", "show-environment")
+	return err == nil
+}
+
+// Install installs and enables the job, replacing one installed before. A
+// systemd timer is used when systemd is running and can express spec;
+// otherwise the job goes into the crontab. A job left in the other backend by
+// an earlier install is removed.
+func (m Manager) Install(ctx context.Context, spec Spec, job Job) (Backend, error) {
+	if m.GOOS == "windows" {
+		return "", errors.New("gup schedule supports systemd and cron, which Windows does not have; use Task Scheduler to run 'gup update' instead")
+	}
+	if spec.OnCalendar != "" && m.systemdAvailable(ctx) {
+		if err := m.installSystemd(ctx, spec, job); err != nil {
+			return "", err
+		}
+		if _, err := m.removeCron(ctx); err != nil {
+			return BackendSystemd, fmt.Errorf("installed the systemd timer, but can't remove the old crontab entry: %w", err)
+		}
+		return BackendSystemd, nil
+	}
+	if err := m.installCron(ctx, spec, job); err != nil {
+		return "", err
+	}
+	if _, err := m.removeSystemd(ctx); err != nil {
+		return BackendCron, fmt.Errorf("installed the crontab entry, but can't remove the old systemd timer: %w", err)
+	}
+	return BackendCron, nil
+}
+
+// Remove removes the job from every backend it is installed in and returns
+// those backends.
+func (m Manager) Remove(ctx context.Context) ([]Backend, error) {
+	removed := []Backend{}
+	ok, err := m.removeSystemd(ctx)
+	if err != nil {
+		return removed, err
+	}
+	if ok {
+		removed = append(removed, BackendSystemd)
+	}
+	ok, err = m.removeCron(ctx)
+	if err != nil {
+		return removed, err
+	}
+	if ok {
+		removed = append(removed, BackendCron)
+	}
+	return removed, nil
+}
+
+// Status reports the installed job. The systemd timer wins when, unusually,
+// both backends have one.
+func (m Manager) Status(ctx context.Context) (Status, error) {
+	if timer, err := os.ReadFile(m.timerPath()); err == nil {
+		service, err := os.ReadFile(m.servicePath())
+		if err != nil {
+			return Status{}, fmt.Errorf("can't read %s: %w", m.servicePath(), err)
+		}
+		st := Status{
+			Backend:  BackendSystemd,
+			Schedule: unitValue(string(timer), "OnCalendar"),
+			Command:  unitValue(string(service), "ExecStart"),
+		}
+		if m.systemdAvailable(ctx) {
+			next, err := m.Run(ctx, "", "systemctl", "--user", "show", UnitName+".timer", "--property=NextElapseUSecRealtime", "--value")
+			if err == nil {
+				st.NextRun = strings.TrimSpace(next)
+			}
+		}
+		return st, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return Status{}, fmt.Errorf("can't read %s: %w", m.timerPath(), err)
+	}
+
+	crontab, err := m.readCrontab(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	block := cronBlockIn(crontab)
+	if len(block) == 0 {
+		return Status{}, nil
+	}
+	fields := strings.Fields(block[0])
+	if len(fields) < len(cronFields()) {
+		return Status{}, fmt.Errorf("can't parse the gup entry in the crontab: %q", block[0])
+	}
+	return Status{
+		Backend:  BackendCron,
+		Schedule: strings.Join(fields[:len(cronFields())], " "),
+		Command:  strings.Join(fields[len(cronFields()):], " "),
+	}, nil
+}
+
+// unitValue returns the value of the first KEY= line in a unit file.
+func unitValue(unit, key string) string {
+	for _, line := range strings.Split(unit, "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), key+"="); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// ServiceUnit renders the systemd service that runs job once.
+func ServiceUnit(job Job) string {
+	var b strings.Builder
+	b.WriteString("# Managed by 'gup schedule'; 'gup schedule remove' deletes it.\n")
+	b.WriteString("[Unit]\nDescription=Run gup on a schedule\n\n[Service]\nType=oneshot\n")
+	for _, kv := range job.Env {
+		_, _ = fmt.Fprintf(&b, "Environment=%s\n", systemdQuote(kv))
+	}
+	args := make([]string, 0, len(job.Args)+1)
+	for _, a := range append([]string{job.Exe}, job.Args...) {
+		args = append(args, systemdQuote(a))
+	}
+	_, _ = fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(args, " "))
+	return b.String()
+}
+
+// TimerUnit renders the systemd timer that starts the service. Persistent
+// makes a run missed while the machine was off happen at the next boot.
+func TimerUnit(spec Spec) string {
+	return fmt.Sprintf(`# Managed by 'gup schedule'; 'gup schedule remove' deletes it.
+[Unit]
+Description=Run gup on a schedule
+
+[Timer]
+OnCalendar=%s
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`, spec.OnCalendar)
+}
+
+// systemdQuote quotes a word for a unit file: '%' and '$' are escaped because
+// systemd expands them, and words with spaces or quotes are double-quoted.
+func systemdQuote(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	s = strings.ReplaceAll(s, "$", "$$")
+	if !strings.ContainsAny(s, " \t\"'\\") {
+		return s
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// CronBlock renders the crontab lines of job, between the markers that let
+// gup find and replace them later.
+func CronBlock(spec Spec, job Job) string {
+	words := make([]string, 0, len(job.Env)+len(job.Args)+1)
+	for _, kv := range job.Env {
+		key, value, _ := strings.Cut(kv, "=")
+		words = append(words, key+"="+shellQuote(value))
+	}
+	for _, a := range append([]string{job.Exe}, job.Args...) {
+		words = append(words, shellQuote(a))
+	}
+	// cron turns an unescaped '%' into a newline.
+	line := strings.ReplaceAll(strings.Join(words, " "), "%", `\%`)
+	return cronBegin + "\n" + spec.Cron + " " + line + "\n" + cronEnd + "\n"
+}
+
+// shellQuote single-quotes s for sh unless it only holds safe characters.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-./:=,+@") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ReplaceCronBlock returns crontab with gup's block replaced by block, or
+// removed when block is empty. A crontab without gup's block gets block
+// appended. Every other line is kept as it is.
+func ReplaceCronBlock(crontab, block string) string {
+	var b strings.Builder
+	inBlock := false
+	for _, line := range strings.SplitAfter(crontab, "\n") {
+		switch strings.TrimSpace(line) {
+		case cronBegin:
+			inBlock = true
+			continue
+		case cronEnd:
+			if inBlock {
+				inBlock = false
+				continue
+			}
+		}
+		if !inBlock && line != "" {
+			b.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				b.WriteString("\n")
+			}
+		}
+	}
+	return b.String() + block
+}
+
+// cronBlockIn returns the entry lines of gup's block in crontab.
+func cronBlockIn(crontab string) []string {
+	lines := []string{}
+	inBlock := false
+	for _, line := range strings.Split(crontab, "\n") {
+		switch strings.TrimSpace(line) {
+		case cronBegin:
+			inBlock = true
+		case cronEnd:
+			inBlock = false
+		default:
+			if inBlock && strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines
+}
+
+func (m Manager) installSystemd(ctx context.Context, spec Spec, job Job) error {
+	if err := os.MkdirAll(m.UnitDir, 0o750); err != nil {
+		return fmt.Errorf("can't create %s: %w", m.UnitDir, err)
+	}
+	if err := os.WriteFile(m.servicePath(), []byte(ServiceUnit(job)), 0o600); err != nil {
+		return fmt.Errorf("can't write %s: %w", m.servicePath(), err)
+	}
+	if err := os.WriteFile(m.timerPath(), []byte(TimerUnit(spec)), 0o600); err != nil {
+		return fmt.Errorf("can't write %s: %w", m.timerPath(), err)
+	}
+	if _, err := m.Run(ctx, "", "systemctl", "--user", "daemon-reload"); err != nil {
+		return err
+	}
+	if _, err := m.Run(ctx, "", "systemctl", "--user", "enable", UnitName+".timer"); err != nil {
+		return err
+	}
+	// restart, not start, so a reinstall picks up a changed OnCalendar.
+	_, err := m.Run(ctx, "", "systemctl", "--user", "restart", UnitName+".timer")
+	return err
+}
+
+// removeSystemd disables the timer and deletes both units. It reports whether
+// there was anything to remove.
+func (m Manager) removeSystemd(ctx context.Context) (bool, error) {
+	found := false
+	for _, path := range []string{m.timerPath(), m.servicePath()} {
+		if _, err := os.Stat(path); err == nil {
+			found = true
+		}
+	}
+	if !found {
+		return false, nil
+	}
+	available := m.systemdAvailable(ctx)
+	if available {
+		if _, err := m.Run(ctx, "", "systemctl", "--user", "disable", "--now", UnitName+".timer"); err != nil {
+			return false, err
+		}
+	}
+	for _, path := range []string{m.timerPath(), m.servicePath()} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return false, fmt.Errorf("can't remove %s: %w", path, err)
+		}
+	}
+	if available {
+		if _, err := m.Run(ctx, "", "systemctl", "--user", "daemon-reload"); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// readCrontab returns the user's crontab, which is empty when there is none
+// or when cron is not installed.
+func (m Manager) readCrontab(ctx context.Context) (string, error) {
+	out, err := m.Run(ctx, "", "crontab", "-l")
+	if err == nil {
+		return out, nil
+	}
+	if errors.Is(err, exec.ErrNotFound) || strings.Contains(err.Error(), "no crontab") {
+		return "", nil
+	}
+	return "", err
+}
+
+func (m Manager) installCron(ctx context.Context, spec Spec, job Job) error {
+	out, err := m.Run(ctx, "", "crontab", "-l")
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return errors.New("neither a systemd user session nor the crontab command is available")
+		}
+		if !strings.Contains(err.Error(), "no crontab") {
+			return err
+		}
+		out = ""
+	}
+	_, err = m.Run(ctx, ReplaceCronBlock(out, CronBlock(spec, job)), "crontab", "-")
+	return err
+}
+
+// removeCron deletes gup's block from the crontab. It reports whether there
+// was one.
+func (m Manager) removeCron(ctx context.Context) (bool, error) {
+	crontab, err := m.readCrontab(ctx)
+	if err != nil {
+		return false, err
+	}
+	if len(cronBlockIn(crontab)) == 0 && !strings.Contains(crontab, cronBegin) {
+		return false, nil
+	}
+	if _, err := m.Run(ctx, ReplaceCronBlock(crontab, ""), "crontab", "-"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
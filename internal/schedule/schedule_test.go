@@ -0,0 +1,266 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseCron(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr    string
+		want    Spec
+		wantErr bool
+	}{
+		{expr: "0 3 * * *", want: Spec{Cron: "0 3 * * *", OnCalendar: "*-*-* 03:00:00"}},
+		{expr: " 30  4 * *  1-5 ", want: Spec{Cron: "30 4 * * 1-5", OnCalendar: "Mon..Fri *-*-* 04:30:00"}},
+		{expr: "*/15 * * * *", want: Spec{Cron: "*/15 * * * *", OnCalendar: "*-*-* *:00/15:00"}},
+		{expr: "0 0 1,15 * *", want: Spec{Cron: "0 0 1,15 * *", OnCalendar: "*-*-01,15 00:00:00"}},
+		{expr: "0 12 * * 0,7", want: Spec{Cron: "0 12 * * 0,7", OnCalendar: "Sun,Sun *-*-* 12:00:00"}},
+		// systemd can't express these, so they are kept for cron only.
+		{expr: "0 3 1 * 1", want: Spec{Cron: "0 3 1 * 1"}},
+		{expr: "0 8-18/2 * * *", want: Spec{Cron: "0 8-18/2 * * *"}},
+		{expr: "0 3 * * */2", want: Spec{Cron: "0 3 * * */2"}},
+		{expr: "0 3 * *", wantErr: true},
+		{expr: "@daily", wantErr: true},
+		{expr: "60 3 * * *", wantErr: true},
+		{expr: "0 3 * * mon", wantErr: true},
+		{expr: "0 5-3 * * *", wantErr: true},
+		{expr: "*/0 3 * * *", wantErr: true},
+		{expr: "0 3 0 * *", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseCron(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCron() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ParseCron() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestServiceUnit(t *testing.T) {
+	t.Parallel()
+
+	got := ServiceUnit(Job{
+		Exe:  "/home/me/go bin/gup",
+		Args: []string{"schedule", "run", "--check-only"},
+		Env:  []string{"PATH=/usr/bin:/home/me/go/bin", "GOFLAGS=-ldflags=-X a=100%"},
+	})
+	for _, want := range []string{
+		"Type=oneshot\n",
+		"Environment=PATH=/usr/bin:/home/me/go/bin\n",
+		`Environment="GOFLAGS=-ldflags=-X a=100%%"` + "\n",
+		`ExecStart="/home/me/go bin/gup" schedule run --check-only` + "\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ServiceUnit() lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestCronBlock(t *testing.T) {
+	t.Parallel()
+
+	got := CronBlock(Daily(), Job{
+		Exe:  "/home/me/go/bin/gup",
+		Args: []string{"schedule", "run"},
+		Env:  []string{"PATH=/usr/bin:/bin", "GOPRIVATE=it's%private"},
+	})
+	want := cronBegin + "\n" +
+		`0 3 * * * PATH=/usr/bin:/bin GOPRIVATE='it'\''s\%private' /home/me/go/bin/gup schedule run` + "\n" +
+		cronEnd + "\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CronBlock() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReplaceCronBlock(t *testing.T) {
+	t.Parallel()
+
+	block := CronBlock(Weekly(), Job{Exe: "/bin/gup", Args: []string{"schedule", "run"}})
+	mine := "MAILTO=me\n15 * * * * backup\n"
+
+	added := ReplaceCronBlock(mine, block)
+	if want := mine + block; added != want {
+		t.Errorf("add: got\n%s\nwant\n%s", added, want)
+	}
+	replaced := ReplaceCronBlock(added+"0 0 * * * other\n", CronBlock(Daily(), Job{Exe: "/bin/gup"}))
+	if strings.Count(replaced, cronBegin) != 1 || !strings.Contains(replaced, "0 3 * * * /bin/gup\n") ||
+		!strings.Contains(replaced, "0 0 * * * other\n") {
+		t.Errorf("replace: got\n%s", replaced)
+	}
+	if removed := ReplaceCronBlock(added, ""); removed != mine {
+		t.Errorf("remove: got\n%s\nwant\n%s", removed, mine)
+	}
+	if got := ReplaceCronBlock("no newline at end", ""); got != "no newline at end\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+// fakeSystem records the commands a Manager runs and keeps a crontab.
+type fakeSystem struct {
+	systemd bool
+	cron    bool
+	crontab string
+	calls   []string
+}
+
+func (f *fakeSystem) run(_ context.Context, stdin, name string, args ...string) (string, error) {
+	call := strings.Join(append([]string{name}, args...), " ")
+	f.calls = append(f.calls, call)
+	switch name {
+	case "systemctl":
+		if !f.systemd {
+			return "", errors.New("systemctl: Failed to connect to bus")
+		}
+		if call == "systemctl --user show gup-update.timer --property=NextElapseUSecRealtime --value" {
+			return "Mon 2026-10-19 03:00:00 UTC\n", nil
+		}
+		return "", nil
+	case "crontab":
+		if !f.cron {
+			return "", fmt.Errorf("crontab: %w", exec.ErrNotFound)
+		}
+		if args[0] == "-l" {
+			if f.crontab == "" {
+				return "", errors.New("crontab -l: exit status 1: no crontab for me")
+			}
+			return f.crontab, nil
+		}
+		f.crontab = stdin
+		return "", nil
+	}
+	return "", fmt.Errorf("unexpected command %s", call)
+}
+
+func newTestManager(t *testing.T, f *fakeSystem) Manager {
+	t.Helper()
+	return Manager{Run: f.run, UnitDir: filepath.Join(t.TempDir(), "systemd", "user"), GOOS: "linux"}
+}
+
+func TestManager_systemd(t *testing.T) {
+	t.Parallel()
+
+	f := &fakeSystem{systemd: true, cron: true, crontab: "MAILTO=me\n"}
+	m := newTestManager(t, f)
+	job := Job{Exe: "/bin/gup", Args: []string{"schedule", "run"}}
+
+	backend, err := m.Install(context.Background(), Weekly(), job)
+	if err != nil || backend != BackendSystemd {
+		t.Fatalf("Install() = %q, %v; want systemd", backend, err)
+	}
+	for _, want := range []string{"systemctl --user daemon-reload", "systemctl --user enable gup-update.timer", "systemctl --user restart gup-update.timer"} {
+		if !slices.Contains(f.calls, want) {
+			t.Errorf("Install() did not run %q: %v", want, f.calls)
+		}
+	}
+
+	st, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Status{Backend: BackendSystemd, Schedule: "Mon *-*-* 03:00:00", Command: "/bin/gup schedule run", NextRun: "Mon 2026-10-19 03:00:00 UTC"}
+	if diff := cmp.Diff(want, st); diff != "" {
+		t.Errorf("Status() mismatch (-want +got):\n%s", diff)
+	}
+
+	removed, err := m.Remove(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]Backend{BackendSystemd}, removed); diff != "" {
+		t.Errorf("Remove() mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := os.Stat(m.timerPath()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("timer unit still exists: %v", err)
+	}
+	if f.crontab != "MAILTO=me\n" {
+		t.Errorf("crontab changed: %q", f.crontab)
+	}
+}
+
+func TestManager_cronFallback(t *testing.T) {
+	t.Parallel()
+
+	f := &fakeSystem{cron: true, crontab: "MAILTO=me\n"}
+	m := newTestManager(t, f)
+	job := Job{Exe: "/bin/gup", Args: []string{"schedule", "run"}}
+
+	backend, err := m.Install(context.Background(), Daily(), job)
+	if err != nil || backend != BackendCron {
+		t.Fatalf("Install() = %q, %v; want cron", backend, err)
+	}
+	// Installing again replaces the entry instead of adding a second one.
+	if _, err := m.Install(context.Background(), Weekly(), job); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(f.crontab, cronBegin) != 1 || !strings.HasPrefix(f.crontab, "MAILTO=me\n") {
+		t.Errorf("crontab = %q", f.crontab)
+	}
+
+	st, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Status{Backend: BackendCron, Schedule: "0 3 * * 1", Command: "/bin/gup schedule run"}
+	if diff := cmp.Diff(want, st); diff != "" {
+		t.Errorf("Status() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := m.Remove(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if f.crontab != "MAILTO=me\n" {
+		t.Errorf("crontab after Remove() = %q", f.crontab)
+	}
+	if st, err := m.Status(context.Background()); err != nil || st.Backend != "" {
+		t.Errorf("Status() after Remove() = %+v, %v", st, err)
+	}
+}
+
+func TestManager_cronOnlyExpression(t *testing.T) {
+	t.Parallel()
+
+	f := &fakeSystem{systemd: true, cron: true}
+	m := newTestManager(t, f)
+	spec, err := ParseCron("0 3 1 * 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend, err := m.Install(context.Background(), spec, Job{Exe: "/bin/gup"})
+	if err != nil || backend != BackendCron {
+		t.Errorf("Install() = %q, %v; want cron", backend, err)
+	}
+}
+
+func TestManager_noScheduler(t *testing.T) {
+	t.Parallel()
+
+	m := newTestManager(t, &fakeSystem{})
+	if _, err := m.Install(context.Background(), Daily(), Job{Exe: "/bin/gup"}); err == nil {
+		t.Error("Install() without systemd or cron succeeded")
+	}
+	m.GOOS = "windows"
+	if _, err := m.Install(context.Background(), Daily(), Job{Exe: "/bin/gup"}); err == nil {
+		t.Error("Install() on windows succeeded")
+	}
+	removed, err := m.Remove(context.Background())
+	if err != nil || len(removed) != 0 {
+		t.Errorf("Remove() = %v, %v; want nothing removed", removed, err)
+	}
+}
@@ -0,0 +1,136 @@
+// Package updateplan reads and writes the update plan that
+// 'gup update --plan' writes and 'gup apply' installs. A plan records, for
+// every binary that needs an update, the exact version it was installed at
+// when the plan was made and the exact version to install, so the update can
+// be reviewed before it runs and applied later without resolving any channel
+// again.
+package updateplan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nao1215/gup/internal/fileutil"
+	"github.com/nao1215/gup/internal/goutil"
+)
+
+// schemaVersion is the plan schema written by this gup.
+const schemaVersion = 1
+
+// Plan is the content of a plan file. Its field names are part of the public
+// contract.
+type Plan struct {
+	SchemaVersion int       `json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Updates       []Entry   `json:"updates"`
+	// Channels maps every planned binary to its update channel. It is set only
+	// when the plan was made with --main, --master or --latest, so apply saves
+	// the channels to gup.json as 'gup update' would.
+	Channels map[string]string `json:"channels,omitempty"`
+}
+
+// Entry is one planned update.
+type Entry struct {
+	// Binary is the binary's file name in $GOBIN when the plan was made.
+	Binary     string `json:"binary"`
+	ImportPath string `json:"import_path"`
+	// From is the installed version when the plan was made.
+	From string `json:"from"`
+	// To is the exact version to install.
+	To      string `json:"to"`
+	Channel string `json:"channel"`
+}
+
+// New returns an empty plan created at now.
+func New(now time.Time) Plan {
+	return Plan{SchemaVersion: schemaVersion, CreatedAt: now.UTC().Truncate(time.Second), Updates: []Entry{}}
+}
+
+// Read reads and validates the plan at path.
+func Read(path string) (Plan, error) {
+	raw, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return Plan{}, fmt.Errorf("can't read %s: %w", path, err)
+	}
+	var plan Plan
+	if err := json.Unmarshal(raw, &plan); err != nil {
+		return Plan{}, fmt.Errorf("%s is not valid JSON: %w", path, err)
+	}
+	if plan.SchemaVersion != schemaVersion {
+		return Plan{}, fmt.Errorf("%s has unsupported schema_version: %d (supported: %d)", path, plan.SchemaVersion, schemaVersion)
+	}
+	seen := map[string]bool{}
+	for i, e := range plan.Updates {
+		if err := validate(e); err != nil {
+			return Plan{}, fmt.Errorf("%s: update %d: %w", path, i, err)
+		}
+		if seen[e.Binary] {
+			return Plan{}, fmt.Errorf("%s: %s is planned more than once", path, e.Binary)
+		}
+		seen[e.Binary] = true
+	}
+	return plan, nil
+}
+
+func validate(e Entry) error {
+	if e.Binary == "" || e.Binary != filepath.Base(e.Binary) || strings.ContainsAny(e.Binary, `/\:`) || e.Binary == ".." {
+		return fmt.Errorf("invalid binary name %q", e.Binary)
+	}
+	if strings.TrimSpace(e.ImportPath) == "" {
+		return fmt.Errorf("%s has no import_path", e.Binary)
+	}
+	if strings.TrimSpace(e.From) == "" {
+		return fmt.Errorf("%s has no from version", e.Binary)
+	}
+	// The plan is only worth reviewing if it names exactly what will be
+	// installed: a channel keyword would be resolved again at apply time.
+	if err := goutil.ValidatePinnedVersion(e.To); err != nil {
+		return fmt.Errorf("%s: to: %w", e.Binary, err)
+	}
+	if _, err := goutil.ParseConfigChannel(e.Channel); err != nil {
+		return fmt.Errorf("%s: %w", e.Binary, err)
+	}
+	return nil
+}
+
+// Write writes plan to path atomically.
+func Write(path string, plan Plan) (err error) {
+	if plan.Updates == nil {
+		return errors.New("plan has no update list")
+	}
+	raw, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't marshal the plan: %w", err)
+	}
+	raw = append(raw, '\n')
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("can't create temp file for %s: %w", path, err)
+	}
+	tmpPath := file.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+	if _, err = file.Write(raw); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("can't write %s: %w", tmpPath, err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("can't write %s: %w", tmpPath, err)
+	}
+	if err = os.Chmod(tmpPath, fileutil.FileModeCreatingFile); err != nil {
+		return fmt.Errorf("can't write %s: %w", tmpPath, err)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("can't write %s: %w", path, err)
+	}
+	return nil
+}
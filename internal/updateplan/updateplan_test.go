@@ -0,0 +1,67 @@
+package updateplan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteRead(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	plan := New(time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC))
+	plan.Updates = append(plan.Updates, Entry{
+		Binary: "tool", ImportPath: "example.com/tool", From: "v1.0.0", To: "v1.1.0", Channel: "latest",
+	})
+	plan.Channels = map[string]string{"tool": "latest"}
+	if err := Write(path, plan); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if diff := cmp.Diff(plan, got); diff != "" {
+		t.Errorf("Read() mismatch (-want +got):\n%s", diff)
+	}
+	if !got.CreatedAt.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("CreatedAt = %v, want second precision", got.CreatedAt)
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "invalid JSON", content: "{"},
+		{name: "unsupported schema", content: `{"schema_version":9,"updates":[]}`},
+		{name: "path in binary name", content: `{"schema_version":1,"updates":[{"binary":"../evil","import_path":"x","from":"v1","to":"v2"}]}`},
+		{name: "missing import path", content: `{"schema_version":1,"updates":[{"binary":"tool","from":"v1","to":"v2"}]}`},
+		{name: "channel keyword as target", content: `{"schema_version":1,"updates":[{"binary":"tool","import_path":"x","from":"v1","to":"latest"}]}`},
+		{name: "unknown channel", content: `{"schema_version":1,"updates":[{"binary":"tool","import_path":"x","from":"v1","to":"v2","channel":"nightly"}]}`},
+		{
+			name:    "duplicate binary",
+			content: `{"schema_version":1,"updates":[{"binary":"tool","import_path":"x","from":"v1","to":"v2"},{"binary":"tool","import_path":"y","from":"v1","to":"v2"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "plan.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Read(path); err == nil {
+				t.Fatal("Read() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Package updaterun reads and writes the state of a 'gup update' run, kept in
+// gup's state directory while the run goes on. The state records the binaries
+// the run selected, the exact version each one resolved to and which of them
+// are done, so 'gup update --resume' can continue an interrupted run with the
+// remaining binaries at the same versions.
+package updaterun
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nao1215/gup/internal/fileutil"
+)
+
+// schemaVersion is the state schema written by this gup.
+const schemaVersion = 1
+
+// FileName is the state file in gup's state directory.
+const FileName = "update-run.json"
+
+// ErrNoRun means there is no state file: no run was interrupted, or the last
+// one finished.
+var ErrNoRun = errors.New("no interrupted 'gup update' to resume")
+
+// State is the content of the state file.
+type State struct {
+	SchemaVersion int       `json:"schema_version"`
+	StartedAt     time.Time `json:"started_at"`
+	// GOBIN is the directory the run installed into. A run is only resumed
+	// into the same directory.
+	GOBIN    string  `json:"gobin"`
+	Packages []Entry `json:"packages"`
+	// Channels maps every binary to its update channel. It is set only when
+	// the run saves channels to gup.json (--main, --master, --latest or a
+	// saved 'update -i' choice), so the resumed run saves them too.
+	Channels map[string]string `json:"channels,omitempty"`
+	// Full reports that the run covers every installed binary, so finishing
+	// it without errors leaves the whole tool set up to date.
+	Full bool `json:"full,omitempty"`
+}
+
+// Entry is one binary of the run.
+type Entry struct {
+	Binary     string `json:"binary"`
+	ImportPath string `json:"import_path"`
+	ModulePath string `json:"module_path,omitempty"`
+	// From is the version the binary was installed at when the run started.
+	From string `json:"from"`
+	// To is the exact version the run resolved for the binary; empty until it
+	// was resolved.
+	To      string `json:"to,omitempty"`
+	Channel string `json:"channel"`
+	// Status is the result status (see the --json statuses) once the binary
+	// is done, "error" when it failed, and empty while it is pending.
+	Status string `json:"status,omitempty"`
+}
+
+// StatusError is the Status of a binary that failed. It is retried on resume.
+const StatusError = "error"
+
+// Done reports whether e finished without an error.
+func (e Entry) Done() bool {
+	return e.Status != "" && e.Status != StatusError
+}
+
+// New returns the state of a run started at now, installing into gobin.
+func New(now time.Time, gobin string) State {
+	return State{SchemaVersion: schemaVersion, StartedAt: now.UTC().Truncate(time.Second), GOBIN: gobin, Packages: []Entry{}}
+}
+
+// Remaining returns the entries that are not done, in run order.
+func (s State) Remaining() []Entry {
+	left := []Entry{}
+	for _, e := range s.Packages {
+		if !e.Done() {
+			left = append(left, e)
+		}
+	}
+	return left
+}
+
+// Read reads the state file at path. It returns ErrNoRun when there is none.
+func Read(path string) (State, error) {
+	raw, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return State{}, ErrNoRun
+	}
+	if err != nil {
+		return State{}, fmt.Errorf("can't read %s: %w", path, err)
+	}
+	var s State
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return State{}, fmt.Errorf("%s is not valid JSON: %w", path, err)
+	}
+	if s.SchemaVersion != schemaVersion {
+		return State{}, fmt.Errorf("%s has unsupported schema_version: %d (supported: %d)", path, s.SchemaVersion, schemaVersion)
+	}
+	for i, e := range s.Packages {
+		if e.Binary == "" || e.Binary != filepath.Base(e.Binary) || e.ImportPath == "" {
+			return State{}, fmt.Errorf("%s: package %d has no valid binary name or import path", path, i)
+		}
+	}
+	return s, nil
+}
+
+// Write replaces the state file at path with s. The file is written to a
+// temporary file and renamed, so a run killed while writing leaves the last
+// state intact.
+func Write(path string, s State) (err error) {
+	raw, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't marshal the update state: %w", err)
+	}
+	raw = append(raw, '\n')
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, fileutil.FileModeCreatingDir); err != nil {
+		return fmt.Errorf("can't create %s: %w", dir, err)
+	}
+	file, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("can't create temp file for %s: %w", path, err)
+	}
+	tmpPath := file.Name()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+	if _, err = file.Write(raw); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("can't write %s: %w", tmpPath, err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("can't write %s: %w", tmpPath, err)
+	}
+	if err = os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("can't write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Remove deletes the state file at path, if there is one.
+func Remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package updaterun
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteRead(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state", FileName)
+	s := New(time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC), "/home/me/go/bin")
+	s.Packages = append(s.Packages,
+		Entry{Binary: "air", ImportPath: "example.com/air", From: "v1.0.0", To: "v1.1.0", Channel: "latest", Status: "updated"},
+		Entry{Binary: "gopls", ImportPath: "example.com/gopls", From: "v0.1.0", Channel: "main", Status: StatusError},
+		Entry{Binary: "lazygit", ImportPath: "example.com/lazygit", From: "v2.0.0", To: "v2.1.0", Channel: "latest"},
+	)
+	if err := Write(path, s); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if diff := cmp.Diff(s, got); diff != "" {
+		t.Errorf("Read() mismatch (-want +got):\n%s", diff)
+	}
+	var left []string
+	for _, e := range got.Remaining() {
+		left = append(left, e.Binary)
+	}
+	if diff := cmp.Diff([]string{"gopls", "lazygit"}, left); diff != "" {
+		t.Errorf("Remaining() mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := Remove(path); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := Remove(path); err != nil {
+		t.Errorf("Remove() of a removed file error = %v", err)
+	}
+	if _, err := Read(path); !errors.Is(err, ErrNoRun) {
+		t.Errorf("Read() after Remove() error = %v, want ErrNoRun", err)
+	}
+}
+
+func TestRead_invalid(t *testing.T) {
+	t.Parallel()
+
+	for name, content := range map[string]string{
+		"not JSON":       "{",
+		"unknown schema": `{"schema_version": 9, "packages": []}`,
+		"path as binary": `{"schema_version": 1, "packages": [{"binary": "../air", "import_path": "example.com/air"}]}`,
+		"no import path": `{"schema_version": 1, "packages": [{"binary": "air"}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), FileName)
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Read(path); err == nil || errors.Is(err, ErrNoRun) {
+				t.Errorf("Read() error = %v, want a parse error", err)
+			}
+		})
+	}
+}
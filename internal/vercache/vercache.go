@@ -15,6 +15,7 @@ import (
 	"sync"
 
 	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/retry"
 )
 
 // errPinnedNotResolvable is returned if a pinned channel ever reaches the
@@ -24,14 +25,16 @@ import (
 var errPinnedNotResolvable = errors.New("pinned channel has no resolvable version; install the recorded version directly")
 
 // Resolver looks up the version that the given update channel would install for
-// modulePath. It mirrors the install-time policy of 'gup update'.
-type Resolver func(ctx context.Context, modulePath string, channel goutil.UpdateChannel) (string, error)
+// modulePath, running the go command with toolchain (see
+// goutil.GetVerWithContext). It mirrors the install-time policy of 'gup update'.
+type Resolver func(ctx context.Context, modulePath string, channel goutil.UpdateChannel, toolchain string) (string, error)
 
 // Cache deduplicates concurrent Resolver calls per (module path, channel).
 // When multiple goroutines request the same key, only one lookup runs; the
 // others wait and share the result.
 type Cache struct {
 	resolve Resolver
+	retry   retry.Policy
 	mu      sync.Mutex
 	entries map[string]*entry
 }
@@ -45,16 +48,23 @@ type entry struct {
 	err      error
 }
 
-// New returns a Cache backed by resolve.
-func New(resolve Resolver) *Cache {
-	return &Cache{resolve: resolve, entries: make(map[string]*entry)}
+// New returns a Cache backed by resolve, which retries a transient failure of
+// resolve by policy.
+func New(resolve Resolver, policy retry.Policy) *Cache {
+	return &Cache{resolve: resolve, retry: policy, entries: make(map[string]*entry)}
 }
 
 // Get returns the resolved version for modulePath on the requested update
 // channel. Results are cached per (module path, channel) pair so that, for
 // example, a package tracked on @main is not confused with the same module
 // queried on @latest. Context failures are not cached, so a later call retries.
-func (c *Cache) Get(ctx context.Context, modulePath string, channel goutil.UpdateChannel) (string, error) {
+// A transient failure is retried by the policy of the Cache before it is
+// cached, and the retries are added to retries unless it is nil.
+//
+// The lookup runs with toolchain. A pair looked up for packages built with
+// different toolchains is still looked up once, with the toolchain of the
+// first caller: the version a channel resolves to does not depend on it.
+func (c *Cache) Get(ctx context.Context, modulePath string, channel goutil.UpdateChannel, toolchain string, retries *retry.Counter) (string, error) {
 	channel = goutil.NormalizeUpdateChannel(string(channel))
 	key := modulePath + "@" + string(channel)
 
@@ -89,7 +99,12 @@ func (c *Cache) Get(ctx context.Context, modulePath string, channel goutil.Updat
 		e.waitCh = make(chan struct{})
 		e.mu.Unlock()
 
-		version, err := c.resolve(ctx, modulePath, channel)
+		var version string
+		err := retry.Do(ctx, c.retry, retries, func(ctx context.Context) error {
+			var err error
+			version, err = c.resolve(ctx, modulePath, channel, toolchain)
+			return err
+		})
 
 		e.mu.Lock()
 		e.fetching = false
@@ -119,11 +134,11 @@ func (c *Cache) Get(ctx context.Context, modulePath string, channel goutil.Updat
 }
 
 // GetLatestFunc resolves a module's version on the @latest channel.
-type GetLatestFunc func(ctx context.Context, modulePath string) (string, error)
+type GetLatestFunc func(ctx context.Context, modulePath, toolchain string) (string, error)
 
 // GetByRefFunc resolves a module's version at an explicit ref (e.g. "main",
 // "master").
-type GetByRefFunc func(ctx context.Context, modulePath, ref string) (string, error)
+type GetByRefFunc func(ctx context.Context, modulePath, ref, toolchain string) (string, error)
 
 // ChannelResolver builds a Resolver implementing gup's install-time channel
 // policy from the underlying version lookups:
@@ -137,10 +152,10 @@ type GetByRefFunc func(ctx context.Context, modulePath, ref string) (string, err
 // version is never silently resolved (#340), and a canceled/expired context is
 // never retried on @master.
 func ChannelResolver(getLatest GetLatestFunc, getByRef GetByRefFunc) Resolver {
-	return func(ctx context.Context, modulePath string, channel goutil.UpdateChannel) (string, error) {
+	return func(ctx context.Context, modulePath string, channel goutil.UpdateChannel, toolchain string) (string, error) {
 		switch goutil.NormalizeUpdateChannel(string(channel)) {
 		case goutil.UpdateChannelMain:
-			ver, err := getByRef(ctx, modulePath, string(goutil.UpdateChannelMain))
+			ver, err := getByRef(ctx, modulePath, string(goutil.UpdateChannelMain), toolchain)
 			if err == nil {
 				return ver, nil
 			}
@@ -154,18 +169,18 @@ func ChannelResolver(getLatest GetLatestFunc, getByRef GetByRefFunc) Resolver {
 			if !goutil.IsBranchNotFound(err, string(goutil.UpdateChannelMain)) {
 				return "", err
 			}
-			return getByRef(ctx, modulePath, string(goutil.UpdateChannelMaster))
+			return getByRef(ctx, modulePath, string(goutil.UpdateChannelMaster), toolchain)
 		case goutil.UpdateChannelMaster:
-			return getByRef(ctx, modulePath, string(goutil.UpdateChannelMaster))
+			return getByRef(ctx, modulePath, string(goutil.UpdateChannelMaster), toolchain)
 		case goutil.UpdateChannelPinned:
 			// A pinned package is installed at its exact recorded version and must
 			// never be resolved against the proxy; callers handle it before reaching
 			// the cache. Surface a clear error instead of silently resolving @latest.
 			return "", errPinnedNotResolvable
 		case goutil.UpdateChannelLatest:
-			return getLatest(ctx, modulePath)
+			return getLatest(ctx, modulePath, toolchain)
 		default:
-			return getLatest(ctx, modulePath)
+			return getLatest(ctx, modulePath, toolchain)
 		}
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/nao1215/gup/internal/goutil"
+	"github.com/nao1215/gup/internal/retry"
 )
 
 const (
@@ -20,17 +21,17 @@ func TestCache_Get_cachesNonContextError(t *testing.T) {
 
 	networkErr := errors.New("network unavailable")
 	callCount := 0
-	cache := New(func(context.Context, string, goutil.UpdateChannel) (string, error) {
+	cache := New(func(context.Context, string, goutil.UpdateChannel, string) (string, error) {
 		callCount++
 		return "", networkErr
-	})
+	}, retry.Policy{})
 
-	_, err := cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest)
+	_, err := cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest, "", nil)
 	if !errors.Is(err, networkErr) {
 		t.Fatalf("Get() error = %v, want %v", err, networkErr)
 	}
 
-	_, err = cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest)
+	_, err = cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest, "", nil)
 	if !errors.Is(err, networkErr) {
 		t.Fatalf("Get() cached error = %v, want %v", err, networkErr)
 	}
@@ -43,20 +44,20 @@ func TestCache_Get_doesNotCacheContextError(t *testing.T) {
 	t.Parallel()
 
 	callCount := 0
-	cache := New(func(ctx context.Context, _ string, _ goutil.UpdateChannel) (string, error) {
+	cache := New(func(ctx context.Context, _ string, _ goutil.UpdateChannel, _ string) (string, error) {
 		callCount++
 		if callCount == 1 {
 			return "", context.Canceled
 		}
 		return testVersion, nil
-	})
+	}, retry.Policy{})
 
-	if _, err := cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest); !errors.Is(err, context.Canceled) {
+	if _, err := cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest, "", nil); !errors.Is(err, context.Canceled) {
 		t.Fatalf("Get() error = %v, want %v", err, context.Canceled)
 	}
 
 	// A context failure must not be cached: the next call retries and succeeds.
-	got, err := cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest)
+	got, err := cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest, "", nil)
 	if err != nil {
 		t.Fatalf("Get() retry error = %v, want nil", err)
 	}
@@ -73,18 +74,18 @@ func TestCache_Get_cachesPerChannel(t *testing.T) {
 
 	var mu sync.Mutex
 	calls := map[goutil.UpdateChannel]int{}
-	cache := New(func(_ context.Context, _ string, channel goutil.UpdateChannel) (string, error) {
+	cache := New(func(_ context.Context, _ string, channel goutil.UpdateChannel, _ string) (string, error) {
 		mu.Lock()
 		calls[channel]++
 		mu.Unlock()
 		return string(channel), nil
-	})
+	}, retry.Policy{})
 
 	for range 2 {
-		if got, _ := cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest); got != string(goutil.UpdateChannelLatest) {
+		if got, _ := cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest, "", nil); got != string(goutil.UpdateChannelLatest) {
 			t.Fatalf("Get(latest) = %q", got)
 		}
-		if got, _ := cache.Get(context.Background(), testModule, goutil.UpdateChannelMain); got != string(goutil.UpdateChannelMain) {
+		if got, _ := cache.Get(context.Background(), testModule, goutil.UpdateChannelMain, "", nil); got != string(goutil.UpdateChannelMain) {
 			t.Fatalf("Get(main) = %q", got)
 		}
 	}
@@ -99,17 +100,17 @@ func TestCache_Get_waiterContextCanceled(t *testing.T) {
 
 	started := make(chan struct{})
 	release := make(chan struct{})
-	cache := New(func(context.Context, string, goutil.UpdateChannel) (string, error) {
+	cache := New(func(context.Context, string, goutil.UpdateChannel, string) (string, error) {
 		close(started)
 		<-release
 		return testVersion, nil
-	})
+	}, retry.Policy{})
 
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		_, _ = cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest)
+		_, _ = cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest, "", nil)
 	}()
 
 	select {
@@ -120,7 +121,7 @@ func TestCache_Get_waiterContextCanceled(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
-	if _, err := cache.Get(ctx, testModule, goutil.UpdateChannelLatest); !errors.Is(err, context.Canceled) {
+	if _, err := cache.Get(ctx, testModule, goutil.UpdateChannelLatest, "", nil); !errors.Is(err, context.Canceled) {
 		t.Fatalf("Get() waiter error = %v, want %v", err, context.Canceled)
 	}
 
@@ -143,12 +144,12 @@ func TestCache_Get_waiterSharesFetchedResult(t *testing.T) {
 	started := make(chan struct{})
 	release := make(chan struct{})
 	callCount := 0
-	cache := New(func(context.Context, string, goutil.UpdateChannel) (string, error) {
+	cache := New(func(context.Context, string, goutil.UpdateChannel, string) (string, error) {
 		callCount++
 		close(started)
 		<-release
 		return testVersion, nil
-	})
+	}, retry.Policy{})
 
 	var wg sync.WaitGroup
 	wg.Add(2)
@@ -157,7 +158,7 @@ func TestCache_Get_waiterSharesFetchedResult(t *testing.T) {
 
 	go func() {
 		defer wg.Done()
-		v, err := cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest)
+		v, err := cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest, "", nil)
 		results <- v
 		errs <- err
 	}()
@@ -170,7 +171,7 @@ func TestCache_Get_waiterSharesFetchedResult(t *testing.T) {
 
 	go func() {
 		defer wg.Done()
-		v, err := cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest)
+		v, err := cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest, "", nil)
 		results <- v
 		errs <- err
 	}()
@@ -212,13 +213,13 @@ func TestChannelResolver(t *testing.T) {
 	t.Run("latest channel uses getLatest", func(t *testing.T) {
 		t.Parallel()
 		resolve := ChannelResolver(
-			func(context.Context, string) (string, error) { return latestVer, nil },
-			func(context.Context, string, string) (string, error) {
+			func(context.Context, string, string) (string, error) { return latestVer, nil },
+			func(context.Context, string, string, string) (string, error) {
 				t.Fatal("getByRef should not be called for @latest")
 				return "", nil
 			},
 		)
-		got, err := resolve(context.Background(), testModule, goutil.UpdateChannelLatest)
+		got, err := resolve(context.Background(), testModule, goutil.UpdateChannelLatest, "")
 		if err != nil || got != latestVer {
 			t.Fatalf("resolve(latest) = %q, %v; want %q, nil", got, err, latestVer)
 		}
@@ -227,15 +228,15 @@ func TestChannelResolver(t *testing.T) {
 	t.Run("main channel uses getByRef(main)", func(t *testing.T) {
 		t.Parallel()
 		resolve := ChannelResolver(
-			func(context.Context, string) (string, error) { return latestVer, nil },
-			func(_ context.Context, _ string, ref string) (string, error) {
+			func(context.Context, string, string) (string, error) { return latestVer, nil },
+			func(_ context.Context, _ string, ref, _ string) (string, error) {
 				if ref != string(goutil.UpdateChannelMain) {
 					t.Fatalf("getByRef ref = %q, want main", ref)
 				}
 				return mainVer, nil
 			},
 		)
-		got, err := resolve(context.Background(), testModule, goutil.UpdateChannelMain)
+		got, err := resolve(context.Background(), testModule, goutil.UpdateChannelMain, "")
 		if err != nil || got != mainVer {
 			t.Fatalf("resolve(main) = %q, %v; want %q, nil", got, err, mainVer)
 		}
@@ -245,8 +246,8 @@ func TestChannelResolver(t *testing.T) {
 		t.Parallel()
 		var refs []string
 		resolve := ChannelResolver(
-			func(context.Context, string) (string, error) { return latestVer, nil },
-			func(_ context.Context, _ string, ref string) (string, error) {
+			func(context.Context, string, string) (string, error) { return latestVer, nil },
+			func(_ context.Context, _ string, ref, _ string) (string, error) {
 				refs = append(refs, ref)
 				if ref == string(goutil.UpdateChannelMain) {
 					return "", errors.New("go: unknown revision main")
@@ -254,7 +255,7 @@ func TestChannelResolver(t *testing.T) {
 				return masterVer, nil
 			},
 		)
-		got, err := resolve(context.Background(), testModule, goutil.UpdateChannelMain)
+		got, err := resolve(context.Background(), testModule, goutil.UpdateChannelMain, "")
 		if err != nil || got != masterVer {
 			t.Fatalf("resolve(main->master) = %q, %v; want %q, nil", got, err, masterVer)
 		}
@@ -269,8 +270,8 @@ func TestChannelResolver(t *testing.T) {
 		buildErr := errors.New("build failed: compile error")
 		calls := 0
 		resolve := ChannelResolver(
-			func(context.Context, string) (string, error) { return latestVer, nil },
-			func(_ context.Context, _ string, ref string) (string, error) {
+			func(context.Context, string, string) (string, error) { return latestVer, nil },
+			func(_ context.Context, _ string, ref, _ string) (string, error) {
 				calls++
 				if ref == string(goutil.UpdateChannelMain) {
 					return "", buildErr
@@ -279,7 +280,7 @@ func TestChannelResolver(t *testing.T) {
 				return "", nil
 			},
 		)
-		_, err := resolve(context.Background(), testModule, goutil.UpdateChannelMain)
+		_, err := resolve(context.Background(), testModule, goutil.UpdateChannelMain, "")
 		if !errors.Is(err, buildErr) {
 			t.Fatalf("resolve(main) error = %v, want %v", err, buildErr)
 		}
@@ -294,8 +295,8 @@ func TestChannelResolver(t *testing.T) {
 		cancel()
 		calls := 0
 		resolve := ChannelResolver(
-			func(context.Context, string) (string, error) { return latestVer, nil },
-			func(_ context.Context, _ string, ref string) (string, error) {
+			func(context.Context, string, string) (string, error) { return latestVer, nil },
+			func(_ context.Context, _ string, ref, _ string) (string, error) {
 				calls++
 				if ref == string(goutil.UpdateChannelMaster) {
 					t.Fatal("getByRef(master) must not be called when the context is canceled")
@@ -303,7 +304,7 @@ func TestChannelResolver(t *testing.T) {
 				return "", errors.New("go: unknown revision main")
 			},
 		)
-		if _, err := resolve(ctx, testModule, goutil.UpdateChannelMain); err == nil {
+		if _, err := resolve(ctx, testModule, goutil.UpdateChannelMain, ""); err == nil {
 			t.Fatal("resolve(main) error = nil, want the main error")
 		}
 		if calls != 1 {
@@ -314,15 +315,15 @@ func TestChannelResolver(t *testing.T) {
 	t.Run("master channel uses getByRef(master)", func(t *testing.T) {
 		t.Parallel()
 		resolve := ChannelResolver(
-			func(context.Context, string) (string, error) { return latestVer, nil },
-			func(_ context.Context, _ string, ref string) (string, error) {
+			func(context.Context, string, string) (string, error) { return latestVer, nil },
+			func(_ context.Context, _ string, ref, _ string) (string, error) {
 				if ref != string(goutil.UpdateChannelMaster) {
 					t.Fatalf("getByRef ref = %q, want master", ref)
 				}
 				return masterVer, nil
 			},
 		)
-		got, err := resolve(context.Background(), testModule, goutil.UpdateChannelMaster)
+		got, err := resolve(context.Background(), testModule, goutil.UpdateChannelMaster, "")
 		if err != nil || got != masterVer {
 			t.Fatalf("resolve(master) = %q, %v; want %q, nil", got, err, masterVer)
 		}
@@ -335,10 +336,10 @@ func TestChannelResolver(t *testing.T) {
 func TestChannelResolver_pinnedNotResolvable(t *testing.T) {
 	t.Parallel()
 	resolve := ChannelResolver(
-		func(context.Context, string) (string, error) { return "v1.0.0", nil },
 		func(context.Context, string, string) (string, error) { return "v1.0.0", nil },
+		func(context.Context, string, string, string) (string, error) { return "v1.0.0", nil },
 	)
-	if _, err := resolve(context.Background(), "example.com/tool", goutil.UpdateChannelPinned); err == nil {
+	if _, err := resolve(context.Background(), "example.com/tool", goutil.UpdateChannelPinned, ""); err == nil {
 		t.Fatal("ChannelResolver() err = nil, want error for pinned channel")
 	}
 }
@@ -349,10 +350,10 @@ func TestChannelResolver_masterRoutesToRef(t *testing.T) {
 	t.Parallel()
 	var ref string
 	resolve := ChannelResolver(
-		func(context.Context, string) (string, error) { return "", nil },
-		func(_ context.Context, _, r string) (string, error) { ref = r; return "v2.0.0", nil },
+		func(context.Context, string, string) (string, error) { return "", nil },
+		func(_ context.Context, _, r, _ string) (string, error) { ref = r; return "v2.0.0", nil },
 	)
-	got, err := resolve(context.Background(), "example.com/tool", goutil.UpdateChannelMaster)
+	got, err := resolve(context.Background(), "example.com/tool", goutil.UpdateChannelMaster, "")
 	if err != nil {
 		t.Fatalf("ChannelResolver() err = %v", err)
 	}
@@ -360,3 +361,40 @@ func TestChannelResolver_masterRoutesToRef(t *testing.T) {
 		t.Fatalf("ChannelResolver() = %q via ref %q, want v2.0.0 via master", got, ref)
 	}
 }
+
+func TestCache_Get_retriesTransientError(t *testing.T) {
+	t.Parallel()
+
+	flaky := errors.New("dial tcp: connection refused")
+	callCount := 0
+	var toolchains []string
+	cache := New(func(_ context.Context, _ string, _ goutil.UpdateChannel, toolchain string) (string, error) {
+		callCount++
+		toolchains = append(toolchains, toolchain)
+		if callCount == 1 {
+			return "", flaky
+		}
+		return testVersion, nil
+	}, retry.Policy{
+		Retries:   1,
+		Backoff:   time.Millisecond,
+		Transient: func(err error) bool { return errors.Is(err, flaky) },
+	})
+
+	counter := &retry.Counter{}
+	got, err := cache.Get(context.Background(), testModule, goutil.UpdateChannelLatest, "go1.22.5", counter)
+	if err != nil || got != testVersion {
+		t.Fatalf("Get() = %q, %v; want %q, nil", got, err, testVersion)
+	}
+	if callCount != 2 {
+		t.Fatalf("resolver call count = %d, want 2", callCount)
+	}
+	if counter.Retries() != 1 {
+		t.Errorf("Retries() = %d, want 1", counter.Retries())
+	}
+	for _, toolchain := range toolchains {
+		if toolchain != "go1.22.5" {
+			t.Errorf("resolver toolchain = %q, want go1.22.5", toolchain)
+		}
+	}
+}